@@ -1,13 +1,12 @@
 package main
 
 import (
-	"log"
-
 	"github.com/lablabs/cloudflare-exporter/internal/cli"
+	"github.com/lablabs/cloudflare-exporter/internal/logging"
 )
 
 func main() {
 	if err := cli.Execute(); err != nil {
-		log.Fatalf("Application failed: %v", err)
+		logging.Fatal("Application failed", map[string]interface{}{"error": err.Error()})
 	}
 }
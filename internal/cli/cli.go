@@ -1,6 +1,15 @@
 package cli
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lablabs/cloudflare-exporter/internal/client"
+	cloudflareAPI "github.com/lablabs/cloudflare-exporter/internal/cloudflare"
 	"github.com/lablabs/cloudflare-exporter/internal/routes"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -12,8 +21,18 @@ func Execute() error {
 	var cmd = &cobra.Command{
 		Use:   "viper-test",
 		Short: "testing viper",
-		Run: func(_ *cobra.Command, _ []string) {
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := loadConfig(viper.GetViper(), viper.GetString("config"), viper.GetString("profile")); err != nil {
+				return err
+			}
+			if viper.GetBool("check") {
+				return runCheck(cmd.Context())
+			}
+			if viper.GetBool("push") {
+				return runPush(cmd.Context())
+			}
 			routes.RunExporter()
+			return nil
 		},
 	}
 
@@ -21,10 +40,24 @@ func Execute() error {
 
 	flags := cmd.Flags()
 
+	flags.String("config", "", "config file (YAML, JSON or TOML) with the same keys as the flags")
+	viper.BindEnv("config")
+
+	flags.String("profile", "", "name of the profiles.<name> section of the config file to merge over its top-level keys")
+	viper.BindEnv("profile")
+
 	flags.String("listen", ":8080", "listen on addr:port ( default :8080), omit addr to listen on all interfaces")
 	viper.BindEnv("listen")
 	viper.SetDefault("listen", ":8080")
 
+	flags.String("admin_listen", "", "serve /health, /ready and /debug/pprof on this addr:port instead of listen, empty keeps them on listen without pprof")
+	viper.BindEnv("admin_listen")
+	viper.SetDefault("admin_listen", "")
+
+	flags.String("gin_mode", "release", "gin mode: release, or debug to log routes and requests")
+	viper.BindEnv("gin_mode")
+	viper.SetDefault("gin_mode", "release")
+
 	flags.String("metrics_path", "/metrics", "path for metrics, default /metrics")
 	viper.BindEnv("metrics_path")
 	viper.SetDefault("metrics_path", "/metrics")
@@ -38,6 +71,17 @@ func Execute() error {
 	flags.String("cf_api_token", "", "cloudflare api token (preferred)")
 	viper.BindEnv("cf_api_token")
 
+	flags.String("cf_api_token_file", "", "file holding the cloudflare api token, reloaded when it changes; takes precedence over cf_api_token")
+	viper.BindEnv("cf_api_token_file")
+
+	flags.String("cf_graphql_endpoint", cloudflareAPI.DefaultGraphQLEndpoint, "cloudflare GraphQL API endpoint, override to use a proxy or mock")
+	viper.BindEnv("cf_graphql_endpoint")
+	viper.SetDefault("cf_graphql_endpoint", cloudflareAPI.DefaultGraphQLEndpoint)
+
+	flags.String("cf_api_base_url", cloudflareAPI.DefaultAPIBaseURL, "cloudflare REST API base URL, override for regional endpoints or gateways")
+	viper.BindEnv("cf_api_base_url")
+	viper.SetDefault("cf_api_base_url", cloudflareAPI.DefaultAPIBaseURL)
+
 	flags.String("cf_zones", "", "cloudflare zones to export, comma delimited list")
 	viper.BindEnv("cf_zones")
 	viper.SetDefault("cf_zones", "")
@@ -46,14 +90,70 @@ func Execute() error {
 	viper.BindEnv("cf_exclude_zones")
 	viper.SetDefault("cf_exclude_zones", "")
 
+	flags.String("cf_accounts", "", "cloudflare accounts to export account-level metrics for, comma delimited list")
+	viper.BindEnv("cf_accounts")
+	viper.SetDefault("cf_accounts", "")
+
+	flags.String("cf_exclude_accounts", "", "cloudflare accounts to exclude from account-level metrics, comma delimited list")
+	viper.BindEnv("cf_exclude_accounts")
+	viper.SetDefault("cf_exclude_accounts", "")
+
+	flags.Bool("account_type_label", true, "add the account_type label to account-level metrics")
+	viper.BindEnv("account_type_label")
+	viper.SetDefault("account_type_label", true)
+
+	flags.String("account_type_fallback", "standard", "account_type label value for accounts without a type, defaults to standard")
+	viper.BindEnv("account_type_fallback")
+	viper.SetDefault("account_type_fallback", "standard")
+
+	flags.Bool("exclude_paused_zones", true, "skip zones that are paused on Cloudflare, defaults to true")
+	viper.BindEnv("exclude_paused_zones")
+	viper.SetDefault("exclude_paused_zones", true)
+
+	flags.String("cf_zone_plans", "", "only export zones on these plans (e.g. enterprise,business), comma delimited list")
+	viper.BindEnv("cf_zone_plans")
+	viper.SetDefault("cf_zone_plans", "")
+
 	flags.Int("scrape_delay", 300, "scrape delay in seconds, defaults to 300")
 	viper.BindEnv("scrape_delay")
 	viper.SetDefault("scrape_delay", 300)
 
+	flags.Int("scrape_timeout", 60, "seconds after which a scrape cycle is cancelled as a whole, 0 disables, defaults to 60")
+	viper.BindEnv("scrape_timeout")
+	viper.SetDefault("scrape_timeout", 60)
+
+	flags.Int("backfill_minutes", 0, "minutes of history the first scrape after startup queries, 0 disables the backfill")
+	viper.BindEnv("backfill_minutes")
+	viper.SetDefault("backfill_minutes", 0)
+
+	flags.String("cf_zone_scrape_delays", "", "per-zone scrape delay overrides in seconds, comma delimited zoneID=seconds list")
+	viper.BindEnv("cf_zone_scrape_delays")
+	viper.SetDefault("cf_zone_scrape_delays", "")
+
+	flags.Bool("reset_stale_metrics", false, "delete the series of zones that are no longer scraped (removed from cf_zones or deleted)")
+	viper.BindEnv("reset_stale_metrics")
+	viper.SetDefault("reset_stale_metrics", false)
+
+	flags.String("cf_group_granularity", "1m", "HTTP groups table granularity (1m|1h); 1h queries the last complete hour and adds it once, defaults to 1m")
+	viper.BindEnv("cf_group_granularity")
+	viper.SetDefault("cf_group_granularity", "1m")
+
 	flags.Int("cf_batch_size", 10, "cloudflare zones batch size (1-10), defaults to 10")
 	viper.BindEnv("cf_batch_size")
 	viper.SetDefault("cf_batch_size", 10)
 
+	flags.Int("cf_max_zones", 0, "max zones to scrape per cycle after filtering, 0 for no limit")
+	viper.BindEnv("cf_max_zones")
+	viper.SetDefault("cf_max_zones", 0)
+
+	flags.Bool("cf_max_zones_rotate", false, "rotate through zones across scrapes when cf_max_zones truncates the list")
+	viper.BindEnv("cf_max_zones_rotate")
+	viper.SetDefault("cf_max_zones_rotate", false)
+
+	flags.Int("cf_stagger_cycles", 0, "spread zones over this many scrapes, each scrape querying about 1/N of them, 0 or 1 to query all zones every scrape")
+	viper.BindEnv("cf_stagger_cycles")
+	viper.SetDefault("cf_stagger_cycles", 0)
+
 	flags.Bool("free_tier", false, "scrape only metrics included in free plan")
 	viper.BindEnv("free_tier")
 	viper.SetDefault("free_tier", false)
@@ -66,14 +166,274 @@ func Execute() error {
 	viper.BindEnv("exclude_host")
 	viper.SetDefault("exclude_host", true)
 
+	flags.String("host_label_metrics", "", "metrics that keep the host label even with exclude_host, comma delimited list")
+	viper.BindEnv("host_label_metrics")
+	viper.SetDefault("host_label_metrics", "")
+
 	flags.Int("cf_query_limit", 1000, "query limit for cloudflare API")
 	viper.BindEnv("cf_query_limit")
 	viper.SetDefault("cf_query_limit", 1000)
 
+	for _, t := range cloudflareAPI.QueryLimitTypes {
+		name := "cf_query_limit_" + t
+		flags.Int(name, 0, fmt.Sprintf("query limit for %s queries, 0 uses cf_query_limit", t))
+		viper.BindEnv(name)
+		viper.SetDefault(name, 0)
+	}
+
 	flags.Bool("cf_http_status_group", false, "query limit for cloudflare API")
 	viper.BindEnv("cf_http_status_group")
 	viper.SetDefault("cf_http_status_group", false)
 
+	flags.String("cf_origin_error_statuses", "400,404,500,502,503,504,522,523,524", "origin response status codes to report as errors, comma delimited list")
+	viper.BindEnv("cf_origin_error_statuses")
+	viper.SetDefault("cf_origin_error_statuses", "400,404,500,502,503,504,522,523,524")
+
+	flags.Int("cf_request_timeout", 30, "cloudflare API request timeout in seconds (1-300), defaults to 30")
+	viper.BindEnv("cf_request_timeout")
+	viper.SetDefault("cf_request_timeout", 30)
+
+	flags.Int("cf_api_max_retries", 3, "attempts for listing zones and accounts before giving up, defaults to 3")
+	viper.BindEnv("cf_api_max_retries")
+	viper.SetDefault("cf_api_max_retries", 3)
+
+	flags.Int("cf_retry_budget", 60, "retries per minute shared by all Cloudflare API calls; further retries fail fast, 0 disables the budget")
+	viper.BindEnv("cf_retry_budget")
+	viper.SetDefault("cf_retry_budget", 60)
+
+	flags.Int("cf_api_retry_backoff", 2, "base backoff in seconds between zone/account listing and per-zone REST attempts, multiplied by the attempt number, defaults to 2")
+	viper.BindEnv("cf_api_retry_backoff")
+	viper.SetDefault("cf_api_retry_backoff", 2)
+
+	flags.Int("cf_circuit_breaker_threshold", 5, "consecutive Cloudflare API failures before calls are short-circuited, 0 disables the breaker")
+	viper.BindEnv("cf_circuit_breaker_threshold")
+	viper.SetDefault("cf_circuit_breaker_threshold", 5)
+
+	flags.Int("cf_circuit_breaker_cooldown", 60, "seconds the circuit breaker stays open before a probe request, defaults to 60")
+	viper.BindEnv("cf_circuit_breaker_cooldown")
+	viper.SetDefault("cf_circuit_breaker_cooldown", 60)
+
+	flags.Int("account_concurrency", 5, "max concurrent account-level jobs (workers, logpush, ...) per scrape (1-100), defaults to 5")
+	viper.BindEnv("account_concurrency")
+	viper.SetDefault("account_concurrency", 5)
+
+	flags.Int("zone_concurrency", 15, "max concurrent zone batch jobs per scrape (1-100), defaults to 15")
+	viper.BindEnv("zone_concurrency")
+	viper.SetDefault("zone_concurrency", 15)
+
+	flags.Int("rest_batch_size", 10, "zones per job for per-zone REST calls (SSL certificates, Page Shield), independent of cf_batch_size (1-100), defaults to 10")
+	viper.BindEnv("rest_batch_size")
+	viper.SetDefault("rest_batch_size", 10)
+
+	flags.Int("ssl_fetch_concurrency", 5, "max concurrent per-zone REST requests (SSL certificates, Page Shield) within a rest_batch_size batch (1-50), defaults to 5")
+	viper.BindEnv("ssl_fetch_concurrency")
+	viper.SetDefault("ssl_fetch_concurrency", 5)
+
+	flags.Int("ssl_fetch_timeout", 10, "SSL certificate request timeout in seconds (1-120), defaults to 10")
+	viper.BindEnv("ssl_fetch_timeout")
+	viper.SetDefault("ssl_fetch_timeout", 10)
+
+	flags.Int("ssl_fetch_retries", 3, "SSL certificate request attempts per zone (1-10), defaults to 3")
+	viper.BindEnv("ssl_fetch_retries")
+	viper.SetDefault("ssl_fetch_retries", 3)
+
+	flags.Int("http_max_idle_conns", client.DefaultMaxIdleConns, "max idle keep-alive connections across all hosts for REST calls, defaults to 100")
+	viper.BindEnv("http_max_idle_conns")
+	viper.SetDefault("http_max_idle_conns", client.DefaultMaxIdleConns)
+
+	flags.Int("http_max_idle_conns_per_host", client.DefaultMaxIdleConnsPerHost, "max idle keep-alive connections per host for REST calls, defaults to 10")
+	viper.BindEnv("http_max_idle_conns_per_host")
+	viper.SetDefault("http_max_idle_conns_per_host", client.DefaultMaxIdleConnsPerHost)
+
+	flags.Int("http_idle_conn_timeout", int(client.DefaultIdleConnTimeout/time.Second), "seconds an idle keep-alive connection is kept open, defaults to 90")
+	viper.BindEnv("http_idle_conn_timeout")
+	viper.SetDefault("http_idle_conn_timeout", int(client.DefaultIdleConnTimeout/time.Second))
+
+	flags.String("egress_client_cert", "", "PEM client certificate for an mTLS egress proxy")
+	viper.BindEnv("egress_client_cert")
+	viper.SetDefault("egress_client_cert", "")
+
+	flags.String("egress_client_key", "", "PEM client key for an mTLS egress proxy")
+	viper.BindEnv("egress_client_key")
+	viper.SetDefault("egress_client_key", "")
+
+	flags.String("egress_ca_bundle", "", "PEM CA bundle to trust in addition to the system roots")
+	viper.BindEnv("egress_ca_bundle")
+	viper.SetDefault("egress_ca_bundle", "")
+
+	flags.String("proxy_url", "", "proxy for Cloudflare API calls (http, https or socks5 URL), defaults to HTTP_PROXY/HTTPS_PROXY")
+	viper.BindEnv("proxy_url")
+	viper.SetDefault("proxy_url", "")
+
+	flags.String("user_agent", "", "User-Agent for Cloudflare API calls, defaults to cloudflare-exporter/<version>")
+	viper.BindEnv("user_agent")
+	viper.SetDefault("user_agent", "")
+
+	flags.Int("cf_host_top_n", 20, "max hosts per zone for per-host bandwidth, the rest are summed as host=\"other\", 0 for no limit")
+	viper.BindEnv("cf_host_top_n")
+	viper.SetDefault("cf_host_top_n", 20)
+
+	flags.Int("cf_asn_top_n", 20, "max source ASNs per zone for firewall events by ASN, the rest are summed as asn=\"other\", 0 for no limit")
+	viper.BindEnv("cf_asn_top_n")
+	viper.SetDefault("cf_asn_top_n", 20)
+
+	flags.Bool("enable_workers", true, "fetch Workers analytics for every account")
+	viper.BindEnv("enable_workers")
+	viper.SetDefault("enable_workers", true)
+
+	flags.Bool("enable_durable_objects", true, "fetch Durable Objects analytics for every account")
+	viper.BindEnv("enable_durable_objects")
+	viper.SetDefault("enable_durable_objects", true)
+
+	flags.Bool("enable_queues", true, "fetch Queues backlog for every account")
+	viper.BindEnv("enable_queues")
+	viper.SetDefault("enable_queues", true)
+
+	flags.Bool("enable_logpush", true, "fetch account-level logpush health for every account")
+	viper.BindEnv("enable_logpush")
+	viper.SetDefault("enable_logpush", true)
+
+	flags.Bool("enable_magic_transit", true, "fetch Magic Transit tunnel health for every account")
+	viper.BindEnv("enable_magic_transit")
+	viper.SetDefault("enable_magic_transit", true)
+
+	flags.Bool("enable_turnstile", true, "fetch Turnstile analytics for every account")
+	viper.BindEnv("enable_turnstile")
+	viper.SetDefault("enable_turnstile", true)
+
+	flags.Bool("enable_stream", true, "fetch Stream analytics for every account")
+	viper.BindEnv("enable_stream")
+	viper.SetDefault("enable_stream", true)
+
+	flags.Bool("enable_images", true, "fetch Images analytics for every account")
+	viper.BindEnv("enable_images")
+	viper.SetDefault("enable_images", true)
+
+	flags.Bool("zone_id_label", false, "add the zone_id label (zone tag) to the zone totals, which unlike zone survives a zone rename")
+	viper.BindEnv("zone_id_label")
+	viper.SetDefault("zone_id_label", false)
+
+	flags.Bool("sample_timestamps", false, "export the zone totals with the Cloudflare group datetime as sample timestamp instead of the scrape time")
+	viper.BindEnv("sample_timestamps")
+	viper.SetDefault("sample_timestamps", false)
+
+	flags.String("worker_script_pattern", "", "regex with the named groups name and environment splitting worker script names into script_name and environment labels")
+	viper.BindEnv("worker_script_pattern")
+	viper.SetDefault("worker_script_pattern", "")
+
+	flags.Bool("enable_path_metrics", false, "export requests per URL path (cloudflare_zone_requests_by_path_total), capped by cf_path_top_n")
+	viper.BindEnv("enable_path_metrics")
+	viper.SetDefault("enable_path_metrics", false)
+
+	flags.Bool("enable_waf_categories", false, "export firewall events per managed WAF rule category (cloudflare_zone_waf_category_events_total), fetching the managed rulesets of every zone hourly")
+	viper.BindEnv("enable_waf_categories")
+	viper.SetDefault("enable_waf_categories", false)
+
+	flags.Int("cf_path_top_n", 20, "max URL paths per zone for requests by path, the rest are summed as path=\"other\", 0 for no limit")
+	viper.BindEnv("cf_path_top_n")
+	viper.SetDefault("cf_path_top_n", 20)
+
+	flags.Int("content_type_top_n", 0, "max content types per zone for requests and bandwidth by content type, ranked by requests, the rest are summed as content_type=\"other\", 0 for no limit")
+	viper.BindEnv("content_type_top_n")
+	viper.SetDefault("content_type_top_n", 0)
+
+	flags.Bool("logpush_final_bool", false, "label logpush failed jobs with final=\"true\"/\"false\" instead of \"1\"/\"0\"")
+	viper.BindEnv("logpush_final_bool")
+	viper.SetDefault("logpush_final_bool", false)
+
+	flags.String("cf_colos", "", "only export colocation metrics for these colo codes (e.g. LAX,FRA,SIN), comma delimited list")
+	viper.BindEnv("cf_colos")
+	viper.SetDefault("cf_colos", "")
+
+	flags.Bool("apply_sampling", false, "multiply colocation counts by the sample interval to estimate true totals (estimates are approximate)")
+	viper.BindEnv("apply_sampling")
+	viper.SetDefault("apply_sampling", false)
+
+	flags.Bool("metrics_warmup_gate", false, "answer /metrics with 503 until the first scrape after startup has finished")
+	viper.BindEnv("metrics_warmup_gate")
+	viper.SetDefault("metrics_warmup_gate", false)
+
+	flags.Int("ready_max_staleness", 300, "max seconds since the last successful scrape before /ready reports not ready, defaults to 300")
+	viper.BindEnv("ready_max_staleness")
+	viper.SetDefault("ready_max_staleness", 300)
+
+	flags.String("otlp_endpoint", "", "OTLP/HTTP metrics URL (e.g. http://collector:4318/v1/metrics) to push metrics to after each scrape, unset disables")
+	viper.BindEnv("otlp_endpoint")
+	viper.SetDefault("otlp_endpoint", "")
+
+	flags.String("web_auth_token", "", "bearer token required for /snapshot, unset disables auth")
+	viper.BindEnv("web_auth_token")
+	viper.SetDefault("web_auth_token", "")
+
+	flags.String("log_level", "info", "log level (debug, info, warn, error), defaults to info")
+	viper.BindEnv("log_level")
+	viper.SetDefault("log_level", "info")
+
+	flags.String("log_format", "text", "log format (json|text), defaults to text")
+	viper.BindEnv("log_format")
+	viper.SetDefault("log_format", "text")
+
+	flags.Bool("check", false, "validate credentials and token permissions per metric family, then exit")
+	viper.BindEnv("check")
+	viper.SetDefault("check", false)
+
+	flags.Bool("push", false, "run a single scrape, push it to remote_write_url and/or pushgateway_url, then exit")
+	viper.BindEnv("push")
+	viper.SetDefault("push", false)
+
+	flags.String("remote_write_url", "", "Prometheus remote-write URL used by push mode")
+	viper.BindEnv("remote_write_url")
+	viper.SetDefault("remote_write_url", "")
+
+	flags.String("pushgateway_url", "", "Pushgateway base URL used by push mode")
+	viper.BindEnv("pushgateway_url")
+	viper.SetDefault("pushgateway_url", "")
+
+	flags.String("push_job", "cloudflare_exporter", "Pushgateway job name used by push mode, defaults to cloudflare_exporter")
+	viper.BindEnv("push_job")
+	viper.SetDefault("push_job", "cloudflare_exporter")
+
+	flags.String("push_headers", "", "extra headers sent with push requests (e.g. Authorization=Bearer xyz), comma delimited Name=Value list")
+	viper.BindEnv("push_headers")
+	viper.SetDefault("push_headers", "")
+
 	viper.BindPFlags(flags)
 	return cmd.Execute()
 }
+
+// runCheck reports which metric families the configured credentials can read.
+func runCheck(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := routes.ConfigureAPIClient(); err != nil {
+		return err
+	}
+	if path := viper.GetString("cf_api_token_file"); path != "" {
+		if err := cloudflareAPI.LoadTokenFile(path); err != nil {
+			return err
+		}
+	}
+
+	results, err := cloudflareAPI.CheckPermissions(ctx)
+	if err != nil {
+		return fmt.Errorf("credential check failed: %w", err)
+	}
+
+	for _, r := range results {
+		fmt.Printf("%s: %s\n", r.Family, r.Status())
+	}
+	return nil
+}
+
+// runPush scrapes once and pushes the result, stopping early on SIGINT/SIGTERM.
+func runPush(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return routes.RunPush(ctx)
+}
@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// loadConfig reads the config file at path into v and, when profile is set,
+// merges the profiles.<profile> section over the top-level keys. Flags and
+// environment variables still take precedence over both.
+func loadConfig(v *viper.Viper, path, profile string) error {
+	if path == "" {
+		if profile != "" {
+			return errors.New("--profile requires --config")
+		}
+		return nil
+	}
+
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("reading config %s: %w", path, err)
+	}
+	if profile == "" {
+		return nil
+	}
+
+	key := "profiles." + profile
+	if !v.IsSet(key) {
+		return fmt.Errorf("profile %q not found in %s", profile, path)
+	}
+	return v.MergeConfigMap(v.GetStringMap(key))
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+const profilesConfig = `
+listen: ":8080"
+cf_batch_size: 10
+zone_concurrency: 15
+profiles:
+  staging:
+    cf_batch_size: 5
+    listen: ":9090"
+  prod:
+    cf_batch_size: 2
+`
+
+func TestLoadConfig_SelectedProfileWins(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(profilesConfig), 0o600))
+
+	v := viper.New()
+	assert.NoError(t, loadConfig(v, path, "staging"))
+	assert.Equal(t, 5, v.GetInt("cf_batch_size"))
+	assert.Equal(t, ":9090", v.GetString("listen"))
+	// Keys the profile doesn't set keep the base value
+	assert.Equal(t, 15, v.GetInt("zone_concurrency"))
+
+	v = viper.New()
+	assert.NoError(t, loadConfig(v, path, "prod"))
+	assert.Equal(t, 2, v.GetInt("cf_batch_size"))
+	assert.Equal(t, ":8080", v.GetString("listen"))
+
+	// Environment variables override the profile
+	t.Setenv("CF_BATCH_SIZE", "7")
+	v = viper.New()
+	v.AutomaticEnv()
+	assert.NoError(t, loadConfig(v, path, "staging"))
+	assert.Equal(t, 7, v.GetInt("cf_batch_size"))
+
+	assert.Error(t, loadConfig(viper.New(), path, "missing"))
+	assert.Error(t, loadConfig(viper.New(), "", "staging"))
+}
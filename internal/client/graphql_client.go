@@ -3,6 +3,7 @@ package client
 import (
 	"context"
 	"fmt"
+	"net/http"
 
 	"github.com/machinebox/graphql"
 )
@@ -12,9 +13,13 @@ type GraphQLClient struct {
 	client *graphql.Client
 }
 
-// NewGraphQLClient creates and returns a new GraphQLClient for the specified endpoint.
-func NewGraphQLClient(endpoint string) *GraphQLClient {
-	client := graphql.NewClient(endpoint)
+// NewGraphQLClient creates and returns a new GraphQLClient for the specified
+// endpoint. Requests go through httpClient, or the shared transport if nil.
+func NewGraphQLClient(endpoint string, httpClient *http.Client) *GraphQLClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Transport: SharedTransport()}
+	}
+	client := graphql.NewClient(endpoint, graphql.WithHTTPClient(httpClient))
 	return &GraphQLClient{client: client}
 }
 
@@ -16,7 +16,8 @@ type RetryableClient struct {
 // NewRetryableClient handles retrying client with intervel.
 func NewRetryableClient(retryMax int, retryInterval time.Duration) *RetryableClient {
 	client := &http.Client{
-		Timeout: 10 * time.Second,
+		Timeout:   10 * time.Second,
+		Transport: SharedTransport(),
 	}
 	return &RetryableClient{client: client, retryMax: retryMax, retryInterval: retryInterval}
 }
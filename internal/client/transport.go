@@ -0,0 +1,160 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// Default connection pool settings for the shared REST transport.
+const (
+	DefaultMaxIdleConns        = 100
+	DefaultMaxIdleConnsPerHost = 10
+	DefaultIdleConnTimeout     = 90 * time.Second
+)
+
+var (
+	transportMu     sync.RWMutex
+	sharedTransport *http.Transport
+	userAgent       string
+)
+
+// TransportOptions configures the transport built by NewTransport.
+type TransportOptions struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	// TLSConfig replaces the default TLS settings when set.
+	TLSConfig *tls.Config
+	// Proxy replaces the HTTP_PROXY/HTTPS_PROXY environment behavior when set.
+	Proxy func(*http.Request) (*url.URL, error)
+}
+
+// NewTransport returns a keep-alive transport with the idle pool settings of opts.
+func NewTransport(opts TransportOptions) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DisableKeepAlives = false
+	t.MaxIdleConns = opts.MaxIdleConns
+	t.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	t.IdleConnTimeout = opts.IdleConnTimeout
+	if opts.TLSConfig != nil {
+		t.TLSClientConfig = opts.TLSConfig
+	}
+	if opts.Proxy != nil {
+		t.Proxy = opts.Proxy
+	}
+	return t
+}
+
+// NewProxyFunc returns a proxy selector that sends every request through
+// rawURL. Supported schemes are http, https and socks5. It returns nil when
+// rawURL is empty.
+func NewProxyFunc(rawURL string) (func(*http.Request) (*url.URL, error), error) {
+	if rawURL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse proxy url: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, expected http, https or socks5", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy url %q has no host", rawURL)
+	}
+	return http.ProxyURL(u), nil
+}
+
+// NewEgressTLSConfig builds the TLS config for an mTLS-authenticated egress
+// proxy from PEM files. It returns nil when no file is given.
+func NewEgressTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" && caFile == "" {
+		return nil, nil
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("egress client certificate and key must be set together")
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load egress client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read egress CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in egress CA bundle %s", caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// ConfigureTransport replaces the shared transport used for REST and GraphQL calls.
+func ConfigureTransport(opts TransportOptions) {
+	t := NewTransport(opts)
+
+	transportMu.Lock()
+	old := sharedTransport
+	sharedTransport = t
+	transportMu.Unlock()
+
+	if old != nil {
+		old.CloseIdleConnections()
+	}
+}
+
+// SetUserAgent sets the User-Agent sent on every request through
+// SharedTransport. An empty ua leaves the client libraries' defaults.
+func SetUserAgent(ua string) {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	userAgent = ua
+}
+
+// SharedTransport returns the transport shared by all API clients, or
+// http.DefaultTransport until ConfigureTransport has been called.
+func SharedTransport() http.RoundTripper {
+	transportMu.RLock()
+	defer transportMu.RUnlock()
+	var t http.RoundTripper = http.DefaultTransport
+	if sharedTransport != nil {
+		t = sharedTransport
+	}
+	if userAgent != "" {
+		return &userAgentTransport{next: t, userAgent: userAgent}
+	}
+	return t
+}
+
+// userAgentTransport overrides the User-Agent header, including the one set
+// by cloudflare-go, so API traffic can be attributed to the exporter.
+type userAgentTransport struct {
+	next      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.next.RoundTrip(req)
+}
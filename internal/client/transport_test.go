@@ -0,0 +1,183 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigureTransport(t *testing.T) {
+	defer func() { sharedTransport = nil }()
+
+	assert.Same(t, http.DefaultTransport, SharedTransport())
+
+	ConfigureTransport(TransportOptions{MaxIdleConns: 50, MaxIdleConnsPerHost: 25, IdleConnTimeout: 45 * time.Second})
+
+	tr, ok := SharedTransport().(*http.Transport)
+	assert.True(t, ok)
+	assert.Equal(t, 50, tr.MaxIdleConns)
+	assert.Equal(t, 25, tr.MaxIdleConnsPerHost)
+	assert.Equal(t, 45*time.Second, tr.IdleConnTimeout)
+	assert.False(t, tr.DisableKeepAlives)
+
+	r := NewRetryableClient(1, time.Millisecond)
+	assert.Same(t, tr, r.client.Transport)
+}
+
+// writeClientCert writes a self-signed client certificate and key to dir.
+func writeClientCert(t *testing.T, dir string) (certFile, keyFile string, cert *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	noError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "exporter"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	noError(t, err)
+	cert, err = x509.ParseCertificate(der)
+	noError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	noError(t, err)
+
+	certFile = filepath.Join(dir, "client.pem")
+	keyFile = filepath.Join(dir, "client-key.pem")
+	noError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	noError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile, cert
+}
+
+func TestEgressTLSConfig_MutualTLS(t *testing.T) {
+	defer func() { sharedTransport = nil }()
+
+	dir := t.TempDir()
+	certFile, keyFile, clientCert := writeClientCert(t, dir)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"viewer": {"zones": []}}}`))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	noError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o600))
+
+	fetch := func() error {
+		var resp interface{}
+		if err := NewGraphQLClient(srv.URL, nil).Query(`{ viewer { zones { zoneTag } } }`, &resp); err != nil {
+			return err
+		}
+		r, err := (&http.Client{Transport: SharedTransport()}).Get(srv.URL)
+		if err != nil {
+			return err
+		}
+		return r.Body.Close()
+	}
+
+	// Trusting the server is not enough without a client certificate
+	cfg, err := NewEgressTLSConfig("", "", caFile)
+	noError(t, err)
+	ConfigureTransport(TransportOptions{MaxIdleConns: DefaultMaxIdleConns, MaxIdleConnsPerHost: DefaultMaxIdleConnsPerHost, IdleConnTimeout: DefaultIdleConnTimeout, TLSConfig: cfg})
+	assert.Error(t, fetch())
+
+	cfg, err = NewEgressTLSConfig(certFile, keyFile, caFile)
+	noError(t, err)
+	ConfigureTransport(TransportOptions{MaxIdleConns: DefaultMaxIdleConns, MaxIdleConnsPerHost: DefaultMaxIdleConnsPerHost, IdleConnTimeout: DefaultIdleConnTimeout, TLSConfig: cfg})
+	assert.NoError(t, fetch())
+
+	_, err = NewEgressTLSConfig(certFile, "", "")
+	assert.Error(t, err)
+}
+
+func noError(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewProxyFunc_RoutesThroughProxy(t *testing.T) {
+	defer func() { sharedTransport = nil }()
+
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A forward proxy receives the absolute target URL
+		proxied = append(proxied, r.URL.String())
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"viewer": {"zones": []}}}`))
+	}))
+	defer proxy.Close()
+
+	proxyFunc, err := NewProxyFunc(proxy.URL)
+	noError(t, err)
+	ConfigureTransport(TransportOptions{MaxIdleConns: DefaultMaxIdleConns, MaxIdleConnsPerHost: DefaultMaxIdleConnsPerHost, IdleConnTimeout: DefaultIdleConnTimeout, Proxy: proxyFunc})
+
+	var resp interface{}
+	assert.NoError(t, NewGraphQLClient("http://api.cloudflare.invalid/graphql", nil).Query(`{ viewer { zones { zoneTag } } }`, &resp))
+	r, err := (&http.Client{Transport: SharedTransport()}).Get("http://api.cloudflare.invalid/client/v4/zones")
+	noError(t, err)
+	r.Body.Close()
+
+	assert.Equal(t, []string{
+		"http://api.cloudflare.invalid/graphql",
+		"http://api.cloudflare.invalid/client/v4/zones",
+	}, proxied)
+
+	_, err = NewProxyFunc("socks5://127.0.0.1:1080")
+	assert.NoError(t, err)
+	_, err = NewProxyFunc("ftp://proxy:21")
+	assert.Error(t, err)
+	proxyFunc, err = NewProxyFunc("")
+	assert.NoError(t, err)
+	assert.Nil(t, proxyFunc)
+}
+
+func TestSetUserAgent(t *testing.T) {
+	defer SetUserAgent("")
+
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("User-Agent"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {}}`))
+	}))
+	defer srv.Close()
+
+	SetUserAgent("cloudflare-exporter/1.11")
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	noError(t, err)
+	req.Header.Set("User-Agent", "cloudflare-go/v4")
+	r, err := (&http.Client{Transport: SharedTransport()}).Do(req)
+	noError(t, err)
+	r.Body.Close()
+
+	var resp interface{}
+	assert.NoError(t, NewGraphQLClient(srv.URL, nil).Query(`{ viewer { zones { zoneTag } } }`, &resp))
+
+	assert.Equal(t, []string{"cloudflare-exporter/1.11", "cloudflare-exporter/1.11"}, got)
+	// The caller's request is left untouched
+	assert.Equal(t, "cloudflare-go/v4", req.Header.Get("User-Agent"))
+}
@@ -6,11 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	cloudflare "github.com/cloudflare/cloudflare-go"
@@ -18,23 +19,41 @@ import (
 	"github.com/spf13/viper"
 	"golang.org/x/time/rate"
 
+	"github.com/lablabs/cloudflare-exporter/internal/client"
 	"github.com/lablabs/cloudflare-exporter/internal/limiter"
+	"github.com/lablabs/cloudflare-exporter/internal/logging"
 	"github.com/lablabs/cloudflare-exporter/internal/models"
-	logging "github.com/sirupsen/logrus"
-
-	_ "net/http/pprof"
 )
 
-func init() {
-	go func() {
-		log.Println(http.ListenAndServe("localhost:6060", nil))
-	}()
-}
+// DefaultGraphQLEndpoint is the Cloudflare GraphQL analytics API endpoint.
+const DefaultGraphQLEndpoint = "https://api.cloudflare.com/client/v4/graphql/"
+
+// DefaultAPIBaseURL is the Cloudflare REST API base URL.
+const DefaultAPIBaseURL = "https://api.cloudflare.com/client/v4"
 
 var (
-	cfGraphQLEndpoint = "https://api.cloudflare.com/client/v4/graphql/"
+	cfGraphQLEndpoint = DefaultGraphQLEndpoint
+	cfAPIBaseURL      = DefaultAPIBaseURL
 )
 
+// SetGraphQLEndpoint overrides the GraphQL endpoint used by all fetchers.
+// An empty endpoint restores DefaultGraphQLEndpoint.
+func SetGraphQLEndpoint(endpoint string) {
+	if endpoint == "" {
+		endpoint = DefaultGraphQLEndpoint
+	}
+	cfGraphQLEndpoint = endpoint
+}
+
+// SetAPIBaseURL overrides the REST API base URL used by all REST calls.
+// An empty URL restores DefaultAPIBaseURL.
+func SetAPIBaseURL(baseURL string) {
+	if baseURL == "" {
+		baseURL = DefaultAPIBaseURL
+	}
+	cfAPIBaseURL = strings.TrimRight(baseURL, "/")
+}
+
 // Cloudflare's API limits: 1200 requests/5min = 4 requests/sec (with burst of 2)
 var apiLimiter = rate.NewLimiter(rate.Every(250*time.Millisecond), 2) // 4 RPS, burst=2
 
@@ -42,16 +61,27 @@ func WaitForRateLimit(ctx context.Context) error {
 	return apiLimiter.Wait(ctx) // Call this before each API request
 }
 
-func FetchZones(ctx context.Context) ([]cloudflare.Zone, error) {
-	var api *cloudflare.API
-	var err error
+// setAuthHeaders sets the Cloudflare credentials on h, preferring the API
+// token over the legacy key and email pair.
+func setAuthHeaders(h http.Header) {
+	if token := apiToken(); token != "" {
+		h.Set("Authorization", "Bearer "+token)
+		return
+	}
+	h.Set("X-AUTH-EMAIL", viper.GetString("cf_api_email"))
+	h.Set("X-AUTH-KEY", viper.GetString("cf_api_key"))
+}
 
-	// Initialize the API client with appropriate credentials.
-	if token := viper.GetString("cf_api_token"); token != "" {
-		api, err = cloudflare.NewWithAPIToken(token)
-	} else {
-		api, err = cloudflare.New(viper.GetString("cf_api_key"), viper.GetString("cf_api_email"))
+// newAPIClient builds a cloudflare-go client from the configured credentials.
+func newAPIClient() (*cloudflare.API, error) {
+	if token := apiToken(); token != "" {
+		return cloudflare.NewWithAPIToken(token, cloudflare.BaseURL(cfAPIBaseURL), cloudflare.HTTPClient(apiHTTPClient()))
 	}
+	return cloudflare.New(viper.GetString("cf_api_key"), viper.GetString("cf_api_email"), cloudflare.BaseURL(cfAPIBaseURL), cloudflare.HTTPClient(apiHTTPClient()))
+}
+
+func FetchZones(ctx context.Context) ([]cloudflare.Zone, error) {
+	api, err := newAPIClient()
 	if err != nil {
 		logging.Error("Failed to initialize Cloudflare API client", map[string]interface{}{
 			"error": err.Error(),
@@ -61,15 +91,25 @@ func FetchZones(ctx context.Context) ([]cloudflare.Zone, error) {
 
 	logging.Info("Fetching zones from Cloudflare API", nil)
 
-	// Retry mechanism with exponential backoff
-	const maxRetries = 3
+	maxRetries := apiMaxRetries()
 	var zones []cloudflare.Zone
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		// Create a new context with a 30s timeout for each attempt
-		reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		// Every attempt counts against the shared Cloudflare API rate limit
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("rate limit wait failed: %w", err)
+		}
+		if err := apiBreaker.allow(); err != nil {
+			return nil, err
+		}
 
+		// Each attempt gets its own cf_request_timeout
+		reqCtx, cancel := withRequestTimeout(ctx, requestTimeout())
+
+		start := time.Now()
 		zones, err = api.ListZones(reqCtx)
+		observeAPIRequest("/zones", start, err)
+		recordAPIResult(reqCtx, err)
 		cancel()
 
 		if err == nil {
@@ -79,6 +119,12 @@ func FetchZones(ctx context.Context) ([]cloudflare.Zone, error) {
 			return zones, nil
 		}
 
+		// Invalid credentials will not recover on retry
+		if isAuthError(err) {
+			logging.ErrorErr("Cloudflare API rejected credentials while fetching zones", err)
+			return nil, wrapPermissionError(0, err)
+		}
+
 		// Handle timeout-specific errors separately
 		if errors.Is(err, context.DeadlineExceeded) {
 			logging.Warn("Cloudflare API request timed out", map[string]interface{}{
@@ -100,14 +146,13 @@ func FetchZones(ctx context.Context) ([]cloudflare.Zone, error) {
 			})
 		}
 
-		// Exponential backoff (2s, 4s, 6s)
-		time.Sleep(time.Duration(attempt*2) * time.Second)
-
-		// Backoff with context awareness
-		select {
-		case <-time.After(time.Duration(attempt*2) * time.Second):
-		case <-ctx.Done():
-			return nil, ctx.Err() // Respect parent cancellation
+		if attempt < maxRetries {
+			if err := spendRetry(err); err != nil {
+				return nil, err
+			}
+			if err := retryBackoff(ctx, attempt); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -120,13 +165,7 @@ func FetchZones(ctx context.Context) ([]cloudflare.Zone, error) {
 
 // FetchAccounts function returns accounts in an array with retry logic.
 func FetchAccounts(ctx context.Context) ([]cloudflare.Account, error) {
-	var api *cloudflare.API
-	var err error
-	if len(viper.GetString("cf_api_token")) > 0 {
-		api, err = cloudflare.NewWithAPIToken(viper.GetString("cf_api_token"))
-	} else {
-		api, err = cloudflare.New(viper.GetString("cf_api_key"), viper.GetString("cf_api_email"))
-	}
+	api, err := newAPIClient()
 	// Handle API client initialization error
 	if err != nil {
 		logging.Error("Failed to initialize Cloudflare API client", map[string]interface{}{
@@ -135,17 +174,27 @@ func FetchAccounts(ctx context.Context) ([]cloudflare.Account, error) {
 		return nil, err
 	}
 
-	// Define retry parameters
-	const maxRetries = 3
+	maxRetries := apiMaxRetries()
 	var accounts []cloudflare.Account
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		// Create a context with timeout to prevent hanging requests
-		reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		// Every attempt counts against the shared Cloudflare API rate limit
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("rate limit wait failed: %w", err)
+		}
+		if err := apiBreaker.allow(); err != nil {
+			return nil, err
+		}
 
+		// Each attempt gets its own cf_request_timeout
+		reqCtx, cancel := withRequestTimeout(ctx, requestTimeout())
+
+		start := time.Now()
 		accounts, _, err = api.Accounts(reqCtx, cloudflare.AccountsListParams{
 			PaginationOptions: cloudflare.PaginationOptions{PerPage: 100},
 		})
+		observeAPIRequest("/accounts", start, err)
+		recordAPIResult(reqCtx, err)
 		cancel()
 		if err == nil {
 			// Log success and return
@@ -155,14 +204,26 @@ func FetchAccounts(ctx context.Context) ([]cloudflare.Account, error) {
 			return accounts, nil
 		}
 
+		// Invalid credentials will not recover on retry
+		if isAuthError(err) {
+			logging.ErrorErr("Cloudflare API rejected credentials while fetching accounts", err)
+			return nil, wrapPermissionError(0, err)
+		}
+
 		// Log retry attempt
 		logging.Warn("Failed to fetch accounts from Cloudflare API, retrying...", map[string]interface{}{
 			"attempt": attempt,
 			"error":   err.Error(),
 		})
 
-		// Exponential backoff
-		time.Sleep(time.Duration(attempt*2) * time.Second)
+		if attempt < maxRetries {
+			if err := spendRetry(err); err != nil {
+				return nil, err
+			}
+			if err := retryBackoff(ctx, attempt); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// Log final failure
@@ -176,17 +237,19 @@ func FetchHTTPMetrics(ctx context.Context, zoneIDs []string) (*models.Cloudflare
 	if err := limiter.Wait(ctx); err != nil {
 		return nil, fmt.Errorf("rate limit wait failed: %w", err)
 	}
-	now := time.Now().Add(-time.Duration(viper.GetInt("scrape_delay")) * time.Second).UTC()
+	now := time.Now().Add(-ScrapeDelay(zoneIDs)).UTC()
 	s := 60 * time.Second
 	now = now.Truncate(s)
-	now1mAgo := now.Add(-60 * time.Second)
+	now1mAgo := now.Add(-ZoneQueryWindow())
 
-	request := graphql.NewRequest(`
-		query ($zoneIDs: [String!], $mintime: Time!, $maxtime: Time!, $limit: Int!)  {
+	httpMintime, httpMaxtime := HTTPGroupsWindow(zoneIDs)
+
+	request := graphql.NewRequest(withGroupGranularity(`
+		query ($zoneIDs: [String!], $mintime: Time!, $maxtime: Time!, $httpMintime: Time!, $httpMaxtime: Time!, $limit: Int!)  {
 			viewer {
 				zones(filter: { zoneTag_in: $zoneIDs }) {
 					zoneTag
-					httpRequests1mGroups(limit: $limit filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
+					httpRequests1mGroups(limit: $limit filter: { datetime_geq: $httpMintime, datetime_lt: $httpMaxtime }) {
 						uniq {
 							uniques
 						}
@@ -252,34 +315,29 @@ func FetchHTTPMetrics(ctx context.Context, zoneIDs []string) (*models.Cloudflare
 				}
 			}
 		}
-		`)
-	if len(viper.GetString("cf_api_token")) > 0 {
-		request.Header.Set("Authorization", "Bearer "+viper.GetString("cf_api_token"))
-	} else {
-		request.Header.Set("X-AUTH-EMAIL", viper.GetString("cf_api_email"))
-		request.Header.Set("X-AUTH-KEY", viper.GetString("cf_api_key"))
-	}
-	request.Var("limit", viper.GetInt("cf_query_limit"))
+		`))
+	setAuthHeaders(request.Header)
+	request.Var("limit", QueryLimit("http"))
 	request.Var("maxtime", now)
 	request.Var("mintime", now1mAgo)
+	request.Var("httpMaxtime", httpMaxtime)
+	request.Var("httpMintime", httpMintime)
 	request.Var("zoneIDs", zoneIDs)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second) // Set 10s timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
 	defer cancel()
 
-	graphqlClient := graphql.NewClient(cfGraphQLEndpoint)
-
 	// Log the query parameters for debugging
 	logging.Info("Fetching FetchHTTPMetrics from Cloudflare API", map[string]interface{}{
 		"zoneIDs":    zoneIDs,
-		"limit":      viper.GetInt("cf_query_limit"),
+		"limit":      QueryLimit("http"),
 		"maxtime":    now,
 		"mintime":    now1mAgo,
 		"time_range": fmt.Sprintf("%s - %s", now1mAgo, now),
 	})
 
 	var resp models.CloudflareResponseHTTPGroups
-	if err := graphqlClient.Run(ctx, request, &resp); err != nil {
+	if err := runGraphQL(ctx, "FetchHTTPMetrics", request, &resp); err != nil {
 		logging.Error("Failed to FetchHTTPMetrics", map[string]interface{}{
 			"error": err.Error(),
 		})
@@ -294,254 +352,547 @@ func FetchHTTPMetrics(ctx context.Context, zoneIDs []string) (*models.Cloudflare
 	return &resp, nil
 }
 
-func FetchFirewallMetrics(ctx context.Context, zoneIDs []string) (*models.CloudflareResponseFirewallGroups, error) {
-	now := time.Now().Add(-time.Duration(viper.GetInt("scrape_delay")) * time.Second).UTC()
+// FetchZoneAnalytics issues a single GraphQL request selecting the HTTP, firewall,
+// health check, origin status, edge status and rate limit groups for a zone batch.
+func FetchZoneAnalytics(ctx context.Context, zoneIDs []string) (*models.CloudflareResponseZoneAnalytics, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+	now := time.Now().Add(-ScrapeDelay(zoneIDs)).UTC()
 	s := 60 * time.Second
 	now = now.Truncate(s)
-	now1mAgo := now.Add(-60 * time.Second)
+	now1mAgo := now.Add(-ZoneQueryWindow())
 
-	request := graphql.NewRequest(`
-		query ($zoneIDs: [String!], $mintime: Time!, $maxtime: Time!, $limit: Int!)  {
+	httpMintime, httpMaxtime := HTTPGroupsWindow(zoneIDs)
+
+	request := graphql.NewRequest(withGroupGranularity(`
+		query ($zoneIDs: [String!], $mintime: Time!, $maxtime: Time!, $httpMintime: Time!, $httpMaxtime: Time!, $httpLimit: Int!, $firewallLimit: Int!, $healthCheckLimit: Int!, $adaptiveLimit: Int!, $statuses: [uint16!])  {
 			viewer {
 				zones(filter: { zoneTag_in: $zoneIDs }) {
 					zoneTag
-					firewallEventsAdaptiveGroups(limit: $limit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
+					httpRequests1mGroups(limit: $httpLimit filter: { datetime_geq: $httpMintime, datetime_lt: $httpMaxtime }) {
+						uniq {
+							uniques
+						}
+						sum {
+							browserMap {
+								pageViews
+								uaBrowserFamily
+							}
+							bytes
+							cachedBytes
+							cachedRequests
+							clientHTTPVersionMap {
+								clientHTTPProtocol
+								requests
+							}
+							clientSSLMap {
+								clientSSLProtocol
+								requests
+							}
+							contentTypeMap {
+								bytes
+								requests
+								edgeResponseContentTypeName
+							}
+							countryMap {
+								bytes
+								clientCountryName
+								requests
+								threats
+							}
+							encryptedBytes
+							encryptedRequests
+							ipClassMap {
+								ipType
+								requests
+							}
+							pageViews
+							requests
+							responseStatusMap {
+								edgeResponseStatus
+								requests
+							}
+							threatPathingMap {
+								requests
+								threatPathingName
+							}
+							threats
+						}
+						dimensions {
+							datetime
+						}
+					}
+					firewallEventsAdaptiveGroups(limit: $firewallLimit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
 						count
 						dimensions {
-						action
-						source
-						ruleId
-						clientRequestHTTPHost
-						clientCountryName
+							action
+							source
+							ruleId
+							clientRequestHTTPHost
+							clientCountryName
+						}
+					}
+					firewallEventsByKind: firewallEventsAdaptiveGroups(limit: $firewallLimit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
+						count
+						dimensions {
+							kind
+						}
+					}
+					firewallEventsByAsn: firewallEventsAdaptiveGroups(limit: $firewallLimit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
+						count
+						dimensions {
+							clientAsn
+							clientASNDescription
+							action
+						}
+					}
+					healthCheckEventsAdaptiveGroups(limit: $healthCheckLimit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
+						count
+						dimensions {
+							healthStatus
+							originIP
+							region
+							fqdn
+						}
+					}
+					httpRequestsAdaptiveGroups(limit: $adaptiveLimit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime, cacheStatus_notin: ["hit"], originResponseStatus_in: $statuses }) {
+						count
+						dimensions {
+							originResponseStatus
+							clientCountryName
+							clientRequestHTTPHost
+						}
+						avg {
+							originResponseDurationMs
+						}
+					}
+					httpRequestsOriginStatus: httpRequestsAdaptiveGroups(limit: $adaptiveLimit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime, cacheStatus_notin: ["hit"], originResponseStatus_geq: 100 }) {
+						count
+						dimensions {
+							originResponseStatus
+						}
+					}
+					httpRequestsOriginConnectivity: httpRequestsAdaptiveGroups(limit: $adaptiveLimit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime, edgeResponseStatus_geq: 520, edgeResponseStatus_leq: 527 }) {
+						count
+						dimensions {
+							edgeResponseStatus
+						}
+					}
+					httpRequestsCacheStatus: httpRequestsAdaptiveGroups(limit: $adaptiveLimit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
+						count
+						dimensions {
+							cacheStatus
+						}
+					}
+					httpRequestsMethod: httpRequestsAdaptiveGroups(limit: $adaptiveLimit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
+						count
+						dimensions {
+							clientRequestHTTPMethodName
+						}
+					}
+					httpRequestsHostBytes: httpRequestsAdaptiveGroups(limit: $adaptiveLimit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
+						sum {
+							edgeResponseBytes
+						}
+						dimensions {
+							clientRequestHTTPHost
+						}
+					}
+					httpRequestsEdgeCountryHost: httpRequestsAdaptiveGroups(limit: $adaptiveLimit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
+						count
+						dimensions {
+							edgeResponseStatus
+							clientCountryName
+							clientRequestHTTPHost
+						}
+					}
+					rateLimitEventsAdaptiveGroups: firewallEventsAdaptiveGroups(limit: $firewallLimit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime, source: "ratelimit" }) {
+						count
+						dimensions {
+							action
+							ruleId
 						}
 					}
 				}
 			}
 		}
-		`)
-	if len(viper.GetString("cf_api_token")) > 0 {
-		request.Header.Set("Authorization", "Bearer "+viper.GetString("cf_api_token"))
-	} else {
-		request.Header.Set("X-AUTH-EMAIL", viper.GetString("cf_api_email"))
-		request.Header.Set("X-AUTH-KEY", viper.GetString("cf_api_key"))
-	}
-	request.Var("limit", viper.GetInt("cf_query_limit"))
+		`))
+	setAuthHeaders(request.Header)
+	request.Var("httpLimit", QueryLimit("http"))
+	request.Var("firewallLimit", QueryLimit("firewall"))
+	request.Var("healthCheckLimit", QueryLimit("health_check"))
+	request.Var("adaptiveLimit", QueryLimit("adaptive"))
 	request.Var("maxtime", now)
 	request.Var("mintime", now1mAgo)
+	request.Var("httpMaxtime", httpMaxtime)
+	request.Var("httpMintime", httpMintime)
 	request.Var("zoneIDs", zoneIDs)
+	request.Var("statuses", originErrorStatuses())
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second) // Set 10s timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
 	defer cancel()
 
-	graphqlClient := graphql.NewClient(cfGraphQLEndpoint)
-
 	// Log the query parameters for debugging
-	logging.Info("Fetching FetchFirewallMetrics from Cloudflare API", map[string]interface{}{
+	logging.Info("Fetching FetchZoneAnalytics from Cloudflare API", map[string]interface{}{
 		"zoneIDs":    zoneIDs,
-		"limit":      viper.GetInt("cf_query_limit"),
+		"limit":      QueryLimit(""),
 		"maxtime":    now,
 		"mintime":    now1mAgo,
 		"time_range": fmt.Sprintf("%s - %s", now1mAgo, now),
 	})
 
-	var resp models.CloudflareResponseFirewallGroups
-	if err := graphqlClient.Run(ctx, request, &resp); err != nil {
-		logging.Error("Failed to FetchFirewallMetrics totals", map[string]interface{}{
-			"error": err.Error(),
-		})
+	var resp models.CloudflareResponseZoneAnalytics
+	if err := runGraphQL(ctx, "FetchZoneAnalytics", request, &resp); err != nil {
+		logging.ErrorErr("Failed to FetchZoneAnalytics", err)
 		return nil, err
 	}
 
 	// Log the successful response
-	logging.Info("Successfully fetched zone totals", map[string]interface{}{
+	logging.Info("Successfully FetchZoneAnalytics", map[string]interface{}{
 		"zone_count": len(resp.Viewer.Zones),
 	})
 
 	return &resp, nil
 }
 
-func HealthCheckEventsAdaptiveMetrics(ctx context.Context, zoneIDs []string) (*models.CloudflareResponseHealthCheckGroups, error) {
-	now := time.Now().Add(-time.Duration(viper.GetInt("scrape_delay")) * time.Second).UTC()
+func FetchFirewallMetrics(ctx context.Context, zoneIDs []string) (*models.CloudflareResponseFirewallGroups, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+	now := time.Now().Add(-ScrapeDelay(zoneIDs)).UTC()
 	s := 60 * time.Second
 	now = now.Truncate(s)
-	now1mAgo := now.Add(-60 * time.Second)
+	now1mAgo := now.Add(-ZoneQueryWindow())
 
 	request := graphql.NewRequest(`
 		query ($zoneIDs: [String!], $mintime: Time!, $maxtime: Time!, $limit: Int!)  {
 			viewer {
 				zones(filter: { zoneTag_in: $zoneIDs }) {
 					zoneTag
-					healthCheckEventsAdaptiveGroups(limit: $limit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
+					firewallEventsAdaptiveGroups(limit: $limit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
 						count
 						dimensions {
-							healthStatus
-							originIP
-							region
-							fqdn
+						action
+						source
+						ruleId
+						clientRequestHTTPHost
+						clientCountryName
+						}
+					}
+					firewallEventsByKind: firewallEventsAdaptiveGroups(limit: $limit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
+						count
+						dimensions {
+							kind
+						}
+					}
+					firewallEventsByAsn: firewallEventsAdaptiveGroups(limit: $limit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
+						count
+						dimensions {
+							clientAsn
+							clientASNDescription
+							action
 						}
 					}
 				}
 			}
 		}
 		`)
-	if len(viper.GetString("cf_api_token")) > 0 {
-		request.Header.Set("Authorization", "Bearer "+viper.GetString("cf_api_token"))
-	} else {
-		request.Header.Set("X-AUTH-EMAIL", viper.GetString("cf_api_email"))
-		request.Header.Set("X-AUTH-KEY", viper.GetString("cf_api_key"))
-	}
-	request.Var("limit", viper.GetInt("cf_query_limit"))
+	setAuthHeaders(request.Header)
+	request.Var("limit", QueryLimit("firewall"))
 	request.Var("maxtime", now)
 	request.Var("mintime", now1mAgo)
 	request.Var("zoneIDs", zoneIDs)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second) // Set 10s timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
 	defer cancel()
 
-	graphqlClient := graphql.NewClient(cfGraphQLEndpoint)
-
 	// Log the query parameters for debugging
-	logging.Info("Fetching HealthCheckGroupMetrics from Cloudflare API", map[string]interface{}{
+	logging.Info("Fetching FetchFirewallMetrics from Cloudflare API", map[string]interface{}{
 		"zoneIDs":    zoneIDs,
-		"limit":      viper.GetInt("cf_query_limit"),
+		"limit":      QueryLimit("firewall"),
 		"maxtime":    now,
 		"mintime":    now1mAgo,
 		"time_range": fmt.Sprintf("%s - %s", now1mAgo, now),
 	})
 
-	var resp models.CloudflareResponseHealthCheckGroups
-	if err := graphqlClient.Run(ctx, request, &resp); err != nil {
-		logging.Error("Failed to HealthCheckEventsAdaptiveMetrics", map[string]interface{}{
+	var resp models.CloudflareResponseFirewallGroups
+	if err := runGraphQL(ctx, "FetchFirewallMetrics", request, &resp); err != nil {
+		logging.Error("Failed to FetchFirewallMetrics totals", map[string]interface{}{
 			"error": err.Error(),
 		})
 		return nil, err
 	}
 
 	// Log the successful response
-	logging.Info("Successfully fetched HealthCheckEventsAdaptiveMetrics", map[string]interface{}{
+	logging.Info("Successfully fetched zone totals", map[string]interface{}{
 		"zone_count": len(resp.Viewer.Zones),
 	})
 
 	return &resp, nil
 }
 
-func HTTPRequestsAdaptiveMetrics(ctx context.Context, zoneIDs []string) (*models.CloudflareResponseAdaptiveGroups, error) {
-	now := time.Now().Add(-time.Duration(viper.GetInt("scrape_delay")) * time.Second).UTC()
+// FetchRateLimitEvents queries firewallEventsAdaptiveGroups for events triggered by rate limiting rules.
+func FetchRateLimitEvents(ctx context.Context, zoneIDs []string) (*models.CloudflareResponseRateLimitGroups, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+	now := time.Now().Add(-ScrapeDelay(zoneIDs)).UTC()
 	s := 60 * time.Second
 	now = now.Truncate(s)
-	now1mAgo := now.Add(-60 * time.Second)
+	now1mAgo := now.Add(-ZoneQueryWindow())
 
 	request := graphql.NewRequest(`
 		query ($zoneIDs: [String!], $mintime: Time!, $maxtime: Time!, $limit: Int!)  {
 			viewer {
 				zones(filter: { zoneTag_in: $zoneIDs }) {
 					zoneTag
-					httpRequestsAdaptiveGroups(limit: $limit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime, cacheStatus_notin: ["hit"], originResponseStatus_in: [400, 404, 500, 502, 503, 504, 522, 523, 524] }) {
+					rateLimitEventsAdaptiveGroups: firewallEventsAdaptiveGroups(limit: $limit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime, source: "ratelimit" }) {
 						count
 						dimensions {
-							originResponseStatus
-							clientCountryName
-							clientRequestHTTPHost
+							action
+							ruleId
 						}
-						avg {
-          					originResponseDurationMs
-        				}
 					}
 				}
 			}
 		}
 		`)
-	if len(viper.GetString("cf_api_token")) > 0 {
-		request.Header.Set("Authorization", "Bearer "+viper.GetString("cf_api_token"))
-	} else {
-		request.Header.Set("X-AUTH-EMAIL", viper.GetString("cf_api_email"))
-		request.Header.Set("X-AUTH-KEY", viper.GetString("cf_api_key"))
-	}
-	request.Var("limit", viper.GetInt("cf_query_limit"))
+	setAuthHeaders(request.Header)
+	request.Var("limit", QueryLimit("firewall"))
 	request.Var("maxtime", now)
 	request.Var("mintime", now1mAgo)
 	request.Var("zoneIDs", zoneIDs)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second) // Set 10s timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
 	defer cancel()
 
-	graphqlClient := graphql.NewClient(cfGraphQLEndpoint)
-
 	// Log the query parameters for debugging
-	logging.Info("Fetching zone totals from Cloudflare API", map[string]interface{}{
+	logging.Info("Fetching FetchRateLimitEvents from Cloudflare API", map[string]interface{}{
 		"zoneIDs":    zoneIDs,
-		"limit":      viper.GetInt("cf_query_limit"),
+		"limit":      QueryLimit("firewall"),
 		"maxtime":    now,
 		"mintime":    now1mAgo,
 		"time_range": fmt.Sprintf("%s - %s", now1mAgo, now),
 	})
 
-	var resp models.CloudflareResponseAdaptiveGroups
-	if err := graphqlClient.Run(ctx, request, &resp); err != nil {
-		logging.Error("Failed to HTTPRequestsAdaptiveMetrics totals", map[string]interface{}{
-			"error": err.Error(),
-		})
+	var resp models.CloudflareResponseRateLimitGroups
+	if err := runGraphQL(ctx, "FetchRateLimitEvents", request, &resp); err != nil {
+		logging.ErrorErr("Failed to FetchRateLimitEvents", err)
 		return nil, err
 	}
 
 	// Log the successful response
-	logging.Info("Successfully HTTPRequestsAdaptiveMetrics totals", map[string]interface{}{
+	logging.Info("Successfully fetched rate limit events", map[string]interface{}{
 		"zone_count": len(resp.Viewer.Zones),
 	})
 
 	return &resp, nil
 }
 
-func HTTPRequestsEdgeCountryMetrics(ctx context.Context, zoneIDs []string) (*models.CloudflareResponseHTTPRequestsEdge, error) {
-	now := time.Now().Add(-time.Duration(viper.GetInt("scrape_delay")) * time.Second).UTC()
+func HealthCheckEventsAdaptiveMetrics(ctx context.Context, zoneIDs []string) (*models.CloudflareResponseHealthCheckGroups, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+	now := time.Now().Add(-ScrapeDelay(zoneIDs)).UTC()
 	s := 60 * time.Second
 	now = now.Truncate(s)
-	now1mAgo := now.Add(-60 * time.Second)
+	now1mAgo := now.Add(-ZoneQueryWindow())
 
 	request := graphql.NewRequest(`
 		query ($zoneIDs: [String!], $mintime: Time!, $maxtime: Time!, $limit: Int!)  {
 			viewer {
 				zones(filter: { zoneTag_in: $zoneIDs }) {
 					zoneTag
-					httpRequestsEdgeCountryHost: httpRequestsAdaptiveGroups(limit: $limit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
+					healthCheckEventsAdaptiveGroups(limit: $limit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
 						count
 						dimensions {
-							edgeResponseStatus
-							clientCountryName
-							clientRequestHTTPHost
+							healthStatus
+							originIP
+							region
+							fqdn
 						}
 					}
 				}
 			}
 		}
 		`)
-	if len(viper.GetString("cf_api_token")) > 0 {
-		request.Header.Set("Authorization", "Bearer "+viper.GetString("cf_api_token"))
-	} else {
-		request.Header.Set("X-AUTH-EMAIL", viper.GetString("cf_api_email"))
-		request.Header.Set("X-AUTH-KEY", viper.GetString("cf_api_key"))
-	}
-	request.Var("limit", viper.GetInt("cf_query_limit"))
+	setAuthHeaders(request.Header)
+	request.Var("limit", QueryLimit("health_check"))
 	request.Var("maxtime", now)
 	request.Var("mintime", now1mAgo)
 	request.Var("zoneIDs", zoneIDs)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second) // Set 10s timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
 	defer cancel()
 
-	graphqlClient := graphql.NewClient(cfGraphQLEndpoint)
-
 	// Log the query parameters for debugging
-	logging.Info("Fetching zone totals from Cloudflare API", map[string]interface{}{
+	logging.Info("Fetching HealthCheckGroupMetrics from Cloudflare API", map[string]interface{}{
+		"zoneIDs":    zoneIDs,
+		"limit":      QueryLimit("health_check"),
+		"maxtime":    now,
+		"mintime":    now1mAgo,
+		"time_range": fmt.Sprintf("%s - %s", now1mAgo, now),
+	})
+
+	var resp models.CloudflareResponseHealthCheckGroups
+	if err := runGraphQL(ctx, "HealthCheckEventsAdaptiveMetrics", request, &resp); err != nil {
+		logging.Error("Failed to HealthCheckEventsAdaptiveMetrics", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	// Log the successful response
+	logging.Info("Successfully fetched HealthCheckEventsAdaptiveMetrics", map[string]interface{}{
+		"zone_count": len(resp.Viewer.Zones),
+	})
+
+	return &resp, nil
+}
+
+func HTTPRequestsAdaptiveMetrics(ctx context.Context, zoneIDs []string) (*models.CloudflareResponseAdaptiveGroups, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+	now := time.Now().Add(-ScrapeDelay(zoneIDs)).UTC()
+	s := 60 * time.Second
+	now = now.Truncate(s)
+	now1mAgo := now.Add(-ZoneQueryWindow())
+
+	request := graphql.NewRequest(`
+		query ($zoneIDs: [String!], $mintime: Time!, $maxtime: Time!, $limit: Int!, $statuses: [uint16!])  {
+			viewer {
+				zones(filter: { zoneTag_in: $zoneIDs }) {
+					zoneTag
+					httpRequestsAdaptiveGroups(limit: $limit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime, cacheStatus_notin: ["hit"], originResponseStatus_in: $statuses }) {
+						count
+						dimensions {
+							originResponseStatus
+							clientCountryName
+							clientRequestHTTPHost
+						}
+						avg {
+          					originResponseDurationMs
+        				}
+					}
+					httpRequestsOriginStatus: httpRequestsAdaptiveGroups(limit: $limit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime, cacheStatus_notin: ["hit"], originResponseStatus_geq: 100 }) {
+						count
+						dimensions {
+							originResponseStatus
+						}
+					}
+					httpRequestsOriginConnectivity: httpRequestsAdaptiveGroups(limit: $limit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime, edgeResponseStatus_geq: 520, edgeResponseStatus_leq: 527 }) {
+						count
+						dimensions {
+							edgeResponseStatus
+						}
+					}
+					httpRequestsCacheStatus: httpRequestsAdaptiveGroups(limit: $limit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
+						count
+						dimensions {
+							cacheStatus
+						}
+					}
+					httpRequestsMethod: httpRequestsAdaptiveGroups(limit: $limit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
+						count
+						dimensions {
+							clientRequestHTTPMethodName
+						}
+					}
+					httpRequestsHostBytes: httpRequestsAdaptiveGroups(limit: $limit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
+						sum {
+							edgeResponseBytes
+						}
+						dimensions {
+							clientRequestHTTPHost
+						}
+					}
+				}
+			}
+		}
+		`)
+	setAuthHeaders(request.Header)
+	request.Var("limit", QueryLimit("adaptive"))
+	request.Var("maxtime", now)
+	request.Var("mintime", now1mAgo)
+	request.Var("zoneIDs", zoneIDs)
+	request.Var("statuses", originErrorStatuses())
+
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
+	defer cancel()
+
+	// Log the query parameters for debugging
+	logging.Info("Fetching zone totals from Cloudflare API", map[string]interface{}{
 		"zoneIDs":    zoneIDs,
-		"limit":      viper.GetInt("cf_query_limit"),
+		"limit":      QueryLimit("adaptive"),
+		"maxtime":    now,
+		"mintime":    now1mAgo,
+		"time_range": fmt.Sprintf("%s - %s", now1mAgo, now),
+	})
+
+	var resp models.CloudflareResponseAdaptiveGroups
+	if err := runGraphQL(ctx, "HTTPRequestsAdaptiveMetrics", request, &resp); err != nil {
+		logging.Error("Failed to HTTPRequestsAdaptiveMetrics totals", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	// Log the successful response
+	logging.Info("Successfully HTTPRequestsAdaptiveMetrics totals", map[string]interface{}{
+		"zone_count": len(resp.Viewer.Zones),
+	})
+
+	return &resp, nil
+}
+
+func HTTPRequestsEdgeCountryMetrics(ctx context.Context, zoneIDs []string) (*models.CloudflareResponseHTTPRequestsEdge, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+	now := time.Now().Add(-ScrapeDelay(zoneIDs)).UTC()
+	s := 60 * time.Second
+	now = now.Truncate(s)
+	now1mAgo := now.Add(-ZoneQueryWindow())
+
+	request := graphql.NewRequest(`
+		query ($zoneIDs: [String!], $mintime: Time!, $maxtime: Time!, $limit: Int!)  {
+			viewer {
+				zones(filter: { zoneTag_in: $zoneIDs }) {
+					zoneTag
+					httpRequestsEdgeCountryHost: httpRequestsAdaptiveGroups(limit: $limit, filter: { datetime_geq: $mintime, datetime_lt: $maxtime }) {
+						count
+						dimensions {
+							edgeResponseStatus
+							clientCountryName
+							clientRequestHTTPHost
+						}
+					}
+				}
+			}
+		}
+		`)
+	setAuthHeaders(request.Header)
+	request.Var("limit", QueryLimit("adaptive"))
+	request.Var("maxtime", now)
+	request.Var("mintime", now1mAgo)
+	request.Var("zoneIDs", zoneIDs)
+
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
+	defer cancel()
+
+	// Log the query parameters for debugging
+	logging.Info("Fetching zone totals from Cloudflare API", map[string]interface{}{
+		"zoneIDs":    zoneIDs,
+		"limit":      QueryLimit("adaptive"),
 		"maxtime":    now,
 		"mintime":    now1mAgo,
 		"time_range": fmt.Sprintf("%s - %s", now1mAgo, now),
 	})
 
 	var resp models.CloudflareResponseHTTPRequestsEdge
-	if err := graphqlClient.Run(ctx, request, &resp); err != nil {
+	if err := runGraphQL(ctx, "HTTPRequestsEdgeCountryMetrics", request, &resp); err != nil {
 		logging.Error("Failed to HTTPRequestsAdaptiveMetrics totals", map[string]interface{}{
 			"error": err.Error(),
 		})
@@ -559,11 +910,14 @@ func HTTPRequestsEdgeCountryMetrics(ctx context.Context, zoneIDs []string) (*mod
 //
 
 // FetchWorkerTotals function query workersInvocationsAdaptive
-func FetchWorkerTotals(accountID string) (*models.CloudflareResponseAccts, error) {
+func FetchWorkerTotals(ctx context.Context, accountID string) (*models.CloudflareResponseAccts, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
 	now := time.Now().Add(-time.Duration(viper.GetInt("scrape_delay")) * time.Second).UTC()
 	s := 60 * time.Second
 	now = now.Truncate(s)
-	now1mAgo := now.Add(-60 * time.Second)
+	now1mAgo := now.Add(-QueryWindow())
 
 	request := graphql.NewRequest(`
 		query ($accountID: String!, $mintime: Time!, $maxtime: Time!, $limit: Int!) {
@@ -597,34 +951,410 @@ func FetchWorkerTotals(accountID string) (*models.CloudflareResponseAccts, error
 			}
 		}
 	`)
-	if len(viper.GetString("cf_api_token")) > 0 {
-		request.Header.Set("Authorization", "Bearer "+viper.GetString("cf_api_token"))
-	} else {
-		request.Header.Set("X-AUTH-EMAIL", viper.GetString("cf_api_email"))
-		request.Header.Set("X-AUTH-KEY", viper.GetString("cf_api_key"))
+	setAuthHeaders(request.Header)
+	request.Var("limit", QueryLimit("workers"))
+	request.Var("maxtime", now)
+	request.Var("mintime", now1mAgo)
+	request.Var("accountID", accountID)
+
+	// Log the query parameters for debugging
+	logging.Info("Fetching worker totals for Cloudflare account", map[string]interface{}{
+		"accountID":         accountID,
+		"limit":             QueryLimit("workers"),
+		"maxtime":           now,
+		"mintime":           now1mAgo,
+		"cfGraphQLEndpoint": cfGraphQLEndpoint,
+	})
+
+	// Use a context with timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
+	defer cancel()
+
+	var resp models.CloudflareResponseAccts
+	if err := runGraphQL(ctx, "FetchWorkerTotals", request, &resp); err != nil {
+		logging.Error("Failed to fetch worker totals", map[string]interface{}{
+			"accountID": accountID,
+			"error":     err.Error(),
+		})
+		return nil, err
 	}
-	request.Var("limit", viper.GetInt("cf_query_limit"))
+
+	// Log the successful response
+	logging.Info("Successfully fetched worker totals", map[string]interface{}{
+		"worker_count": len(resp.Viewer.Accounts),
+		"accountID":    accountID,
+	})
+
+	return &resp, nil
+}
+
+// FetchLogpushAccount queries logpushHealthAdaptiveGroups and returns CloudflareResponseLogpushAccount.
+func FetchLogpushAccount(ctx context.Context, accountID string) (*models.CloudflareResponseLogpushAccount, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+	now := time.Now().Add(-time.Duration(viper.GetInt("scrape_delay")) * time.Second).UTC()
+	s := 60 * time.Second
+	now = now.Truncate(s)
+	now1mAgo := now.Add(-QueryWindow())
+
+	request := graphql.NewRequest(`query($accountID: String!, $limit: Int!, $mintime: Time!, $maxtime: Time!) {
+			viewer {
+			accounts(filter: {accountTag : $accountID }) {
+				logpushHealthAdaptiveGroups(
+				filter: {
+					datetime_geq: $mintime
+					datetime_lt: $maxtime
+					status_neq: 200
+				}
+				limit: $limit
+				) {
+				count
+				dimensions {
+					jobId
+					status
+					destinationType
+					datetime
+					final
+				}
+				}
+				logpushHealthSuccess: logpushHealthAdaptiveGroups(
+				filter: {
+					datetime_geq: $mintime
+					datetime_lt: $maxtime
+					status: 200
+				}
+				limit: $limit
+				) {
+				count
+				dimensions {
+					jobId
+					destinationType
+				}
+				}
+			}
+			}
+		}`)
+
+	setAuthHeaders(request.Header)
+
+	request.Var("accountID", accountID)
+	request.Var("limit", QueryLimit("logpush"))
+	request.Var("maxtime", now)
+	request.Var("mintime", now1mAgo)
+
+	// Log the query parameters for debugging
+	logging.Info("Fetching logpush health data for Cloudflare account", map[string]interface{}{
+		"accountID":         accountID,
+		"limit":             QueryLimit("logpush"),
+		"maxtime":           now,
+		"mintime":           now1mAgo,
+		"cfGraphQLEndpoint": cfGraphQLEndpoint,
+	})
+
+	// Use a context with timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
+	defer cancel()
+
+	var resp models.CloudflareResponseLogpushAccount
+	if err := runGraphQL(ctx, "FetchLogpushAccount", request, &resp); err != nil {
+		logging.Error("Failed to fetch logpush health data", map[string]interface{}{
+			"accountID": accountID,
+			"error":     err.Error(),
+		})
+		return nil, err
+	}
+
+	// Ensure the response is not nil
+	if resp.Viewer.Accounts == nil {
+		logging.Error("Received nil Accounts from Cloudflare API", map[string]interface{}{
+			"accountID": accountID,
+		})
+		return nil, fmt.Errorf("Cloudflare API returned nil accounts")
+	}
+
+	// Ensure accounts slice is not empty before accessing index 0
+	if len(resp.Viewer.Accounts) == 0 {
+		logging.Error("Received empty accounts list from Cloudflare API", map[string]interface{}{
+			"accountID": accountID,
+		})
+		return nil, fmt.Errorf("Cloudflare API returned empty accounts list")
+	}
+
+	// Ensure LogpushHealthAdaptiveGroups is not nil before accessing it
+	if resp.Viewer.Accounts[0].LogpushHealthAdaptiveGroups == nil {
+		logging.Error("Received nil LogpushHealthAdaptiveGroups from Cloudflare API", map[string]interface{}{
+			"accountID": accountID,
+		})
+		return nil, fmt.Errorf("Cloudflare API returned nil LogpushHealthAdaptiveGroups")
+	}
+
+	// Log the successful response
+	logging.Info("Successfully fetched logpush health data", map[string]interface{}{
+		"logpush_count": len(resp.Viewer.Accounts[0].LogpushHealthAdaptiveGroups),
+		"accountID":     accountID,
+	})
+
+	return &resp, nil
+}
+
+// FetchTurnstileAnalytics queries turnstileAdaptiveGroups and returns CloudflareResponseTurnstile.
+func FetchTurnstileAnalytics(ctx context.Context, accountID string) (*models.CloudflareResponseTurnstile, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+	now := time.Now().Add(-time.Duration(viper.GetInt("scrape_delay")) * time.Second).UTC()
+	s := 60 * time.Second
+	now = now.Truncate(s)
+	now1mAgo := now.Add(-QueryWindow())
+
+	request := graphql.NewRequest(`query($accountID: String!, $limit: Int!, $mintime: Time!, $maxtime: Time!) {
+		viewer {
+			accounts(filter: {accountTag : $accountID }) {
+				turnstileAdaptiveGroups(
+					limit: $limit
+					filter: { datetime_geq: $mintime, datetime_lt: $maxtime }
+				) {
+					count
+					dimensions {
+						siteKey
+						eventType
+					}
+				}
+			}
+		}
+	}`)
+
+	setAuthHeaders(request.Header)
+
+	request.Var("accountID", accountID)
+	request.Var("limit", QueryLimit("account"))
+	request.Var("maxtime", now)
+	request.Var("mintime", now1mAgo)
+
+	// Log the query parameters for debugging
+	logging.Info("Fetching Turnstile analytics for Cloudflare account", map[string]interface{}{
+		"accountID": accountID,
+		"limit":     QueryLimit("account"),
+		"maxtime":   now,
+		"mintime":   now1mAgo,
+	})
+
+	// Use a context with timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
+	defer cancel()
+
+	var resp models.CloudflareResponseTurnstile
+	if err := runGraphQL(ctx, "FetchTurnstileAnalytics", request, &resp); err != nil {
+		logging.Error("Failed to fetch Turnstile analytics", map[string]interface{}{
+			"accountID": accountID,
+			"error":     err.Error(),
+		})
+		return nil, err
+	}
+
+	// Log the successful response
+	logging.Info("Successfully fetched Turnstile analytics", map[string]interface{}{
+		"accountID": accountID,
+		"count":     len(resp.Viewer.Accounts),
+	})
+
+	return &resp, nil
+}
+
+// FetchStreamAnalytics queries Stream minutes viewed and bandwidth and returns CloudflareResponseStream.
+func FetchStreamAnalytics(ctx context.Context, accountID string) (*models.CloudflareResponseStream, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+	now := time.Now().Add(-time.Duration(viper.GetInt("scrape_delay")) * time.Second).UTC()
+	s := 60 * time.Second
+	now = now.Truncate(s)
+	now1mAgo := now.Add(-QueryWindow())
+
+	request := graphql.NewRequest(`query($accountID: String!, $limit: Int!, $mintime: Time!, $maxtime: Time!) {
+		viewer {
+			accounts(filter: {accountTag : $accountID }) {
+				streamMinutesViewedAdaptiveGroups(
+					limit: $limit
+					filter: { datetime_geq: $mintime, datetime_lt: $maxtime }
+				) {
+					sum {
+						minutesViewed
+					}
+				}
+				streamBandwidth: videoBandwidthAdaptiveGroups(
+					limit: $limit
+					filter: { datetime_geq: $mintime, datetime_lt: $maxtime }
+				) {
+					sum {
+						egressBytes
+					}
+				}
+			}
+		}
+	}`)
+
+	setAuthHeaders(request.Header)
+
+	request.Var("accountID", accountID)
+	request.Var("limit", QueryLimit("account"))
+	request.Var("maxtime", now)
+	request.Var("mintime", now1mAgo)
+
+	// Log the query parameters for debugging
+	logging.Info("Fetching Stream analytics for Cloudflare account", map[string]interface{}{
+		"accountID": accountID,
+		"limit":     QueryLimit("account"),
+		"maxtime":   now,
+		"mintime":   now1mAgo,
+	})
+
+	// Use a context with timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
+	defer cancel()
+
+	var resp models.CloudflareResponseStream
+	if err := runGraphQL(ctx, "FetchStreamAnalytics", request, &resp); err != nil {
+		logging.Error("Failed to fetch Stream analytics", map[string]interface{}{
+			"accountID": accountID,
+			"error":     err.Error(),
+		})
+		return nil, err
+	}
+
+	// Log the successful response
+	logging.Info("Successfully fetched Stream analytics", map[string]interface{}{
+		"accountID": accountID,
+		"count":     len(resp.Viewer.Accounts),
+	})
+
+	return &resp, nil
+}
+
+// FetchImagesAnalytics queries Images requests and transformations and returns CloudflareResponseImages.
+func FetchImagesAnalytics(ctx context.Context, accountID string) (*models.CloudflareResponseImages, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+	now := time.Now().Add(-time.Duration(viper.GetInt("scrape_delay")) * time.Second).UTC()
+	s := 60 * time.Second
+	now = now.Truncate(s)
+	now1mAgo := now.Add(-QueryWindow())
+
+	request := graphql.NewRequest(`query($accountID: String!, $limit: Int!, $mintime: Time!, $maxtime: Time!) {
+		viewer {
+			accounts(filter: {accountTag : $accountID }) {
+				imagesRequestsAdaptiveGroups(
+					limit: $limit
+					filter: { datetime_geq: $mintime, datetime_lt: $maxtime }
+				) {
+					count
+				}
+				imagesUniqueTransformations(
+					limit: $limit
+					filter: { datetime_geq: $mintime, datetime_lt: $maxtime }
+				) {
+					count
+				}
+			}
+		}
+	}`)
+
+	setAuthHeaders(request.Header)
+
+	request.Var("accountID", accountID)
+	request.Var("limit", QueryLimit("account"))
+	request.Var("maxtime", now)
+	request.Var("mintime", now1mAgo)
+
+	// Log the query parameters for debugging
+	logging.Info("Fetching Images analytics for Cloudflare account", map[string]interface{}{
+		"accountID": accountID,
+		"limit":     QueryLimit("account"),
+		"maxtime":   now,
+		"mintime":   now1mAgo,
+	})
+
+	// Use a context with timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
+	defer cancel()
+
+	var resp models.CloudflareResponseImages
+	if err := runGraphQL(ctx, "FetchImagesAnalytics", request, &resp); err != nil {
+		logging.Error("Failed to fetch Images analytics", map[string]interface{}{
+			"accountID": accountID,
+			"error":     err.Error(),
+		})
+		return nil, err
+	}
+
+	// Log the successful response
+	logging.Info("Successfully fetched Images analytics", map[string]interface{}{
+		"accountID": accountID,
+		"count":     len(resp.Viewer.Accounts),
+	})
+
+	return &resp, nil
+}
+
+// FetchDurableObjectsAnalytics queries durableObjectsInvocationsAdaptiveGroups and returns CloudflareResponseDurableObjects.
+func FetchDurableObjectsAnalytics(ctx context.Context, accountID string) (*models.CloudflareResponseDurableObjects, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+	now := time.Now().Add(-time.Duration(viper.GetInt("scrape_delay")) * time.Second).UTC()
+	s := 60 * time.Second
+	now = now.Truncate(s)
+	now1mAgo := now.Add(-QueryWindow())
+
+	request := graphql.NewRequest(`query($accountID: String!, $limit: Int!, $mintime: Time!, $maxtime: Time!) {
+		viewer {
+			accounts(filter: {accountTag : $accountID }) {
+				durableObjectsInvocationsAdaptiveGroups(
+					limit: $limit
+					filter: { datetime_geq: $mintime, datetime_lt: $maxtime }
+				) {
+					sum {
+						requests
+					}
+					dimensions {
+						namespaceId
+					}
+				}
+			}
+		}
+	}`)
+
+	setAuthHeaders(request.Header)
+
+	request.Var("accountID", accountID)
+	request.Var("limit", QueryLimit("account"))
 	request.Var("maxtime", now)
 	request.Var("mintime", now1mAgo)
-	request.Var("accountID", accountID)
 
 	// Log the query parameters for debugging
-	logging.Info("Fetching worker totals for Cloudflare account", map[string]interface{}{
-		"accountID":         accountID,
-		"limit":             viper.GetInt("cf_query_limit"),
-		"maxtime":           now,
-		"mintime":           now1mAgo,
-		"cfGraphQLEndpoint": cfGraphQLEndpoint,
+	logging.Info("Fetching Durable Objects analytics for Cloudflare account", map[string]interface{}{
+		"accountID": accountID,
+		"limit":     QueryLimit("account"),
+		"maxtime":   now,
+		"mintime":   now1mAgo,
 	})
 
 	// Use a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) // Set 10s timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
 	defer cancel()
 
-	graphqlClient := graphql.NewClient(cfGraphQLEndpoint)
-	var resp models.CloudflareResponseAccts
-	if err := graphqlClient.Run(ctx, request, &resp); err != nil {
-		logging.Error("Failed to fetch worker totals", map[string]interface{}{
+	var resp models.CloudflareResponseDurableObjects
+	if err := runGraphQL(ctx, "FetchDurableObjectsAnalytics", request, &resp); err != nil {
+		// Accounts that don't use Durable Objects have nothing to export
+		if IsProductNotEnabledError(err) {
+			logging.Info("Durable Objects not enabled for Cloudflare account", map[string]interface{}{
+				"accountID": accountID,
+			})
+			return &models.CloudflareResponseDurableObjects{}, nil
+		}
+		logging.Error("Failed to fetch Durable Objects analytics", map[string]interface{}{
 			"accountID": accountID,
 			"error":     err.Error(),
 		})
@@ -632,108 +1362,81 @@ func FetchWorkerTotals(accountID string) (*models.CloudflareResponseAccts, error
 	}
 
 	// Log the successful response
-	logging.Info("Successfully fetched worker totals", map[string]interface{}{
-		"worker_count": len(resp.Viewer.Accounts),
-		"accountID":    accountID,
+	logging.Info("Successfully fetched Durable Objects analytics", map[string]interface{}{
+		"accountID": accountID,
+		"count":     len(resp.Viewer.Accounts),
 	})
 
 	return &resp, nil
 }
 
-// FetchLogpushAccount queries logpushHealthAdaptiveGroups and returns CloudflareResponseLogpushAccount.
-func FetchLogpushAccount(accountID string) (*models.CloudflareResponseLogpushAccount, error) {
+// FetchQueueBacklog queries queueBacklogAdaptiveGroups and returns CloudflareResponseQueues.
+func FetchQueueBacklog(ctx context.Context, accountID string) (*models.CloudflareResponseQueues, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
 	now := time.Now().Add(-time.Duration(viper.GetInt("scrape_delay")) * time.Second).UTC()
 	s := 60 * time.Second
 	now = now.Truncate(s)
-	now1mAgo := now.Add(-60 * time.Second)
+	now1mAgo := now.Add(-QueryWindow())
 
 	request := graphql.NewRequest(`query($accountID: String!, $limit: Int!, $mintime: Time!, $maxtime: Time!) {
-			viewer {
+		viewer {
 			accounts(filter: {accountTag : $accountID }) {
-				logpushHealthAdaptiveGroups(
-				filter: {
-					datetime_geq: $mintime
-					datetime_lt: $maxtime
-					status_neq: 200
-				}
-				limit: $limit
+				queueBacklogAdaptiveGroups(
+					limit: $limit
+					filter: { datetime_geq: $mintime, datetime_lt: $maxtime }
 				) {
-				count
-				dimensions {
-					jobId
-					status
-					destinationType
-					datetime
-					final
-				}
+					avg {
+						messages
+					}
+					dimensions {
+						queueId
+					}
 				}
 			}
-			}
-		}`)
+		}
+	}`)
 
-	if len(viper.GetString("cf_api_token")) > 0 {
-		request.Header.Set("Authorization", "Bearer "+viper.GetString("cf_api_token"))
-	} else {
-		request.Header.Set("X-AUTH-EMAIL", viper.GetString("cf_api_email"))
-		request.Header.Set("X-AUTH-KEY", viper.GetString("cf_api_key"))
-	}
+	setAuthHeaders(request.Header)
 
 	request.Var("accountID", accountID)
-	request.Var("limit", viper.GetInt("cf_query_limit"))
+	request.Var("limit", QueryLimit("account"))
 	request.Var("maxtime", now)
 	request.Var("mintime", now1mAgo)
 
 	// Log the query parameters for debugging
-	logging.Info("Fetching logpush health data for Cloudflare account", map[string]interface{}{
-		"accountID":         accountID,
-		"limit":             viper.GetInt("cf_query_limit"),
-		"maxtime":           now,
-		"mintime":           now1mAgo,
-		"cfGraphQLEndpoint": cfGraphQLEndpoint,
+	logging.Info("Fetching Queue backlog for Cloudflare account", map[string]interface{}{
+		"accountID": accountID,
+		"limit":     QueryLimit("account"),
+		"maxtime":   now,
+		"mintime":   now1mAgo,
 	})
 
 	// Use a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) // Set 10s timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
 	defer cancel()
 
-	graphqlClient := graphql.NewClient(cfGraphQLEndpoint)
-	var resp models.CloudflareResponseLogpushAccount
-	if err := graphqlClient.Run(ctx, request, &resp); err != nil {
-		logging.Error("Failed to fetch logpush health data", map[string]interface{}{
+	var resp models.CloudflareResponseQueues
+	if err := runGraphQL(ctx, "FetchQueueBacklog", request, &resp); err != nil {
+		// Accounts that don't use Queues have nothing to export
+		if IsProductNotEnabledError(err) {
+			logging.Info("Queues not enabled for Cloudflare account", map[string]interface{}{
+				"accountID": accountID,
+			})
+			return &models.CloudflareResponseQueues{}, nil
+		}
+		logging.Error("Failed to fetch Queue backlog", map[string]interface{}{
 			"accountID": accountID,
 			"error":     err.Error(),
 		})
 		return nil, err
 	}
 
-	// Ensure the response is not nil
-	if resp.Viewer.Accounts == nil {
-		logging.Error("Received nil Accounts from Cloudflare API", map[string]interface{}{
-			"accountID": accountID,
-		})
-		return nil, fmt.Errorf("Cloudflare API returned nil accounts")
-	}
-
-	// Ensure accounts slice is not empty before accessing index 0
-	if len(resp.Viewer.Accounts) == 0 {
-		logging.Error("Received empty accounts list from Cloudflare API", map[string]interface{}{
-			"accountID": accountID,
-		})
-		return nil, fmt.Errorf("Cloudflare API returned empty accounts list")
-	}
-
-	// Ensure LogpushHealthAdaptiveGroups is not nil before accessing it
-	if resp.Viewer.Accounts[0].LogpushHealthAdaptiveGroups == nil {
-		logging.Error("Received nil LogpushHealthAdaptiveGroups from Cloudflare API", map[string]interface{}{
-			"accountID": accountID,
-		})
-		return nil, fmt.Errorf("Cloudflare API returned nil LogpushHealthAdaptiveGroups")
-	}
-
 	// Log the successful response
-	logging.Info("Successfully fetched logpush health data", map[string]interface{}{
-		"logpush_count": len(resp.Viewer.Accounts[0].LogpushHealthAdaptiveGroups),
-		"accountID":     accountID,
+	logging.Info("Successfully fetched Queue backlog", map[string]interface{}{
+		"accountID": accountID,
+		"count":     len(resp.Viewer.Accounts),
 	})
 
 	return &resp, nil
@@ -789,15 +1492,9 @@ func FilterExcludedZones(all []cloudflare.Zone, exclude []string) []cloudflare.Z
 }
 
 // FetchFirewallRules queries firewall rules.
-func FetchFirewallRules(zoneID string) map[string]string {
+func FetchFirewallRules(ctx context.Context, zoneID string) map[string]string {
 
-	var api *cloudflare.API
-	var err error
-	if len(viper.GetString("cf_api_token")) > 0 {
-		api, err = cloudflare.NewWithAPIToken(viper.GetString("cf_api_token"))
-	} else {
-		api, err = cloudflare.New(viper.GetString("cf_api_key"), viper.GetString("cf_api_email"))
-	}
+	api, err := newAPIClient()
 	if err != nil {
 		logging.Error("Failed to initialize Cloudflare API client", map[string]interface{}{"error": err.Error()})
 		return map[string]string{}
@@ -808,15 +1505,20 @@ func FetchFirewallRules(zoneID string) map[string]string {
 		"zoneID": zoneID,
 	})
 
+	if err := limiter.Wait(ctx); err != nil {
+		logging.ErrorErr("Rate limit wait failed", err)
+		return map[string]string{}
+	}
+
 	// Use a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) // Set 10s timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
 	defer cancel()
 
 	listOfRules, _, err := api.FirewallRules(ctx,
 		cloudflare.ZoneIdentifier(zoneID),
 		cloudflare.FirewallRuleListParams{})
 	if err != nil {
-		logging.Error(err)
+		logging.ErrorErr("Failed to fetch firewall rules", err)
 	}
 	firewallRulesMap := make(map[string]string)
 
@@ -826,7 +1528,7 @@ func FetchFirewallRules(zoneID string) map[string]string {
 
 	listOfRulesets, err := api.ListRulesets(ctx, cloudflare.ZoneIdentifier(zoneID), cloudflare.ListRulesetsParams{})
 	if err != nil {
-		logging.Error(err)
+		logging.ErrorErr("Failed to fetch rulesets", err)
 	}
 
 	logging.Info("Fetched rulesets", map[string]interface{}{
@@ -861,17 +1563,20 @@ func FetchFirewallRules(zoneID string) map[string]string {
 }
 
 // FetchColoTotals returns queries httpRequestsAdaptiveGroups.
-func FetchColoTotals(zoneIDs []string) (*models.CloudflareResponseColo, error) {
+func FetchColoTotals(ctx context.Context, zoneIDs []string) (*models.CloudflareResponseColo, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
 
 	// Log the start of the process
 	logging.Info("Fetching Colo totals for zoneIDs", map[string]interface{}{
 		"zoneIDs": zoneIDs,
 	})
 
-	now := time.Now().Add(-time.Duration(viper.GetInt("scrape_delay")) * time.Second).UTC()
+	now := time.Now().Add(-ScrapeDelay(zoneIDs)).UTC()
 	s := 60 * time.Second
 	now = now.Truncate(s)
-	now1mAgo := now.Add(-60 * time.Second)
+	now1mAgo := now.Add(-ZoneQueryWindow())
 
 	request := graphql.NewRequest(`
 	query ($zoneIDs: [String!], $mintime: Time!, $maxtime: Time!, $limit: Int!) {
@@ -901,32 +1606,26 @@ func FetchColoTotals(zoneIDs []string) (*models.CloudflareResponseColo, error) {
 			}
 		}
 `)
-	if len(viper.GetString("cf_api_token")) > 0 {
-		request.Header.Set("Authorization", "Bearer "+viper.GetString("cf_api_token"))
-	} else {
-		request.Header.Set("X-AUTH-EMAIL", viper.GetString("cf_api_email"))
-		request.Header.Set("X-AUTH-KEY", viper.GetString("cf_api_key"))
-	}
-	request.Var("limit", viper.GetInt("cf_query_limit"))
+	setAuthHeaders(request.Header)
+	request.Var("limit", QueryLimit("colo"))
 	request.Var("maxtime", now)
 	request.Var("mintime", now1mAgo)
 	request.Var("zoneIDs", zoneIDs)
 
 	// Log request variables
 	logging.Info("GraphQL request variables", map[string]interface{}{
-		"limit":   viper.GetInt("cf_query_limit"),
+		"limit":   QueryLimit("colo"),
 		"maxtime": now,
 		"mintime": now1mAgo,
 		"zoneIDs": zoneIDs,
 	})
 
 	// Use a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) // Set 10s timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
 	defer cancel()
 
-	graphqlClient := graphql.NewClient(cfGraphQLEndpoint)
 	var resp models.CloudflareResponseColo
-	if err := graphqlClient.Run(ctx, request, &resp); err != nil {
+	if err := runGraphQL(ctx, "FetchColoTotals", request, &resp); err != nil {
 		// Log the error if request fails
 		logging.Error("Failed to fetch Colo totals", map[string]interface{}{
 			"error": err,
@@ -942,17 +1641,202 @@ func FetchColoTotals(zoneIDs []string) (*models.CloudflareResponseColo, error) {
 	return &resp, nil
 }
 
+// FetchBotScore returns requests grouped by bot management score. It is kept out
+// of FetchZoneAnalytics since the botScore dimension requires the Bot Management add-on.
+func FetchBotScore(ctx context.Context, zoneIDs []string) (*models.CloudflareResponseBotScore, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+
+	// Log the start of the process
+	logging.Info("Fetching bot scores for zoneIDs", map[string]interface{}{
+		"zoneIDs": zoneIDs,
+	})
+
+	now := time.Now().Add(-ScrapeDelay(zoneIDs)).UTC()
+	s := 60 * time.Second
+	now = now.Truncate(s)
+	now1mAgo := now.Add(-ZoneQueryWindow())
+
+	request := graphql.NewRequest(`
+	query ($zoneIDs: [String!], $mintime: Time!, $maxtime: Time!, $limit: Int!) {
+		viewer {
+			zones(filter: { zoneTag_in: $zoneIDs }) {
+				zoneTag
+				httpRequestsBotScore: httpRequestsAdaptiveGroups(
+					limit: $limit
+					filter: { datetime_geq: $mintime, datetime_lt: $maxtime }
+					) {
+						count
+						dimensions {
+							botScore
+						}
+					}
+				}
+			}
+		}
+`)
+	setAuthHeaders(request.Header)
+	request.Var("limit", QueryLimit("adaptive"))
+	request.Var("maxtime", now)
+	request.Var("mintime", now1mAgo)
+	request.Var("zoneIDs", zoneIDs)
+
+	// Use a context with timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
+	defer cancel()
+
+	var resp models.CloudflareResponseBotScore
+	if err := runGraphQL(ctx, "FetchBotScore", request, &resp); err != nil {
+		logging.ErrorErr("Failed to fetch bot scores", err)
+		return nil, err
+	}
+
+	// Log success after receiving response
+	logging.Info("Successfully fetched bot scores", map[string]interface{}{
+		"zoneIDs": zoneIDs,
+	})
+
+	return &resp, nil
+}
+
+// FetchRequestPaths returns the busiest request paths by querying
+// httpRequestsAdaptiveGroups grouped by clientRequestPath.
+func FetchRequestPaths(ctx context.Context, zoneIDs []string) (*models.CloudflareResponseRequestPath, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+
+	// Log the start of the process
+	logging.Info("Fetching request paths for zoneIDs", map[string]interface{}{
+		"zoneIDs": zoneIDs,
+	})
+
+	now := time.Now().Add(-ScrapeDelay(zoneIDs)).UTC()
+	s := 60 * time.Second
+	now = now.Truncate(s)
+	now1mAgo := now.Add(-ZoneQueryWindow())
+
+	// Ordered by count so a truncated result still holds the busiest paths
+	request := graphql.NewRequest(`
+	query ($zoneIDs: [String!], $mintime: Time!, $maxtime: Time!, $limit: Int!) {
+		viewer {
+			zones(filter: { zoneTag_in: $zoneIDs }) {
+				zoneTag
+				httpRequestsPath: httpRequestsAdaptiveGroups(
+					limit: $limit
+					orderBy: [count_DESC]
+					filter: { datetime_geq: $mintime, datetime_lt: $maxtime }
+					) {
+						count
+						dimensions {
+							clientRequestPath
+						}
+					}
+				}
+			}
+		}
+`)
+	setAuthHeaders(request.Header)
+	request.Var("limit", QueryLimit("adaptive"))
+	request.Var("maxtime", now)
+	request.Var("mintime", now1mAgo)
+	request.Var("zoneIDs", zoneIDs)
+
+	// Use a context with timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
+	defer cancel()
+
+	var resp models.CloudflareResponseRequestPath
+	if err := runGraphQL(ctx, "FetchRequestPaths", request, &resp); err != nil {
+		logging.ErrorErr("Failed to fetch request paths", err)
+		return nil, err
+	}
+
+	// Log success after receiving response
+	logging.Info("Successfully fetched request paths", map[string]interface{}{
+		"zoneIDs": zoneIDs,
+	})
+
+	return &resp, nil
+}
+
+// FetchArgoAnalytics returns data by querying argoAnalyticsAdaptiveGroups.
+func FetchArgoAnalytics(ctx context.Context, zoneIDs []string) (*models.CloudflareResponseArgo, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+
+	// Log the start of the process
+	logging.Info("Fetching Argo analytics for zoneIDs", map[string]interface{}{
+		"zoneIDs": zoneIDs,
+	})
+
+	now := time.Now().Add(-ScrapeDelay(zoneIDs)).UTC()
+	s := 60 * time.Second
+	now = now.Truncate(s)
+	now1mAgo := now.Add(-ZoneQueryWindow())
+
+	request := graphql.NewRequest(`
+	query ($zoneIDs: [String!], $mintime: Time!, $maxtime: Time!, $limit: Int!) {
+		viewer {
+			zones(filter: { zoneTag_in: $zoneIDs }) {
+				zoneTag
+				argoAnalyticsAdaptiveGroups(
+					limit: $limit
+					filter: { datetime_geq: $mintime, datetime_lt: $maxtime }
+					) {
+						count
+						avg {
+							originResponseDurationMs
+						}
+						dimensions {
+							smartRouted
+						}
+					}
+				}
+			}
+		}
+`)
+	setAuthHeaders(request.Header)
+	request.Var("limit", QueryLimit("adaptive"))
+	request.Var("maxtime", now)
+	request.Var("mintime", now1mAgo)
+	request.Var("zoneIDs", zoneIDs)
+
+	// Use a context with timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
+	defer cancel()
+
+	var resp models.CloudflareResponseArgo
+	if err := runGraphQL(ctx, "FetchArgoAnalytics", request, &resp); err != nil {
+		logging.ErrorErr("Failed to fetch Argo analytics", err)
+		return nil, err
+	}
+
+	// Log success after receiving response
+	logging.Info("Successfully fetched Argo analytics", map[string]interface{}{
+		"zoneIDs": zoneIDs,
+	})
+
+	return &resp, nil
+}
+
 // FetchLoadBalancerTotals returns data by querying loadBalancingRequestsAdaptiveGroups and loadBalancingRequestsAdaptive.
-func FetchLoadBalancerTotals(zoneIDs []string) (*models.CloudflareResponseLb, error) {
+func FetchLoadBalancerTotals(ctx context.Context, zoneIDs []string) (*models.CloudflareResponseLb, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+
 	// Log the start of the process
 	logging.Info("Fetching Load Balancer totals for zoneIDs", map[string]interface{}{
 		"zoneIDs": zoneIDs,
 	})
 
-	now := time.Now().Add(-time.Duration(viper.GetInt("scrape_delay")) * time.Second).UTC()
+	now := time.Now().Add(-ScrapeDelay(zoneIDs)).UTC()
 	s := 60 * time.Second
 	now = now.Truncate(s)
-	now1mAgo := now.Add(-60 * time.Second)
+	now1mAgo := now.Add(-ZoneQueryWindow())
 
 	request := graphql.NewRequest(`
 	query ($zoneIDs: [String!], $mintime: Time!, $maxtime: Time!, $limit: Int!) {
@@ -1003,32 +1887,26 @@ func FetchLoadBalancerTotals(zoneIDs []string) (*models.CloudflareResponseLb, er
 		}
 	}
 `)
-	if len(viper.GetString("cf_api_token")) > 0 {
-		request.Header.Set("Authorization", "Bearer "+viper.GetString("cf_api_token"))
-	} else {
-		request.Header.Set("X-AUTH-EMAIL", viper.GetString("cf_api_email"))
-		request.Header.Set("X-AUTH-KEY", viper.GetString("cf_api_key"))
-	}
-	request.Var("limit", viper.GetInt("cf_query_limit"))
+	setAuthHeaders(request.Header)
+	request.Var("limit", QueryLimit("load_balancer"))
 	request.Var("maxtime", now)
 	request.Var("mintime", now1mAgo)
 	request.Var("zoneIDs", zoneIDs)
 
 	// Log request variables
 	logging.Info("GraphQL request variables", map[string]interface{}{
-		"limit":   viper.GetInt("cf_query_limit"),
+		"limit":   QueryLimit("load_balancer"),
 		"maxtime": now,
 		"mintime": now1mAgo,
 		"zoneIDs": zoneIDs,
 	})
 
 	// Use a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second) // Set 10s timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
 	defer cancel()
 
-	graphqlClient := graphql.NewClient(cfGraphQLEndpoint)
 	var resp models.CloudflareResponseLb
-	if err := graphqlClient.Run(ctx, request, &resp); err != nil {
+	if err := runGraphQL(ctx, "FetchLoadBalancerTotals", request, &resp); err != nil {
 		// Log the error if request fails
 		logging.Error("Failed to fetch Load Balancer totals", map[string]interface{}{
 			"error": err,
@@ -1045,20 +1923,25 @@ func FetchLoadBalancerTotals(zoneIDs []string) (*models.CloudflareResponseLb, er
 }
 
 // FetchLogpushZone query logpushHealthAdaptiveGroups and return CloudflareResponseLogpushZone
-func FetchLogpushZone(zoneIDs []string) (*models.CloudflareResponseLogpushZone, error) {
+func FetchLogpushZone(ctx context.Context, zoneIDs []string) (*models.CloudflareResponseLogpushZone, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+
 	// Log the start of the process
 	logging.Info("Fetching Logpush zone for zoneIDs", map[string]interface{}{
 		"zoneIDs": zoneIDs,
 	})
 
-	now := time.Now().Add(-time.Duration(viper.GetInt("scrape_delay")) * time.Second).UTC()
+	now := time.Now().Add(-ScrapeDelay(zoneIDs)).UTC()
 	s := 60 * time.Second
 	now = now.Truncate(s)
-	now1mAgo := now.Add(-60 * time.Second)
+	now1mAgo := now.Add(-ZoneQueryWindow())
 
 	request := graphql.NewRequest(`query($zoneIDs: String!, $limit: Int!, $mintime: Time!, $maxtime: Time!) {
 		viewer {
 			zones(filter: {zoneTag_in : $zoneIDs }) {
+			zoneTag
 			logpushHealthAdaptiveGroups(
 			  filter: {
 				datetime_geq: $mintime
@@ -1080,34 +1963,28 @@ func FetchLogpushZone(zoneIDs []string) (*models.CloudflareResponseLogpushZone,
 		}
 	  }`)
 
-	if len(viper.GetString("cf_api_token")) > 0 {
-		request.Header.Set("Authorization", "Bearer "+viper.GetString("cf_api_token"))
-	} else {
-		request.Header.Set("X-AUTH-EMAIL", viper.GetString("cf_api_email"))
-		request.Header.Set("X-AUTH-KEY", viper.GetString("cf_api_key"))
-	}
+	setAuthHeaders(request.Header)
 
 	request.Var("zoneIDs", zoneIDs)
-	request.Var("limit", viper.GetInt("cf_query_limit"))
+	request.Var("limit", QueryLimit("logpush"))
 	request.Var("maxtime", now)
 	request.Var("mintime", now1mAgo)
 
 	// Log request variables
 	logging.Info("FetchLogpushZone GraphQL request variables", map[string]interface{}{
 		"zoneIDs": zoneIDs,
-		"limit":   viper.GetInt("cf_query_limit"),
+		"limit":   QueryLimit("logpush"),
 		"maxtime": now,
 		"mintime": now1mAgo,
 	})
 
 	// Use a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) // Set 10s timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
 	defer cancel()
 
-	graphqlClient := graphql.NewClient(cfGraphQLEndpoint)
 	var resp models.CloudflareResponseLogpushZone
-	if err := graphqlClient.Run(ctx, request, &resp); err != nil {
-		logging.Error(err)
+	if err := runGraphQL(ctx, "FetchLogpushZone", request, &resp); err != nil {
+		logging.ErrorErr("Failed to fetch Logpush zone data", err)
 		return nil, err
 	}
 
@@ -1120,16 +1997,20 @@ func FetchLogpushZone(zoneIDs []string) (*models.CloudflareResponseLogpushZone,
 }
 
 // FetchFirewallEventsAllowedDenied queries logpushHealthAdaptiveGroups.
-func FetchFirewallEventsAllowedDenied(zoneIDs []string) (*models.CloudflareResponseLogpushZone, error) {
+func FetchFirewallEventsAllowedDenied(ctx context.Context, zoneIDs []string) (*models.CloudflareResponseLogpushZone, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+
 	// Log the start of the process
 	logging.Info("Fetching firewall events for allowed/denied status", map[string]interface{}{
 		"zoneIDs": zoneIDs,
 	})
 
-	now := time.Now().Add(-time.Duration(viper.GetInt("scrape_delay")) * time.Second).UTC()
+	now := time.Now().Add(-ScrapeDelay(zoneIDs)).UTC()
 	s := 60 * time.Second
 	now = now.Truncate(s)
-	now1mAgo := now.Add(-60 * time.Second)
+	now1mAgo := now.Add(-ZoneQueryWindow())
 
 	request := graphql.NewRequest(`query($zoneIDs: String!, $limit: Int!, $mintime: Time!, $maxtime: Time!) {
 		viewer {
@@ -1155,33 +2036,27 @@ func FetchFirewallEventsAllowedDenied(zoneIDs []string) (*models.CloudflareRespo
 		}
 	  }`)
 
-	if len(viper.GetString("cf_api_token")) > 0 {
-		request.Header.Set("Authorization", "Bearer "+viper.GetString("cf_api_token"))
-	} else {
-		request.Header.Set("X-AUTH-EMAIL", viper.GetString("cf_api_email"))
-		request.Header.Set("X-AUTH-KEY", viper.GetString("cf_api_key"))
-	}
+	setAuthHeaders(request.Header)
 
 	request.Var("zoneIDs", zoneIDs)
-	request.Var("limit", viper.GetInt("cf_query_limit"))
+	request.Var("limit", QueryLimit("firewall"))
 	request.Var("maxtime", now)
 	request.Var("mintime", now1mAgo)
 
 	// Log request variables
 	logging.Info("GraphQL request variables", map[string]interface{}{
 		"zoneIDs": zoneIDs,
-		"limit":   viper.GetInt("cf_query_limit"),
+		"limit":   QueryLimit("firewall"),
 		"maxtime": now,
 		"mintime": now1mAgo,
 	})
 
 	// Use a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) // Set 10s timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
 	defer cancel()
 
-	graphqlClient := graphql.NewClient(cfGraphQLEndpoint)
 	var resp models.CloudflareResponseLogpushZone
-	if err := graphqlClient.Run(ctx, request, &resp); err != nil {
+	if err := runGraphQL(ctx, "FetchFirewallEventsAllowedDenied", request, &resp); err != nil {
 		// Log the error if request fails
 		logging.Error("Failed to fetch firewall events", map[string]interface{}{
 			"error": err,
@@ -1199,11 +2074,14 @@ func FetchFirewallEventsAllowedDenied(zoneIDs []string) (*models.CloudflareRespo
 }
 
 // MagicTransitTunnelHealthChecksAdaptiveGroups query magicTransitTunnelHealthChecksAdaptiveGroups.
-func MagicTransitTunnelHealthChecksAdaptiveGroups(accountID string) (*models.CloudflareResponseMagicTransit, error) {
+func MagicTransitTunnelHealthChecksAdaptiveGroups(ctx context.Context, accountID string) (*models.CloudflareResponseMagicTransit, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
 	now := time.Now().Add(-time.Duration(viper.GetInt("scrape_delay")) * time.Second).UTC()
 	s := 60 * time.Second
 	now = now.Truncate(s)
-	now1mAgo := now.Add(-60 * time.Second)
+	now1mAgo := now.Add(-QueryWindow())
 
 	// Log the computed time range
 	logging.Info("Computed time range for Magic Transit query", map[string]interface{}{
@@ -1236,33 +2114,27 @@ func MagicTransitTunnelHealthChecksAdaptiveGroups(accountID string) (*models.Clo
 		}
 	}`)
 
-	if len(viper.GetString("cf_api_token")) > 0 {
-		request.Header.Set("Authorization", "Bearer "+viper.GetString("cf_api_token"))
-	} else {
-		request.Header.Set("X-AUTH-EMAIL", viper.GetString("cf_api_email"))
-		request.Header.Set("X-AUTH-KEY", viper.GetString("cf_api_key"))
-	}
+	setAuthHeaders(request.Header)
 
 	request.Var("accountID", accountID)
-	request.Var("limit", viper.GetInt("cf_query_limit"))
+	request.Var("limit", QueryLimit("magic_transit"))
 	request.Var("maxtime", now)
 	request.Var("mintime", now1mAgo)
 
 	// Log the request headers and variables before sending the request
 	logging.Info("GraphQL request details", map[string]interface{}{
 		"accountID": accountID,
-		"limit":     viper.GetInt("cf_query_limit"),
+		"limit":     QueryLimit("magic_transit"),
 		"maxtime":   now,
 		"mintime":   now1mAgo,
 	})
 
 	// Use a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) // Set 10s timeout
+	ctx, cancel := withRequestTimeout(ctx, requestTimeout())
 	defer cancel()
 
-	graphqlClient := graphql.NewClient(cfGraphQLEndpoint)
 	var resp models.CloudflareResponseMagicTransit
-	if err := graphqlClient.Run(ctx, request, &resp); err != nil {
+	if err := runGraphQL(ctx, "MagicTransitTunnelHealthChecksAdaptiveGroups", request, &resp); err != nil {
 		logging.Error("Failed to execute GraphQL query", map[string]interface{}{
 			"error":     err.Error(),
 			"accountID": accountID,
@@ -1280,19 +2152,249 @@ func MagicTransitTunnelHealthChecksAdaptiveGroups(accountID string) (*models.Clo
 	return &resp, nil
 }
 
-// HTTP client with timeout
-var httpClient = &http.Client{
-	Timeout: 10 * time.Second, // Set a per-request timeout
+// apiHTTPClient returns the client for REST and GraphQL calls. It shares the pooled
+// transport from the client package. Per-request timeouts are applied through
+// the request context (see requestTimeout and sslFetchTimeout); the client
+// timeout, the longer of the two, bounds calls whose context has no deadline.
+func apiHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: client.SharedTransport(),
+		Timeout:   max(requestTimeout(), sslFetchTimeout()),
+	}
+}
+
+// Defaults for the SSL certificate fan-out, used when the settings are unset.
+const (
+	defaultRequestTimeout      = 30 * time.Second
+	defaultSSLFetchConcurrency = 5
+	defaultSSLFetchTimeout     = 10 * time.Second
+	defaultSSLFetchRetries     = 3
+)
+
+// defaultOriginErrorStatuses are the origin status codes queried for error metrics.
+var defaultOriginErrorStatuses = []int{400, 404, 500, 502, 503, 504, 522, 523, 524}
+
+// originErrorStatuses returns the origin status codes from cf_origin_error_statuses,
+// falling back to defaultOriginErrorStatuses when unset or invalid.
+func originErrorStatuses() []int {
+	raw := strings.TrimSpace(viper.GetString("cf_origin_error_statuses"))
+	if raw == "" {
+		return defaultOriginErrorStatuses
+	}
+
+	var statuses []int
+	for _, field := range strings.Split(raw, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || code < 100 || code > 599 {
+			logging.Warn("Ignoring invalid origin error status", map[string]interface{}{"status": field})
+			continue
+		}
+		statuses = append(statuses, code)
+	}
+	if len(statuses) == 0 {
+		return defaultOriginErrorStatuses
+	}
+	return statuses
+}
+
+// zoneScrapeDelays holds the per-zone scrape delays set by SetZoneScrapeDelays.
+var zoneScrapeDelays map[string]time.Duration
+
+// SetZoneScrapeDelays parses raw, a comma delimited zoneID=seconds list as in
+// cf_zone_scrape_delays, and uses it for ScrapeDelay. Malformed entries are an
+// error and leave the previous delays in place; an empty list clears them.
+func SetZoneScrapeDelays(raw string) error {
+	delays := map[string]time.Duration{}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		zoneID, seconds, ok := strings.Cut(entry, "=")
+		zoneID = strings.TrimSpace(zoneID)
+		if !ok || zoneID == "" {
+			return fmt.Errorf("invalid scrape delay %q, expected zoneID=seconds", entry)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(seconds))
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid scrape delay %q, seconds must be a non-negative integer", entry)
+		}
+		delays[zoneID] = time.Duration(n) * time.Second
+	}
+	zoneScrapeDelays = delays
+	return nil
+}
+
+// GroupGranularity returns the HTTP groups table granularity, "1m" or "1h".
+func GroupGranularity() string {
+	if viper.GetString("cf_group_granularity") == "1h" {
+		return "1h"
+	}
+	return "1m"
+}
+
+// HTTPGroupsWindow returns the query window for the HTTP groups of zoneIDs:
+// the last complete minute, or the last complete hour at 1h granularity.
+// A backfill window longer than that widens it.
+func HTTPGroupsWindow(zoneIDs []string) (time.Time, time.Time) {
+	step := time.Minute
+	if GroupGranularity() == "1h" {
+		step = time.Hour
+	}
+	maxtime := time.Now().Add(-ScrapeDelay(zoneIDs)).UTC().Truncate(step)
+	return maxtime.Add(-max(step, ZoneQueryWindow())), maxtime
+}
+
+// backfillWindow is the query window length in nanoseconds while a backfill
+// is active, 0 otherwise.
+var backfillWindow atomic.Int64
+
+// SetBackfill widens the query windows to d until it is called again with 0.
+func SetBackfill(d time.Duration) {
+	backfillWindow.Store(int64(d))
+}
+
+// QueryWindow returns how far back the queries reach from the end of their
+// window: one minute, or the backfill window while one is active.
+func QueryWindow() time.Duration {
+	return max(time.Minute, time.Duration(backfillWindow.Load()))
+}
+
+// staggerWindow is the time in nanoseconds between two scrapes of the same
+// zone when zones are staggered, 0 otherwise.
+var staggerWindow atomic.Int64
+
+// SetStaggerWindow widens the zone query windows to d, so a zone scraped
+// every d still has its counters cover the whole time since its last scrape.
+func SetStaggerWindow(d time.Duration) {
+	staggerWindow.Store(int64(d))
+}
+
+// ZoneQueryWindow returns how far back the zone queries reach: the query
+// window, or the stagger window when that is longer.
+func ZoneQueryWindow() time.Duration {
+	return max(QueryWindow(), time.Duration(staggerWindow.Load()))
+}
+
+// withGroupGranularity points the httpRequests1mGroups selection of query at
+// httpRequests1hGroups when the granularity is 1h. The alias keeps the
+// response key, so both tables decode into the same HTTP1mGroups model.
+func withGroupGranularity(query string) string {
+	if GroupGranularity() != "1h" {
+		return query
+	}
+	return strings.Replace(query, "httpRequests1mGroups(", "httpRequests1mGroups: httpRequests1hGroups(", 1)
+}
+
+// ScrapeDelay returns how far behind now the query window for zoneIDs ends:
+// the largest cf_zone_scrape_delays override among them, else scrape_delay.
+func ScrapeDelay(zoneIDs []string) time.Duration {
+	delay := time.Duration(viper.GetInt("scrape_delay")) * time.Second
+
+	found := false
+	var longest time.Duration
+	for _, id := range zoneIDs {
+		if d, ok := zoneScrapeDelays[id]; ok && (!found || d > longest) {
+			longest, found = d, true
+		}
+	}
+	if found {
+		return longest
+	}
+	return delay
+}
+
+// QueryLimitTypes are the query families with their own cf_query_limit_<type> override.
+var QueryLimitTypes = []string{"http", "firewall", "health_check", "adaptive", "colo", "workers", "logpush", "load_balancer", "magic_transit", "account"}
+
+// QueryLimit returns the GraphQL group limit for queryType from
+// cf_query_limit_<queryType>, falling back to cf_query_limit.
+func QueryLimit(queryType string) int {
+	if queryType != "" {
+		if n := viper.GetInt("cf_query_limit_" + queryType); n > 0 {
+			return n
+		}
+	}
+	return viper.GetInt("cf_query_limit")
+}
+
+// apiMaxRetries returns how many times zone and account listing is attempted.
+func apiMaxRetries() int {
+	if n := viper.GetInt("cf_api_max_retries"); n > 0 {
+		return n
+	}
+	return 1
+}
+
+// sleepContext waits for d or until ctx is done. Tests replace it to observe
+// backoff without waiting.
+var sleepContext = func(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryBackoff waits before the next attempt: attempt times the configured
+// base backoff. It returns early with the context error on cancellation.
+func retryBackoff(ctx context.Context, attempt int) error {
+	base := time.Duration(viper.GetInt("cf_api_retry_backoff")) * time.Second
+	return sleepContext(ctx, time.Duration(attempt)*base)
+}
+
+// errRequestTimeout is the cause of an expired per-request timeout, telling it
+// apart from a deadline of the caller such as scrape_timeout.
+var errRequestTimeout = errors.New("cloudflare API request timed out")
+
+// withRequestTimeout derives the context of a single API request from ctx.
+func withRequestTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeoutCause(ctx, timeout, errRequestTimeout)
+}
+
+// requestTimeout returns the per-request timeout for GraphQL and REST calls.
+func requestTimeout() time.Duration {
+	if n := viper.GetInt("cf_request_timeout"); n > 0 {
+		return time.Duration(n) * time.Second
+	}
+	return defaultRequestTimeout
+}
+
+// sslFetchConcurrency returns the maximum number of concurrent SSL requests.
+func sslFetchConcurrency() int {
+	if n := viper.GetInt("ssl_fetch_concurrency"); n > 0 {
+		return n
+	}
+	return defaultSSLFetchConcurrency
+}
+
+// sslFetchTimeout returns the per-request timeout for SSL requests.
+func sslFetchTimeout() time.Duration {
+	if n := viper.GetInt("ssl_fetch_timeout"); n > 0 {
+		return time.Duration(n) * time.Second
+	}
+	return defaultSSLFetchTimeout
+}
+
+// sslFetchRetries returns the number of attempts per zone for SSL requests.
+func sslFetchRetries() int {
+	if n := viper.GetInt("ssl_fetch_retries"); n > 0 {
+		return n
+	}
+	return defaultSSLFetchRetries
 }
 
 // FetchSSLCertificateStatus fetches SSL certificate status for multiple zones concurrently
-func FetchSSLCertificateStatus(zoneIDs []string) (*models.SSLResponse, error) {
+func FetchSSLCertificateStatus(ctx context.Context, zoneIDs []string) (*models.SSLResponse, error) {
 	var combinedResponse models.SSLResponse
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 
 	// Use a buffered channel to limit concurrency (avoid hitting rate limits)
-	maxConcurrentRequests := 5
+	maxConcurrentRequests := sslFetchConcurrency()
 	sem := make(chan struct{}, maxConcurrentRequests)
 
 	for _, zoneID := range zoneIDs {
@@ -1303,7 +2405,7 @@ func FetchSSLCertificateStatus(zoneIDs []string) (*models.SSLResponse, error) {
 			defer wg.Done()
 			defer func() { <-sem }() // Release slot
 
-			sslResponse, err := fetchSSLForZone(zoneID)
+			sslResponse, err := fetchSSLForZone(ctx, zoneID)
 			if err != nil {
 				logging.Error("Failed to fetch SSL data", map[string]interface{}{
 					"zone_id": zoneID,
@@ -1314,6 +2416,7 @@ func FetchSSLCertificateStatus(zoneIDs []string) (*models.SSLResponse, error) {
 
 			mu.Lock()
 			combinedResponse.Result = append(combinedResponse.Result, sslResponse.Result...)
+			combinedResponse.FetchedZoneIDs = append(combinedResponse.FetchedZoneIDs, zoneID)
 			mu.Unlock()
 		}(zoneID)
 	}
@@ -1325,94 +2428,347 @@ func FetchSSLCertificateStatus(zoneIDs []string) (*models.SSLResponse, error) {
 }
 
 // fetchSSLForZone fetches SSL certificate data for a single zone with retry logic
-func fetchSSLForZone(zoneID string) (*models.SSLResponse, error) {
-	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/ssl/certificate_packs", zoneID)
+func fetchSSLForZone(parent context.Context, zoneID string) (*models.SSLResponse, error) {
+	url := fmt.Sprintf("%s/zones/%s/ssl/certificate_packs", cfAPIBaseURL, zoneID)
 	logging.Info("Fetching SSL certificate status", map[string]interface{}{
 		"zone_id":  zoneID,
 		"endpoint": url,
 	})
 
-	req, err := http.NewRequest("GET", url, nil)
+	body, err := getZoneREST(parent, zoneID, url, "/zones/:zone_id/ssl/certificate_packs")
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	// Set authentication headers
-	if len(viper.GetString("cf_api_token")) > 0 {
-		req.Header.Set("Authorization", "Bearer "+viper.GetString("cf_api_token"))
-	} else {
-		req.Header.Set("X-AUTH-EMAIL", viper.GetString("cf_api_email"))
-		req.Header.Set("X-AUTH-KEY", viper.GetString("cf_api_key"))
+	// Parse response
+	var sslResponse models.SSLResponse
+	if err := json.Unmarshal(body, &sslResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	// Implement retry with exponential backoff
-	maxRetries := 3
-	var body []byte
+	// Assign ZoneID to results
+	for i := range sslResponse.Result {
+		sslResponse.Result[i].ZoneID = zoneID
+	}
 
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) // Set 10s timeout
-		defer cancel()
+	logging.Info("SSL certificate data fetched successfully", map[string]interface{}{
+		"zone_id":    zoneID,
+		"cert_count": len(sslResponse.Result),
+	})
 
-		req = req.WithContext(ctx)
+	return &sslResponse, nil
+}
 
-		resp, err := httpClient.Do(req)
-		if err != nil {
-			logging.Warn("API request failed, retrying...", map[string]interface{}{
-				"zone_id": zoneID,
-				"attempt": attempt,
-				"error":   err.Error(),
-			})
-			time.Sleep(time.Duration(attempt*2) * time.Second)
+// FetchPageShieldScripts fetches the Page Shield scripts for each zone,
+// keyed by zone ID. Zones whose request failed are omitted; if every zone
+// failed, the last error is returned.
+func FetchPageShieldScripts(ctx context.Context, zoneIDs []string) (map[string][]models.PageShieldScript, error) {
+	scripts := make(map[string][]models.PageShieldScript, len(zoneIDs))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var lastErr error
+
+	sem := make(chan struct{}, sslFetchConcurrency())
+
+	for _, zoneID := range zoneIDs {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(zoneID string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			zoneScripts, err := fetchPageShieldForZone(ctx, zoneID)
+			if err != nil {
+				logging.Error("Failed to fetch Page Shield scripts", map[string]interface{}{
+					"zone_id": zoneID,
+					"error":   err.Error(),
+				})
+				mu.Lock()
+				lastErr = err
+				mu.Unlock()
+				return
+			}
+
+			mu.Lock()
+			scripts[zoneID] = zoneScripts
+			mu.Unlock()
+		}(zoneID)
+	}
+
+	wg.Wait()
+
+	if len(scripts) == 0 && lastErr != nil {
+		return nil, fmt.Errorf("page shield scripts failed for all %d zones: %w", len(zoneIDs), lastErr)
+	}
+	return scripts, nil
+}
+
+// FetchWAFRuleCategories returns, per zone ID, the categories (e.g. sqli,
+// xss) of each rule of the zone's managed WAF rulesets keyed by rule ID.
+// Zones that fail are logged and left out.
+func FetchWAFRuleCategories(ctx context.Context, zoneIDs []string) (map[string]map[string][]string, error) {
+	categories := make(map[string]map[string][]string, len(zoneIDs))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	sem := make(chan struct{}, sslFetchConcurrency())
+
+	for _, zoneID := range zoneIDs {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(zoneID string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			zoneCategories, err := fetchWAFRuleCategoriesForZone(ctx, zoneID)
+			if err != nil {
+				logging.Error("Failed to fetch WAF rule categories", map[string]interface{}{
+					"zone_id": zoneID,
+					"error":   err.Error(),
+				})
+				return
+			}
+
+			mu.Lock()
+			categories[zoneID] = zoneCategories
+			mu.Unlock()
+		}(zoneID)
+	}
+
+	wg.Wait()
+
+	return categories, nil
+}
+
+// fetchWAFRuleCategoriesForZone reads the rules of every managed ruleset in
+// the http_request_firewall_managed phase of a zone. It uses the REST API
+// directly as cloudflare-go drops the rule categories.
+func fetchWAFRuleCategoriesForZone(parent context.Context, zoneID string) (map[string][]string, error) {
+	url := fmt.Sprintf("%s/zones/%s/rulesets", cfAPIBaseURL, zoneID)
+	body, err := getZoneREST(parent, zoneID, url, "/zones/:zone_id/rulesets")
+	if err != nil {
+		return nil, err
+	}
+
+	var rulesets models.RulesetsResponse
+	if err := json.Unmarshal(body, &rulesets); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	categories := make(map[string][]string)
+	for _, rs := range rulesets.Result {
+		if rs.Phase != "http_request_firewall_managed" {
 			continue
 		}
-		defer resp.Body.Close()
-
-		// Handle rate limit (429)
-		if resp.StatusCode == 429 {
-			logging.Warn("Rate limited, waiting before retry...", map[string]interface{}{
-				"zone_id":  zoneID,
-				"attempt":  attempt,
-				"response": resp.Status,
-			})
-			time.Sleep(time.Duration(attempt*3) * time.Second)
-			continue
+
+		url := fmt.Sprintf("%s/zones/%s/rulesets/%s", cfAPIBaseURL, zoneID, rs.ID)
+		body, err := getZoneREST(parent, zoneID, url, "/zones/:zone_id/rulesets/:ruleset_id")
+		if err != nil {
+			return nil, err
 		}
 
-		// Read body
-		body, err = io.ReadAll(resp.Body)
+		var ruleset models.RulesetResponse
+		if err := json.Unmarshal(body, &ruleset); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		for _, rule := range ruleset.Result.Rules {
+			if len(rule.Categories) > 0 {
+				categories[rule.ID] = rule.Categories
+			}
+		}
+	}
+
+	return categories, nil
+}
+
+// fetchPageShieldForZone fetches every page of Page Shield scripts for a zone.
+func fetchPageShieldForZone(parent context.Context, zoneID string) ([]models.PageShieldScript, error) {
+	var scripts []models.PageShieldScript
+
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/zones/%s/page_shield/scripts?per_page=100&page=%d", cfAPIBaseURL, zoneID, page)
+		body, err := getZoneREST(parent, zoneID, url, "/zones/:zone_id/page_shield/scripts")
 		if err != nil {
-			return nil, fmt.Errorf("failed to read response: %w", err)
+			return nil, err
 		}
 
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("failed to fetch SSL data, status: %d, response: %s", resp.StatusCode, string(body))
+		var resp models.PageShieldResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
 		}
+		scripts = append(scripts, resp.Result...)
 
-		logging.Info("API response received", map[string]interface{}{
-			"zone_id":       zoneID,
-			"status_code":   resp.StatusCode,
-			"response_time": resp.Header.Get("Date"),
-		})
+		if page >= resp.ResultInfo.TotalPages {
+			return scripts, nil
+		}
+	}
+}
 
-		break // Success, exit retry loop
+// FetchLogpushJobs returns the logpush jobs of an account or zone keyed by job
+// ID. scope is "accounts" or "zones".
+func FetchLogpushJobs(ctx context.Context, scope, id string) (map[int]models.LogpushJob, error) {
+	url := fmt.Sprintf("%s/%s/%s/logpush/jobs", cfAPIBaseURL, scope, id)
+	body, err := getZoneREST(ctx, id, url, "/"+scope+"/:id/logpush/jobs")
+	if err != nil {
+		return nil, err
 	}
 
-	// Parse response
-	var sslResponse models.SSLResponse
-	if err := json.Unmarshal(body, &sslResponse); err != nil {
+	var resp models.LogpushJobsResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	// Assign ZoneID to results
-	for i := range sslResponse.Result {
-		sslResponse.Result[i].ZoneID = zoneID
+	jobs := make(map[int]models.LogpushJob, len(resp.Result))
+	for _, job := range resp.Result {
+		jobs[job.ID] = job
 	}
+	return jobs, nil
+}
 
-	logging.Info("SSL certificate data fetched successfully", map[string]interface{}{
-		"zone_id":    zoneID,
-		"cert_count": len(sslResponse.Result),
-	})
+// FetchDurableObjectNamespaces returns the names of the Durable Object
+// namespaces of an account keyed by namespace ID.
+func FetchDurableObjectNamespaces(ctx context.Context, accountID string) (map[string]string, error) {
+	url := fmt.Sprintf("%s/accounts/%s/workers/durable_objects/namespaces", cfAPIBaseURL, accountID)
+	body, err := getZoneREST(ctx, accountID, url, "/accounts/:id/workers/durable_objects/namespaces")
+	if err != nil {
+		return nil, err
+	}
 
-	return &sslResponse, nil
+	var resp models.DurableObjectNamespacesResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	names := make(map[string]string, len(resp.Result))
+	for _, ns := range resp.Result {
+		names[ns.ID] = ns.Name
+	}
+	return names, nil
+}
+
+// FetchQueueNames returns the names of the Queues of an account keyed by
+// queue ID.
+func FetchQueueNames(ctx context.Context, accountID string) (map[string]string, error) {
+	url := fmt.Sprintf("%s/accounts/%s/queues", cfAPIBaseURL, accountID)
+	body, err := getZoneREST(ctx, accountID, url, "/accounts/:id/queues")
+	if err != nil {
+		return nil, err
+	}
+
+	var resp models.QueuesResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	names := make(map[string]string, len(resp.Result))
+	for _, q := range resp.Result {
+		names[q.QueueID] = q.QueueName
+	}
+	return names, nil
+}
+
+// getZoneREST GETs url for a zone with the SSL fetch timeout and retries,
+// returning the body of the first 200 response.
+func getZoneREST(parent context.Context, zoneID, url, route string) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set authentication headers
+	setAuthHeaders(req.Header)
+	req.Header.Set("Content-Type", "application/json")
+
+	// Retry with the same backoff as zone and account listing
+	maxRetries := sslFetchRetries()
+
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		// Every attempt counts against the shared Cloudflare API rate limit.
+		// The wait happens before the attempt's timeout starts.
+		if err := limiter.Wait(parent); err != nil {
+			return nil, fmt.Errorf("rate limiter: %w", err)
+		}
+
+		if err := apiBreaker.allow(); err != nil {
+			return nil, err
+		}
+
+		body, retryErr, err := getZoneRESTAttempt(parent, req, zoneID, route, attempt)
+		if err != nil {
+			return nil, err
+		}
+		if retryErr == nil {
+			return body, nil
+		}
+		if attempt == maxRetries {
+			break
+		}
+		if err := spendRetry(retryErr); err != nil {
+			return nil, err
+		}
+		if err := retryBackoff(parent, attempt); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("no response from %s after %d attempts", route, maxRetries)
+}
+
+// getZoneRESTAttempt makes one attempt of getZoneREST with its own
+// ssl_fetch_timeout. A non-nil retryErr means the attempt is worth retrying,
+// a non-nil err ends the retries.
+func getZoneRESTAttempt(parent context.Context, req *http.Request, zoneID, route string, attempt int) (body []byte, retryErr, err error) {
+	ctx, cancel := withRequestTimeout(parent, sslFetchTimeout())
+	defer cancel()
+
+	start := time.Now()
+	resp, err := apiHTTPClient().Do(req.WithContext(ctx))
+	reqErr := err
+	if reqErr == nil && resp.StatusCode != http.StatusOK {
+		reqErr = fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	observeAPIRequest(route, start, reqErr)
+	if err == nil && resp.StatusCode < 500 {
+		recordAPIResult(ctx, nil)
+	} else {
+		recordAPIResult(ctx, reqErr)
+	}
+	if err != nil {
+		logging.Warn("API request failed, retrying...", map[string]interface{}{
+			"zone_id": zoneID,
+			"attempt": attempt,
+			"error":   err.Error(),
+		})
+		return nil, err, nil
+	}
+	defer resp.Body.Close()
+
+	// Handle rate limit (429)
+	if resp.StatusCode == 429 {
+		logging.Warn("Rate limited, waiting before retry...", map[string]interface{}{
+			"zone_id":  zoneID,
+			"attempt":  attempt,
+			"response": resp.Status,
+		})
+		return nil, reqErr, nil
+	}
+
+	// Read body
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, wrapPermissionError(resp.StatusCode, fmt.Errorf("unexpected status: %d, response: %s", resp.StatusCode, string(body)))
+	}
+
+	logging.Info("API response received", map[string]interface{}{
+		"zone_id":       zoneID,
+		"status_code":   resp.StatusCode,
+		"response_time": resp.Header.Get("Date"),
+	})
+	return body, nil, nil
 }
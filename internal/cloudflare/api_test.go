@@ -2,31 +2,44 @@ package cloudflare_test
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/jarcoal/httpmock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 
 	"github.com/lablabs/cloudflare-exporter/internal/cloudflare"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
 
+// setViper sets key to value and restores the previous value when the test ends.
+func setViper(t *testing.T, key string, value interface{}) {
+	t.Helper()
+	prev := viper.Get(key)
+	viper.Set(key, value)
+	t.Cleanup(func() { viper.Set(key, prev) })
+}
+
 func TestAuthHeader_WithToken(t *testing.T) {
 	// Setup: mock viper values
-	viper.Set("cf_api_token", "dummy-token")
-	viper.Set("cf_api_email", "")
-	viper.Set("cf_api_key", "")
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "cf_api_email", "")
+	setViper(t, "cf_api_key", "")
 
 	// Create a dummy request
 	req, _ := http.NewRequest("GET", "http://example.com", nil)
 
-	// Mimic auth logic from api.go
-	if token := viper.GetString("cf_api_token"); token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
-	}
-	req.Header.Set("X-AUTH-EMAIL", viper.GetString("cf_api_email"))
-	req.Header.Set("X-AUTH-KEY", viper.GetString("cf_api_key"))
+	cloudflare.SetAuthHeaders(req.Header)
 
 	// Assertions
 	assert.Equal(t, "Bearer dummy-token", req.Header.Get("Authorization"))
@@ -34,12 +47,26 @@ func TestAuthHeader_WithToken(t *testing.T) {
 	assert.Equal(t, "", req.Header.Get("X-AUTH-KEY"))
 }
 
+func TestAuthHeader_WithKeyAndEmail(t *testing.T) {
+	setViper(t, "cf_api_token", "")
+	setViper(t, "cf_api_email", "user@example.com")
+	setViper(t, "cf_api_key", "dummy-key")
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+
+	cloudflare.SetAuthHeaders(req.Header)
+
+	assert.Equal(t, "", req.Header.Get("Authorization"))
+	assert.Equal(t, "user@example.com", req.Header.Get("X-AUTH-EMAIL"))
+	assert.Equal(t, "dummy-key", req.Header.Get("X-AUTH-KEY"))
+}
+
 func TestFetchZones_Mocked(t *testing.T) {
 	// Setup mock HTTP
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
 
-	viper.Set("cf_api_token", "dummy-token")
+	setViper(t, "cf_api_token", "dummy-token")
 
 	// Mock the Cloudflare zones API
 	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/zones",
@@ -65,7 +92,7 @@ func TestFetchZones_Mocked(t *testing.T) {
 
 func TestFetchAccounts_WithMockedHTTP(t *testing.T) {
 	// Mock env vars
-	viper.Set("cf_api_token", "dummy-token")
+	setViper(t, "cf_api_token", "dummy-token")
 
 	// Activate HTTP mock
 	httpmock.Activate()
@@ -92,3 +119,671 @@ func TestFetchAccounts_WithMockedHTTP(t *testing.T) {
 	assert.Len(t, accounts, 1)
 	assert.Equal(t, "Test Account", accounts[0].Name)
 }
+
+func TestSetGraphQLEndpoint_QueriesHitOverride(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"viewer": {"zones": [{"zoneTag": "zone1"}]}}}`))
+	}))
+	defer srv.Close()
+
+	cloudflare.SetGraphQLEndpoint(srv.URL)
+	defer cloudflare.SetGraphQLEndpoint("")
+
+	setViper(t, "cf_api_token", "dummy-token")
+	resp, err := cloudflare.FetchFirewallMetrics(context.Background(), []string{"zone1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+	assert.Len(t, resp.Viewer.Zones, 1)
+	assert.Equal(t, "zone1", resp.Viewer.Zones[0].ZoneTag)
+}
+
+func TestFetchZoneAnalytics_DecodesCombinedPayload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"viewer": {"zones": [{
+			"zoneTag": "zone1",
+			"httpRequests1mGroups": [{"sum": {"requests": 100, "cachedRequests": 40}, "uniq": {"uniques": 9}}],
+			"firewallEventsAdaptiveGroups": [{"count": 5, "dimensions": {"action": "block", "source": "waf"}}],
+			"healthCheckEventsAdaptiveGroups": [{"count": 2, "dimensions": {"healthStatus": "unhealthy", "originIP": "10.0.0.1"}}],
+			"httpRequestsAdaptiveGroups": [{"count": 3, "dimensions": {"originResponseStatus": 502}, "avg": {"originResponseDurationMs": 120.5}}],
+			"httpRequestsEdgeCountryHost": [{"count": 4, "dimensions": {"edgeResponseStatus": 404, "clientCountryName": "DE"}}],
+			"rateLimitEventsAdaptiveGroups": [{"count": 6, "dimensions": {"action": "block", "ruleId": "rl-1"}}]
+		}]}}}`))
+	}))
+	defer srv.Close()
+
+	cloudflare.SetGraphQLEndpoint(srv.URL)
+	defer cloudflare.SetGraphQLEndpoint("")
+
+	setViper(t, "cf_api_token", "dummy-token")
+	resp, err := cloudflare.FetchZoneAnalytics(context.Background(), []string{"zone1"})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Viewer.Zones, 1)
+
+	z := resp.Viewer.Zones[0]
+	assert.Equal(t, uint64(100), uint64(z.HTTPGroups().HTTP1mGroups[0].Sum.Requests))
+	assert.Equal(t, uint64(9), uint64(z.HTTPGroups().HTTP1mGroups[0].Unique.Uniques))
+	assert.Equal(t, "block", z.FirewallGroups().FirewallEventsAdaptiveGroups[0].Dimensions.Action)
+	assert.Equal(t, "unhealthy", z.HealthCheckGroups().HealthCheckEventsAdaptiveGroups[0].Dimensions.HealthStatus)
+	assert.Equal(t, uint16(502), z.AdaptiveGroups().HTTPRequestsAdaptiveGroups[0].Dimensions.OriginResponseStatus)
+	assert.Equal(t, 120.5, z.AdaptiveGroups().HTTPRequestsAdaptiveGroups[0].Avg.OriginResponseDurationMs)
+	assert.Equal(t, uint16(404), z.HTTPRequestsEdge().HTTPRequestsEdgeCountryHost[0].Dimensions.EdgeResponseStatus)
+	assert.Equal(t, "rl-1", z.RateLimitGroups().RateLimitEventsAdaptiveGroups[0].Dimensions.RuleID)
+	assert.Equal(t, "zone1", z.RateLimitGroups().ZoneTag)
+}
+
+func TestRunGraphQL_RecordsLatency(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"viewer": {"zones": []}}}`))
+	}))
+	defer srv.Close()
+
+	cloudflare.SetGraphQLEndpoint(srv.URL)
+	defer cloudflare.SetGraphQLEndpoint("")
+
+	cloudflare.APIRequestDuration.Reset()
+	setViper(t, "cf_api_token", "dummy-token")
+	_, err := cloudflare.FetchFirewallMetrics(context.Background(), []string{"zone1"})
+	assert.NoError(t, err)
+
+	observer, err := cloudflare.APIRequestDuration.GetMetricWith(prometheus.Labels{
+		"endpoint": "FetchFirewallMetrics",
+		"outcome":  "success",
+	})
+	assert.NoError(t, err)
+
+	var m dto.Metric
+	assert.NoError(t, observer.(prometheus.Histogram).Write(&m))
+	assert.Equal(t, uint64(1), m.GetHistogram().GetSampleCount())
+	assert.GreaterOrEqual(t, m.GetHistogram().GetSampleSum(), 0.02)
+}
+
+func TestFetchSSLCertificateStatus_BoundedConcurrency(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "ssl_fetch_concurrency", 2)
+
+	var inFlight, maxInFlight int32
+	httpmock.RegisterRegexpResponder("GET", regexp.MustCompile(`/zones/[^/]+/ssl/certificate_packs$`),
+		func(req *http.Request) (*http.Response, error) {
+			n := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for {
+				m := atomic.LoadInt32(&maxInFlight)
+				if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+					break
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+			return httpmock.NewStringResponse(200, `{"success": true, "result": [{"id": "pack", "status": "active"}]}`), nil
+		})
+
+	zoneIDs := []string{"z1", "z2", "z3", "z4", "z5", "z6"}
+	resp, err := cloudflare.FetchSSLCertificateStatus(context.Background(), zoneIDs)
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Result, len(zoneIDs))
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxInFlight), int32(2))
+}
+
+func TestFetchSSLCertificateStatus_RetryBackoff(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	defer cloudflare.ResetRetryBudget()
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "ssl_fetch_retries", 3)
+	setViper(t, "cf_api_retry_backoff", 3)
+
+	var waits []time.Duration
+	defer cloudflare.StubSleep(&waits)()
+
+	url := "https://api.cloudflare.com/client/v4/zones/z1/ssl/certificate_packs"
+	httpmock.RegisterResponder("GET", url, httpmock.NewStringResponder(429, `{"success": false}`))
+
+	resp, err := cloudflare.FetchSSLCertificateStatus(context.Background(), []string{"z1"})
+	assert.NoError(t, err)
+	assert.Empty(t, resp.Result)
+	assert.Equal(t, 3, httpmock.GetCallCountInfo()["GET "+url])
+	// One wait between attempts, none after the last
+	assert.Equal(t, []time.Duration{3 * time.Second, 6 * time.Second}, waits)
+
+	// Cancellation aborts the backoff instead of retrying
+	httpmock.ZeroCallCounters()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _ = cloudflare.FetchSSLCertificateStatus(ctx, []string{"z1"})
+	assert.LessOrEqual(t, httpmock.GetCallCountInfo()["GET "+url], 1)
+}
+
+func TestSetAPIBaseURL_SSLFetchUsesOverride(t *testing.T) {
+	var hits int32
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		gotPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success": true, "result": [{"id": "pack", "status": "active"}]}`))
+	}))
+	defer srv.Close()
+
+	setViper(t, "cf_api_token", "dummy-token")
+	cloudflare.SetAPIBaseURL(srv.URL + "/client/v4/")
+	defer cloudflare.SetAPIBaseURL("")
+
+	resp, err := cloudflare.FetchSSLCertificateStatus(context.Background(), []string{"zone1"})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Result, 1)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+	assert.Equal(t, "/client/v4/zones/zone1/ssl/certificate_packs", gotPath)
+}
+
+func TestFetchColoTotals_ParentContextCancelled(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {"viewer": {"zones": []}}}`))
+	}))
+	defer srv.Close()
+
+	cloudflare.SetGraphQLEndpoint(srv.URL)
+	defer cloudflare.SetGraphQLEndpoint("")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := cloudflare.FetchColoTotals(ctx, []string{"zone1"})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&hits))
+}
+
+func TestHTTPRequestsAdaptiveMetrics_OriginErrorStatusesVariable(t *testing.T) {
+	var gotStatuses []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string `json:"query"`
+			Variables struct {
+				Statuses []int `json:"statuses"`
+			} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		gotStatuses = body.Variables.Statuses
+		assert.Contains(t, body.Query, "originResponseStatus_in: $statuses")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {"viewer": {"zones": []}}}`))
+	}))
+	defer srv.Close()
+
+	cloudflare.SetGraphQLEndpoint(srv.URL)
+	defer cloudflare.SetGraphQLEndpoint("")
+
+	// Default list when unset
+	setViper(t, "cf_origin_error_statuses", "")
+	_, err := cloudflare.HTTPRequestsAdaptiveMetrics(context.Background(), []string{"zone1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{400, 404, 500, 502, 503, 504, 522, 523, 524}, gotStatuses)
+
+	// Configured list is passed through as a variable
+	setViper(t, "cf_origin_error_statuses", "521, 525,526")
+	_, err = cloudflare.HTTPRequestsAdaptiveMetrics(context.Background(), []string{"zone1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{521, 525, 526}, gotStatuses)
+}
+
+func TestAPIHTTPClient_Timeout(t *testing.T) {
+	setViper(t, "cf_request_timeout", 45)
+	setViper(t, "ssl_fetch_timeout", 10)
+	assert.Equal(t, 45*time.Second, cloudflare.APIHTTPClient().Timeout)
+
+	// Slow SSL fetches are not cut short by the client
+	setViper(t, "ssl_fetch_timeout", 60)
+	assert.Equal(t, 60*time.Second, cloudflare.APIHTTPClient().Timeout)
+}
+
+func TestSetZoneScrapeDelays_RejectsMalformedEntries(t *testing.T) {
+	defer cloudflare.SetZoneScrapeDelays("")
+	setViper(t, "scrape_delay", 300)
+
+	assert.NoError(t, cloudflare.SetZoneScrapeDelays(" slow = 1800, "))
+	assert.Equal(t, 1800*time.Second, cloudflare.ScrapeDelay([]string{"slow"}))
+
+	for _, raw := range []string{"slow", "=900", "slow=soon", "slow=-1"} {
+		assert.Error(t, cloudflare.SetZoneScrapeDelays(raw), raw)
+	}
+	// A rejected list keeps the previous delays
+	assert.Equal(t, 1800*time.Second, cloudflare.ScrapeDelay([]string{"slow"}))
+}
+
+func TestScrapeDelay_ZoneOverrideShiftsWindow(t *testing.T) {
+	var maxtimes []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables struct {
+				MaxTime time.Time `json:"maxtime"`
+			} `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		maxtimes = append(maxtimes, body.Variables.MaxTime)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"viewer": {"zones": []}}}`))
+	}))
+	defer srv.Close()
+
+	cloudflare.SetGraphQLEndpoint(srv.URL)
+	defer cloudflare.SetGraphQLEndpoint("")
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "scrape_delay", 300)
+	assert.NoError(t, cloudflare.SetZoneScrapeDelays("slow=1800"))
+	defer cloudflare.SetZoneScrapeDelays("")
+
+	assert.Equal(t, 300*time.Second, cloudflare.ScrapeDelay([]string{"zone1"}))
+	assert.Equal(t, 1800*time.Second, cloudflare.ScrapeDelay([]string{"slow"}))
+
+	_, err := cloudflare.FetchFirewallMetrics(context.Background(), []string{"zone1"})
+	assert.NoError(t, err)
+	_, err = cloudflare.FetchFirewallMetrics(context.Background(), []string{"slow"})
+	assert.NoError(t, err)
+
+	assert.Len(t, maxtimes, 2)
+	assert.InDelta(t, 1500, maxtimes[0].Sub(maxtimes[1]).Seconds(), 60)
+}
+
+func TestQueryLimit_PerTypeOverride(t *testing.T) {
+	var vars []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		vars = append(vars, body.Variables)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"viewer": {"zones": []}}}`))
+	}))
+	defer srv.Close()
+
+	cloudflare.SetGraphQLEndpoint(srv.URL)
+	defer cloudflare.SetGraphQLEndpoint("")
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "cf_query_limit", 1000)
+	setViper(t, "cf_query_limit_firewall", 5000)
+
+	_, err := cloudflare.FetchFirewallMetrics(context.Background(), []string{"zone1"})
+	assert.NoError(t, err)
+	_, err = cloudflare.HealthCheckEventsAdaptiveMetrics(context.Background(), []string{"zone1"})
+	assert.NoError(t, err)
+	_, err = cloudflare.FetchZoneAnalytics(context.Background(), []string{"zone1"})
+	assert.NoError(t, err)
+
+	assert.Len(t, vars, 3)
+	assert.Equal(t, float64(5000), vars[0]["limit"])
+	assert.Equal(t, float64(1000), vars[1]["limit"])
+	assert.Equal(t, float64(5000), vars[2]["firewallLimit"])
+	assert.Equal(t, float64(1000), vars[2]["httpLimit"])
+}
+
+func TestCircuitBreaker_Transitions(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := cloudflare.NewCircuitBreaker(3, time.Minute, func() time.Time { return now })
+
+	// Closed: failures below the threshold keep calls flowing
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, b.Allow())
+		b.Record(true)
+	}
+	assert.Equal(t, float64(0), testutil.ToFloat64(cloudflare.CircuitBreakerOpen))
+
+	// Third consecutive failure opens the breaker
+	assert.NoError(t, b.Allow())
+	b.Record(true)
+	assert.ErrorIs(t, b.Allow(), cloudflare.ErrCircuitOpen)
+	assert.Equal(t, float64(1), testutil.ToFloat64(cloudflare.CircuitBreakerOpen))
+
+	// After the cooldown a single probe is let through (half-open)
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.Allow())
+	assert.ErrorIs(t, b.Allow(), cloudflare.ErrCircuitOpen)
+	assert.Equal(t, float64(0), testutil.ToFloat64(cloudflare.CircuitBreakerOpen))
+
+	// A failed probe re-opens immediately
+	b.Record(true)
+	assert.ErrorIs(t, b.Allow(), cloudflare.ErrCircuitOpen)
+
+	// A successful probe closes it again
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.Allow())
+	b.Record(false)
+	assert.NoError(t, b.Allow())
+	assert.NoError(t, b.Allow())
+	assert.Equal(t, float64(0), testutil.ToFloat64(cloudflare.CircuitBreakerOpen))
+}
+
+func TestCircuitBreaker_ShortCircuitsGraphQL(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("upstream unavailable"))
+	}))
+	defer srv.Close()
+
+	cloudflare.SetGraphQLEndpoint(srv.URL)
+	defer cloudflare.SetGraphQLEndpoint("")
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "cf_circuit_breaker_threshold", 2)
+	setViper(t, "cf_circuit_breaker_cooldown", 60)
+	cloudflare.ResetAPIBreaker()
+	defer cloudflare.ResetAPIBreaker()
+
+	for i := 0; i < 2; i++ {
+		_, err := cloudflare.FetchFirewallMetrics(context.Background(), []string{"zone1"})
+		assert.Error(t, err)
+	}
+
+	_, err := cloudflare.FetchFirewallMetrics(context.Background(), []string{"zone1"})
+	assert.ErrorIs(t, err, cloudflare.ErrCircuitOpen)
+	assert.Equal(t, int32(2), hits.Load())
+}
+
+func TestCircuitBreaker_IgnoresCallerDeadline(t *testing.T) {
+	var hits atomic.Int32
+	var slow atomic.Bool
+	slow.Store(true)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if slow.Load() {
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {"viewer": {"zones": []}}}`))
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	cloudflare.SetGraphQLEndpoint(srv.URL)
+	defer cloudflare.SetGraphQLEndpoint("")
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "cf_circuit_breaker_threshold", 2)
+	setViper(t, "cf_circuit_breaker_cooldown", 60)
+	cloudflare.ResetAPIBreaker()
+	defer cloudflare.ResetAPIBreaker()
+
+	// The scrape deadline passing mid-request is not an API failure
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		_, err := cloudflare.FetchFirewallMetrics(ctx, []string{"zone1"})
+		cancel()
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	}
+
+	slow.Store(false)
+	_, err := cloudflare.FetchFirewallMetrics(context.Background(), []string{"zone1"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), hits.Load())
+}
+
+func TestFetchHTTPMetrics_GroupGranularity(t *testing.T) {
+	var body struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {"viewer": {"zones": []}}}`))
+	}))
+	defer srv.Close()
+
+	cloudflare.SetGraphQLEndpoint(srv.URL)
+	defer cloudflare.SetGraphQLEndpoint("")
+	setViper(t, "cf_api_token", "dummy-token")
+
+	// Default: minute groups over the last minute
+	_, err := cloudflare.FetchHTTPMetrics(context.Background(), []string{"zone1"})
+	assert.NoError(t, err)
+	assert.Contains(t, body.Query, "httpRequests1mGroups(limit")
+	assert.NotContains(t, body.Query, "httpRequests1hGroups")
+
+	// 1h: hour groups over the last complete hour, aliased to the 1m key
+	setViper(t, "cf_group_granularity", "1h")
+	_, err = cloudflare.FetchHTTPMetrics(context.Background(), []string{"zone1"})
+	assert.NoError(t, err)
+	assert.Contains(t, body.Query, "httpRequests1mGroups: httpRequests1hGroups(limit")
+
+	minT, err := time.Parse(time.RFC3339, body.Variables["httpMintime"].(string))
+	assert.NoError(t, err)
+	maxT, err := time.Parse(time.RFC3339, body.Variables["httpMaxtime"].(string))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, maxT.Sub(minT))
+	assert.True(t, maxT.Equal(maxT.Truncate(time.Hour)))
+}
+
+func TestFetchZones_RetryBackoff(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "cf_api_max_retries", 3)
+	setViper(t, "cf_api_retry_backoff", 2)
+
+	var waits []time.Duration
+	defer cloudflare.StubSleep(&waits)()
+
+	failing := httpmock.NewStringResponder(400, `{"success": false, "errors": [{"code": 1000, "message": "bad request"}]}`)
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/zones", failing)
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/accounts", failing)
+
+	_, err := cloudflare.FetchZones(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 3, httpmock.GetCallCountInfo()["GET https://api.cloudflare.com/client/v4/zones"])
+	// One wait between attempts, none after the last
+	assert.Equal(t, []time.Duration{2 * time.Second, 4 * time.Second}, waits)
+
+	waits = nil
+	_, err = cloudflare.FetchAccounts(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 3, httpmock.GetCallCountInfo()["GET https://api.cloudflare.com/client/v4/accounts"])
+	assert.Equal(t, []time.Duration{2 * time.Second, 4 * time.Second}, waits)
+
+	// Cancellation aborts the backoff instead of retrying
+	httpmock.ZeroCallCounters()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = cloudflare.FetchZones(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.LessOrEqual(t, httpmock.GetCallCountInfo()["GET https://api.cloudflare.com/client/v4/zones"], 1)
+}
+
+func TestRunGraphQL_RecordsRowsRead(t *testing.T) {
+	withCost := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if withCost {
+			_, _ = w.Write([]byte(`{"data": {"viewer": {"zones": [{"zoneTag": "zone1"}]}}, "extensions": {"cost": {"rowsRead": 1250}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"viewer": {"zones": [{"zoneTag": "zone1"}]}}}`))
+	}))
+	defer srv.Close()
+
+	cloudflare.SetGraphQLEndpoint(srv.URL)
+	defer cloudflare.SetGraphQLEndpoint("")
+	setViper(t, "cf_api_token", "dummy-token")
+	cloudflare.GraphQLRowsRead.Reset()
+
+	resp, err := cloudflare.FetchFirewallMetrics(context.Background(), []string{"zone1"})
+	assert.NoError(t, err)
+	// The data still decodes with the extensions read off the body
+	assert.Equal(t, "zone1", resp.Viewer.Zones[0].ZoneTag)
+	assert.Equal(t, float64(1250), testutil.ToFloat64(cloudflare.GraphQLRowsRead.WithLabelValues("FetchFirewallMetrics")))
+
+	// Responses without cost information emit nothing
+	withCost = false
+	cloudflare.GraphQLRowsRead.Reset()
+	_, err = cloudflare.FetchFirewallMetrics(context.Background(), []string{"zone1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, testutil.CollectAndCount(cloudflare.GraphQLRowsRead))
+}
+
+func TestWatchTokenFile_UsesRotatedToken(t *testing.T) {
+	var lastAuth atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/client/v4/user/tokens/verify" {
+			w.Write([]byte(`{"success": true, "result": {"id": "t", "status": "active"}}`))
+			return
+		}
+		lastAuth.Store(r.Header.Get("Authorization"))
+		w.Write([]byte(`{"data": {"viewer": {"zones": []}}}`))
+	}))
+	defer srv.Close()
+
+	cloudflare.SetGraphQLEndpoint(srv.URL)
+	defer cloudflare.SetGraphQLEndpoint("")
+	cloudflare.SetAPIBaseURL(srv.URL + "/client/v4/")
+	defer cloudflare.SetAPIBaseURL("")
+	setViper(t, "cf_api_token", "flag-token")
+	defer cloudflare.ResetTokenFile()
+
+	path := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(path, []byte("first-token\n"), 0o600))
+	assert.NoError(t, cloudflare.LoadTokenFile(path))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, cloudflare.WatchTokenFile(ctx, path))
+
+	_, err := cloudflare.FetchBotScore(ctx, []string{"zone1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer first-token", lastAuth.Load())
+
+	assert.NoError(t, os.WriteFile(path, []byte("second-token\n"), 0o600))
+	assert.Eventually(t, func() bool {
+		if _, err := cloudflare.FetchBotScore(ctx, []string{"zone1"}); err != nil {
+			return false
+		}
+		return lastAuth.Load() == "Bearer second-token"
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestPermissionError_FromForbiddenResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"success": false, "errors": [{"code": 10000, "message": "Authentication error"}]}`))
+	}))
+	defer srv.Close()
+
+	cloudflare.SetAPIBaseURL(srv.URL + "/client/v4/")
+	defer cloudflare.SetAPIBaseURL("")
+	cloudflare.SetGraphQLEndpoint(srv.URL)
+	defer cloudflare.SetGraphQLEndpoint("")
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "ssl_fetch_retries", 1)
+	defer cloudflare.ResetAPIBreaker()
+
+	_, err := cloudflare.FetchLogpushJobs(context.Background(), "zones", "zone1")
+	var permErr *cloudflare.PermissionError
+	assert.ErrorAs(t, err, &permErr)
+	assert.Equal(t, http.StatusForbidden, permErr.StatusCode)
+
+	// GraphQL reports the status only through the client error
+	_, err = cloudflare.FetchBotScore(context.Background(), []string{"zone1"})
+	assert.True(t, cloudflare.IsPermissionError(err))
+}
+
+func TestFetchZones_RetryBudgetFailsFast(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "cf_api_max_retries", 5)
+	setViper(t, "cf_retry_budget", 1)
+	cloudflare.ResetRetryBudget()
+	defer cloudflare.ResetRetryBudget()
+
+	var waits []time.Duration
+	defer cloudflare.StubSleep(&waits)()
+
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/zones",
+		httpmock.NewStringResponder(400, `{"success": false, "errors": [{"code": 1000, "message": "bad request"}]}`))
+
+	exhaustedBefore := testutil.ToFloat64(cloudflare.RetryBudgetExhaustedTotal)
+
+	// The single retry in the budget is used, the next one fails fast
+	_, err := cloudflare.FetchZones(context.Background())
+	assert.ErrorIs(t, err, cloudflare.ErrRetryBudgetExhausted)
+	assert.Equal(t, 2, httpmock.GetCallCountInfo()["GET https://api.cloudflare.com/client/v4/zones"])
+	assert.Len(t, waits, 1)
+
+	// Still exhausted: no retry at all
+	httpmock.ZeroCallCounters()
+	_, err = cloudflare.FetchZones(context.Background())
+	assert.ErrorIs(t, err, cloudflare.ErrRetryBudgetExhausted)
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["GET https://api.cloudflare.com/client/v4/zones"])
+	assert.Equal(t, float64(2), testutil.ToFloat64(cloudflare.RetryBudgetExhaustedTotal)-exhaustedBefore)
+}
+
+func TestFetchPageShieldScripts_AllZonesFail(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	setViper(t, "cf_api_token", "dummy-token")
+
+	httpmock.RegisterRegexpResponder("GET", regexp.MustCompile(`/zones/zone[12]/page_shield/scripts`),
+		httpmock.NewStringResponder(403, `{"success": false, "errors": [{"code": 10000, "message": "Authentication error"}]}`))
+
+	resp, err := cloudflare.FetchPageShieldScripts(context.Background(), []string{"zone1", "zone2"})
+
+	assert.Error(t, err)
+	assert.True(t, cloudflare.IsPermissionError(err))
+	assert.Nil(t, resp)
+}
+
+func TestFetchWAFRuleCategories_ManagedRulesetsOnly(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	setViper(t, "cf_api_token", "dummy-token")
+
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/zones/zone1/rulesets",
+		httpmock.NewStringResponder(200, `{"success": true, "result": [
+			{"id": "managed", "name": "Cloudflare Managed Ruleset", "kind": "managed", "phase": "http_request_firewall_managed"},
+			{"id": "custom", "name": "Custom rules", "kind": "zone", "phase": "http_request_firewall_custom"}
+		]}`))
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/zones/zone1/rulesets/managed",
+		httpmock.NewStringResponder(200, `{"success": true, "result": {"id": "managed", "rules": [
+			{"id": "r-sqli", "categories": ["sqli"]},
+			{"id": "r-both", "categories": ["xss", "php"]},
+			{"id": "r-none"}
+		]}}`))
+
+	resp, err := cloudflare.FetchWAFRuleCategories(context.Background(), []string{"zone1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"r-sqli": {"sqli"},
+		"r-both": {"xss", "php"},
+	}, resp["zone1"])
+	assert.Equal(t, 0, httpmock.GetCallCountInfo()["GET https://api.cloudflare.com/client/v4/zones/zone1/rulesets/custom"])
+}
@@ -0,0 +1,144 @@
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lablabs/cloudflare-exporter/internal/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+)
+
+// ErrCircuitOpen is returned instead of calling the API while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("cloudflare API circuit breaker is open")
+
+// CircuitBreakerOpen is 1 while API calls are short-circuited, 0 otherwise.
+// It is registered by metrics.MustRegisterMetrics.
+var CircuitBreakerOpen = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "cloudflare_exporter_circuit_breaker_open",
+	Help: "Whether the Cloudflare API circuit breaker is open (1) or closed/half-open (0)",
+})
+
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker opens after threshold consecutive API failures. While open,
+// calls fail fast with ErrCircuitOpen; after cooldown a single probe call is
+// let through (half-open) and its outcome closes or re-opens the breaker.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold func() int
+	cooldown  func() time.Duration
+	now       func() time.Time
+
+	state    int
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// apiBreaker guards every Cloudflare API call.
+var apiBreaker = &circuitBreaker{
+	threshold: func() int { return viper.GetInt("cf_circuit_breaker_threshold") },
+	cooldown:  func() time.Duration { return time.Duration(viper.GetInt("cf_circuit_breaker_cooldown")) * time.Second },
+	now:       time.Now,
+}
+
+// allow returns ErrCircuitOpen if the call must not be made.
+func (b *circuitBreaker) allow() error {
+	if b.threshold() <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown() {
+			return ErrCircuitOpen
+		}
+		b.setState(breakerHalfOpen)
+		b.probing = true
+		return nil
+	case breakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	}
+	return nil
+}
+
+// record updates the breaker with the outcome of an allowed call. Calls that
+// ended without a verdict (e.g. cancelled) only release the half-open probe.
+func (b *circuitBreaker) record(failed, verdict bool) {
+	if b.threshold() <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if !verdict {
+		return
+	}
+
+	if !failed {
+		b.failures = 0
+		if b.state != breakerClosed {
+			logging.Info("Cloudflare API recovered, closing circuit breaker", nil)
+			b.setState(breakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || (b.state == breakerClosed && b.failures >= b.threshold()) {
+		logging.Warn("Cloudflare API failing, opening circuit breaker", map[string]interface{}{
+			"consecutive_failures": b.failures,
+			"cooldown":             b.cooldown().String(),
+		})
+		b.openedAt = b.now()
+		b.setState(breakerOpen)
+	}
+}
+
+func (b *circuitBreaker) setState(state int) {
+	b.state = state
+	if state == breakerOpen {
+		CircuitBreakerOpen.Set(1)
+	} else {
+		CircuitBreakerOpen.Set(0)
+	}
+}
+
+// recordAPIResult feeds err of a request made with ctx into the breaker. Only
+// errors suggesting the API is unavailable count as failures; rejected
+// credentials and GraphQL errors for a single query mean the API answered.
+// A cancelled or expired caller context, e.g. after scrape_timeout, says
+// nothing about the API and is not counted.
+func recordAPIResult(ctx context.Context, err error) {
+	switch {
+	case err == nil:
+		apiBreaker.record(false, true)
+	case errors.Is(err, context.Canceled):
+		apiBreaker.record(false, false)
+	case ctx.Err() != nil && !errors.Is(context.Cause(ctx), errRequestTimeout):
+		apiBreaker.record(false, false)
+	case isAuthError(err):
+		apiBreaker.record(false, true)
+	case strings.HasPrefix(err.Error(), "graphql: "):
+		apiBreaker.record(false, true)
+	default:
+		apiBreaker.record(true, true)
+	}
+}
@@ -0,0 +1,100 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+)
+
+// CheckResult reports whether the configured credentials can read one metric family.
+type CheckResult struct {
+	Family  string
+	Err     error
+	Skipped string
+}
+
+// Status returns a short human readable status for the check.
+func (r CheckResult) Status() string {
+	switch {
+	case r.Skipped != "":
+		return "skipped (" + r.Skipped + ")"
+	case r.Err == nil:
+		return "OK"
+	case isPermissionDenied(r.Err):
+		return "permission denied"
+	default:
+		return "error: " + r.Err.Error()
+	}
+}
+
+// familyCheck maps a metric family to the fetcher that exercises its permission.
+type familyCheck struct {
+	family string
+	zone   func(ctx context.Context, zoneID string) error
+	acct   func(ctx context.Context, accountID string) error
+}
+
+var familyChecks = []familyCheck{
+	{family: "zone analytics", zone: func(ctx context.Context, zoneID string) error {
+		_, err := FetchHTTPMetrics(ctx, []string{zoneID})
+		return err
+	}},
+	{family: "firewall", zone: func(ctx context.Context, zoneID string) error {
+		_, err := FetchFirewallMetrics(ctx, []string{zoneID})
+		return err
+	}},
+	{family: "load balancers", zone: func(ctx context.Context, zoneID string) error {
+		_, err := FetchLoadBalancerTotals(ctx, []string{zoneID})
+		return err
+	}},
+	{family: "ssl certificates", zone: func(ctx context.Context, zoneID string) error {
+		_, err := fetchSSLForZone(ctx, zoneID)
+		return err
+	}},
+	{family: "workers", acct: func(ctx context.Context, accountID string) error {
+		_, err := FetchWorkerTotals(ctx, accountID)
+		return err
+	}},
+	{family: "logpush", acct: func(ctx context.Context, accountID string) error {
+		_, err := FetchLogpushAccount(ctx, accountID)
+		return err
+	}},
+	{family: "magic transit", acct: func(ctx context.Context, accountID string) error {
+		_, err := MagicTransitTunnelHealthChecksAdaptiveGroups(ctx, accountID)
+		return err
+	}},
+}
+
+// CheckPermissions validates the configured credentials and reports which metric
+// families they can read. An error is returned when zones or accounts cannot be listed.
+func CheckPermissions(ctx context.Context) ([]CheckResult, error) {
+	zones, err := FetchZones(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list zones: %w", err)
+	}
+	accounts, err := FetchAccounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+
+	results := []CheckResult{{Family: "zones"}, {Family: "accounts"}}
+	for _, c := range familyChecks {
+		result := CheckResult{Family: c.family}
+		switch {
+		case c.zone != nil && len(zones) == 0:
+			result.Skipped = "no zones"
+		case c.zone != nil:
+			result.Err = c.zone(ctx, zones[0].ID)
+		case len(accounts) == 0:
+			result.Skipped = "no accounts"
+		default:
+			result.Err = c.acct(ctx, accounts[0].ID)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// isPermissionDenied reports whether err indicates the token lacks access.
+func isPermissionDenied(err error) bool {
+	return IsPermissionError(wrapPermissionError(0, err))
+}
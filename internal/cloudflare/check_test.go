@@ -0,0 +1,86 @@
+package cloudflare_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lablabs/cloudflare-exporter/internal/cloudflare"
+)
+
+const (
+	zonesBody    = `{"success": true, "errors": [], "messages": [], "result": [{"id": "zone1", "name": "example.com"}]}`
+	accountsBody = `{"success": true, "errors": [], "messages": [], "result": [{"id": "acc1", "name": "Test Account"}]}`
+)
+
+func registerListResponders() {
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/zones",
+		httpmock.NewStringResponder(200, zonesBody))
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/accounts",
+		httpmock.NewStringResponder(200, accountsBody))
+}
+
+func statusByFamily(results []cloudflare.CheckResult) map[string]string {
+	statuses := map[string]string{}
+	for _, r := range results {
+		statuses[r.Family] = r.Status()
+	}
+	return statuses
+}
+
+func TestCheckPermissions_AllGranted(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	setViper(t, "cf_api_token", "dummy-token")
+
+	registerListResponders()
+	httpmock.RegisterResponder("POST", cloudflare.DefaultGraphQLEndpoint,
+		httpmock.NewStringResponder(200, `{"data": {"viewer": {"zones": [], "accounts": [{"logpushHealthAdaptiveGroups": []}]}}}`))
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/zones/zone1/ssl/certificate_packs",
+		httpmock.NewStringResponder(200, `{"result": []}`))
+
+	results, err := cloudflare.CheckPermissions(context.Background())
+
+	assert.NoError(t, err)
+	for family, status := range statusByFamily(results) {
+		assert.Equal(t, "OK", status, family)
+	}
+}
+
+func TestCheckPermissions_PermissionDenied(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	setViper(t, "cf_api_token", "dummy-token")
+
+	registerListResponders()
+	httpmock.RegisterResponder("POST", cloudflare.DefaultGraphQLEndpoint,
+		httpmock.NewStringResponder(200, `{"data": null, "errors": [{"message": "not authorized for that account"}]}`))
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/zones/zone1/ssl/certificate_packs",
+		httpmock.NewStringResponder(403, `{"success": false, "errors": [{"code": 10000, "message": "Authentication error"}]}`))
+
+	results, err := cloudflare.CheckPermissions(context.Background())
+
+	assert.NoError(t, err)
+	statuses := statusByFamily(results)
+	assert.Equal(t, "OK", statuses["zones"])
+	assert.Equal(t, "OK", statuses["accounts"])
+	assert.Equal(t, "permission denied", statuses["firewall"])
+	assert.Equal(t, "permission denied", statuses["workers"])
+	assert.Equal(t, "permission denied", statuses["ssl certificates"])
+}
+
+func TestCheckPermissions_InvalidCredentials(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	setViper(t, "cf_api_token", "bad-token")
+
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/zones",
+		httpmock.NewStringResponder(401, `{"success": false, "errors": [{"code": 10000, "message": "Authentication error"}]}`))
+
+	results, err := cloudflare.CheckPermissions(context.Background())
+
+	assert.Error(t, err)
+	assert.Nil(t, results)
+}
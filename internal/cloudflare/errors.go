@@ -0,0 +1,89 @@
+package cloudflare
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+)
+
+// PermissionError is returned when the Cloudflare API rejects the configured
+// credentials or they lack access to the requested data. It wraps the
+// underlying API error.
+type PermissionError struct {
+	// StatusCode is the HTTP status of the response, or 0 when the failure
+	// was recognised from the error itself (e.g. a GraphQL error message).
+	StatusCode int
+	Err        error
+}
+
+func (e *PermissionError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("permission denied (status %d): %s", e.StatusCode, e.Err)
+	}
+	return "permission denied: " + e.Err.Error()
+}
+
+func (e *PermissionError) Unwrap() error {
+	return e.Err
+}
+
+// IsPermissionError reports whether err is or wraps a *PermissionError.
+func IsPermissionError(err error) bool {
+	var permErr *PermissionError
+	return errors.As(err, &permErr)
+}
+
+// wrapPermissionError returns err as a *PermissionError when statusCode is
+// 401/403 or err is recognised as an authentication or authorization
+// failure, and err unchanged otherwise.
+func wrapPermissionError(statusCode int, err error) error {
+	if err == nil || IsPermissionError(err) {
+		return err
+	}
+	if statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden {
+		return &PermissionError{StatusCode: statusCode, Err: err}
+	}
+	if isAuthError(err) || hasPermissionMarker(err) {
+		return &PermissionError{Err: err}
+	}
+	return err
+}
+
+// isAuthError reports whether err is a REST authentication or authorization failure.
+func isAuthError(err error) bool {
+	var authnErr *cloudflare.AuthenticationError
+	var authzErr *cloudflare.AuthorizationError
+	return errors.As(err, &authnErr) || errors.As(err, &authzErr)
+}
+
+// IsProductNotEnabledError reports whether err is a GraphQL error saying the
+// account has no access to the queried dataset, which is what Cloudflare
+// returns for accounts that don't use a product such as Durable Objects or
+// Queues.
+func IsProductNotEnabledError(err error) bool {
+	if err == nil || IsPermissionError(err) {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range []string{"does not have access to the path", "not enabled", "not entitled"} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPermissionMarker reports whether the error message reads like a
+// permission failure, for GraphQL errors which carry no status.
+func hasPermissionMarker(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, marker := range []string{"not authorized", "permission", "access denied", "status: 401", "status: 403"} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package cloudflare
+
+import (
+	"context"
+	"time"
+)
+
+// SetAuthHeaders exposes setAuthHeaders to the external test package.
+var SetAuthHeaders = setAuthHeaders
+
+// APIHTTPClient exposes apiHTTPClient to the external test package.
+var APIHTTPClient = apiHTTPClient
+
+// CircuitBreaker exposes circuitBreaker to the external test package.
+type CircuitBreaker = circuitBreaker
+
+// NewCircuitBreaker returns a breaker with fixed settings and clock.
+func NewCircuitBreaker(threshold int, cooldown time.Duration, now func() time.Time) *CircuitBreaker {
+	return &circuitBreaker{
+		threshold: func() int { return threshold },
+		cooldown:  func() time.Duration { return cooldown },
+		now:       now,
+	}
+}
+
+func (b *circuitBreaker) Allow() error { return b.allow() }
+
+func (b *circuitBreaker) Record(failed bool) { b.record(failed, true) }
+
+// StubSleep replaces the backoff sleep, recording each wait in waits. The
+// returned func restores it.
+func StubSleep(waits *[]time.Duration) func() {
+	orig := sleepContext
+	sleepContext = func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return ctx.Err()
+	}
+	return func() { sleepContext = orig }
+}
+
+// ResetAPIBreaker closes the shared API breaker.
+func ResetAPIBreaker() {
+	apiBreaker.mu.Lock()
+	defer apiBreaker.mu.Unlock()
+	apiBreaker.failures = 0
+	apiBreaker.probing = false
+	apiBreaker.setState(breakerClosed)
+}
+
+// ResetRetryBudget refills the shared retry budget.
+func ResetRetryBudget() {
+	apiRetryBudget.mu.Lock()
+	defer apiRetryBudget.mu.Unlock()
+	apiRetryBudget.limiter = nil
+}
+
+// ResetTokenFile drops the token loaded from cf_api_token_file.
+func ResetTokenFile() {
+	fileToken.Store(nil)
+}
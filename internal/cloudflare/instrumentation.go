@@ -0,0 +1,92 @@
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/lablabs/cloudflare-exporter/internal/models"
+	"github.com/machinebox/graphql"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// APIRequestDuration records the latency of Cloudflare API calls by endpoint and outcome.
+// It is registered by metrics.MustRegisterMetrics.
+var APIRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "cloudflare_api_request_duration_seconds",
+	Help:    "Latency of Cloudflare API requests by endpoint and outcome",
+	Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+}, []string{"endpoint", "outcome"},
+)
+
+// GraphQLRowsRead sums the rows read reported in GraphQL response extensions
+// by operation. Nothing is recorded for responses without cost information.
+// It is registered by metrics.MustRegisterMetrics.
+var GraphQLRowsRead = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "cloudflare_exporter_graphql_rows_read_total",
+	Help: "Rows read by Cloudflare GraphQL queries as reported by the API, by operation",
+}, []string{"operation"},
+)
+
+// extensionsRecorder decodes the extensions of GraphQL responses, which the
+// graphql client drops, keeps the status code and hands the body on unchanged.
+type extensionsRecorder struct {
+	next       http.RoundTripper
+	extensions models.GraphQLExtensions
+	statusCode int
+}
+
+func (r *extensionsRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	r.statusCode = resp.StatusCode
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	// A body that isn't JSON is left for the graphql client to report
+	_ = json.Unmarshal(body, &r.extensions)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// observeAPIRequest records the duration since start for endpoint.
+func observeAPIRequest(endpoint string, start time.Time, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	APIRequestDuration.With(prometheus.Labels{
+		"endpoint": endpoint,
+		"outcome":  outcome,
+	}).Observe(time.Since(start).Seconds())
+}
+
+// runGraphQL executes request against the configured GraphQL endpoint and
+// records its latency under operation. It fails fast while the circuit
+// breaker is open. Rejected credentials are returned as a *PermissionError.
+func runGraphQL(ctx context.Context, operation string, request *graphql.Request, resp interface{}) error {
+	if err := apiBreaker.allow(); err != nil {
+		return err
+	}
+
+	httpClient := apiHTTPClient()
+	recorder := &extensionsRecorder{next: httpClient.Transport}
+	httpClient.Transport = recorder
+	graphqlClient := graphql.NewClient(cfGraphQLEndpoint, graphql.WithHTTPClient(httpClient))
+
+	start := time.Now()
+	err := graphqlClient.Run(ctx, request, resp)
+	observeAPIRequest(operation, start, err)
+	recordAPIResult(ctx, err)
+	if rows, ok := recorder.extensions.RowsRead(); ok {
+		GraphQLRowsRead.With(prometheus.Labels{"operation": operation}).Add(rows)
+	}
+	return wrapPermissionError(recorder.statusCode, err)
+}
@@ -0,0 +1,81 @@
+package cloudflare
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/lablabs/cloudflare-exporter/internal/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+	"golang.org/x/time/rate"
+)
+
+// ErrRetryBudgetExhausted is returned instead of retrying once the shared
+// retry budget is spent.
+var ErrRetryBudgetExhausted = errors.New("cloudflare API retry budget exhausted")
+
+// RetriesTotal counts the API call retries taken from the retry budget.
+// It is registered by metrics.MustRegisterMetrics.
+var RetriesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "cloudflare_exporter_retries_total",
+	Help: "Number of Cloudflare API call retries",
+})
+
+// RetryBudgetExhaustedTotal counts the retries skipped because the retry
+// budget was spent. It is registered by metrics.MustRegisterMetrics.
+var RetryBudgetExhaustedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "cloudflare_exporter_retries_budget_exhausted_total",
+	Help: "Number of Cloudflare API call retries skipped because the shared retry budget was exhausted",
+})
+
+// retryBudget is a token bucket shared by all retry loops, so that during an
+// outage the retries across all metric families stay bounded. It holds up to
+// perMinute retries and refills at perMinute per minute.
+type retryBudget struct {
+	mu        sync.Mutex
+	perMinute func() int
+
+	limiter *rate.Limiter
+	limit   int
+}
+
+// apiRetryBudget bounds the retries of every Cloudflare API call.
+var apiRetryBudget = &retryBudget{
+	perMinute: func() int { return viper.GetInt("cf_retry_budget") },
+}
+
+// take spends one retry, returning ErrRetryBudgetExhausted when none is left.
+// A perMinute of 0 disables the budget.
+func (b *retryBudget) take() error {
+	n := b.perMinute()
+	if n > 0 {
+		b.mu.Lock()
+		if b.limiter == nil || b.limit != n {
+			b.limiter = rate.NewLimiter(rate.Limit(float64(n)/60), n)
+			b.limit = n
+		}
+		ok := b.limiter.Allow()
+		b.mu.Unlock()
+
+		if !ok {
+			RetryBudgetExhaustedTotal.Inc()
+			return ErrRetryBudgetExhausted
+		}
+	}
+	RetriesTotal.Inc()
+	return nil
+}
+
+// spendRetry takes a retry from the shared budget before retrying after
+// lastErr. When the budget is spent it returns an error wrapping both
+// ErrRetryBudgetExhausted and lastErr, and the caller fails fast.
+func spendRetry(lastErr error) error {
+	if err := apiRetryBudget.take(); err != nil {
+		logging.Warn("Retry budget exhausted, not retrying", map[string]interface{}{
+			"error": lastErr.Error(),
+		})
+		return fmt.Errorf("%w: %w", err, lastErr)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+
+	"github.com/lablabs/cloudflare-exporter/internal/logging"
+)
+
+// fileToken holds the API token read from cf_api_token_file. It is swapped
+// atomically on rotation so in-flight fetchers never see a partial value.
+var fileToken atomic.Pointer[string]
+
+// apiToken returns the API token, preferring the one loaded by LoadTokenFile
+// over cf_api_token.
+func apiToken() string {
+	if t := fileToken.Load(); t != nil {
+		return *t
+	}
+	return viper.GetString("cf_api_token")
+}
+
+// LoadTokenFile reads the API token from path. Surrounding whitespace is
+// trimmed and an empty file is an error.
+func LoadTokenFile(path string) error {
+	token, err := readTokenFile(path)
+	if err != nil {
+		return err
+	}
+	fileToken.Store(&token)
+	return nil
+}
+
+func readTokenFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// WatchTokenFile reloads the API token from path whenever it changes, until
+// ctx is cancelled. A rotated token is verified against the API; an empty or
+// unreadable file keeps the previous token.
+func WatchTokenFile(ctx context.Context, path string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch token file: %w", err)
+	}
+	// Watch the directory: editors and Kubernetes secret mounts replace the
+	// file (or a symlink to it) instead of writing it in place.
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch token file: %w", err)
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				reloadTokenFile(ctx, path)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logging.ErrorErr("Token file watcher error", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// reloadTokenFile swaps in the token from path if it differs from the current one.
+func reloadTokenFile(ctx context.Context, path string) {
+	token, err := readTokenFile(path)
+	if err != nil {
+		logging.Error("Failed to reload API token, keeping the previous one", map[string]interface{}{
+			"path":  path,
+			"error": err.Error(),
+		})
+		return
+	}
+	if token == apiToken() {
+		return
+	}
+	fileToken.Store(&token)
+	logging.Info("Reloaded API token", map[string]interface{}{"path": path})
+
+	api, err := newAPIClient()
+	if err == nil {
+		_, err = api.VerifyAPIToken(ctx)
+	}
+	if err != nil {
+		logging.Error("Rotated API token failed verification", map[string]interface{}{
+			"path":  path,
+			"error": err.Error(),
+		})
+	}
+}
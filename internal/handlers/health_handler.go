@@ -1,10 +1,78 @@
 package handlers
 
-import "github.com/gin-gonic/gin"
+import (
+	"net/http"
+	"strconv"
+	"time"
 
-// HealthCheck function handles health check.
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// HealthCheck function handles liveness check.
 func HealthCheck(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"status": "healthy",
 	})
 }
+
+// VerboseHealthCheck returns a liveness handler that behaves like HealthCheck
+// and, with ?verbose=1, also includes the value from status for debugging.
+func VerboseHealthCheck(status func() interface{}) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if verbose, _ := strconv.ParseBool(c.Query("verbose")); !verbose {
+			HealthCheck(c)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status": "healthy",
+			"scrape": status(),
+		})
+	}
+}
+
+// ReadinessCheck returns a handler reporting ready once lastSuccess reports a
+// scrape within the ready_max_staleness window.
+func ReadinessCheck(lastSuccess func() time.Time) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		last := lastSuccess()
+		if last.IsZero() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status": "not ready",
+				"reason": "no successful scrape yet",
+			})
+			return
+		}
+
+		maxStaleness := time.Duration(viper.GetInt("ready_max_staleness")) * time.Second
+		if age := time.Since(last); age > maxStaleness {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":            "not ready",
+				"reason":            "last successful scrape is stale",
+				"last_scrape":       last.UTC().Format(time.RFC3339),
+				"staleness_seconds": int(age.Seconds()),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":      "ready",
+			"last_scrape": last.UTC().Format(time.RFC3339),
+		})
+	}
+}
+
+// AvailableMetrics returns a handler listing the metric names accepted by
+// metrics_denylist, as JSON.
+func AvailableMetrics(names func() []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"metrics": names()})
+	}
+}
+
+// Snapshot returns a handler serving the value from snapshot as JSON.
+func Snapshot(snapshot func() interface{}) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, snapshot())
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// setViper sets key to value and restores the previous value when the test ends.
+func setViper(t *testing.T, key string, value interface{}) {
+	t.Helper()
+	prev := viper.Get(key)
+	viper.Set(key, value)
+	t.Cleanup(func() { viper.Set(key, prev) })
+}
+
+func serveReady(lastSuccess func() time.Time) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ready", ReadinessCheck(lastSuccess))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/ready", nil)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestHealthCheck_AlwaysHealthy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", HealthCheck)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/health", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestVerboseHealthCheck(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", VerboseHealthCheck(func() interface{} {
+		return map[string]string{"last_error": "boom"}
+	}))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/health", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "boom")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/health?verbose=1", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status": "healthy", "scrape": {"last_error": "boom"}}`, w.Body.String())
+}
+
+func TestReadinessCheck_NotReadyYet(t *testing.T) {
+	setViper(t, "ready_max_staleness", 300)
+	w := serveReady(func() time.Time { return time.Time{} })
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "no successful scrape yet")
+}
+
+func TestReadinessCheck_Stale(t *testing.T) {
+	setViper(t, "ready_max_staleness", 300)
+	w := serveReady(func() time.Time { return time.Now().Add(-10 * time.Minute) })
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "stale")
+}
+
+func TestReadinessCheck_Ready(t *testing.T) {
+	setViper(t, "ready_max_staleness", 300)
+	w := serveReady(func() time.Time { return time.Now().Add(-time.Minute) })
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http/pprof"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Pprof serves the net/http/pprof endpoints under a /*profile route.
+func Pprof(c *gin.Context) {
+	switch strings.TrimPrefix(c.Param("profile"), "/") {
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		// The index page and the named profiles (heap, goroutine, ...)
+		pprof.Index(c.Writer, c.Request)
+	}
+}
@@ -5,11 +5,54 @@ import (
 	"context"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/time/rate"
 )
 
+// WaitSeconds records how long each Wait call waited for the limiter, 0 when
+// a token was available. It is registered by metrics.MustRegisterMetrics.
+var WaitSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Name:    "cloudflare_exporter_rate_limit_wait_seconds",
+	Help:    "Time Cloudflare API calls waited for the exporter rate limiter",
+	Buckets: []float64{0, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+})
+
+// BlockedTotal counts the Wait calls that had to wait for a token.
+// It is registered by metrics.MustRegisterMetrics.
+var BlockedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "cloudflare_exporter_rate_limit_blocked_total",
+	Help: "Number of Cloudflare API calls that waited for the exporter rate limiter",
+})
+
+// Limiter is a rate.Limiter that records its waits in WaitSeconds and BlockedTotal.
+type Limiter struct {
+	limiter *rate.Limiter
+}
+
+// New wraps l.
+func New(l *rate.Limiter) *Limiter {
+	return &Limiter{limiter: l}
+}
+
+// Wait blocks until the limiter allows the request or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if l.limiter.Allow() {
+		WaitSeconds.Observe(0)
+		return nil
+	}
+
+	BlockedTotal.Inc()
+	start := time.Now()
+	err := l.limiter.Wait(ctx)
+	WaitSeconds.Observe(time.Since(start).Seconds())
+	return err
+}
+
 // Cloudflare API rate limiter (4 requests/second with burst of 2)
-var CloudflareLimiter = rate.NewLimiter(rate.Every(250*time.Millisecond), 2)
+var CloudflareLimiter = New(rate.NewLimiter(rate.Every(250*time.Millisecond), 2))
 
 // Wait blocks until the limiter allows the request
 func Wait(ctx context.Context) error {
@@ -0,0 +1,31 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestLimiterWait_RecordsBlockedWaits(t *testing.T) {
+	l := New(rate.NewLimiter(rate.Every(20*time.Millisecond), 1))
+	blockedBefore := testutil.ToFloat64(BlockedTotal)
+
+	var before dto.Metric
+	assert.NoError(t, WaitSeconds.Write(&before))
+
+	// The burst of 1 is used up by the first call, the others wait
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, l.Wait(context.Background()))
+	}
+
+	var after dto.Metric
+	assert.NoError(t, WaitSeconds.Write(&after))
+	assert.Equal(t, uint64(3), after.GetHistogram().GetSampleCount()-before.GetHistogram().GetSampleCount())
+	assert.Greater(t, after.GetHistogram().GetSampleSum()-before.GetHistogram().GetSampleSum(), 0.02)
+	assert.Equal(t, float64(2), testutil.ToFloat64(BlockedTotal)-blockedBefore)
+}
@@ -2,18 +2,45 @@ package logging
 
 import (
 	"os"
+	"strings"
 
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 )
 
-var log *logrus.Logger
+var log = newLogger()
 
-// InitializeLogger initializes the global logger with standard configurations.
+// newLogger builds the logger used until InitializeLogger is called.
+func newLogger() *logrus.Logger {
+	l := logrus.New()
+	l.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp:   true,
+		TimestampFormat: "2006-01-02 15:04:05",
+	})
+	l.SetOutput(os.Stdout)
+	l.SetLevel(logrus.InfoLevel)
+	return l
+}
+
+// InitializeLogger initializes the global logger from the log_level and log_format settings.
 func InitializeLogger() {
-	log = logrus.New()
-	log.SetFormatter(&logrus.JSONFormatter{}) // Use JSON format for structured logs
-	log.SetOutput(os.Stdout)                  // Log to standard output
-	log.SetLevel(logrus.DebugLevel)           // Set log level
+	l := newLogger()
+
+	if strings.EqualFold(viper.GetString("log_format"), "json") {
+		l.SetFormatter(&logrus.JSONFormatter{})
+	}
+
+	levelName := viper.GetString("log_level")
+	if levelName != "" {
+		level, err := logrus.ParseLevel(levelName)
+		if err != nil {
+			l.WithFields(logrus.Fields{"log_level": levelName}).Warn("Unknown log level, falling back to info")
+		} else {
+			l.SetLevel(level)
+		}
+	}
+
+	log = l
 }
 
 // Info logs informational messages.
@@ -21,12 +48,31 @@ func Info(message string, fields map[string]interface{}) {
 	log.WithFields(fields).Info(message)
 }
 
+// Warn logs warning messages.
+func Warn(message string, fields map[string]interface{}) {
+	log.WithFields(fields).Warn(message)
+}
+
 // Error logs error messages.
 func Error(message string, fields map[string]interface{}) {
 	log.WithFields(fields).Error(message)
 }
 
+// ErrorErr logs an error message with err attached as the "error" field.
+func ErrorErr(message string, err error) {
+	fields := map[string]interface{}{}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	log.WithFields(fields).Error(message)
+}
+
 // Debug logs debug messages.
 func Debug(message string, fields map[string]interface{}) {
 	log.WithFields(fields).Debug(message)
 }
+
+// Fatal logs the message and exits the process.
+func Fatal(message string, fields map[string]interface{}) {
+	log.WithFields(fields).Fatal(message)
+}
@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// setViper sets key to value and restores the previous value when the test ends.
+func setViper(t *testing.T, key string, value interface{}) {
+	t.Helper()
+	prev := viper.Get(key)
+	viper.Set(key, value)
+	t.Cleanup(func() { viper.Set(key, prev) })
+}
+
+func TestInitializeLogger_WarnLevelSuppressesDebug(t *testing.T) {
+	// Registered first so it runs after the settings are restored
+	t.Cleanup(InitializeLogger)
+	setViper(t, "log_level", "warn")
+	setViper(t, "log_format", "json")
+
+	InitializeLogger()
+	hook := test.NewLocal(log)
+	defer hook.Reset()
+
+	Debug("debug message", nil)
+	Info("info message", nil)
+	assert.Empty(t, hook.AllEntries())
+
+	Warn("warn message", nil)
+	assert.Len(t, hook.AllEntries(), 1)
+	assert.IsType(t, &logrus.JSONFormatter{}, log.Formatter)
+}
+
+func TestErrorErr_AddsErrorField(t *testing.T) {
+	hook := test.NewLocal(log)
+	defer hook.Reset()
+
+	ErrorErr("Failed to fetch zones", errors.New("boom"))
+
+	entry := hook.LastEntry()
+	assert.NotNil(t, entry)
+	assert.Equal(t, logrus.ErrorLevel, entry.Level)
+	assert.Equal(t, "Failed to fetch zones", entry.Message)
+	assert.Equal(t, "boom", entry.Data["error"])
+}
+
+func TestErrorErr_NilError(t *testing.T) {
+	hook := test.NewLocal(log)
+	defer hook.Reset()
+
+	ErrorErr("Something failed", nil)
+
+	entry := hook.LastEntry()
+	assert.NotNil(t, entry)
+	_, exists := entry.Data["error"]
+	assert.False(t, exists)
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+
+	promclient "github.com/prometheus/client_golang/prometheus"
+	otelprom "go.opentelemetry.io/contrib/bridges/prometheus"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// OTLPPusher mirrors the registered Prometheus metrics to an OTLP/HTTP endpoint.
+type OTLPPusher struct {
+	provider *sdkmetric.MeterProvider
+	reader   *sdkmetric.ManualReader
+	exporter sdkmetric.Exporter
+}
+
+// NewOTLPPusher returns a pusher sending the metrics from gatherer to endpoint,
+// a full URL such as http://collector:4318/v1/metrics.
+func NewOTLPPusher(ctx context.Context, endpoint string, gatherer promclient.Gatherer) (*OTLPPusher, error) {
+	exporter, err := otlpmetrichttp.New(ctx, otlpmetrichttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+
+	reader := sdkmetric.NewManualReader(sdkmetric.WithProducer(otelprom.NewMetricProducer(otelprom.WithGatherer(gatherer))))
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithResource(resource.NewSchemaless(
+			semconv.ServiceName("cloudflare-exporter"),
+			semconv.ServiceVersion(Version),
+		)),
+	)
+
+	return &OTLPPusher{provider: provider, reader: reader, exporter: exporter}, nil
+}
+
+// Push collects the current metrics and exports them.
+func (p *OTLPPusher) Push(ctx context.Context) error {
+	var rm metricdata.ResourceMetrics
+	if err := p.reader.Collect(ctx, &rm); err != nil {
+		return fmt.Errorf("collect metrics: %w", err)
+	}
+	if err := p.exporter.Export(ctx, &rm); err != nil {
+		return fmt.Errorf("export metrics: %w", err)
+	}
+	return nil
+}
+
+// Shutdown flushes and stops the exporter.
+func (p *OTLPPusher) Shutdown(ctx context.Context) error {
+	if err := p.provider.Shutdown(ctx); err != nil {
+		return err
+	}
+	return p.exporter.Shutdown(ctx)
+}
@@ -3,23 +3,32 @@ package metrics
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
 	"math"
 	"os"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cloudflare/cloudflare-go"
 	"github.com/gammazero/workerpool"
 	cloudflareAPI "github.com/lablabs/cloudflare-exporter/internal/cloudflare"
 	limiter "github.com/lablabs/cloudflare-exporter/internal/limiter"
+	"github.com/lablabs/cloudflare-exporter/internal/logging"
 	"github.com/lablabs/cloudflare-exporter/internal/models"
 	"github.com/prometheus/client_golang/prometheus"
-	logging "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
+// Version is the exporter release reported in logs and build info.
+const Version = "1.11"
+
 // MetricName represent metric name
 type MetricName string
 
@@ -61,11 +70,17 @@ const (
 	logpushFailedJobsAccountMetricName           MetricName = "cloudflare_logpush_failed_jobs_account_count"
 	logpushFailedJobsZoneMetricName              MetricName = "cloudflare_logpush_failed_jobs_zone_count"
 	// new added
-	zoneCustomerError4xxRate               MetricName = "cloudflare_zone_customer_error_4xx_rate" //host
-	zoneCustomerError5xxRate               MetricName = "cloudflare_zone_customer_error_5xx_rate" //host
-	zoneEdgeErrorRate                      MetricName = "cloudflare_zone_edge_error_rate"         //host
-	zoneOriginErrorRate                    MetricName = "cloudflare_zone_origin_error_rate"       //host
-	zoneBotRequestsByCountry               MetricName = "cloudflare_zone_bot_request_by_country"  //host
+	zoneCustomerError4xxRate MetricName = "cloudflare_zone_customer_error_4xx_rate" //host
+	zoneCustomerError5xxRate MetricName = "cloudflare_zone_customer_error_5xx_rate" //host
+	zoneEdgeErrorRate        MetricName = "cloudflare_zone_edge_error_rate"         //host
+	zoneOriginErrorRate      MetricName = "cloudflare_zone_origin_error_rate"       //host
+
+	// Counter names replacing the deprecated *_rate names above.
+	zoneCustomerError4xxTotal              MetricName = "cloudflare_zone_customer_error_4xx_total" //host
+	zoneCustomerError5xxTotal              MetricName = "cloudflare_zone_customer_error_5xx_total" //host
+	zoneEdgeErrorsTotal                    MetricName = "cloudflare_zone_edge_errors_total"        //host
+	zoneOriginErrorsTotal                  MetricName = "cloudflare_zone_origin_errors_total"      //host
+	zoneBotRequestsByCountry               MetricName = "cloudflare_zone_bot_request_by_country"   //host
 	zoneCacheHitRatio                      MetricName = "cloudflare_zone_cache_hit_ratio"
 	zoneHealthCheckEventsAdaptiveGroupsAvg MetricName = "cloudflare_zone_health_check_events_avg"
 	zoneFirewallBotsDetectedSource         MetricName = "cloudflare_zone_firewall_bots_detected" //host
@@ -77,10 +92,68 @@ const (
 	magicTransitEdgeColoCount              MetricName = "cloudflare_magic_transit_edge_colo_count"
 	zoneCertificateValidationStatus        MetricName = "cloudflare_zone_certificate_validation_status"
 	// other new
-	zoneOriginResponseDurationMsMetricName         MetricName = "cloudflare_zone_origin_response_duration_ms"
+	zoneOriginResponseDurationMsMetricName         MetricName = "cloudflare_zone_origin_response_duration_ms"          //host
 	zoneColocationVisitsErrorMetricName            MetricName = "cloudflare_zone_colocation_visits_error"              //host
 	zoneColocationEdgeResponseBytesErrorMetricName MetricName = "cloudflare_zone_colocation_edge_response_bytes_error" //host
 	zoneColocationRequestsTotalErrorMetricName     MetricName = "cloudflare_zone_colocation_requests_total_error"      //host
+	zoneRateLimitEventsTotalMetricName             MetricName = "cloudflare_zone_rate_limit_events_total"
+	zoneOriginRequestsTotalMetricName              MetricName = "cloudflare_zone_origin_requests_total"
+	zoneArgoRequestsTotalMetricName                MetricName = "cloudflare_zone_argo_requests_total"
+	zoneArgoResponseTimeMsMetricName               MetricName = "cloudflare_zone_argo_response_time_ms"
+	zoneArgoResponseTimeImprovementMsMetricName    MetricName = "cloudflare_zone_argo_response_time_improvement_ms"
+	apiRequestDurationMetricName                   MetricName = "cloudflare_api_request_duration_seconds"
+	turnstileChallengesTotalMetricName             MetricName = "cloudflare_turnstile_challenges_total"
+	turnstileSolvesTotalMetricName                 MetricName = "cloudflare_turnstile_solves_total"
+	poolAvgRttMsMetricName                         MetricName = "cloudflare_zone_pool_avg_rtt_ms"
+	originHealthMetricName                         MetricName = "cloudflare_zone_origin_health"
+	zoneRequestIPClassMetricName                   MetricName = "cloudflare_zone_requests_ip_class_total"
+	zoneRequestHTTPVersionMetricName               MetricName = "cloudflare_zone_requests_http_version_total"
+	zoneRequestSSLProtocolMetricName               MetricName = "cloudflare_zone_requests_ssl_protocol_total"
+	exporterFetchErrorsTotalMetricName             MetricName = "cloudflare_exporter_fetch_errors_total"
+	zoneRequestsByCacheStatusMetricName            MetricName = "cloudflare_zone_requests_by_cache_status_total"
+	zoneCertificateDaysUntilExpiryMetricName       MetricName = "cloudflare_zone_certificate_days_until_expiry"
+	streamMinutesViewedTotalMetricName             MetricName = "cloudflare_stream_minutes_viewed_total"
+	streamBandwidthBytesTotalMetricName            MetricName = "cloudflare_stream_bandwidth_bytes_total"
+	imagesRequestsTotalMetricName                  MetricName = "cloudflare_images_requests_total"
+	imagesTransformationsTotalMetricName           MetricName = "cloudflare_images_transformations_total"
+	zoneSampleIntervalMetricName                   MetricName = "cloudflare_zone_sample_interval"
+	durableObjectsRequestsTotalMetricName          MetricName = "cloudflare_durable_objects_requests_total"
+	queueBacklogMessagesMetricName                 MetricName = "cloudflare_queue_backlog_messages"
+	exporterScrapesSkippedTotalMetricName          MetricName = "cloudflare_exporter_scrapes_skipped_total"
+	zoneBotScoreRequestsTotalMetricName            MetricName = "cloudflare_zone_bot_score_requests_total"
+	zoneThreatsTypeCountryTotalMetricName          MetricName = "cloudflare_zone_threats_type_country_total"
+	exporterBuildInfoMetricName                    MetricName = "cloudflare_exporter_build_info"
+	zoneFirewallEventsDetailedTotalMetricName      MetricName = "cloudflare_zone_firewall_events_detailed_total" //host
+	zonePageShieldScriptsMetricName                MetricName = "cloudflare_zone_page_shield_scripts"
+	zonePageShieldViolationsTotalMetricName        MetricName = "cloudflare_zone_page_shield_violations_total"
+	magicTransitTunnelHealthMetricName             MetricName = "cloudflare_magic_transit_tunnel_health"
+	exporterQueryTruncatedTotalMetricName          MetricName = "cloudflare_exporter_query_truncated_total"
+	zoneBandwidthHostBytesTotalMetricName          MetricName = "cloudflare_zone_bandwidth_host_bytes_total" //host
+	zoneFirewallEventsByKindTotalMetricName        MetricName = "cloudflare_zone_firewall_events_by_kind_total"
+	exporterCircuitBreakerOpenMetricName           MetricName = "cloudflare_exporter_circuit_breaker_open"
+	exporterZonesTotalMetricName                   MetricName = "cloudflare_exporter_zones_total"
+	exporterAccountsTotalMetricName                MetricName = "cloudflare_exporter_accounts_total"
+	zoneOriginConnectivityErrorsTotalMetricName    MetricName = "cloudflare_zone_origin_connectivity_errors_total"
+	logpushJobsTotalMetricName                     MetricName = "cloudflare_logpush_jobs_total"
+	zoneFirewallEventsByASNTotalMetricName         MetricName = "cloudflare_zone_firewall_events_by_asn_total"
+	exporterGraphQLRowsReadTotalMetricName         MetricName = "cloudflare_exporter_graphql_rows_read_total"
+	zoneAvailabilityRatioMetricName                MetricName = "cloudflare_zone_availability_ratio"
+	workerInvocationsByStatusTotalMetricName       MetricName = "cloudflare_worker_invocations_by_status_total"
+	originLBHealthMetricName                       MetricName = "cloudflare_zone_origin_lb_health"
+	zoneRequestsByPathTotalMetricName              MetricName = "cloudflare_zone_requests_by_path_total"
+	zoneBandwidthCacheRatioMetricName              MetricName = "cloudflare_zone_bandwidth_cache_ratio"
+	exporterPermissionErrorsTotalMetricName        MetricName = "cloudflare_exporter_permission_errors_total"
+	exporterFamilyLastSuccessMetricName            MetricName = "cloudflare_exporter_family_last_success_timestamp_seconds"
+	exporterRateLimitWaitSecondsMetricName         MetricName = "cloudflare_exporter_rate_limit_wait_seconds"
+	exporterRateLimitBlockedTotalMetricName        MetricName = "cloudflare_exporter_rate_limit_blocked_total"
+	exporterDecodeErrorsTotalMetricName            MetricName = "cloudflare_exporter_decode_errors_total"
+	zoneSampleRateMetricName                       MetricName = "cloudflare_zone_sample_rate"
+	exporterRetriesTotalMetricName                 MetricName = "cloudflare_exporter_retries_total"
+	exporterRetriesBudgetExhaustedTotalMetricName  MetricName = "cloudflare_exporter_retries_budget_exhausted_total"
+	exporterScrapeTimeoutsTotalMetricName          MetricName = "cloudflare_exporter_scrape_timeouts_total"
+	zoneHTTP2PercentageMetricName                  MetricName = "cloudflare_zone_http2_percentage"
+	zoneHTTP3PercentageMetricName                  MetricName = "cloudflare_zone_http3_percentage"
+	zoneWAFCategoryEventsTotalMetricName           MetricName = "cloudflare_zone_waf_category_events_total"
 )
 
 // Set map to check metric name availability.
@@ -97,26 +170,18 @@ func (ms Set) Add(mn MetricName) {
 	ms[mn] = struct{}{}
 }
 
+// Names returns the metric names in the set, sorted.
+func (ms Set) Names() []string {
+	names := make([]string, 0, len(ms))
+	for mn := range ms {
+		names = append(names, mn.String())
+	}
+	sort.Strings(names)
+	return names
+}
+
 var (
 	// Requests
-	zoneRequestTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: zoneRequestTotalMetricName.String(),
-		Help: "Number of requests for zone",
-	}, []string{"zone", "account"},
-	)
-
-	zoneRequestCached = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: zoneRequestCachedMetricName.String(),
-		Help: "Number of cached requests for zone",
-	}, []string{"zone", "account"},
-	)
-
-	zoneRequestSSLEncrypted = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: zoneRequestSSLEncryptedMetricName.String(),
-		Help: "Number of encrypted requests for zone",
-	}, []string{"zone", "account"},
-	)
-
 	zoneRequestContentType = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: zoneRequestContentTypeMetricName.String(),
 		Help: "Number of request for zone per content type",
@@ -141,24 +206,6 @@ var (
 	}, []string{"zone", "account", "family"},
 	)
 
-	zoneBandwidthTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: zoneBandwidthTotalMetricName.String(),
-		Help: "Total bandwidth per zone in bytes",
-	}, []string{"zone", "account"},
-	)
-
-	zoneBandwidthCached = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: zoneBandwidthCachedMetricName.String(),
-		Help: "Cached bandwidth per zone in bytes",
-	}, []string{"zone", "account"},
-	)
-
-	zoneBandwidthSSLEncrypted = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: zoneBandwidthSSLEncryptedMetricName.String(),
-		Help: "Encrypted bandwidth per zone in bytes",
-	}, []string{"zone", "account"},
-	)
-
 	zoneBandwidthContentType = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: zoneBandwidthContentTypeMetricName.String(),
 		Help: "Bandwidth per zone per content type",
@@ -171,12 +218,6 @@ var (
 	}, []string{"zone", "account", "country"},
 	)
 
-	zoneThreatsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: zoneThreatsTotalMetricName.String(),
-		Help: "Threats per zone",
-	}, []string{"zone", "account"},
-	)
-
 	zoneThreatsCountry = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: zoneThreatsCountryMetricName.String(),
 		Help: "Threats per zone per country",
@@ -189,18 +230,6 @@ var (
 	}, []string{"zone", "account", "type"},
 	)
 
-	zonePageviewsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: zonePageviewsTotalMetricName.String(),
-		Help: "Pageviews per zone",
-	}, []string{"zone", "account"},
-	)
-
-	zoneUniquesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: zoneUniquesTotalMetricName.String(),
-		Help: "Uniques per zone",
-	}, []string{"zone", "account"},
-	)
-
 	zoneFirewallEventsCount = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: zoneFirewallEventsCountMetricName.String(),
 		Help: "Count of Firewall events",
@@ -216,25 +245,25 @@ var (
 	workerRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: workerRequestsMetricName.String(),
 		Help: "Number of requests sent to worker by script name",
-	}, []string{"script_name", "account"},
+	}, []string{"script_name", "account", "environment"},
 	)
 
 	workerErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: workerErrorsMetricName.String(),
 		Help: "Number of errors by script name",
-	}, []string{"script_name", "account"},
+	}, []string{"script_name", "account", "environment"},
 	)
 
 	workerCPUTime = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Name: workerCPUTimeMetricName.String(),
 		Help: "CPU time quantiles by script name",
-	}, []string{"script_name", "account", "quantile"},
+	}, []string{"script_name", "account", "environment", "quantile"},
 	)
 
 	workerDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Name: workerDurationMetricName.String(),
 		Help: "Duration quantiles by script name (GB*s)",
-	}, []string{"script_name", "account", "quantile"},
+	}, []string{"script_name", "account", "environment", "quantile"},
 	)
 
 	poolHealthStatus = prometheus.NewGaugeVec(prometheus.GaugeOpts{
@@ -251,18 +280,11 @@ var (
 		[]string{"zone", "account", "load_balancer_name", "pool_name", "origin_name"},
 	)
 
-	logpushFailedJobsAccount = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: "cloudflare_logpush_failed_jobs_account_count",
-		Help: "Number of failed logpush jobs on the account level",
-	},
-		[]string{"account", "account_type", "destination", "job_id", "final"},
-	)
-
 	logpushFailedJobsZone = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: logpushFailedJobsZoneMetricName.String(),
 		Help: "Number of failed logpush jobs on the zone level",
 	},
-		[]string{"destination", "job_id", "final"},
+		[]string{"destination", "job_id", "job_name", "dataset", "final"},
 	)
 
 	zoneCacheHit = prometheus.NewGaugeVec(prometheus.GaugeOpts{
@@ -288,34 +310,6 @@ var (
 		Help: "Number of zone request method",
 	}, []string{"zone", "account", "method"},
 	)
-	magicTransitActiveTunnel = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: magicTransitActiveTunnels.String(),
-			Help: "Number of active Magic Transit tunnels",
-		},
-		[]string{"account", "account_type"},
-	)
-	magicTransitHealthyTunnel = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: magicTransitHealthyTunnels.String(),
-			Help: "Number of healthy Magic Transit tunnels",
-		},
-		[]string{"account", "account_type"},
-	)
-	magicTransitTunnelFailure = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: magicTransitTunnelFailures.String(),
-			Help: "Number of failed Magic Transit tunnels",
-		},
-		[]string{"account", "account_type"},
-	)
-	magicTransitEdgeColo = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: magicTransitEdgeColoCount.String(),
-			Help: "Number of edge colocation sites involved in Magic Transit tunnels",
-		},
-		[]string{"account", "account_type"},
-	)
 
 	zoneCertificateValidation = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -324,740 +318,2246 @@ var (
 		},
 		[]string{"zone_id", "zone_name", "status", "issuer"},
 	)
-)
 
-func getLabels(baseLabels prometheus.Labels, hostValue string) prometheus.Labels {
+	zoneRateLimitEventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneRateLimitEventsTotalMetricName.String(),
+		Help: "Number of rate limiting rule events per zone per action per rule",
+	}, []string{"zone", "account", "action", "rule_id"},
+	)
 
-	exclude_host := viper.GetBool("exclude_host")
+	zoneOriginRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneOriginRequestsTotalMetricName.String(),
+		Help: "Number of not cached requests for zone per origin HTTP status class",
+	}, []string{"zone", "account", "status_class"},
+	)
 
-	// Check if "exclude_host" is false and add "host" dynamically
-	if !exclude_host {
-		baseLabels["host"] = hostValue
-	}
+	zoneArgoRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneArgoRequestsTotalMetricName.String(),
+		Help: "Number of requests routed through Argo Smart Routing per zone",
+	}, []string{"zone", "account"},
+	)
 
-	return baseLabels
-}
+	zoneArgoResponseTimeMs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: zoneArgoResponseTimeMsMetricName.String(),
+		Help: "Average origin response time in ms for Argo Smart Routing requests per zone",
+	}, []string{"zone", "account"},
+	)
 
-// BuildAllMetricsSet helps to build all metric and return as Set.
-func BuildAllMetricsSet() Set {
-	allMetricsSet := Set{}
-	allMetricsSet.Add(zoneRequestTotalMetricName)
-	allMetricsSet.Add(zoneRequestCachedMetricName)
-	allMetricsSet.Add(zoneRequestSSLEncryptedMetricName)
-	allMetricsSet.Add(zoneRequestContentTypeMetricName)
-	allMetricsSet.Add(zoneRequestCountryMetricName)
-	allMetricsSet.Add(zoneRequestHTTPStatusMetricName)
-	allMetricsSet.Add(zoneRequestBrowserMapMetricName)
-	allMetricsSet.Add(zoneRequestOriginStatusCountryHostMetricName)
-	allMetricsSet.Add(zoneRequestStatusCountryHostMetricName)
-	allMetricsSet.Add(zoneBandwidthTotalMetricName)
-	allMetricsSet.Add(zoneBandwidthCachedMetricName)
-	allMetricsSet.Add(zoneBandwidthSSLEncryptedMetricName)
-	allMetricsSet.Add(zoneBandwidthContentTypeMetricName)
-	allMetricsSet.Add(zoneBandwidthCountryMetricName)
-	allMetricsSet.Add(zoneThreatsTotalMetricName)
-	allMetricsSet.Add(zoneThreatsCountryMetricName)
-	allMetricsSet.Add(zoneThreatsTypeMetricName)
-	allMetricsSet.Add(zonePageviewsTotalMetricName)
-	allMetricsSet.Add(zoneUniquesTotalMetricName)
-	allMetricsSet.Add(zoneColocationVisitsMetricName)
-	allMetricsSet.Add(zoneColocationEdgeResponseBytesMetricName)
-	allMetricsSet.Add(zoneColocationRequestsTotalMetricName)
-	allMetricsSet.Add(zoneFirewallEventsCountMetricName)
-	allMetricsSet.Add(zoneHealthCheckEventsOriginCountMetricName)
-	allMetricsSet.Add(workerRequestsMetricName)
-	allMetricsSet.Add(workerErrorsMetricName)
-	allMetricsSet.Add(workerCPUTimeMetricName)
-	allMetricsSet.Add(workerDurationMetricName)
-	allMetricsSet.Add(poolHealthStatusMetricName)
-	allMetricsSet.Add(poolRequestsTotalMetricName)
-	allMetricsSet.Add(logpushFailedJobsAccountMetricName)
-	allMetricsSet.Add(logpushFailedJobsZoneMetricName)
-	// new
-	allMetricsSet.Add(zoneCustomerError4xxRate)
-	allMetricsSet.Add(zoneCustomerError5xxRate)
-	allMetricsSet.Add(zoneEdgeErrorRate)
-	allMetricsSet.Add(zoneOriginErrorRate)
-	allMetricsSet.Add(zoneBotRequestsByCountry)
-	allMetricsSet.Add(zoneHealthCheckEventsAdaptiveGroupsAvg)
-	allMetricsSet.Add(zoneFirewallBotsDetectedSource)
-	allMetricsSet.Add(zoneFirewallRequestAction)
-	allMetricsSet.Add(zoneRequestMethodCount)
-	allMetricsSet.Add(magicTransitActiveTunnels)
-	allMetricsSet.Add(magicTransitEdgeColoCount)
-	allMetricsSet.Add(magicTransitHealthyTunnels)
-	allMetricsSet.Add(magicTransitTunnelFailures)
-	allMetricsSet.Add(zoneCertificateValidationStatus)
-	// other new
-	allMetricsSet.Add(zoneOriginResponseDurationMsMetricName)
-	allMetricsSet.Add(zoneColocationVisitsErrorMetricName)
-	allMetricsSet.Add(zoneColocationEdgeResponseBytesErrorMetricName)
-	allMetricsSet.Add(zoneColocationRequestsTotalErrorMetricName)
+	zoneArgoResponseTimeImprovementMs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: zoneArgoResponseTimeImprovementMsMetricName.String(),
+		Help: "Average origin response time in ms saved by Argo Smart Routing per zone, compared to requests not smart routed",
+	}, []string{"zone", "account"},
+	)
 
-	return allMetricsSet
-}
+	turnstileChallengesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: turnstileChallengesTotalMetricName.String(),
+		Help: "Number of Turnstile challenges issued per sitekey",
+	}, []string{"account", "sitekey"},
+	)
 
-// BuildDeniedMetricsSet returns Set and error.
-func BuildDeniedMetricsSet(metricsDenylist []string) (Set, error) {
-	deniedMetricsSet := Set{}
-	allMetricsSet := BuildAllMetricsSet()
-	for _, metric := range metricsDenylist {
-		if !allMetricsSet.Has(MetricName(metric)) {
-			return nil, fmt.Errorf("metric %s doesn't exists", metric)
-		}
-		deniedMetricsSet.Add(MetricName(metric))
-	}
-	return deniedMetricsSet, nil
-}
+	turnstileSolvesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: turnstileSolvesTotalMetricName.String(),
+		Help: "Number of Turnstile challenges solved per sitekey",
+	}, []string{"account", "sitekey"},
+	)
 
-var zoneRequestOriginStatusCountryHost *prometheus.CounterVec
-var zoneRequestStatusCountryHost *prometheus.CounterVec
-var zoneColocationVisits *prometheus.CounterVec
-var zoneColocationEdgeResponseBytes *prometheus.CounterVec
-var zoneColocationRequestsTotal *prometheus.CounterVec
-var zoneCustomerError4xx *prometheus.CounterVec
-var zoneCustomerError5xx *prometheus.CounterVec
-var zoneEdgeError *prometheus.GaugeVec
-var zoneOriginError *prometheus.CounterVec
-var zoneFirewallBotsDetected *prometheus.CounterVec
-var zoneBotRequests *prometheus.CounterVec
+	poolAvgRttMs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: poolAvgRttMsMetricName.String(),
+		Help: "Reports the average round-trip time to a pool in ms",
+	},
+		[]string{"zone", "account", "load_balancer_name", "pool_name"},
+	)
 
-// other new added
-var zoneOriginResponseDuration *prometheus.GaugeVec
-var zoneColocationVisitsError *prometheus.CounterVec
-var zoneColocationEdgeResponseBytesError *prometheus.CounterVec
-var zoneColocationRequestsTotalError *prometheus.CounterVec
+	originHealth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: originHealthMetricName.String(),
+		Help: "Reports the health of a load balancer origin, 1 for healthy, 0 for unhealthy.",
+	},
+		[]string{"zone", "account", "load_balancer_name", "origin_name"},
+	)
 
-// MustRegisterMetrics register the metrics.
-func MustRegisterMetrics(deniedMetrics Set) {
-	if !deniedMetrics.Has(zoneRequestTotalMetricName) {
-		prometheus.MustRegister(zoneRequestTotal)
-	}
-	if !deniedMetrics.Has(zoneRequestCachedMetricName) {
-		prometheus.MustRegister(zoneRequestCached)
-	}
-	if !deniedMetrics.Has(zoneRequestSSLEncryptedMetricName) {
-		prometheus.MustRegister(zoneRequestSSLEncrypted)
-	}
-	if !deniedMetrics.Has(zoneRequestContentTypeMetricName) {
-		prometheus.MustRegister(zoneRequestContentType)
-	}
-	if !deniedMetrics.Has(zoneRequestCountryMetricName) {
-		prometheus.MustRegister(zoneRequestCountry)
-	}
-	if !deniedMetrics.Has(zoneRequestHTTPStatusMetricName) {
-		prometheus.MustRegister(zoneRequestHTTPStatus)
-	}
-	if !deniedMetrics.Has(zoneRequestBrowserMapMetricName) {
-		prometheus.MustRegister(zoneRequestBrowserMap)
-	}
-	if !deniedMetrics.Has(zoneRequestOriginStatusCountryHostMetricName) {
-		if zoneRequestOriginStatusCountryHost == nil { // Ensure it is not nil before registration
-			metricLabels := []string{"zone", "account", "status", "country"} // Base labels
+	originLBHealth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: originLBHealthMetricName.String(),
+		Help: "Reports the health of each origin in the selected load balancer pool, 1 for healthy, 0 for unhealthy.",
+	},
+		[]string{"zone", "account", "load_balancer_name", "pool_name", "origin_name", "origin_ip"},
+	)
 
-			exclude_host := viper.GetBool("exclude_host")
+	zoneRequestIPClass = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneRequestIPClassMetricName.String(),
+		Help: "Number of requests for zone per IP class",
+	}, []string{"zone", "account", "ip_class"},
+	)
 
-			if !exclude_host {
-				metricLabels = append(metricLabels, "host") // Conditionally add "host"
-			}
+	zoneRequestHTTPVersion = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneRequestHTTPVersionMetricName.String(),
+		Help: "Number of requests for zone per client HTTP protocol version",
+	}, []string{"zone", "account", "http_version"},
+	)
 
-			zoneRequestOriginStatusCountryHost = prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Name: zoneRequestOriginStatusCountryHostMetricName.String(),
-					Help: "Count of not cached requests for zone per origin HTTP status per country per host",
-				},
-				metricLabels,
-			)
+	zoneHTTP2Percentage = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: zoneHTTP2PercentageMetricName.String(),
+		Help: "Percentage of requests for zone over HTTP/2 in the last query window",
+	}, []string{"zone", "account"},
+	)
 
-			prometheus.MustRegister(zoneRequestOriginStatusCountryHost)
-		}
-	}
-	if !deniedMetrics.Has(zoneRequestStatusCountryHostMetricName) {
-		if zoneRequestStatusCountryHost == nil { // Ensure it is not nil before registration
-			metricLabels := []string{"zone", "account", "status", "country"} // Base labels
+	zoneHTTP3Percentage = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: zoneHTTP3PercentageMetricName.String(),
+		Help: "Percentage of requests for zone over HTTP/3 in the last query window",
+	}, []string{"zone", "account"},
+	)
 
-			exclude_host := viper.GetBool("exclude_host")
+	zoneRequestSSLProtocol = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneRequestSSLProtocolMetricName.String(),
+		Help: "Number of requests for zone per client SSL/TLS protocol",
+	}, []string{"zone", "account", "ssl_protocol"},
+	)
 
-			if !exclude_host {
-				metricLabels = append(metricLabels, "host") // Conditionally add "host"
-			}
+	exporterFetchErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: exporterFetchErrorsTotalMetricName.String(),
+		Help: "Number of failed Cloudflare API fetches per metric family",
+	}, []string{"family"},
+	)
 
-			zoneRequestStatusCountryHost = prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Name: zoneRequestStatusCountryHostMetricName.String(),
-					Help: "Count of requests for zone per edge HTTP status per country per host",
-				},
-				metricLabels,
-			)
+	exporterPermissionErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: exporterPermissionErrorsTotalMetricName.String(),
+		Help: "Number of Cloudflare API fetches rejected for missing permissions or invalid credentials per metric family",
+	}, []string{"family"},
+	)
 
-			prometheus.MustRegister(zoneRequestStatusCountryHost)
-		}
-	}
-	if !deniedMetrics.Has(zoneBandwidthTotalMetricName) {
-		prometheus.MustRegister(zoneBandwidthTotal)
-	}
-	if !deniedMetrics.Has(zoneBandwidthCachedMetricName) {
-		prometheus.MustRegister(zoneBandwidthCached)
-	}
-	if !deniedMetrics.Has(zoneBandwidthSSLEncryptedMetricName) {
-		prometheus.MustRegister(zoneBandwidthSSLEncrypted)
-	}
-	if !deniedMetrics.Has(zoneBandwidthContentTypeMetricName) {
-		prometheus.MustRegister(zoneBandwidthContentType)
-	}
-	if !deniedMetrics.Has(zoneBandwidthCountryMetricName) {
-		prometheus.MustRegister(zoneBandwidthCountry)
-	}
-	if !deniedMetrics.Has(zoneThreatsTotalMetricName) {
-		prometheus.MustRegister(zoneThreatsTotal)
-	}
-	if !deniedMetrics.Has(zoneThreatsCountryMetricName) {
-		prometheus.MustRegister(zoneThreatsCountry)
-	}
-	if !deniedMetrics.Has(zoneThreatsTypeMetricName) {
-		prometheus.MustRegister(zoneThreatsType)
-	}
-	if !deniedMetrics.Has(zonePageviewsTotalMetricName) {
-		prometheus.MustRegister(zonePageviewsTotal)
-	}
-	if !deniedMetrics.Has(zoneUniquesTotalMetricName) {
-		prometheus.MustRegister(zoneUniquesTotal)
-	}
-	if !deniedMetrics.Has(zoneColocationVisitsMetricName) {
-		if zoneColocationVisits == nil { // Ensure it is not nil before registration
-			metricLabels1 := []string{"zone", "account", "colocation"} // Base labels
+	exporterScrapeTimeoutsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: exporterScrapeTimeoutsTotalMetricName.String(),
+		Help: "Number of scrapes cancelled after scrape_timeout",
+	})
 
-			exclude_host := viper.GetBool("exclude_host")
+	exporterFamilyLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: exporterFamilyLastSuccessMetricName.String(),
+		Help: "Unix time of the last successful Cloudflare API fetch per metric family",
+	}, []string{"family"},
+	)
 
-			if !exclude_host {
-				metricLabels1 = append(metricLabels1, "host") // Conditionally add "host"
-			}
+	zoneRequestsByCacheStatus = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneRequestsByCacheStatusMetricName.String(),
+		Help: "Number of requests for zone per cache status",
+	}, []string{"zone", "account", "cache_status"},
+	)
+
+	zoneCertificateDaysUntilExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: zoneCertificateDaysUntilExpiryMetricName.String(),
+		Help: "Days until the SSL certificate expires, negative once expired",
+	}, []string{"zone_id", "zone_name", "status", "issuer"},
+	)
+
+	streamMinutesViewedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: streamMinutesViewedTotalMetricName.String(),
+		Help: "Number of Stream video minutes viewed per account",
+	}, []string{"account"},
+	)
+
+	streamBandwidthBytesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: streamBandwidthBytesTotalMetricName.String(),
+		Help: "Number of Stream video bytes delivered per account",
+	}, []string{"account"},
+	)
+
+	imagesRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: imagesRequestsTotalMetricName.String(),
+		Help: "Number of Images requests per account",
+	}, []string{"account"},
+	)
+
+	imagesTransformationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: imagesTransformationsTotalMetricName.String(),
+		Help: "Number of Images transformations per account",
+	}, []string{"account"},
+	)
+
+	zoneSampleInterval = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: zoneSampleIntervalMetricName.String(),
+		Help: "Average adaptive sampling interval of colocation data for zone, 1 means unsampled",
+	}, []string{"zone", "account"},
+	)
+
+	zoneSampleRate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: zoneSampleRateMetricName.String(),
+		Help: "Share of requests kept by adaptive sampling in the colocation data for zone, 1 means unsampled",
+	}, []string{"zone", "account"},
+	)
+
+	durableObjectsRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: durableObjectsRequestsTotalMetricName.String(),
+		Help: "Number of Durable Objects requests per namespace",
+	}, []string{"account", "namespace"},
+	)
+
+	queueBacklogMessages = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: queueBacklogMessagesMetricName.String(),
+		Help: "Average number of messages in the Queue backlog",
+	}, []string{"account", "queue"},
+	)
+
+	exporterScrapesSkippedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: exporterScrapesSkippedTotalMetricName.String(),
+		Help: "Number of scrape ticks skipped because the previous scrape was still running",
+	})
+
+	zoneRequestsByPathTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneRequestsByPathTotalMetricName.String(),
+		Help: "Number of requests for zone per URL path, capped to the top cf_path_top_n paths (requires enable_path_metrics)",
+	}, []string{"zone", "account", "path"},
+	)
+
+	zoneBotScoreRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneBotScoreRequestsTotalMetricName.String(),
+		Help: "Number of requests for zone per bot score bucket (1-29 likely bot, 30-99 likely human)",
+	}, []string{"zone", "account", "score_bucket"},
+	)
+
+	// The GraphQL API does not join threatPathingMap with countryMap, so the
+	// type here is the firewall event source (waf, firewallrules, bic, ...)
+	// of mitigated events rather than the threat pathing name.
+	zoneThreatsTypeCountryTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneThreatsTypeCountryTotalMetricName.String(),
+		Help: "Mitigated firewall events per zone per source type per country",
+	}, []string{"zone", "account", "type", "country"},
+	)
+
+	exporterBuildInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: exporterBuildInfoMetricName.String(),
+		Help: "Exporter build and configuration, always 1",
+	}, []string{"version", "go_version", "free_tier", "exclude_host", "batch_size"},
+	)
+
+	zonePageShieldScripts = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: zonePageShieldScriptsMetricName.String(),
+		Help: "Number of scripts seen by Page Shield per zone",
+	}, []string{"zone", "account"},
+	)
+
+	zonePageShieldViolationsTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: zonePageShieldViolationsTotalMetricName.String(),
+		Help: "Number of Page Shield scripts per zone whose URL or domain is reported malicious",
+	}, []string{"zone", "account"},
+	)
+
+	magicTransitTunnelHealth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: magicTransitTunnelHealthMetricName.String(),
+		Help: "Latest Magic Transit tunnel health check result per edge colo (1 healthy, 0 unhealthy)",
+	}, []string{"account", "tunnel_name", "site_name", "edge_colo"},
+	)
+
+	exporterQueryTruncatedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: exporterQueryTruncatedTotalMetricName.String(),
+		Help: "Number of zone query results that reached the query limit and are likely truncated",
+	}, []string{"query"},
+	)
+
+	zoneFirewallEventsByKindTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneFirewallEventsByKindTotalMetricName.String(),
+		Help: "Number of firewall events per zone per kind",
+	}, []string{"zone", "account", "kind"},
+	)
+
+	zoneWAFCategoryEventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneWAFCategoryEventsTotalMetricName.String(),
+		Help: "Number of firewall events per zone per managed WAF rule category",
+	}, []string{"zone", "account", "category"},
+	)
+
+	exporterZonesTotal = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: exporterZonesTotalMetricName.String(),
+		Help: "Number of zones discovered after zone, exclusion, plan and paused filters",
+	})
+
+	exporterAccountsTotal = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: exporterAccountsTotalMetricName.String(),
+		Help: "Number of accounts discovered after account filters",
+	})
+
+	workerInvocationsByStatusTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: workerInvocationsByStatusTotalMetricName.String(),
+		Help: "Number of worker invocations by script name and invocation status (success, scriptThrewException, exceededCpu, ...)",
+	}, []string{"script_name", "account", "environment", "status"},
+	)
+
+	zoneFirewallEventsByASNTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneFirewallEventsByASNTotalMetricName.String(),
+		Help: "Number of firewall events per zone per source ASN and action, capped to the top ASNs",
+	}, []string{"zone", "account", "asn", "asn_description", "action"},
+	)
+
+	zoneOriginConnectivityErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneOriginConnectivityErrorsTotalMetricName.String(),
+		Help: "Number of Cloudflare 52x responses for failed origin connections (timeouts, unreachable, TLS errors) per zone per status",
+	}, []string{"zone", "account", "status"},
+	)
+)
+
+// setBuildInfo records the running version and non-secret config.
+func setBuildInfo() {
+	exporterBuildInfo.Reset()
+	exporterBuildInfo.With(prometheus.Labels{
+		"version":      Version,
+		"go_version":   runtime.Version(),
+		"free_tier":    strconv.FormatBool(viper.GetBool("free_tier")),
+		"exclude_host": strconv.FormatBool(viper.GetBool("exclude_host")),
+		"batch_size":   strconv.Itoa(viper.GetInt("cf_batch_size")),
+	}).Set(1)
+}
+
+// checkTruncated warns and counts when a zone's groups reached the query
+// limit, meaning the results are likely truncated and undercounted.
+func checkTruncated(query string, zone string, groups int, limit int) {
+	if limit <= 0 || groups < limit {
+		return
+	}
+
+	logging.Warn("Query returned as many groups as the limit, results are likely truncated", map[string]interface{}{
+		"query":  query,
+		"zone":   zone,
+		"groups": groups,
+		"limit":  limit,
+	})
+	exporterQueryTruncatedTotal.With(prometheus.Labels{"query": query}).Inc()
+}
+
+// topN keeps the n largest values and folds the rest into otherTopNKey.
+// n <= 0 disables the cap.
+func topN(values map[string]float64, n int) map[string]float64 {
+	if n <= 0 || len(values) <= n {
+		return values
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if values[keys[i]] != values[keys[j]] {
+			return values[keys[i]] > values[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	capped := make(map[string]float64, n+1)
+	for i, k := range keys {
+		if i < n {
+			capped[k] = values[k]
+		} else {
+			capped[otherTopNKey] += values[k]
+		}
+	}
+	return capped
+}
+
+// otherTopNKey is the label value collecting everything outside the top N.
+const otherTopNKey = "other"
+
+// topNContentTypes keeps the n content types with the most requests and sums
+// the rest as "other". Bytes are bucketed by the same content types, so both
+// content type metrics share one set of label values.
+func topNContentTypes(requests, bytes map[string]float64, n int) (map[string]float64, map[string]float64) {
+	if n <= 0 || len(requests) <= n {
+		return requests, bytes
+	}
+
+	cappedRequests := topN(requests, n)
+
+	cappedBytes := make(map[string]float64, len(cappedRequests))
+	for contentType, b := range bytes {
+		if _, kept := cappedRequests[contentType]; !kept {
+			contentType = otherTopNKey
+		}
+		cappedBytes[contentType] += b
+	}
+	return cappedRequests, cappedBytes
+}
+
+// errorMetricLabels returns the labels of the error families, adding "host"
+// when hostLabelEnabled for metrics.
+func errorMetricLabels(metrics ...MetricName) []string {
+	labels := []string{"zone", "account", "status", "country"}
+	if hostLabelEnabled(metrics...) {
+		labels = append(labels, "host")
+	}
+	return labels
+}
+
+// hostLabelMetrics holds the metrics that keep the host label even when
+// exclude_host is set. See SetHostLabelMetrics.
+var hostLabelMetrics = Set{}
+
+// hostMetricNames are the metrics that can carry the host label.
+var hostMetricNames = []MetricName{
+	zoneRequestOriginStatusCountryHostMetricName,
+	zoneRequestStatusCountryHostMetricName,
+	zoneColocationVisitsMetricName,
+	zoneColocationEdgeResponseBytesMetricName,
+	zoneColocationRequestsTotalMetricName,
+	zoneColocationVisitsErrorMetricName,
+	zoneColocationEdgeResponseBytesErrorMetricName,
+	zoneColocationRequestsTotalErrorMetricName,
+	zoneCustomerError4xxRate,
+	zoneCustomerError4xxTotal,
+	zoneCustomerError5xxRate,
+	zoneCustomerError5xxTotal,
+	zoneEdgeErrorRate,
+	zoneEdgeErrorsTotal,
+	zoneOriginErrorRate,
+	zoneOriginErrorsTotal,
+	zoneBotRequestsByCountry,
+	zoneFirewallBotsDetectedSource,
+	zoneOriginResponseDurationMsMetricName,
+	zoneFirewallEventsDetailedTotalMetricName,
+	zoneBandwidthHostBytesTotalMetricName,
+}
+
+// BuildHostLabelMetricsSet validates a host_label_metrics allowlist.
+func BuildHostLabelMetricsSet(metrics []string) (Set, error) {
+	hostMetrics := Set{}
+	for _, m := range hostMetricNames {
+		hostMetrics.Add(m)
+	}
+	set := Set{}
+	for _, metric := range metrics {
+		if !hostMetrics.Has(MetricName(metric)) {
+			return nil, fmt.Errorf("metric %s has no host label", metric)
+		}
+		set.Add(MetricName(metric))
+	}
+	return set, nil
+}
+
+// SetHostLabelMetrics sets the metrics that keep the host label regardless
+// of exclude_host. It must be called before MustRegisterMetrics.
+func SetHostLabelMetrics(metrics Set) {
+	hostLabelMetrics = metrics
+}
+
+// hostLabelEnabled reports whether a metric registered under any of names
+// carries the host label: always for metrics in host_label_metrics, otherwise
+// unless exclude_host is set. With no names only exclude_host applies.
+func hostLabelEnabled(names ...MetricName) bool {
+	for _, name := range names {
+		if hostLabelMetrics.Has(name) {
+			return true
+		}
+	}
+	return !viper.GetBool("exclude_host")
+}
+
+// zoneIDLabel adds the zone_id label to the zone metrics. See SetZoneIDLabel.
+var zoneIDLabel bool
+
+func init() {
+	buildZoneMetrics()
+}
+
+// SetZoneIDLabel adds the zone_id label, the zone tag that survives a zone
+// rename, next to zone on the zone metrics built by buildZoneMetrics. It must
+// be called before MustRegisterMetrics.
+func SetZoneIDLabel(enabled bool) {
+	zoneIDLabel = enabled
+	buildZoneMetrics()
+}
+
+// buildZoneMetrics (re)creates the per-zone totals with zoneMetricLabels.
+func buildZoneMetrics() {
+	labels := zoneMetricLabels()
+
+	zoneRequestTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneRequestTotalMetricName.String(),
+		Help: "Number of requests for zone",
+	}, labels,
+	)
+
+	zoneRequestCached = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: zoneRequestCachedMetricName.String(),
+		Help: "Number of cached requests for zone",
+	}, labels,
+	)
+
+	zoneRequestSSLEncrypted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneRequestSSLEncryptedMetricName.String(),
+		Help: "Number of encrypted requests for zone",
+	}, labels,
+	)
+
+	zoneBandwidthTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneBandwidthTotalMetricName.String(),
+		Help: "Total bandwidth per zone in bytes",
+	}, labels,
+	)
+
+	zoneBandwidthCached = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneBandwidthCachedMetricName.String(),
+		Help: "Cached bandwidth per zone in bytes",
+	}, labels,
+	)
+
+	zoneBandwidthSSLEncrypted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneBandwidthSSLEncryptedMetricName.String(),
+		Help: "Encrypted bandwidth per zone in bytes",
+	}, labels,
+	)
+
+	zoneThreatsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneThreatsTotalMetricName.String(),
+		Help: "Threats per zone",
+	}, labels,
+	)
+
+	zonePageviewsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zonePageviewsTotalMetricName.String(),
+		Help: "Pageviews per zone",
+	}, labels,
+	)
+
+	zoneUniquesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneUniquesTotalMetricName.String(),
+		Help: "Uniques per zone",
+	}, labels,
+	)
+
+	zoneAvailabilityRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: zoneAvailabilityRatioMetricName.String(),
+		Help: "Approximate availability per zone over the query window: 1 - edge 5xx requests / requests",
+	}, labels,
+	)
+
+	zoneBandwidthCacheRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: zoneBandwidthCacheRatioMetricName.String(),
+		Help: "Share of bandwidth served from cache per zone over the query window: cached bytes / bytes",
+	}, labels,
+	)
+}
+
+// zoneMetricLabels returns "zone", "account" and, with zoneIDLabel, "zone_id".
+func zoneMetricLabels() []string {
+	labels := []string{"zone", "account"}
+	if zoneIDLabel {
+		labels = append(labels, "zone_id")
+	}
+	return labels
+}
+
+// zoneLabels returns the label values for zoneMetricLabels.
+func zoneLabels(name, account, zoneID string) prometheus.Labels {
+	labels := prometheus.Labels{"zone": name, "account": account}
+	if zoneIDLabel {
+		labels["zone_id"] = zoneID
+	}
+	return labels
+}
+
+// accountMetricLabels returns "account", then "account_type" unless
+// account_type_label is disabled, then extra.
+func accountMetricLabels(extra ...string) []string {
+	labels := []string{"account"}
+	if viper.GetBool("account_type_label") {
+		labels = append(labels, "account_type")
+	}
+	return append(labels, extra...)
+}
+
+// accountLabels returns a copy of baseLabels with the account name and, unless
+// account_type_label is disabled, its type. An empty type falls back to
+// account_type_fallback.
+func accountLabels(account cloudflare.Account, baseLabels prometheus.Labels) prometheus.Labels {
+	labels := make(prometheus.Labels, len(baseLabels)+2)
+	for k, v := range baseLabels {
+		labels[k] = v
+	}
+	labels["account"] = account.Name
+
+	if viper.GetBool("account_type_label") {
+		accountType := account.Type
+		if accountType == "" {
+			accountType = viper.GetString("account_type_fallback")
+		}
+		labels["account_type"] = accountType
+	}
+	return labels
+}
+
+// getLabels returns a copy of baseLabels, adding "host" when hostLabelEnabled
+// for the metric's names. The caller's map is never modified.
+func getLabels(baseLabels prometheus.Labels, hostValue string, metrics ...MetricName) prometheus.Labels {
+
+	labels := make(prometheus.Labels, len(baseLabels)+1)
+	for k, v := range baseLabels {
+		labels[k] = v
+	}
+
+	// Add "host" dynamically for metrics that carry it
+	if hostLabelEnabled(metrics...) {
+		labels["host"] = hostValue
+	}
+
+	return labels
+}
+
+// BuildAllMetricsSet helps to build all metric and return as Set.
+func BuildAllMetricsSet() Set {
+	allMetricsSet := Set{}
+	allMetricsSet.Add(zoneRequestTotalMetricName)
+	allMetricsSet.Add(zoneRequestCachedMetricName)
+	allMetricsSet.Add(zoneRequestSSLEncryptedMetricName)
+	allMetricsSet.Add(zoneRequestContentTypeMetricName)
+	allMetricsSet.Add(zoneRequestCountryMetricName)
+	allMetricsSet.Add(zoneRequestHTTPStatusMetricName)
+	allMetricsSet.Add(zoneRequestBrowserMapMetricName)
+	allMetricsSet.Add(zoneRequestOriginStatusCountryHostMetricName)
+	allMetricsSet.Add(zoneRequestStatusCountryHostMetricName)
+	allMetricsSet.Add(zoneBandwidthTotalMetricName)
+	allMetricsSet.Add(zoneBandwidthCachedMetricName)
+	allMetricsSet.Add(zoneBandwidthSSLEncryptedMetricName)
+	allMetricsSet.Add(zoneBandwidthContentTypeMetricName)
+	allMetricsSet.Add(zoneBandwidthCountryMetricName)
+	allMetricsSet.Add(zoneThreatsTotalMetricName)
+	allMetricsSet.Add(zoneThreatsCountryMetricName)
+	allMetricsSet.Add(zoneThreatsTypeMetricName)
+	allMetricsSet.Add(zonePageviewsTotalMetricName)
+	allMetricsSet.Add(zoneUniquesTotalMetricName)
+	allMetricsSet.Add(zoneColocationVisitsMetricName)
+	allMetricsSet.Add(zoneColocationEdgeResponseBytesMetricName)
+	allMetricsSet.Add(zoneColocationRequestsTotalMetricName)
+	allMetricsSet.Add(zoneFirewallEventsCountMetricName)
+	allMetricsSet.Add(zoneHealthCheckEventsOriginCountMetricName)
+	allMetricsSet.Add(workerRequestsMetricName)
+	allMetricsSet.Add(workerErrorsMetricName)
+	allMetricsSet.Add(workerCPUTimeMetricName)
+	allMetricsSet.Add(workerDurationMetricName)
+	allMetricsSet.Add(poolHealthStatusMetricName)
+	allMetricsSet.Add(poolRequestsTotalMetricName)
+	allMetricsSet.Add(logpushFailedJobsAccountMetricName)
+	allMetricsSet.Add(logpushFailedJobsZoneMetricName)
+	// new
+	allMetricsSet.Add(zoneCustomerError4xxRate)
+	allMetricsSet.Add(zoneCustomerError5xxRate)
+	allMetricsSet.Add(zoneEdgeErrorRate)
+	allMetricsSet.Add(zoneOriginErrorRate)
+	allMetricsSet.Add(zoneCustomerError4xxTotal)
+	allMetricsSet.Add(zoneCustomerError5xxTotal)
+	allMetricsSet.Add(zoneEdgeErrorsTotal)
+	allMetricsSet.Add(zoneOriginErrorsTotal)
+	allMetricsSet.Add(zoneBotRequestsByCountry)
+	allMetricsSet.Add(zoneHealthCheckEventsAdaptiveGroupsAvg)
+	allMetricsSet.Add(zoneFirewallBotsDetectedSource)
+	allMetricsSet.Add(zoneFirewallRequestAction)
+	allMetricsSet.Add(zoneRequestMethodCount)
+	allMetricsSet.Add(magicTransitActiveTunnels)
+	allMetricsSet.Add(magicTransitEdgeColoCount)
+	allMetricsSet.Add(magicTransitHealthyTunnels)
+	allMetricsSet.Add(magicTransitTunnelFailures)
+	allMetricsSet.Add(zoneCertificateValidationStatus)
+	// other new
+	allMetricsSet.Add(zoneOriginResponseDurationMsMetricName)
+	allMetricsSet.Add(zoneColocationVisitsErrorMetricName)
+	allMetricsSet.Add(zoneColocationEdgeResponseBytesErrorMetricName)
+	allMetricsSet.Add(zoneColocationRequestsTotalErrorMetricName)
+	allMetricsSet.Add(zoneRateLimitEventsTotalMetricName)
+	allMetricsSet.Add(zoneOriginRequestsTotalMetricName)
+	allMetricsSet.Add(zoneArgoRequestsTotalMetricName)
+	allMetricsSet.Add(zoneArgoResponseTimeMsMetricName)
+	allMetricsSet.Add(zoneArgoResponseTimeImprovementMsMetricName)
+	allMetricsSet.Add(apiRequestDurationMetricName)
+	allMetricsSet.Add(turnstileChallengesTotalMetricName)
+	allMetricsSet.Add(turnstileSolvesTotalMetricName)
+	allMetricsSet.Add(poolAvgRttMsMetricName)
+	allMetricsSet.Add(originHealthMetricName)
+	allMetricsSet.Add(zoneRequestIPClassMetricName)
+	allMetricsSet.Add(zoneRequestHTTPVersionMetricName)
+	allMetricsSet.Add(zoneRequestSSLProtocolMetricName)
+	allMetricsSet.Add(exporterFetchErrorsTotalMetricName)
+	allMetricsSet.Add(zoneRequestsByCacheStatusMetricName)
+	allMetricsSet.Add(zoneCertificateDaysUntilExpiryMetricName)
+	allMetricsSet.Add(streamMinutesViewedTotalMetricName)
+	allMetricsSet.Add(streamBandwidthBytesTotalMetricName)
+	allMetricsSet.Add(imagesRequestsTotalMetricName)
+	allMetricsSet.Add(imagesTransformationsTotalMetricName)
+	allMetricsSet.Add(zoneSampleIntervalMetricName)
+	allMetricsSet.Add(durableObjectsRequestsTotalMetricName)
+	allMetricsSet.Add(queueBacklogMessagesMetricName)
+	allMetricsSet.Add(exporterScrapesSkippedTotalMetricName)
+	allMetricsSet.Add(zoneBotScoreRequestsTotalMetricName)
+	allMetricsSet.Add(zoneThreatsTypeCountryTotalMetricName)
+	allMetricsSet.Add(exporterBuildInfoMetricName)
+	allMetricsSet.Add(zoneFirewallEventsDetailedTotalMetricName)
+	allMetricsSet.Add(zonePageShieldScriptsMetricName)
+	allMetricsSet.Add(zonePageShieldViolationsTotalMetricName)
+	allMetricsSet.Add(magicTransitTunnelHealthMetricName)
+	allMetricsSet.Add(exporterQueryTruncatedTotalMetricName)
+	allMetricsSet.Add(zoneBandwidthHostBytesTotalMetricName)
+	allMetricsSet.Add(zoneFirewallEventsByKindTotalMetricName)
+	allMetricsSet.Add(exporterCircuitBreakerOpenMetricName)
+	allMetricsSet.Add(exporterZonesTotalMetricName)
+	allMetricsSet.Add(exporterAccountsTotalMetricName)
+	allMetricsSet.Add(zoneOriginConnectivityErrorsTotalMetricName)
+	allMetricsSet.Add(logpushJobsTotalMetricName)
+	allMetricsSet.Add(zoneFirewallEventsByASNTotalMetricName)
+	allMetricsSet.Add(exporterGraphQLRowsReadTotalMetricName)
+	allMetricsSet.Add(zoneAvailabilityRatioMetricName)
+	allMetricsSet.Add(workerInvocationsByStatusTotalMetricName)
+	allMetricsSet.Add(originLBHealthMetricName)
+	allMetricsSet.Add(zoneRequestsByPathTotalMetricName)
+	allMetricsSet.Add(zoneBandwidthCacheRatioMetricName)
+	allMetricsSet.Add(exporterPermissionErrorsTotalMetricName)
+	allMetricsSet.Add(exporterFamilyLastSuccessMetricName)
+	allMetricsSet.Add(exporterRateLimitWaitSecondsMetricName)
+	allMetricsSet.Add(exporterRateLimitBlockedTotalMetricName)
+	allMetricsSet.Add(exporterDecodeErrorsTotalMetricName)
+	allMetricsSet.Add(zoneSampleRateMetricName)
+	allMetricsSet.Add(exporterRetriesTotalMetricName)
+	allMetricsSet.Add(exporterRetriesBudgetExhaustedTotalMetricName)
+	allMetricsSet.Add(exporterScrapeTimeoutsTotalMetricName)
+	allMetricsSet.Add(zoneHTTP2PercentageMetricName)
+	allMetricsSet.Add(zoneHTTP3PercentageMetricName)
+	allMetricsSet.Add(zoneWAFCategoryEventsTotalMetricName)
+
+	return allMetricsSet
+}
+
+// BuildDeniedMetricsSet returns Set and error.
+func BuildDeniedMetricsSet(metricsDenylist []string) (Set, error) {
+	deniedMetricsSet := Set{}
+	allMetricsSet := BuildAllMetricsSet()
+	for _, metric := range metricsDenylist {
+		if !allMetricsSet.Has(MetricName(metric)) {
+			return nil, fmt.Errorf("metric %s doesn't exists", metric)
+		}
+		deniedMetricsSet.Add(MetricName(metric))
+	}
+	return deniedMetricsSet, nil
+}
+
+var zoneRequestOriginStatusCountryHost *prometheus.CounterVec
+var zoneRequestStatusCountryHost *prometheus.CounterVec
+var zoneColocationVisits *prometheus.CounterVec
+var zoneColocationEdgeResponseBytes *prometheus.CounterVec
+var zoneColocationRequestsTotal *prometheus.CounterVec
+var zoneCustomerError4xx *prometheus.CounterVec
+var zoneCustomerError5xx *prometheus.CounterVec
+var zoneEdgeError *prometheus.CounterVec
+
+// zoneEdgeErrorLegacy keeps the deprecated edge error gauge, which counts
+// response groups rather than requests, until it is removed.
+var zoneEdgeErrorLegacy *prometheus.GaugeVec
+var zoneOriginError *prometheus.CounterVec
+var zoneFirewallBotsDetected *prometheus.CounterVec
+var zoneBotRequests *prometheus.CounterVec
+var zoneFirewallEventsDetailedTotal *prometheus.CounterVec
+var zoneBandwidthHostBytesTotal *prometheus.CounterVec
+
+// Zone metrics carrying the optional zone_id label, see buildZoneMetrics
+var zoneRequestTotal *prometheus.CounterVec
+var zoneRequestCached *prometheus.GaugeVec
+var zoneRequestSSLEncrypted *prometheus.CounterVec
+var zoneBandwidthTotal *prometheus.CounterVec
+var zoneBandwidthCached *prometheus.CounterVec
+var zoneBandwidthSSLEncrypted *prometheus.CounterVec
+var zoneThreatsTotal *prometheus.CounterVec
+var zonePageviewsTotal *prometheus.CounterVec
+var zoneUniquesTotal *prometheus.CounterVec
+var zoneAvailabilityRatio *prometheus.GaugeVec
+var zoneBandwidthCacheRatio *prometheus.GaugeVec
+
+// Account metrics carrying the optional account_type label
+var logpushFailedJobsAccount *prometheus.CounterVec
+var logpushJobsTotal *prometheus.CounterVec
+var magicTransitActiveTunnel *prometheus.GaugeVec
+var magicTransitHealthyTunnel *prometheus.GaugeVec
+var magicTransitTunnelFailure *prometheus.GaugeVec
+var magicTransitEdgeColo *prometheus.GaugeVec
+
+// other new added
+var zoneOriginResponseDuration *prometheus.GaugeVec
+var zoneColocationVisitsError *prometheus.CounterVec
+var zoneColocationEdgeResponseBytesError *prometheus.CounterVec
+var zoneColocationRequestsTotalError *prometheus.CounterVec
+
+// MustRegisterMetrics register the metrics.
+func MustRegisterMetrics(deniedMetrics Set) {
+	if !deniedMetrics.Has(zoneRequestTotalMetricName) {
+		mustRegister(withSampleTimestamps(zoneRequestTotal))
+	}
+	if !deniedMetrics.Has(zoneRequestCachedMetricName) {
+		mustRegister(withSampleTimestamps(zoneRequestCached))
+	}
+	if !deniedMetrics.Has(zoneRequestSSLEncryptedMetricName) {
+		mustRegister(withSampleTimestamps(zoneRequestSSLEncrypted))
+	}
+	if !deniedMetrics.Has(zoneRequestContentTypeMetricName) {
+		mustRegister(zoneRequestContentType)
+	}
+	if !deniedMetrics.Has(zoneRequestCountryMetricName) {
+		mustRegister(zoneRequestCountry)
+	}
+	if !deniedMetrics.Has(zoneRequestHTTPStatusMetricName) {
+		mustRegister(zoneRequestHTTPStatus)
+	}
+	if !deniedMetrics.Has(zoneRequestBrowserMapMetricName) {
+		mustRegister(zoneRequestBrowserMap)
+	}
+	if !deniedMetrics.Has(zoneRequestOriginStatusCountryHostMetricName) {
+		if zoneRequestOriginStatusCountryHost == nil { // Ensure it is not nil before registration
+			metricLabels := []string{"zone", "account", "status", "country"} // Base labels
+
+			if hostLabelEnabled(zoneRequestOriginStatusCountryHostMetricName) {
+				metricLabels = append(metricLabels, "host") // Conditionally add "host"
+			}
+
+			zoneRequestOriginStatusCountryHost = prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: zoneRequestOriginStatusCountryHostMetricName.String(),
+					Help: "Count of not cached requests for zone per origin HTTP status per country per host",
+				},
+				metricLabels,
+			)
+
+			mustRegister(zoneRequestOriginStatusCountryHost)
+		}
+	}
+	if !deniedMetrics.Has(zoneRequestStatusCountryHostMetricName) {
+		if zoneRequestStatusCountryHost == nil { // Ensure it is not nil before registration
+			metricLabels := []string{"zone", "account", "status", "country"} // Base labels
+
+			if hostLabelEnabled(zoneRequestStatusCountryHostMetricName) {
+				metricLabels = append(metricLabels, "host") // Conditionally add "host"
+			}
+
+			zoneRequestStatusCountryHost = prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: zoneRequestStatusCountryHostMetricName.String(),
+					Help: "Count of requests for zone per edge HTTP status per country per host",
+				},
+				metricLabels,
+			)
+
+			mustRegister(zoneRequestStatusCountryHost)
+		}
+	}
+	if !deniedMetrics.Has(zoneBandwidthTotalMetricName) {
+		mustRegister(withSampleTimestamps(zoneBandwidthTotal))
+	}
+	if !deniedMetrics.Has(zoneBandwidthCachedMetricName) {
+		mustRegister(withSampleTimestamps(zoneBandwidthCached))
+	}
+	if !deniedMetrics.Has(zoneBandwidthSSLEncryptedMetricName) {
+		mustRegister(withSampleTimestamps(zoneBandwidthSSLEncrypted))
+	}
+	if !deniedMetrics.Has(zoneBandwidthContentTypeMetricName) {
+		mustRegister(zoneBandwidthContentType)
+	}
+	if !deniedMetrics.Has(zoneBandwidthCountryMetricName) {
+		mustRegister(zoneBandwidthCountry)
+	}
+	if !deniedMetrics.Has(zoneThreatsTotalMetricName) {
+		mustRegister(withSampleTimestamps(zoneThreatsTotal))
+	}
+	if !deniedMetrics.Has(zoneThreatsCountryMetricName) {
+		mustRegister(zoneThreatsCountry)
+	}
+	if !deniedMetrics.Has(zoneThreatsTypeMetricName) {
+		mustRegister(zoneThreatsType)
+	}
+	if !deniedMetrics.Has(zonePageviewsTotalMetricName) {
+		mustRegister(withSampleTimestamps(zonePageviewsTotal))
+	}
+	if !deniedMetrics.Has(zoneUniquesTotalMetricName) {
+		mustRegister(withSampleTimestamps(zoneUniquesTotal))
+	}
+	if !deniedMetrics.Has(zoneColocationVisitsMetricName) {
+		if zoneColocationVisits == nil { // Ensure it is not nil before registration
+			metricLabels1 := []string{"zone", "account", "colocation"} // Base labels
+
+			if hostLabelEnabled(zoneColocationVisitsMetricName) {
+				metricLabels1 = append(metricLabels1, "host") // Conditionally add "host"
+			}
+
+			zoneColocationVisits = prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: zoneColocationVisitsMetricName.String(),
+					Help: "Total visits per colocation",
+				},
+				metricLabels1,
+			)
+
+			mustRegister(zoneColocationVisits)
+		}
+	}
+	if !deniedMetrics.Has(zoneColocationEdgeResponseBytesMetricName) {
+		if zoneColocationEdgeResponseBytes == nil { // Ensure it is not nil before registration
+			metricLabels2 := []string{"zone", "account", "colocation"} // Base labels
+
+			if hostLabelEnabled(zoneColocationEdgeResponseBytesMetricName) {
+				metricLabels2 = append(metricLabels2, "host") // Conditionally add "host"
+			}
+
+			zoneColocationEdgeResponseBytes = prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: zoneColocationEdgeResponseBytesMetricName.String(),
+					Help: "Edge response bytes per colocation",
+				},
+				metricLabels2,
+			)
+
+			mustRegister(zoneColocationEdgeResponseBytes)
+		}
+	}
+	if !deniedMetrics.Has(zoneColocationRequestsTotalMetricName) {
+		if zoneColocationRequestsTotal == nil { // Ensure it is not nil before registration
+			metricLabels3 := []string{"zone", "account", "colocation"} // Base labels
+
+			if hostLabelEnabled(zoneColocationRequestsTotalMetricName) {
+				metricLabels3 = append(metricLabels3, "host") // Conditionally add "host"
+			}
+
+			zoneColocationRequestsTotal = prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: zoneColocationRequestsTotalMetricName.String(),
+					Help: "Total requests per colocation",
+				},
+				metricLabels3,
+			)
+
+			mustRegister(zoneColocationRequestsTotal)
+		}
+	}
+	if !deniedMetrics.Has(zoneFirewallEventsCountMetricName) {
+		mustRegister(zoneFirewallEventsCount)
+	}
+	if !deniedMetrics.Has(zoneHealthCheckEventsOriginCountMetricName) {
+		mustRegister(zoneHealthCheckEventsOriginCount)
+	}
+	if !deniedMetrics.Has(workerRequestsMetricName) {
+		mustRegister(workerRequests)
+	}
+	if !deniedMetrics.Has(workerErrorsMetricName) {
+		mustRegister(workerErrors)
+	}
+	if !deniedMetrics.Has(workerCPUTimeMetricName) {
+		mustRegister(workerCPUTime)
+	}
+	if !deniedMetrics.Has(workerDurationMetricName) {
+		mustRegister(workerDuration)
+	}
+	if !deniedMetrics.Has(poolHealthStatusMetricName) {
+		mustRegister(poolHealthStatus)
+	}
+	if !deniedMetrics.Has(poolRequestsTotalMetricName) {
+		mustRegister(poolRequestsTotal)
+	}
+	if !deniedMetrics.Has(logpushFailedJobsAccountMetricName) {
+		if logpushFailedJobsAccount == nil {
+			logpushFailedJobsAccount = prometheus.NewCounterVec(prometheus.CounterOpts{
+				Name: logpushFailedJobsAccountMetricName.String(),
+				Help: "Number of failed logpush jobs on the account level",
+			}, accountMetricLabels("destination", "job_id", "job_name", "dataset", "final"))
+
+			mustRegister(logpushFailedJobsAccount)
+		}
+	}
+	if !deniedMetrics.Has(logpushJobsTotalMetricName) {
+		if logpushJobsTotal == nil {
+			logpushJobsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+				Name: logpushJobsTotalMetricName.String(),
+				Help: "Number of logpush deliveries on the account level by the status class of their final attempt",
+			}, accountMetricLabels("destination", "job_id", "status_class"))
+
+			mustRegister(logpushJobsTotal)
+		}
+	}
+	if !deniedMetrics.Has(logpushFailedJobsZoneMetricName) {
+		mustRegister(logpushFailedJobsZone)
+	}
+	// new
+	if !deniedMetrics.Has(zoneCustomerError4xxRate) && !deniedMetrics.Has(zoneCustomerError4xxTotal) {
+		if zoneCustomerError4xx == nil { // Ensure it is not nil before registration
+			metricLabels := errorMetricLabels(zoneCustomerError4xxRate, zoneCustomerError4xxTotal)
+
+			zoneCustomerError4xx = prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: zoneCustomerError4xxTotal.String(),
+					Help: "Number of origin 4xx responses",
+				},
+				metricLabels,
+			)
+
+			mustRegister(newRenamedCounter(zoneCustomerError4xx, zoneCustomerError4xxRate, zoneCustomerError4xxTotal, prometheus.CounterValue, metricLabels))
+		}
+	}
+	if !deniedMetrics.Has(zoneCustomerError5xxRate) && !deniedMetrics.Has(zoneCustomerError5xxTotal) {
+		if zoneCustomerError5xx == nil { // Ensure it is not nil before registration
+			metricLabels := errorMetricLabels(zoneCustomerError5xxRate, zoneCustomerError5xxTotal)
+
+			zoneCustomerError5xx = prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: zoneCustomerError5xxTotal.String(),
+					Help: "Number of origin 5xx responses, excluding 52x connectivity errors",
+				},
+				metricLabels,
+			)
+
+			mustRegister(newRenamedCounter(zoneCustomerError5xx, zoneCustomerError5xxRate, zoneCustomerError5xxTotal, prometheus.CounterValue, metricLabels))
+		}
+	}
+	if !deniedMetrics.Has(zoneEdgeErrorRate) && !deniedMetrics.Has(zoneEdgeErrorsTotal) {
+		if zoneEdgeError == nil { // Ensure it is not nil before registration
+			metricLabels := errorMetricLabels(zoneEdgeErrorRate, zoneEdgeErrorsTotal)
+
+			zoneEdgeError = prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: zoneEdgeErrorsTotal.String(),
+					Help: "Number of edge 4xx and 5xx responses",
+				},
+				metricLabels,
+			)
+
+			zoneEdgeErrorLegacy = prometheus.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: zoneEdgeErrorRate.String(),
+					Help: fmt.Sprintf("Deprecated: use %s. Number of error rate of 4xx and 5xx", zoneEdgeErrorsTotal),
+				},
+				metricLabels,
+			)
+
+			mustRegister(zoneEdgeError)
+			// The old name counted groups, not requests; keep it unchanged until it is removed
+			mustRegister(zoneEdgeErrorLegacy)
+		}
+	}
+	if !deniedMetrics.Has(zoneOriginErrorRate) && !deniedMetrics.Has(zoneOriginErrorsTotal) {
+		if zoneOriginError == nil { // Ensure it is not nil before registration
+			metricLabels := errorMetricLabels(zoneOriginErrorRate, zoneOriginErrorsTotal)
+
+			zoneOriginError = prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: zoneOriginErrorsTotal.String(),
+					Help: "Number of origin 4xx and 5xx responses",
+				},
+				metricLabels,
+			)
+
+			mustRegister(newRenamedCounter(zoneOriginError, zoneOriginErrorRate, zoneOriginErrorsTotal, prometheus.CounterValue, metricLabels))
+		}
+	}
+	if !deniedMetrics.Has(zoneBotRequestsByCountry) {
+		if zoneBotRequests == nil { // Ensure it is not nil before registration
+			zoneBotRequestsMetricLabels := []string{"zone", "account", "country", "action"}
+
+			if hostLabelEnabled(zoneBotRequestsByCountry) {
+				zoneBotRequestsMetricLabels = append(zoneBotRequestsMetricLabels, "host")
+			}
+
+			zoneBotRequests = prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: "cloudflare_zone_bot_request_by_country",
+					Help: "Number of bot requests over country",
+				},
+				zoneBotRequestsMetricLabels,
+			)
+
+			mustRegister(zoneBotRequests)
+		}
+	}
+	if !deniedMetrics.Has(zoneCacheHitRatio) {
+		mustRegister(zoneCacheHit)
+	}
+	if !deniedMetrics.Has(zoneHealthCheckEventsAdaptiveGroupsAvg) {
+		mustRegister(zoneHealthCheckEventsAvg)
+	}
+	if !deniedMetrics.Has(zoneFirewallBotsDetectedSource) {
+		if zoneFirewallBotsDetected == nil { // Ensure it is not nil before registration
+			zoneFirewallBotsDetectedLabels := []string{"zone", "account", "source", "action"} // Base labels
+
+			if hostLabelEnabled(zoneFirewallBotsDetectedSource) {
+				zoneFirewallBotsDetectedLabels = append(zoneFirewallBotsDetectedLabels, "host") // Conditionally add "host"
+			}
+
+			zoneFirewallBotsDetected = prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: zoneFirewallBotsDetectedSource.String(),
+					Help: "Number of bot requests over country",
+				},
+				zoneFirewallBotsDetectedLabels,
+			)
+
+			mustRegister(zoneFirewallBotsDetected)
+		}
+	}
+	if !deniedMetrics.Has(zoneFirewallRequestAction) {
+		mustRegister(zoneFirewallAction)
+	}
+	if !deniedMetrics.Has(zoneRequestMethodCount) {
+		mustRegister(zoneRequestMethod)
+	}
+	if !deniedMetrics.Has(magicTransitActiveTunnels) {
+		if magicTransitActiveTunnel == nil {
+			magicTransitActiveTunnel = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Name: magicTransitActiveTunnels.String(),
+				Help: "Number of active Magic Transit tunnels",
+			}, accountMetricLabels())
+
+			mustRegister(magicTransitActiveTunnel)
+		}
+	}
+	if !deniedMetrics.Has(magicTransitEdgeColoCount) {
+		if magicTransitEdgeColo == nil {
+			magicTransitEdgeColo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Name: magicTransitEdgeColoCount.String(),
+				Help: "Number of edge colocation sites involved in Magic Transit tunnels",
+			}, accountMetricLabels())
+
+			mustRegister(magicTransitEdgeColo)
+		}
+	}
+	if !deniedMetrics.Has(magicTransitHealthyTunnels) {
+		if magicTransitHealthyTunnel == nil {
+			magicTransitHealthyTunnel = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Name: magicTransitHealthyTunnels.String(),
+				Help: "Number of healthy Magic Transit tunnels",
+			}, accountMetricLabels())
 
-			zoneColocationVisits = prometheus.NewCounterVec(
+			mustRegister(magicTransitHealthyTunnel)
+		}
+	}
+	if !deniedMetrics.Has(magicTransitTunnelFailures) {
+		if magicTransitTunnelFailure == nil {
+			magicTransitTunnelFailure = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Name: magicTransitTunnelFailures.String(),
+				Help: "Number of failed Magic Transit tunnels",
+			}, accountMetricLabels())
+
+			mustRegister(magicTransitTunnelFailure)
+		}
+	}
+	if !deniedMetrics.Has(zoneCertificateValidationStatus) {
+		mustRegister(zoneCertificateValidation)
+	}
+	if !deniedMetrics.Has(zoneOriginResponseDurationMsMetricName) {
+		if zoneOriginResponseDuration == nil { // Ensure it is not nil before registration
+			zoneOriginResponseDurationMsLabels := []string{"zone", "account", "status", "country"} // Base labels
+
+			if hostLabelEnabled(zoneOriginResponseDurationMsMetricName) {
+				zoneOriginResponseDurationMsLabels = append(zoneOriginResponseDurationMsLabels, "host") // Conditionally add "host"
+			}
+
+			zoneOriginResponseDuration = prometheus.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: zoneOriginResponseDurationMsMetricName.String(),
+					Help: "Zone Origin Response Time MS",
+				},
+				zoneOriginResponseDurationMsLabels, // Correctly pass the label slice
+			)
+
+			mustRegister(zoneOriginResponseDuration)
+		}
+	}
+	if !deniedMetrics.Has(zoneColocationVisitsErrorMetricName) {
+		if zoneColocationVisitsError == nil { // Ensure it is not nil before registration
+			metricLabelsError1 := []string{"zone", "account", "colocation", "status"} // Base labels
+
+			if hostLabelEnabled(zoneColocationVisitsErrorMetricName) {
+				metricLabelsError1 = append(metricLabelsError1, "host") // Conditionally add "host"
+			}
+
+			zoneColocationVisitsError = prometheus.NewCounterVec(
 				prometheus.CounterOpts{
-					Name: zoneColocationVisitsMetricName.String(),
-					Help: "Total visits per colocation",
+					Name: zoneColocationVisitsErrorMetricName.String(),
+					Help: "Total visits per colocation with error code",
 				},
-				metricLabels1,
+				metricLabelsError1,
 			)
 
-			prometheus.MustRegister(zoneColocationVisits)
+			mustRegister(zoneColocationVisitsError)
 		}
 	}
-	if !deniedMetrics.Has(zoneColocationEdgeResponseBytesMetricName) {
-		if zoneColocationEdgeResponseBytes == nil { // Ensure it is not nil before registration
-			metricLabels2 := []string{"zone", "account", "colocation"} // Base labels
+	if !deniedMetrics.Has(zoneColocationEdgeResponseBytesErrorMetricName) {
+		if zoneColocationEdgeResponseBytesError == nil { // Ensure it is not nil before registration
+			metricLabelsError2 := []string{"zone", "account", "colocation", "status"} // Base labels
 
-			exclude_host := viper.GetBool("exclude_host")
+			if hostLabelEnabled(zoneColocationEdgeResponseBytesErrorMetricName) {
+				metricLabelsError2 = append(metricLabelsError2, "host") // Conditionally add "host"
+			}
 
-			if !exclude_host {
-				metricLabels2 = append(metricLabels2, "host") // Conditionally add "host"
+			zoneColocationEdgeResponseBytesError = prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: zoneColocationEdgeResponseBytesErrorMetricName.String(),
+					Help: "Edge response bytes per colocation with error code",
+				},
+				metricLabelsError2,
+			)
+
+			mustRegister(zoneColocationEdgeResponseBytesError)
+		}
+	}
+	if !deniedMetrics.Has(zoneColocationRequestsTotalErrorMetricName) {
+		if zoneColocationRequestsTotalError == nil { // Ensure it is not nil before registration
+			metricLabelsError3 := []string{"zone", "account", "colocation", "status"} // Base labels
+
+			if hostLabelEnabled(zoneColocationRequestsTotalErrorMetricName) {
+				metricLabelsError3 = append(metricLabelsError3, "host") // Conditionally add "host"
+			}
+
+			zoneColocationRequestsTotalError = prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: zoneColocationRequestsTotalErrorMetricName.String(),
+					Help: "Total requests per colocation with error code",
+				},
+				metricLabelsError3,
+			)
+
+			mustRegister(zoneColocationRequestsTotalError)
+		}
+	}
+	if !deniedMetrics.Has(zoneRateLimitEventsTotalMetricName) {
+		mustRegister(zoneRateLimitEventsTotal)
+	}
+	if !deniedMetrics.Has(zoneOriginRequestsTotalMetricName) {
+		mustRegister(zoneOriginRequestsTotal)
+	}
+	if !deniedMetrics.Has(zoneArgoRequestsTotalMetricName) {
+		mustRegister(zoneArgoRequestsTotal)
+	}
+	if !deniedMetrics.Has(zoneArgoResponseTimeMsMetricName) {
+		mustRegister(zoneArgoResponseTimeMs)
+	}
+	if !deniedMetrics.Has(zoneArgoResponseTimeImprovementMsMetricName) {
+		mustRegister(zoneArgoResponseTimeImprovementMs)
+	}
+	if !deniedMetrics.Has(apiRequestDurationMetricName) {
+		mustRegister(cloudflareAPI.APIRequestDuration)
+	}
+	if !deniedMetrics.Has(turnstileChallengesTotalMetricName) {
+		mustRegister(turnstileChallengesTotal)
+	}
+	if !deniedMetrics.Has(turnstileSolvesTotalMetricName) {
+		mustRegister(turnstileSolvesTotal)
+	}
+	if !deniedMetrics.Has(poolAvgRttMsMetricName) {
+		mustRegister(poolAvgRttMs)
+	}
+	if !deniedMetrics.Has(originHealthMetricName) {
+		mustRegister(originHealth)
+	}
+	if !deniedMetrics.Has(originLBHealthMetricName) {
+		mustRegister(originLBHealth)
+	}
+	if !deniedMetrics.Has(zoneRequestIPClassMetricName) {
+		mustRegister(zoneRequestIPClass)
+	}
+	if !deniedMetrics.Has(zoneRequestHTTPVersionMetricName) {
+		mustRegister(zoneRequestHTTPVersion)
+	}
+	if !deniedMetrics.Has(zoneRequestSSLProtocolMetricName) {
+		mustRegister(zoneRequestSSLProtocol)
+	}
+	if !deniedMetrics.Has(exporterFetchErrorsTotalMetricName) {
+		mustRegister(exporterFetchErrorsTotal)
+	}
+	if !deniedMetrics.Has(exporterPermissionErrorsTotalMetricName) {
+		mustRegister(exporterPermissionErrorsTotal)
+	}
+	if !deniedMetrics.Has(exporterFamilyLastSuccessMetricName) {
+		mustRegister(exporterFamilyLastSuccess)
+	}
+	if !deniedMetrics.Has(exporterRateLimitWaitSecondsMetricName) {
+		mustRegister(limiter.WaitSeconds)
+	}
+	if !deniedMetrics.Has(exporterRateLimitBlockedTotalMetricName) {
+		mustRegister(limiter.BlockedTotal)
+	}
+	if !deniedMetrics.Has(exporterDecodeErrorsTotalMetricName) {
+		mustRegister(models.DecodeErrorsTotal)
+	}
+	if !deniedMetrics.Has(zoneSampleRateMetricName) {
+		mustRegister(zoneSampleRate)
+	}
+	if !deniedMetrics.Has(exporterRetriesTotalMetricName) {
+		mustRegister(cloudflareAPI.RetriesTotal)
+	}
+	if !deniedMetrics.Has(exporterRetriesBudgetExhaustedTotalMetricName) {
+		mustRegister(cloudflareAPI.RetryBudgetExhaustedTotal)
+	}
+	if !deniedMetrics.Has(exporterScrapeTimeoutsTotalMetricName) {
+		mustRegister(exporterScrapeTimeoutsTotal)
+	}
+	if !deniedMetrics.Has(zoneHTTP2PercentageMetricName) {
+		mustRegister(zoneHTTP2Percentage)
+	}
+	if !deniedMetrics.Has(zoneHTTP3PercentageMetricName) {
+		mustRegister(zoneHTTP3Percentage)
+	}
+	if !deniedMetrics.Has(zoneRequestsByCacheStatusMetricName) {
+		mustRegister(zoneRequestsByCacheStatus)
+	}
+	if !deniedMetrics.Has(zoneCertificateDaysUntilExpiryMetricName) {
+		mustRegister(zoneCertificateDaysUntilExpiry)
+	}
+	if !deniedMetrics.Has(streamMinutesViewedTotalMetricName) {
+		mustRegister(streamMinutesViewedTotal)
+	}
+	if !deniedMetrics.Has(streamBandwidthBytesTotalMetricName) {
+		mustRegister(streamBandwidthBytesTotal)
+	}
+	if !deniedMetrics.Has(imagesRequestsTotalMetricName) {
+		mustRegister(imagesRequestsTotal)
+	}
+	if !deniedMetrics.Has(imagesTransformationsTotalMetricName) {
+		mustRegister(imagesTransformationsTotal)
+	}
+	if !deniedMetrics.Has(zoneSampleIntervalMetricName) {
+		mustRegister(zoneSampleInterval)
+	}
+	if !deniedMetrics.Has(durableObjectsRequestsTotalMetricName) {
+		mustRegister(durableObjectsRequestsTotal)
+	}
+	if !deniedMetrics.Has(queueBacklogMessagesMetricName) {
+		mustRegister(queueBacklogMessages)
+	}
+	if !deniedMetrics.Has(exporterScrapesSkippedTotalMetricName) {
+		mustRegister(exporterScrapesSkippedTotal)
+	}
+	if !deniedMetrics.Has(zoneBotScoreRequestsTotalMetricName) {
+		mustRegister(zoneBotScoreRequestsTotal)
+	}
+	if !deniedMetrics.Has(zoneRequestsByPathTotalMetricName) {
+		mustRegister(zoneRequestsByPathTotal)
+	}
+	if !deniedMetrics.Has(zoneThreatsTypeCountryTotalMetricName) {
+		mustRegister(zoneThreatsTypeCountryTotal)
+	}
+	if !deniedMetrics.Has(exporterBuildInfoMetricName) {
+		mustRegister(exporterBuildInfo)
+		setBuildInfo()
+	}
+	if !deniedMetrics.Has(zoneFirewallEventsDetailedTotalMetricName) {
+		if zoneFirewallEventsDetailedTotal == nil {
+			labels := []string{"zone", "account", "action", "source"}
+			if hostLabelEnabled(zoneFirewallEventsDetailedTotalMetricName) {
+				labels = append(labels, "host")
+			}
+
+			zoneFirewallEventsDetailedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+				Name: zoneFirewallEventsDetailedTotalMetricName.String(),
+				Help: "Number of firewall events per zone per action per source",
+			}, labels)
+
+			mustRegister(zoneFirewallEventsDetailedTotal)
+		}
+	}
+	if !deniedMetrics.Has(zonePageShieldScriptsMetricName) {
+		mustRegister(zonePageShieldScripts)
+	}
+	if !deniedMetrics.Has(zonePageShieldViolationsTotalMetricName) {
+		mustRegister(zonePageShieldViolationsTotal)
+	}
+	if !deniedMetrics.Has(magicTransitTunnelHealthMetricName) {
+		mustRegister(magicTransitTunnelHealth)
+	}
+	if !deniedMetrics.Has(exporterQueryTruncatedTotalMetricName) {
+		mustRegister(exporterQueryTruncatedTotal)
+	}
+	if !deniedMetrics.Has(zoneBandwidthHostBytesTotalMetricName) {
+		if zoneBandwidthHostBytesTotal == nil {
+			labels := []string{"zone", "account"}
+			if hostLabelEnabled(zoneBandwidthHostBytesTotalMetricName) {
+				labels = append(labels, "host")
 			}
 
-			zoneColocationEdgeResponseBytes = prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Name: zoneColocationEdgeResponseBytesMetricName.String(),
-					Help: "Edge response bytes per colocation",
-				},
-				metricLabels2,
-			)
+			zoneBandwidthHostBytesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+				Name: zoneBandwidthHostBytesTotalMetricName.String(),
+				Help: "Edge response bytes per zone per host, capped to the top cf_host_top_n hosts",
+			}, labels)
+
+			mustRegister(zoneBandwidthHostBytesTotal)
+		}
+	}
+	if !deniedMetrics.Has(zoneFirewallEventsByKindTotalMetricName) {
+		mustRegister(zoneFirewallEventsByKindTotal)
+	}
+	if !deniedMetrics.Has(zoneWAFCategoryEventsTotalMetricName) {
+		mustRegister(zoneWAFCategoryEventsTotal)
+	}
+	if !deniedMetrics.Has(exporterCircuitBreakerOpenMetricName) {
+		mustRegister(cloudflareAPI.CircuitBreakerOpen)
+	}
+	if !deniedMetrics.Has(exporterZonesTotalMetricName) {
+		mustRegister(exporterZonesTotal)
+	}
+	if !deniedMetrics.Has(exporterAccountsTotalMetricName) {
+		mustRegister(exporterAccountsTotal)
+	}
+	if !deniedMetrics.Has(workerInvocationsByStatusTotalMetricName) {
+		mustRegister(workerInvocationsByStatusTotal)
+	}
+	if !deniedMetrics.Has(zoneAvailabilityRatioMetricName) {
+		mustRegister(withSampleTimestamps(zoneAvailabilityRatio))
+	}
+	if !deniedMetrics.Has(zoneBandwidthCacheRatioMetricName) {
+		mustRegister(withSampleTimestamps(zoneBandwidthCacheRatio))
+	}
+	if !deniedMetrics.Has(exporterGraphQLRowsReadTotalMetricName) {
+		mustRegister(cloudflareAPI.GraphQLRowsRead)
+	}
+	if !deniedMetrics.Has(zoneFirewallEventsByASNTotalMetricName) {
+		mustRegister(zoneFirewallEventsByASNTotal)
+	}
+	if !deniedMetrics.Has(zoneOriginConnectivityErrorsTotalMetricName) {
+		mustRegister(zoneOriginConnectivityErrorsTotal)
+	}
+
+}
+
+// workerScriptPattern splits worker script names into name and environment,
+// see SetWorkerScriptPattern. Nil keeps script names as they are.
+var workerScriptPattern *regexp.Regexp
+
+// SetWorkerScriptPattern compiles expr, which must have a "name" and an
+// "environment" named group, e.g. ^(?P<name>.+?)-(?P<environment>staging|production)$.
+// An empty expr disables the split. It must be called before scraping.
+func SetWorkerScriptPattern(expr string) error {
+	if expr == "" {
+		workerScriptPattern = nil
+		return nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid worker script pattern: %w", err)
+	}
+	if re.SubexpIndex("name") < 0 || re.SubexpIndex("environment") < 0 {
+		return fmt.Errorf("worker script pattern %q needs the named groups name and environment", expr)
+	}
+	workerScriptPattern = re
+	return nil
+}
+
+// splitWorkerScript returns the script name and environment of script. Names
+// that don't match workerScriptPattern are returned unchanged with an empty
+// environment, which leaves the label off the series.
+func splitWorkerScript(script string) (string, string) {
+	if workerScriptPattern == nil {
+		return script, ""
+	}
+	m := workerScriptPattern.FindStringSubmatch(script)
+	if m == nil {
+		return script, ""
+	}
+	return m[workerScriptPattern.SubexpIndex("name")], m[workerScriptPattern.SubexpIndex("environment")]
+}
+
+// FetchWorkerAnalytics handles cloudflare account and expose metrics like requests, error, Worker CPUTime and Duration.
+func FetchWorkerAnalytics(ctx context.Context, account cloudflare.Account) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Error("Panic in FetchWorkerAnalytics", map[string]interface{}{
+				"panic": r,
+			})
+		}
+	}()
+
+	// Replace spaces with hyphens and convert to lowercase
+	accountName := strings.ToLower(strings.ReplaceAll(account.Name, " ", "-"))
+
+	r, err := cloudflareAPI.FetchWorkerTotals(ctx, account.ID)
+	if err != nil {
+		// Return early if API call fails, keeping default metrics
+		logging.Error("FetchWorkerAnalytics: Failed to fetch worker totals", map[string]interface{}{
+			"accountID": account.ID,
+			"error":     err.Error(),
+		})
+		recordFetchError("workers", err)
+		return
+	}
+	markFamilySuccess("workers")
+
+	for _, a := range r.Viewer.Accounts {
+		if len(a.WorkersInvocationsAdaptive) == 0 {
+			// Ensure metrics for "unknown" are set when no worker data is present
+			// initializeDefaultMetrics(accountName, "unknown")
+			continue
+		}
+
+		for _, w := range a.WorkersInvocationsAdaptive {
+			name, environment := splitWorkerScript(w.Dimensions.ScriptName)
+			script := prometheus.Labels{"script_name": name, "account": accountName, "environment": environment}
+			withLabel := func(key, value string) prometheus.Labels {
+				labels := prometheus.Labels{key: value}
+				for k, v := range script {
+					labels[k] = v
+				}
+				return labels
+			}
+
+			// Add actual metrics
+			workerRequests.With(script).Add(float64(w.Sum.Requests))
+			workerErrors.With(script).Add(float64(w.Sum.Errors))
+			workerInvocationsByStatusTotal.With(withLabel("status", w.Dimensions.Status)).Add(float64(w.Sum.Requests))
+			workerCPUTime.With(withLabel("quantile", "P50")).Set(float64(w.Quantiles.CPUTimeP50))
+			workerCPUTime.With(withLabel("quantile", "P75")).Set(float64(w.Quantiles.CPUTimeP75))
+			workerCPUTime.With(withLabel("quantile", "P99")).Set(float64(w.Quantiles.CPUTimeP99))
+			workerCPUTime.With(withLabel("quantile", "P999")).Set(float64(w.Quantiles.CPUTimeP999))
+			workerDuration.With(withLabel("quantile", "P50")).Set(math.Round(float64(w.Quantiles.DurationP50)*1000) / 1000)
+			workerDuration.With(withLabel("quantile", "P75")).Set(math.Round(float64(w.Quantiles.DurationP75)*1000) / 1000)
+			workerDuration.With(withLabel("quantile", "P99")).Set(math.Round(float64(w.Quantiles.DurationP99)*1000) / 1000)
+			workerDuration.With(withLabel("quantile", "P999")).Set(math.Round(float64(w.Quantiles.DurationP999)*1000) / 1000)
+		}
+	}
+}
+
+// filterZones helper function to filter the zones.
+func filterZones(all []cloudflare.Zone, target []string) []cloudflare.Zone {
+	var filtered []cloudflare.Zone
+
+	if (len(target)) == 0 {
+		return all
+	}
+
+	for _, tz := range target {
+		for _, z := range all {
+			if tz == z.ID {
+				filtered = append(filtered, z)
+				logging.Info("Filtering zone", map[string]interface{}{
+					"zoneID":   z.ID,
+					"zoneName": z.Name,
+				})
+			}
+		}
+	}
+	return filtered
+}
+
+// zoneRotationOffset is where the next capped scrape starts when rotation is enabled.
+var zoneRotationOffset int
+
+// capZones limits zones to max entries; max <= 0 disables the cap. With rotate,
+// each call starts where the previous one stopped so every zone is covered over time.
+func capZones(zones []cloudflare.Zone, max int, rotate bool) []cloudflare.Zone {
+	if max <= 0 || len(zones) <= max {
+		return zones
+	}
+
+	logging.Warn("Zone count exceeds cf_max_zones, truncating", map[string]interface{}{
+		"zones":    len(zones),
+		"maxZones": max,
+		"rotate":   rotate,
+	})
+
+	if !rotate {
+		return zones[:max]
+	}
+
+	start := zoneRotationOffset % len(zones)
+	capped := make([]cloudflare.Zone, 0, max)
+	for i := 0; i < max; i++ {
+		capped = append(capped, zones[(start+i)%len(zones)])
+	}
+	zoneRotationOffset = (start + max) % len(zones)
+	return capped
+}
+
+// zoneStaggerTick counts scrapes so staggerZones can pick the next slot.
+var zoneStaggerTick atomic.Uint64
+
+// staggerZones returns the zones due in this scrape when zones are spread over
+// cycles scrapes; cycles <= 1 disables staggering. Zones are assigned to a
+// slot by a hash of their ID, so a zone keeps its slot as others come and go
+// and every zone is scraped exactly once every cycles scrapes.
+func staggerZones(zones []cloudflare.Zone, cycles int) []cloudflare.Zone {
+	if cycles <= 1 {
+		return zones
+	}
+
+	slot := uint32((zoneStaggerTick.Add(1) - 1) % uint64(cycles))
+
+	due := make([]cloudflare.Zone, 0, len(zones)/cycles+1)
+	for _, z := range zones {
+		h := fnv.New32a()
+		h.Write([]byte(z.ID))
+		if h.Sum32()%uint32(cycles) == slot {
+			due = append(due, z)
+		}
+	}
+	logging.Debug("Staggering zones across scrapes", map[string]interface{}{
+		"zones":  len(zones),
+		"due":    len(due),
+		"slot":   slot,
+		"cycles": cycles,
+	})
+	return due
+}
+
+// batchZones splits zones into batches of at most size. Zones are grouped by
+// scrape delay first so every zone in a batch shares the same query window.
+func batchZones(zones []cloudflare.Zone, size int) [][]cloudflare.Zone {
+	var delays []time.Duration
+	groups := map[time.Duration][]cloudflare.Zone{}
+	for _, z := range zones {
+		d := cloudflareAPI.ScrapeDelay([]string{z.ID})
+		if _, ok := groups[d]; !ok {
+			delays = append(delays, d)
+		}
+		groups[d] = append(groups[d], z)
+	}
+
+	var batches [][]cloudflare.Zone
+	for _, d := range delays {
+		group := groups[d]
+		for len(group) > 0 {
+			batch := group[:min(size, len(group))]
+			group = group[len(batch):]
+			batches = append(batches, batch)
+		}
+	}
+	return batches
+}
+
+// getTargetZones helper function to get targeted zones.
+func getTargetZones() []string {
+	var zoneIDs []string
+	if len(viper.GetString("cf_zones")) > 0 {
+		zoneIDs = strings.Split(viper.GetString("cf_zones"), ",")
+	} else {
+		// deprecated
+		for _, e := range os.Environ() {
+			if strings.HasPrefix(e, "ZONE_") {
+				split := strings.SplitN(e, "=", 2)
+				zoneIDs = append(zoneIDs, split[1])
+			}
+		}
+	}
+	return zoneIDs
+}
+
+// getTargetAccounts returns array of accounts to export.
+func getTargetAccounts() []string {
+	var accountIDs []string
 
-			prometheus.MustRegister(zoneColocationEdgeResponseBytes)
-		}
+	if len(viper.GetString("cf_accounts")) > 0 {
+		accountIDs = strings.Split(viper.GetString("cf_accounts"), ",")
 	}
-	if !deniedMetrics.Has(zoneColocationRequestsTotalMetricName) {
-		if zoneColocationRequestsTotal == nil { // Ensure it is not nil before registration
-			metricLabels3 := []string{"zone", "account", "colocation"} // Base labels
+	return accountIDs
+}
 
-			exclude_host := viper.GetBool("exclude_host")
+// getExcludedAccounts returns array of excluded accounts.
+func getExcludedAccounts() []string {
+	var accountIDs []string
 
-			if !exclude_host {
-				metricLabels3 = append(metricLabels3, "host") // Conditionally add "host"
-			}
+	if len(viper.GetString("cf_exclude_accounts")) > 0 {
+		accountIDs = strings.Split(viper.GetString("cf_exclude_accounts"), ",")
+	}
+	return accountIDs
+}
 
-			zoneColocationRequestsTotal = prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Name: zoneColocationRequestsTotalMetricName.String(),
-					Help: "Total requests per colocation",
-				},
-				metricLabels3,
-			)
+// filterAccounts keeps accounts listed in target (all when empty) and drops
+// those listed in exclude.
+func filterAccounts(all []cloudflare.Account, target []string, exclude []string) []cloudflare.Account {
+	var filtered []cloudflare.Account
 
-			prometheus.MustRegister(zoneColocationRequestsTotal)
+	for _, a := range all {
+		id := strings.TrimSpace(a.ID)
+		if len(target) > 0 && !slices.ContainsFunc(target, func(t string) bool { return strings.TrimSpace(t) == id }) {
+			continue
 		}
+		if slices.ContainsFunc(exclude, func(e string) bool { return strings.TrimSpace(e) == id }) {
+			continue
+		}
+		filtered = append(filtered, a)
 	}
-	if !deniedMetrics.Has(zoneFirewallEventsCountMetricName) {
-		prometheus.MustRegister(zoneFirewallEventsCount)
-	}
-	if !deniedMetrics.Has(zoneHealthCheckEventsOriginCountMetricName) {
-		prometheus.MustRegister(zoneHealthCheckEventsOriginCount)
-	}
-	if !deniedMetrics.Has(workerRequestsMetricName) {
-		prometheus.MustRegister(workerRequests)
-	}
-	if !deniedMetrics.Has(workerErrorsMetricName) {
-		prometheus.MustRegister(workerErrors)
-	}
-	if !deniedMetrics.Has(workerCPUTimeMetricName) {
-		prometheus.MustRegister(workerCPUTime)
-	}
-	if !deniedMetrics.Has(workerDurationMetricName) {
-		prometheus.MustRegister(workerDuration)
-	}
-	if !deniedMetrics.Has(poolHealthStatusMetricName) {
-		prometheus.MustRegister(poolHealthStatus)
-	}
-	if !deniedMetrics.Has(poolRequestsTotalMetricName) {
-		prometheus.MustRegister(poolRequestsTotal)
+	return filtered
+}
+
+// getColos returns the uppercased colocation codes from cf_colos.
+func getColos() map[string]bool {
+	colos := map[string]bool{}
+	for _, c := range strings.Split(viper.GetString("cf_colos"), ",") {
+		if c = strings.ToUpper(strings.TrimSpace(c)); c != "" {
+			colos[c] = true
+		}
 	}
-	if !deniedMetrics.Has(logpushFailedJobsAccountMetricName) {
-		prometheus.MustRegister(logpushFailedJobsAccount)
+	return colos
+}
+
+// getZonePlans returns the plans from cf_zone_plans, lowercased.
+func getZonePlans() []string {
+	var plans []string
+	for _, p := range strings.Split(viper.GetString("cf_zone_plans"), ",") {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			plans = append(plans, p)
+		}
 	}
-	if !deniedMetrics.Has(logpushFailedJobsZoneMetricName) {
-		prometheus.MustRegister(logpushFailedJobsZone)
+	return plans
+}
+
+// filterZonesByPlan keeps zones on one of plans. A plan matches the legacy ID
+// (free, pro, business, enterprise), the plan ID, or the first word of the plan
+// name ("Enterprise Website"). No plans returns all zones.
+func filterZonesByPlan(zones []cloudflare.Zone, plans []string) []cloudflare.Zone {
+	if len(plans) == 0 {
+		return zones
 	}
-	// new
-	if !deniedMetrics.Has(zoneCustomerError4xxRate) {
-		if zoneCustomerError4xx == nil { // Ensure it is not nil before registration
-			metricLabels := []string{"zone", "account", "status", "country"} // Base labels
 
-			exclude_host := viper.GetBool("exclude_host")
+	var filtered []cloudflare.Zone
+	for _, z := range zones {
+		name := strings.ToLower(z.Plan.ZonePlanCommon.Name)
+		candidates := []string{
+			strings.ToLower(z.Plan.LegacyID),
+			strings.ToLower(z.Plan.ZonePlanCommon.ID),
+			name,
+		}
+		if fields := strings.Fields(name); len(fields) > 0 {
+			candidates = append(candidates, fields[0])
+		}
 
-			if !exclude_host {
-				metricLabels = append(metricLabels, "host") // Conditionally add "host"
+		for _, p := range plans {
+			if slices.Contains(candidates, p) {
+				filtered = append(filtered, z)
+				break
 			}
-
-			zoneCustomerError4xx = prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Name: zoneCustomerError4xxRate.String(),
-					Help: "Number of error rates of 4xx",
-				},
-				metricLabels,
-			)
-
-			prometheus.MustRegister(zoneCustomerError4xx)
 		}
 	}
-	if !deniedMetrics.Has(zoneCustomerError5xxRate) {
-		if zoneCustomerError5xx == nil { // Ensure it is not nil before registration
-			metricLabels := []string{"zone", "account", "status", "country"} // Base labels
-
-			exclude_host := viper.GetBool("exclude_host")
-
-			if !exclude_host {
-				metricLabels = append(metricLabels, "host") // Conditionally add "host"
-			}
+	return filtered
+}
 
-			zoneCustomerError5xx = prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Name: zoneCustomerError5xxRate.String(),
-					Help: "Number of error rates of 5xx",
-				},
-				metricLabels,
-			)
+// filterPausedZones drops paused zones, which serve no traffic through
+// Cloudflare, unless exclude_paused_zones is disabled.
+func filterPausedZones(zones []cloudflare.Zone) []cloudflare.Zone {
+	if viper.IsSet("exclude_paused_zones") && !viper.GetBool("exclude_paused_zones") {
+		return zones
+	}
 
-			prometheus.MustRegister(zoneCustomerError5xx)
+	var filtered []cloudflare.Zone
+	for _, z := range zones {
+		if z.Paused || z.Status == "paused" {
+			logging.Info("Skipping paused zone", map[string]interface{}{
+				"zoneID":   z.ID,
+				"zoneName": z.Name,
+			})
+			continue
 		}
+		filtered = append(filtered, z)
 	}
-	if !deniedMetrics.Has(zoneEdgeErrorRate) {
-		if zoneEdgeError == nil { // Ensure it is not nil before registration
-			var metricLabels = []string{"zone", "account", "status", "country"} // Base labels
-
-			exclude_host := viper.GetBool("exclude_host")
+	return filtered
+}
 
-			if !exclude_host {
-				metricLabels = append(metricLabels, "host") // Conditionally add "host"
-			}
+// getExcludedZones returns array of excluded zones.
+func getExcludedZones() []string {
+	var zoneIDs []string
 
-			zoneEdgeError = prometheus.NewGaugeVec(
-				prometheus.GaugeOpts{
-					Name: zoneEdgeErrorRate.String(),
-					Help: "Number of error rate of 4xx and 5xx",
-				},
-				metricLabels, // Correctly pass the label slice
-			)
+	if len(viper.GetString("cf_exclude_zones")) > 0 {
+		zoneIDs = strings.Split(viper.GetString("cf_exclude_zones"), ",")
+	}
+	return zoneIDs
+}
 
-			prometheus.MustRegister(zoneEdgeError)
+func allZonesAreEmpty(account []models.LogpushResponse) bool {
+	// Check if all zones are empty
+	for _, zone := range account {
+		if len(zone.LogpushHealthAdaptiveGroups) > 0 || len(zone.LogpushHealthSuccessGroups) > 0 {
+			return false
 		}
 	}
-	if !deniedMetrics.Has(zoneOriginErrorRate) {
-		if zoneOriginError == nil { // Ensure it is not nil before registration
-			metricLabels := []string{"zone", "account", "status", "country"} // Base labels
+	return true // All zones are empty
+}
 
-			exclude_host := viper.GetBool("exclude_host")
+// logpushJobCache holds the logpush jobs looked up during the current scrape,
+// keyed by scope and account or zone ID.
+var logpushJobCache = struct {
+	sync.Mutex
+	jobs map[string]map[int]models.LogpushJob
+}{jobs: map[string]map[int]models.LogpushJob{}}
+
+// resetLogpushJobCache drops the jobs cached by the previous scrape.
+func resetLogpushJobCache() {
+	logpushJobCache.Lock()
+	defer logpushJobCache.Unlock()
+	logpushJobCache.jobs = map[string]map[int]models.LogpushJob{}
+}
 
-			if !exclude_host {
-				metricLabels = append(metricLabels, "host") // Conditionally add "host"
-			}
+// logpushJobLabels resolves a logpush job ID to its name and dataset, looking
+// the jobs up once per scrape. Jobs that can't be resolved keep their ID as
+// the name and an empty dataset.
+func logpushJobLabels(ctx context.Context, scope, id string, jobID int) (string, string) {
+	key := scope + "/" + id
 
-			zoneOriginError = prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Name: zoneOriginErrorRate.String(),
-					Help: "Number of error rates of 4xx and 5xx in HTTP requests",
-				},
-				metricLabels,
-			)
+	logpushJobCache.Lock()
+	jobs, ok := logpushJobCache.jobs[key]
+	logpushJobCache.Unlock()
 
-			prometheus.MustRegister(zoneOriginError)
+	if !ok {
+		var err error
+		jobs, err = cloudflareAPI.FetchLogpushJobs(ctx, scope, id)
+		if err != nil {
+			logging.Warn("Failed to resolve logpush job names", map[string]interface{}{
+				"scope": scope,
+				"id":    id,
+				"error": err.Error(),
+			})
 		}
+
+		// Cache failures too so a scrape makes one lookup per account or zone
+		logpushJobCache.Lock()
+		logpushJobCache.jobs[key] = jobs
+		logpushJobCache.Unlock()
 	}
-	if !deniedMetrics.Has(zoneBotRequestsByCountry) {
-		if zoneBotRequests == nil { // Ensure it is not nil before registration
-			zoneBotRequestsMetricLabels := []string{"zone", "account", "country", "action"}
 
-			exclude_host := viper.GetBool("exclude_host")
+	if job, ok := jobs[jobID]; ok && job.Name != "" {
+		return job.Name, job.Dataset
+	}
+	return strconv.Itoa(jobID), ""
+}
 
-			if !exclude_host {
-				zoneBotRequestsMetricLabels = append(zoneBotRequestsMetricLabels, "host")
-			}
+// fetchLogpushAnalyticsForAccount expose metrics related to logpush.
+func fetchLogpushAnalyticsForAccount(ctx context.Context, account cloudflare.Account) {
+	defer func() { // Panic Recovery
+		if r := recover(); r != nil {
+			logging.Error("Recovered from panic in fetchLogpushAnalyticsForAccount", map[string]interface{}{
+				"accountID": account.ID,
+				"panic":     r,
+			})
+		}
+	}()
 
-			zoneBotRequests = prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Name: "cloudflare_zone_bot_request_by_country",
-					Help: "Number of bot requests over country",
-				},
-				zoneBotRequestsMetricLabels,
-			)
+	r, err := cloudflareAPI.FetchLogpushAccount(ctx, account.ID)
+	if err != nil {
+		logging.Error("Failed to fetch logpush health data", map[string]interface{}{
+			"accountID": account.ID,
+			"error":     err.Error(),
+		})
+		recordFetchError("logpush", err)
 
-			prometheus.MustRegister(zoneBotRequests)
-		}
+		return
 	}
-	if !deniedMetrics.Has(zoneCacheHitRatio) {
-		prometheus.MustRegister(zoneCacheHit)
+	markFamilySuccess("logpush")
+
+	if r == nil || r.Viewer.Accounts == nil {
+		return
 	}
-	if !deniedMetrics.Has(zoneHealthCheckEventsAdaptiveGroupsAvg) {
-		prometheus.MustRegister(zoneHealthCheckEventsAvg)
+
+	// Check if the API response is empty and handle accordingly
+	if len(r.Viewer.Accounts) == 0 || allZonesAreEmpty(r.Viewer.Accounts) {
+
+		return
 	}
-	if !deniedMetrics.Has(zoneFirewallBotsDetectedSource) {
-		if zoneFirewallBotsDetected == nil { // Ensure it is not nil before registration
-			zoneFirewallBotsDetectedLabels := []string{"zone", "account", "source", "action"} // Base labels
 
-			exclude_host := viper.GetBool("exclude_host")
+	addLogpushAccountGroups(ctx, account, r.Viewer.Accounts)
+}
 
-			if !exclude_host {
-				zoneFirewallBotsDetectedLabels = append(zoneFirewallBotsDetectedLabels, "host") // Conditionally add "host"
+// addLogpushAccountGroups records failed deliveries and, together with the
+// successful ones, the delivery totals by status class. The totals count each
+// delivery once: failed attempts that were retried are left out, and a
+// successful attempt is always the last one.
+func addLogpushAccountGroups(ctx context.Context, account cloudflare.Account, accounts []models.LogpushResponse) {
+	for _, acc := range accounts {
+		for _, LogpushHealthAdaptiveGroup := range acc.LogpushHealthAdaptiveGroups {
+			if logpushJobsTotal != nil && LogpushHealthAdaptiveGroup.Dimensions.Final != 0 {
+				logpushJobsTotal.With(accountLabels(account, prometheus.Labels{
+					"destination":  LogpushHealthAdaptiveGroup.Dimensions.DestinationType,
+					"job_id":       strconv.Itoa(LogpushHealthAdaptiveGroup.Dimensions.JobID),
+					"status_class": logpushStatusClass(LogpushHealthAdaptiveGroup.Dimensions.Status),
+				})).Add(float64(LogpushHealthAdaptiveGroup.Count))
 			}
 
-			zoneFirewallBotsDetected = prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Name: zoneFirewallBotsDetectedSource.String(),
-					Help: "Number of bot requests over country",
-				},
-				zoneFirewallBotsDetectedLabels,
-			)
+			if logpushFailedJobsAccount == nil {
+				continue
+			}
+			jobName, dataset := logpushJobLabels(ctx, "accounts", account.ID, LogpushHealthAdaptiveGroup.Dimensions.JobID)
+			logpushFailedJobsAccount.With(accountLabels(account, prometheus.Labels{
+				"destination": LogpushHealthAdaptiveGroup.Dimensions.DestinationType,
+				"job_id":      strconv.Itoa(LogpushHealthAdaptiveGroup.Dimensions.JobID),
+				"job_name":    jobName,
+				"dataset":     dataset,
+				"final":       logpushFinalLabel(LogpushHealthAdaptiveGroup.Dimensions.Final),
+			})).Add(float64(LogpushHealthAdaptiveGroup.Count))
+		}
 
-			prometheus.MustRegister(zoneFirewallBotsDetected)
+		if logpushJobsTotal == nil {
+			continue
+		}
+		for _, g := range acc.LogpushHealthSuccessGroups {
+			logpushJobsTotal.With(accountLabels(account, prometheus.Labels{
+				"destination":  g.Dimensions.DestinationType,
+				"job_id":       strconv.Itoa(g.Dimensions.JobID),
+				"status_class": "2xx",
+			})).Add(float64(g.Count))
 		}
 	}
-	if !deniedMetrics.Has(zoneFirewallRequestAction) {
-		prometheus.MustRegister(zoneFirewallAction)
-	}
-	if !deniedMetrics.Has(zoneRequestMethodCount) {
-		prometheus.MustRegister(zoneRequestMethod)
-	}
-	if !deniedMetrics.Has(magicTransitActiveTunnels) {
-		prometheus.MustRegister(magicTransitActiveTunnel)
-	}
-	if !deniedMetrics.Has(magicTransitEdgeColoCount) {
-		prometheus.MustRegister(magicTransitEdgeColo)
-	}
-	if !deniedMetrics.Has(magicTransitHealthyTunnels) {
-		prometheus.MustRegister(magicTransitHealthyTunnel)
-	}
-	if !deniedMetrics.Has(magicTransitTunnelFailures) {
-		prometheus.MustRegister(magicTransitTunnelFailure)
+}
+
+// logpushFinalLabel formats the final dimension of a logpush health group. It
+// is "0"/"1" as returned by the API unless logpush_final_bool is set, in which
+// case it is "false"/"true".
+func logpushFinalLabel(final int) string {
+	if viper.GetBool("logpush_final_bool") {
+		return strconv.FormatBool(final != 0)
 	}
-	if !deniedMetrics.Has(zoneCertificateValidationStatus) {
-		prometheus.MustRegister(zoneCertificateValidation)
+	return strconv.Itoa(final)
+}
+
+// logpushStatusClass maps a logpush destination status to its class, e.g.
+// 503 to 5xx. Statuses outside the HTTP range (failed connections) are "error".
+func logpushStatusClass(status int) string {
+	if status < 100 || status >= 600 {
+		return "error"
 	}
-	if !deniedMetrics.Has(zoneOriginResponseDurationMsMetricName) {
-		if zoneOriginResponseDuration == nil { // Ensure it is not nil before registration
-			zoneOriginResponseDurationMsLabels := []string{"zone", "account", "status", "country"} // Base labels
+	return fmt.Sprintf("%dxx", status/100)
+}
 
-			exclude_host := viper.GetBool("exclude_host")
+func fetchMagicTransitHealth(ctx context.Context, account cloudflare.Account) {
 
-			if !exclude_host {
-				zoneOriginResponseDurationMsLabels = append(zoneOriginResponseDurationMsLabels, "host") // Conditionally add "host"
-			}
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Error("Panic in fetchMagicTransitHealth", map[string]interface{}{
+				"accountID": account.ID,
+				"panic":     r,
+			})
+			// Optionally, set default metrics here as well
+		}
+	}()
 
-			zoneOriginResponseDuration = prometheus.NewGaugeVec(
-				prometheus.GaugeOpts{
-					Name: zoneOriginResponseDurationMsMetricName.String(),
-					Help: "Zone Origin Response Time MS",
-				},
-				zoneOriginResponseDurationMsLabels, // Correctly pass the label slice
-			)
+	// Fetch data from the Magic Transit API
+	r, err := cloudflareAPI.MagicTransitTunnelHealthChecksAdaptiveGroups(ctx, account.ID)
+	if err != nil {
+		logging.Error("Failed to fetch Magic Transit data", map[string]interface{}{
+			"accountID": account.ID,
+			"error":     err.Error(),
+		})
+		recordFetchError("magic_transit", err)
+		return
+	}
+	markFamilySuccess("magic_transit")
 
-			prometheus.MustRegister(zoneOriginResponseDuration)
-		}
+	// Check if the API response is empty and handle accordingly
+	if r == nil || len(r.Viewer.Accounts) == 0 {
+		return
 	}
-	if !deniedMetrics.Has(zoneColocationVisitsErrorMetricName) {
-		if zoneColocationVisitsError == nil { // Ensure it is not nil before registration
-			metricLabelsError1 := []string{"zone", "account", "colocation", "status"} // Base labels
 
-			exclude_host := viper.GetBool("exclude_host")
+	// Initialize metrics
+	var activeTunnels, healthyTunnels, tunnelFailures, edgeColoCount float64
 
-			if !exclude_host {
-				metricLabelsError1 = append(metricLabelsError1, "host") // Conditionally add "host"
+	// Process metrics from the API response
+	for _, acc := range r.Viewer.Accounts {
+		for _, group := range acc.MagicTransitTunnelHealthChecksAdaptiveGroups {
+			if group.Dimensions.Active == 1 {
+				activeTunnels++
+			}
+			if group.Dimensions.ResultStatus == "healthy" {
+				healthyTunnels++
+			} else {
+				tunnelFailures++
+			}
+			if group.Dimensions.EdgePopName != "" {
+				edgeColoCount++
 			}
-
-			zoneColocationVisitsError = prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Name: zoneColocationVisitsErrorMetricName.String(),
-					Help: "Total visits per colocation with error code",
-				},
-				metricLabelsError1,
-			)
-
-			prometheus.MustRegister(zoneColocationVisitsError)
 		}
 	}
-	if !deniedMetrics.Has(zoneColocationEdgeResponseBytesErrorMetricName) {
-		if zoneColocationEdgeResponseBytesError == nil { // Ensure it is not nil before registration
-			metricLabelsError2 := []string{"zone", "account", "colocation", "status"} // Base labels
 
-			exclude_host := viper.GetBool("exclude_host")
+	// Set Prometheus metrics
+	labels := accountLabels(account, nil)
+	if magicTransitActiveTunnel != nil {
+		magicTransitActiveTunnel.With(labels).Set(activeTunnels)
+	}
+	if magicTransitHealthyTunnel != nil {
+		magicTransitHealthyTunnel.With(labels).Set(healthyTunnels)
+	}
+	if magicTransitTunnelFailure != nil {
+		magicTransitTunnelFailure.With(labels).Set(tunnelFailures)
+	}
+	if magicTransitEdgeColo != nil {
+		magicTransitEdgeColo.With(labels).Set(edgeColoCount)
+	}
 
-			if !exclude_host {
-				metricLabelsError2 = append(metricLabelsError2, "host") // Conditionally add "host"
-			}
+	// Drop tunnels that no longer report before setting the current ones
+	magicTransitTunnelHealth.DeletePartialMatch(prometheus.Labels{"account": account.Name})
+	for _, acc := range r.Viewer.Accounts {
+		acc := acc
+		addMagicTransitTunnelHealth(&acc, account.Name)
+	}
+}
 
-			zoneColocationEdgeResponseBytesError = prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Name: zoneColocationEdgeResponseBytesErrorMetricName.String(),
-					Help: "Edge response bytes per colocation with error code",
-				},
-				metricLabelsError2,
-			)
+// addMagicTransitTunnelHealth sets the health of each tunnel per edge colo
+// from its most recent health check.
+func addMagicTransitTunnelHealth(acc *models.MagicTransitAccount, account string) {
+	type tunnelKey struct {
+		tunnel, site, colo string
+	}
+	type tunnelCheck struct {
+		datetime string
+		healthy  bool
+	}
 
-			prometheus.MustRegister(zoneColocationEdgeResponseBytesError)
+	latest := map[tunnelKey]tunnelCheck{}
+	for _, g := range acc.MagicTransitTunnelHealthChecksAdaptiveGroups {
+		key := tunnelKey{g.Dimensions.TunnelName, g.Dimensions.SiteName, g.Dimensions.EdgePopName}
+		// RFC 3339 timestamps in UTC sort lexically
+		if prev, ok := latest[key]; ok && prev.datetime > g.Dimensions.Datetime {
+			continue
 		}
+		latest[key] = tunnelCheck{datetime: g.Dimensions.Datetime, healthy: g.Dimensions.ResultStatus == "healthy"}
 	}
-	if !deniedMetrics.Has(zoneColocationRequestsTotalErrorMetricName) {
-		if zoneColocationRequestsTotalError == nil { // Ensure it is not nil before registration
-			metricLabelsError3 := []string{"zone", "account", "colocation", "status"} // Base labels
-
-			exclude_host := viper.GetBool("exclude_host")
-
-			if !exclude_host {
-				metricLabelsError3 = append(metricLabelsError3, "host") // Conditionally add "host"
-			}
-
-			zoneColocationRequestsTotalError = prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Name: zoneColocationRequestsTotalErrorMetricName.String(),
-					Help: "Total requests per colocation with error code",
-				},
-				metricLabelsError3,
-			)
 
-			prometheus.MustRegister(zoneColocationRequestsTotalError)
+	for key, check := range latest {
+		value := 0.0
+		if check.healthy {
+			value = 1
 		}
+		magicTransitTunnelHealth.With(prometheus.Labels{
+			"account":     account,
+			"tunnel_name": key.tunnel,
+			"site_name":   key.site,
+			"edge_colo":   key.colo,
+		}).Set(value)
 	}
-
 }
 
-// FetchWorkerAnalytics handles cloudflare account and expose metrics like requests, error, Worker CPUTime and Duration.
-func FetchWorkerAnalytics(account cloudflare.Account) {
+func fetchTurnstileAnalytics(ctx context.Context, account cloudflare.Account) {
 
 	defer func() {
 		if r := recover(); r != nil {
-			logging.Error("Panic in FetchWorkerAnalytics", map[string]interface{}{
-				"panic": r,
+			logging.Error("Panic in fetchTurnstileAnalytics", map[string]interface{}{
+				"accountID": account.ID,
+				"panic":     r,
 			})
 		}
 	}()
 
-	// Replace spaces with hyphens and convert to lowercase
-	accountName := strings.ToLower(strings.ReplaceAll(account.Name, " ", "-"))
-
-	r, err := cloudflareAPI.FetchWorkerTotals(account.ID)
+	r, err := cloudflareAPI.FetchTurnstileAnalytics(ctx, account.ID)
 	if err != nil {
-		// Return early if API call fails, keeping default metrics
-		logging.Error("FetchWorkerAnalytics: Failed to fetch worker totals", map[string]interface{}{
+		logging.Error("Failed to fetch Turnstile analytics", map[string]interface{}{
 			"accountID": account.ID,
 			"error":     err.Error(),
 		})
+		recordFetchError("turnstile", err)
 		return
 	}
+	markFamilySuccess("turnstile")
 
-	for _, a := range r.Viewer.Accounts {
-		if len(a.WorkersInvocationsAdaptive) == 0 {
-			// Ensure metrics for "unknown" are set when no worker data is present
-			// initializeDefaultMetrics(accountName, "unknown")
+	// Accounts without Turnstile sitekeys return no groups
+	if r == nil || len(r.Viewer.Accounts) == 0 {
+		return
+	}
+
+	accountName := strings.ToLower(strings.ReplaceAll(account.Name, " ", "-"))
+	for _, acc := range r.Viewer.Accounts {
+		acc := acc
+		addTurnstileGroups(&acc, accountName)
+	}
+}
+
+func addTurnstileGroups(acc *models.TurnstileAccount, account string) {
+	for _, g := range acc.TurnstileAdaptiveGroups {
+		if g.Dimensions.SiteKey == "" {
 			continue
 		}
-
-		for _, w := range a.WorkersInvocationsAdaptive {
-			// Add actual metrics
-			workerRequests.With(prometheus.Labels{"script_name": w.Dimensions.ScriptName, "account": accountName}).Add(float64(w.Sum.Requests))
-			workerErrors.With(prometheus.Labels{"script_name": w.Dimensions.ScriptName, "account": accountName}).Add(float64(w.Sum.Errors))
-			workerCPUTime.With(prometheus.Labels{"script_name": w.Dimensions.ScriptName, "account": accountName, "quantile": "P50"}).Set(float64(w.Quantiles.CPUTimeP50))
-			workerCPUTime.With(prometheus.Labels{"script_name": w.Dimensions.ScriptName, "account": accountName, "quantile": "P75"}).Set(float64(w.Quantiles.CPUTimeP75))
-			workerCPUTime.With(prometheus.Labels{"script_name": w.Dimensions.ScriptName, "account": accountName, "quantile": "P99"}).Set(float64(w.Quantiles.CPUTimeP99))
-			workerCPUTime.With(prometheus.Labels{"script_name": w.Dimensions.ScriptName, "account": accountName, "quantile": "P999"}).Set(float64(w.Quantiles.CPUTimeP999))
-			workerDuration.With(prometheus.Labels{"script_name": w.Dimensions.ScriptName, "account": accountName, "quantile": "P50"}).Set(math.Round(float64(w.Quantiles.DurationP50)*1000) / 1000)
-			workerDuration.With(prometheus.Labels{"script_name": w.Dimensions.ScriptName, "account": accountName, "quantile": "P75"}).Set(math.Round(float64(w.Quantiles.DurationP75)*1000) / 1000)
-			workerDuration.With(prometheus.Labels{"script_name": w.Dimensions.ScriptName, "account": accountName, "quantile": "P99"}).Set(math.Round(float64(w.Quantiles.DurationP99)*1000) / 1000)
-			workerDuration.With(prometheus.Labels{"script_name": w.Dimensions.ScriptName, "account": accountName, "quantile": "P999"}).Set(math.Round(float64(w.Quantiles.DurationP999)*1000) / 1000)
+		labels := prometheus.Labels{"account": account, "sitekey": g.Dimensions.SiteKey}
+		switch g.Dimensions.EventType {
+		case "challenge_issued":
+			turnstileChallengesTotal.With(labels).Add(float64(g.Count))
+		case "challenge_solved":
+			turnstileSolvesTotal.With(labels).Add(float64(g.Count))
 		}
 	}
 }
 
-// filterZones helper function to filter the zones.
-func filterZones(all []cloudflare.Zone, target []string) []cloudflare.Zone {
-	var filtered []cloudflare.Zone
+func fetchStreamAnalytics(ctx context.Context, account cloudflare.Account) {
 
-	if (len(target)) == 0 {
-		return all
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Error("Panic in fetchStreamAnalytics", map[string]interface{}{
+				"accountID": account.ID,
+				"panic":     r,
+			})
+		}
+	}()
+
+	r, err := cloudflareAPI.FetchStreamAnalytics(ctx, account.ID)
+	if err != nil {
+		logging.Error("Failed to fetch Stream analytics", map[string]interface{}{
+			"accountID": account.ID,
+			"error":     err.Error(),
+		})
+		recordFetchError("stream", err)
+		return
 	}
+	markFamilySuccess("stream")
 
-	for _, tz := range target {
-		for _, z := range all {
-			if tz == z.ID {
-				filtered = append(filtered, z)
-				logging.Info("Filtering zone: ", z.ID, " ", z.Name)
-			}
-		}
+	// Accounts without Stream usage return no groups
+	if r == nil || len(r.Viewer.Accounts) == 0 {
+		return
+	}
+
+	accountName := strings.ToLower(strings.ReplaceAll(account.Name, " ", "-"))
+	for _, acc := range r.Viewer.Accounts {
+		acc := acc
+		addStreamGroups(&acc, accountName)
 	}
-	return filtered
 }
 
-// getTargetZones helper function to get targeted zones.
-func getTargetZones() []string {
-	var zoneIDs []string
-	if len(viper.GetString("cf_zones")) > 0 {
-		zoneIDs = strings.Split(viper.GetString("cf_zones"), ",")
-	} else {
-		// deprecated
-		for _, e := range os.Environ() {
-			if strings.HasPrefix(e, "ZONE_") {
-				split := strings.SplitN(e, "=", 2)
-				zoneIDs = append(zoneIDs, split[1])
-			}
-		}
+func addStreamGroups(acc *models.StreamAccount, account string) {
+	for _, g := range acc.StreamMinutesViewedAdaptiveGroups {
+		streamMinutesViewedTotal.With(prometheus.Labels{"account": account}).Add(g.Sum.MinutesViewed)
+	}
+	for _, g := range acc.StreamBandwidth {
+		streamBandwidthBytesTotal.With(prometheus.Labels{"account": account}).Add(float64(g.Sum.EgressBytes))
 	}
-	return zoneIDs
 }
 
-// getExcludedZones returns array of excluded zones.
-func getExcludedZones() []string {
-	var zoneIDs []string
+func fetchImagesAnalytics(ctx context.Context, account cloudflare.Account) {
 
-	if len(viper.GetString("cf_exclude_zones")) > 0 {
-		zoneIDs = strings.Split(viper.GetString("cf_exclude_zones"), ",")
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Error("Panic in fetchImagesAnalytics", map[string]interface{}{
+				"accountID": account.ID,
+				"panic":     r,
+			})
+		}
+	}()
+
+	r, err := cloudflareAPI.FetchImagesAnalytics(ctx, account.ID)
+	if err != nil {
+		logging.Error("Failed to fetch Images analytics", map[string]interface{}{
+			"accountID": account.ID,
+			"error":     err.Error(),
+		})
+		recordFetchError("images", err)
+		return
+	}
+	markFamilySuccess("images")
+
+	// Accounts not using Images return no groups
+	if r == nil || len(r.Viewer.Accounts) == 0 {
+		return
+	}
+
+	accountName := strings.ToLower(strings.ReplaceAll(account.Name, " ", "-"))
+	for _, acc := range r.Viewer.Accounts {
+		acc := acc
+		addImagesGroups(&acc, accountName)
 	}
-	return zoneIDs
 }
 
-func allZonesAreEmpty(account []models.LogpushResponse) bool {
-	// Check if all zones are empty
-	for _, zone := range account {
-		if len(zone.LogpushHealthAdaptiveGroups) > 0 {
-			return false
-		}
+func addImagesGroups(acc *models.ImagesAccount, account string) {
+	for _, g := range acc.ImagesRequestsAdaptiveGroups {
+		imagesRequestsTotal.With(prometheus.Labels{"account": account}).Add(float64(g.Count))
+	}
+	for _, g := range acc.ImagesUniqueTransformations {
+		imagesTransformationsTotal.With(prometheus.Labels{"account": account}).Add(float64(g.Count))
 	}
-	return true // All zones are empty
 }
 
-// fetchLogpushAnalyticsForAccount expose metrics related to logpush.
-func fetchLogpushAnalyticsForAccount(account cloudflare.Account) {
-	defer func() { // Panic Recovery
+func fetchDurableObjectsAnalytics(ctx context.Context, account cloudflare.Account) {
+
+	defer func() {
 		if r := recover(); r != nil {
-			logging.Error("Recovered from panic in fetchLogpushAnalyticsForAccount", map[string]interface{}{
+			logging.Error("Panic in fetchDurableObjectsAnalytics", map[string]interface{}{
 				"accountID": account.ID,
 				"panic":     r,
 			})
 		}
 	}()
 
-	r, err := cloudflareAPI.FetchLogpushAccount(account.ID)
+	r, err := cloudflareAPI.FetchDurableObjectsAnalytics(ctx, account.ID)
 	if err != nil {
-		logging.Error("Failed to fetch logpush health data", map[string]interface{}{
+		logging.Error("Failed to fetch Durable Objects analytics", map[string]interface{}{
 			"accountID": account.ID,
 			"error":     err.Error(),
 		})
-
+		recordFetchError("durable_objects", err)
 		return
 	}
+	markFamilySuccess("durable_objects")
 
-	if r == nil || r.Viewer.Accounts == nil {
+	// Accounts without Durable Objects return no groups
+	if r == nil || len(r.Viewer.Accounts) == 0 {
 		return
 	}
 
-	// Check if the API response is empty and handle accordingly
-	if len(r.Viewer.Accounts) == 0 || allZonesAreEmpty(r.Viewer.Accounts) {
-
-		return
+	names, err := cloudflareAPI.FetchDurableObjectNamespaces(ctx, account.ID)
+	if err != nil {
+		logging.Warn("Failed to resolve Durable Object namespace names", map[string]interface{}{
+			"accountID": account.ID,
+			"error":     err.Error(),
+		})
 	}
 
-	// Process metrics from the API response
+	accountName := strings.ToLower(strings.ReplaceAll(account.Name, " ", "-"))
 	for _, acc := range r.Viewer.Accounts {
-		for _, LogpushHealthAdaptiveGroup := range acc.LogpushHealthAdaptiveGroups {
-			logpushFailedJobsAccount.With(prometheus.Labels{
-				"account":      account.Name,
-				"account_type": account.Type,
-				"destination":  LogpushHealthAdaptiveGroup.Dimensions.DestinationType,
-				"job_id":       strconv.Itoa(LogpushHealthAdaptiveGroup.Dimensions.JobID),
-				"final":        strconv.Itoa(LogpushHealthAdaptiveGroup.Dimensions.Final),
-			}).Add(float64(LogpushHealthAdaptiveGroup.Count))
-		}
+		acc := acc
+		addDurableObjectsGroups(&acc, accountName, names)
+	}
+}
+
+// addDurableObjectsGroups adds the requests of each namespace, labelled with
+// its name from names or its ID when the name is unknown.
+func addDurableObjectsGroups(acc *models.DurableObjectsAccount, account string, names map[string]string) {
+	for _, g := range acc.DurableObjectsInvocationsAdaptiveGroups {
+		durableObjectsRequestsTotal.With(prometheus.Labels{
+			"account":   account,
+			"namespace": nameOrID(names, g.Dimensions.NamespaceID),
+		}).Add(float64(g.Sum.Requests))
 	}
 }
 
-func fetchMagicTransitHealth(account cloudflare.Account) {
+func fetchQueueBacklog(ctx context.Context, account cloudflare.Account) {
 
 	defer func() {
 		if r := recover(); r != nil {
-			logging.Error("Panic in fetchMagicTransitHealth", map[string]interface{}{
+			logging.Error("Panic in fetchQueueBacklog", map[string]interface{}{
 				"accountID": account.ID,
 				"panic":     r,
 			})
-			// Optionally, set default metrics here as well
 		}
 	}()
 
-	// Fetch data from the Magic Transit API
-	r, err := cloudflareAPI.MagicTransitTunnelHealthChecksAdaptiveGroups(account.ID)
+	r, err := cloudflareAPI.FetchQueueBacklog(ctx, account.ID)
 	if err != nil {
-		logging.Error("Failed to fetch Magic Transit data", map[string]interface{}{
+		logging.Error("Failed to fetch Queue backlog", map[string]interface{}{
 			"accountID": account.ID,
 			"error":     err.Error(),
 		})
+		recordFetchError("queues", err)
 		return
 	}
+	markFamilySuccess("queues")
 
-	// Check if the API response is empty and handle accordingly
+	// Accounts without Queues return no groups
 	if r == nil || len(r.Viewer.Accounts) == 0 {
 		return
 	}
 
-	// Initialize metrics
-	var activeTunnels, healthyTunnels, tunnelFailures, edgeColoCount float64
+	names, err := cloudflareAPI.FetchQueueNames(ctx, account.ID)
+	if err != nil {
+		logging.Warn("Failed to resolve Queue names", map[string]interface{}{
+			"accountID": account.ID,
+			"error":     err.Error(),
+		})
+	}
 
-	// Process metrics from the API response
+	accountName := strings.ToLower(strings.ReplaceAll(account.Name, " ", "-"))
 	for _, acc := range r.Viewer.Accounts {
-		fmt.Println(":::::::::::::::::::::::::::::", acc.MagicTransitTunnelHealthChecksAdaptiveGroups)
-		for _, group := range acc.MagicTransitTunnelHealthChecksAdaptiveGroups {
-			if group.Dimensions.Active == 1 {
-				activeTunnels++
-			}
-			if group.Dimensions.ResultStatus == "healthy" {
-				healthyTunnels++
-			} else {
-				tunnelFailures++
-			}
-			if group.Dimensions.EdgePopName != "" {
-				edgeColoCount++
-			}
-		}
+		acc := acc
+		addQueueBacklogGroups(&acc, accountName, names)
+	}
+}
+
+// addQueueBacklogGroups sets the backlog of each queue, labelled with its
+// name from names or its ID when the name is unknown.
+func addQueueBacklogGroups(acc *models.QueuesAccount, account string, names map[string]string) {
+	for _, g := range acc.QueueBacklogAdaptiveGroups {
+		queueBacklogMessages.With(prometheus.Labels{
+			"account": account,
+			"queue":   nameOrID(names, g.Dimensions.QueueID),
+		}).Set(g.Avg.Messages)
 	}
+}
 
-	// Set Prometheus metrics
-	magicTransitActiveTunnel.With(prometheus.Labels{"account": account.Name, "account_type": account.Type}).Set(activeTunnels)
-	magicTransitHealthyTunnel.With(prometheus.Labels{"account": account.Name, "account_type": account.Type}).Set(healthyTunnels)
-	magicTransitTunnelFailure.With(prometheus.Labels{"account": account.Name, "account_type": account.Type}).Set(tunnelFailures)
-	magicTransitEdgeColo.With(prometheus.Labels{"account": account.Name, "account_type": account.Type}).Set(edgeColoCount)
+// nameOrID returns the name of id in names, or id when it has none.
+func nameOrID(names map[string]string, id string) string {
+	if name := names[id]; name != "" {
+		return name
+	}
+	return id
 }
 
 func filterNonFreePlanZones(zones []cloudflare.Zone) (filteredZones []cloudflare.Zone) {
@@ -1084,6 +2584,20 @@ func findZoneAccountName(zones []cloudflare.Zone, ID string) (string, string) {
 	return "", ""
 }
 
+// lookupZoneLabels returns the zone and account label values for zoneTag. It
+// warns and returns false when the zone is not in zones, so callers skip it
+// instead of emitting series with empty labels.
+func lookupZoneLabels(zones []cloudflare.Zone, zoneTag string) (string, string, bool) {
+	name, account := findZoneAccountName(zones, zoneTag)
+	if name == "" {
+		logging.Warn("Skipping metrics for zone not in the zone list", map[string]interface{}{
+			"zoneTag": zoneTag,
+		})
+		return "", "", false
+	}
+	return name, account, true
+}
+
 func fetchZoneAnalytics(ctx context.Context, zones []cloudflare.Zone) {
 
 	defer func() {
@@ -1109,65 +2623,151 @@ func fetchZoneAnalytics(ctx context.Context, zones []cloudflare.Zone) {
 	for i := 0; i < len(zoneIDs); i += batchSize {
 		batch := zoneIDs[i:min(i+batchSize, len(zoneIDs))]
 
-		// Parallel fetch per metric type
-		httpData, err := cloudflareAPI.FetchHTTPMetrics(ctx, batch)
+		// Single round trip for every zone-level group in the batch
+		data, err := cloudflareAPI.FetchZoneAnalytics(ctx, batch)
 		if err != nil {
-			logging.Error("Failed to fetch HTTP metrics", err)
+			logging.ErrorErr("Failed to fetch zone analytics, falling back to per-family queries", err)
+			recordFetchError("zone_analytics", err)
+			fetchZoneAnalyticsPerFamily(ctx, zones, batch)
 			continue
 		}
-
-		firewallData, err := cloudflareAPI.FetchFirewallMetrics(ctx, batch)
-		if err != nil {
-			logging.Error("Failed to fetch firewallData", err)
-			continue
+		for _, family := range zoneAnalyticsFamilies {
+			markFamilySuccess(family)
 		}
 
-		healthCheckEventsAdaptiveData, err := cloudflareAPI.HealthCheckEventsAdaptiveMetrics(ctx, batch)
-		if err != nil {
-			logging.Error("Failed to fetch healthCheckEventsAdaptiveData", err)
-			continue
-		}
+		for _, z := range data.Viewer.Zones {
+			name, account, ok := lookupZoneLabels(zones, z.ZoneTag)
+			if !ok {
+				continue
+			}
 
-		httpRequestsAdaptiveGroupsData, err := cloudflareAPI.HTTPRequestsAdaptiveMetrics(ctx, batch)
-		if err != nil {
-			logging.Error("Failed to fetch httpRequestsAdaptiveGroupsData", err)
-			continue
+			httpGroups := z.HTTPGroups()
+			addHTTPGroups(&httpGroups, name, account)
+
+			firewallGroups := z.FirewallGroups()
+			addFirewallGroups(&firewallGroups, name, account)
+
+			healthCheckGroups := z.HealthCheckGroups()
+			addHealthCheckGroups(&healthCheckGroups, name, account)
+
+			adaptiveGroups := z.AdaptiveGroups()
+			addHTTPAdaptiveGroups(&adaptiveGroups, name, account)
+
+			edgeGroups := z.HTTPRequestsEdge()
+			addHTTPRequestsEdgeCountryHost(&edgeGroups, name, account)
+
+			rateLimitGroups := z.RateLimitGroups()
+			addRateLimitGroups(&rateLimitGroups, name, account)
 		}
+	}
+}
 
-		httpRequestsEdgeCountryHostData, err := cloudflareAPI.HTTPRequestsEdgeCountryMetrics(ctx, batch)
-		if err != nil {
-			logging.Error("Failed to fetch httpRequestsEdgeCountryHostData", err)
-			continue
+// zoneAnalyticsFamilies are the families covered by the combined zone
+// analytics query, each queried on its own by fetchZoneAnalyticsPerFamily.
+var zoneAnalyticsFamilies = []string{"http", "firewall", "health_check", "http_adaptive", "edge_country", "rate_limit"}
+
+// fetchZoneAnalyticsPerFamily queries each zone-level family separately so that
+// a failure in one family does not drop the others for the batch. Each query
+// waits on the rate limiter like the combined one.
+func fetchZoneAnalyticsPerFamily(ctx context.Context, zones []cloudflare.Zone, batch []string) {
+	fetchErr := func(family string, err error) {
+		logging.Error("Failed to fetch zone metric family", map[string]interface{}{
+			"family":  family,
+			"zoneIDs": batch,
+			"error":   err.Error(),
+		})
+		recordFetchError(family, err)
+	}
+
+	if r, err := cloudflareAPI.FetchHTTPMetrics(ctx, batch); err != nil {
+		fetchErr("http", err)
+	} else {
+		markFamilySuccess("http")
+		for _, z := range r.Viewer.Zones {
+			z := z
+			name, account, ok := lookupZoneLabels(zones, z.ZoneTag)
+			if !ok {
+				continue
+			}
+			addHTTPGroups(&z, name, account)
 		}
+	}
 
-		for _, z := range httpData.Viewer.Zones {
-			name, account := findZoneAccountName(zones, z.ZoneTag)
-			currentZone := z
-			addHTTPGroups(&currentZone, name, account)
+	if r, err := cloudflareAPI.FetchFirewallMetrics(ctx, batch); err != nil {
+		fetchErr("firewall", err)
+	} else {
+		markFamilySuccess("firewall")
+		for _, z := range r.Viewer.Zones {
+			z := z
+			name, account, ok := lookupZoneLabels(zones, z.ZoneTag)
+			if !ok {
+				continue
+			}
+			addFirewallGroups(&z, name, account)
 		}
-		for _, z := range firewallData.Viewer.Zones {
-			name, account := findZoneAccountName(zones, z.ZoneTag)
-			currentZone := z
-			addFirewallGroups(&currentZone, name, account)
+	}
+
+	if r, err := cloudflareAPI.HealthCheckEventsAdaptiveMetrics(ctx, batch); err != nil {
+		fetchErr("health_check", err)
+	} else {
+		markFamilySuccess("health_check")
+		for _, z := range r.Viewer.Zones {
+			z := z
+			name, account, ok := lookupZoneLabels(zones, z.ZoneTag)
+			if !ok {
+				continue
+			}
+			addHealthCheckGroups(&z, name, account)
 		}
-		for _, z := range healthCheckEventsAdaptiveData.Viewer.Zones {
-			name, account := findZoneAccountName(zones, z.ZoneTag)
-			currentZone := z
-			addHealthCheckGroups(&currentZone, name, account)
+	}
+
+	if r, err := cloudflareAPI.HTTPRequestsAdaptiveMetrics(ctx, batch); err != nil {
+		fetchErr("http_adaptive", err)
+	} else {
+		markFamilySuccess("http_adaptive")
+		for _, z := range r.Viewer.Zones {
+			z := z
+			name, account, ok := lookupZoneLabels(zones, z.ZoneTag)
+			if !ok {
+				continue
+			}
+			addHTTPAdaptiveGroups(&z, name, account)
 		}
-		for _, z := range httpRequestsAdaptiveGroupsData.Viewer.Zones {
-			name, account := findZoneAccountName(zones, z.ZoneTag)
-			currentZone := z
-			addHTTPAdaptiveGroups(&currentZone, name, account)
+	}
+
+	if r, err := cloudflareAPI.HTTPRequestsEdgeCountryMetrics(ctx, batch); err != nil {
+		fetchErr("edge_country", err)
+	} else {
+		markFamilySuccess("edge_country")
+		for _, z := range r.Viewer.Zones {
+			z := z
+			name, account, ok := lookupZoneLabels(zones, z.ZoneTag)
+			if !ok {
+				continue
+			}
+			addHTTPRequestsEdgeCountryHost(&z, name, account)
 		}
-		for _, z := range httpRequestsEdgeCountryHostData.Viewer.Zones {
-			name, account := findZoneAccountName(zones, z.ZoneTag)
-			currentZone := z
-			addHTTPRequestsEdgeCountryHost(&currentZone, name, account)
+	}
+
+	if r, err := cloudflareAPI.FetchRateLimitEvents(ctx, batch); err != nil {
+		fetchErr("rate_limit", err)
+	} else {
+		markFamilySuccess("rate_limit")
+		for _, z := range r.Viewer.Zones {
+			z := z
+			name, account, ok := lookupZoneLabels(zones, z.ZoneTag)
+			if !ok {
+				continue
+			}
+			addRateLimitGroups(&z, name, account)
 		}
 	}
 }
 
+// lastHourlyHTTPGroup holds, per zone, the datetime of the last hourly HTTP
+// group added to the counters.
+var lastHourlyHTTPGroup sync.Map
+
 func addHTTPGroups(z *models.ZoneRespHTTPGroups, name string, account string) {
 
 	if z == nil {
@@ -1180,16 +2780,68 @@ func addHTTPGroups(z *models.ZoneRespHTTPGroups, name string, account string) {
 		return
 	}
 
+	// A widened query window (backfill) returns one group per minute; add
+	// them all up and date the result with the latest group
 	zt := z.HTTP1mGroups[0]
+	for _, g := range z.HTTP1mGroups[1:] {
+		if g.Dimensions.Datetime > zt.Dimensions.Datetime {
+			zt.Dimensions.Datetime = g.Dimensions.Datetime
+		}
+		zt.Unique.Uniques += g.Unique.Uniques
+		zt.Sum.Bytes += g.Sum.Bytes
+		zt.Sum.CachedBytes += g.Sum.CachedBytes
+		zt.Sum.CachedRequests += g.Sum.CachedRequests
+		zt.Sum.Requests += g.Sum.Requests
+		zt.Sum.EncryptedBytes += g.Sum.EncryptedBytes
+		zt.Sum.EncryptedRequests += g.Sum.EncryptedRequests
+		zt.Sum.PageViews += g.Sum.PageViews
+		zt.Sum.Threats += g.Sum.Threats
+		zt.Sum.BrowserMap = append(slices.Clip(zt.Sum.BrowserMap), g.Sum.BrowserMap...)
+		zt.Sum.ClientHTTPVersion = append(slices.Clip(zt.Sum.ClientHTTPVersion), g.Sum.ClientHTTPVersion...)
+		zt.Sum.ClientSSL = append(slices.Clip(zt.Sum.ClientSSL), g.Sum.ClientSSL...)
+		zt.Sum.ContentType = append(slices.Clip(zt.Sum.ContentType), g.Sum.ContentType...)
+		zt.Sum.Country = append(slices.Clip(zt.Sum.Country), g.Sum.Country...)
+		zt.Sum.IPClass = append(slices.Clip(zt.Sum.IPClass), g.Sum.IPClass...)
+		zt.Sum.ResponseStatus = append(slices.Clip(zt.Sum.ResponseStatus), g.Sum.ResponseStatus...)
+		zt.Sum.ThreatPathing = append(slices.Clip(zt.Sum.ThreatPathing), g.Sum.ThreatPathing...)
+	}
+
+	// An hourly group is re-fetched on every scrape within the hour; add it once
+	if cloudflareAPI.GroupGranularity() == "1h" {
+		if prev, ok := lastHourlyHTTPGroup.Load(name); ok && prev == zt.Dimensions.Datetime {
+			return
+		}
+		lastHourlyHTTPGroup.Store(name, zt.Dimensions.Datetime)
+	}
 
 	// Update metrics with actual data
-	zoneRequestTotal.With(prometheus.Labels{"zone": name, "account": account}).Add(float64(zt.Sum.Requests))
-	zoneRequestCached.With(prometheus.Labels{"zone": name, "account": account}).Set(float64(zt.Sum.CachedRequests))
-	zoneRequestSSLEncrypted.With(prometheus.Labels{"zone": name, "account": account}).Add(float64(zt.Sum.EncryptedRequests))
+	recordZoneGroupTime(name, zt.Dimensions.Datetime)
+	labels := zoneLabels(name, account, z.ZoneTag)
+	zoneRequestTotal.With(labels).Add(float64(zt.Sum.Requests))
+	zoneRequestCached.With(labels).Set(float64(zt.Sum.CachedRequests))
+	zoneRequestSSLEncrypted.With(labels).Add(float64(zt.Sum.EncryptedRequests))
+
+	// A window without requests has no defined availability; keep the last value
+	if zt.Sum.Requests > 0 {
+		var edge5xx uint64
+		for _, status := range zt.Sum.ResponseStatus {
+			if status.EdgeResponseStatus >= 500 && status.EdgeResponseStatus < 600 {
+				edge5xx += status.Requests
+			}
+		}
+		zoneAvailabilityRatio.With(labels).Set(1 - float64(edge5xx)/float64(zt.Sum.Requests))
+	}
 
+	ctRequests := map[string]float64{}
+	ctBytes := map[string]float64{}
 	for _, ct := range zt.Sum.ContentType {
-		zoneRequestContentType.With(prometheus.Labels{"zone": name, "account": account, "content_type": ct.EdgeResponseContentType}).Add(float64(ct.Requests))
-		zoneBandwidthContentType.With(prometheus.Labels{"zone": name, "account": account, "content_type": ct.EdgeResponseContentType}).Add(float64(ct.Bytes))
+		ctRequests[ct.EdgeResponseContentType] += float64(ct.Requests)
+		ctBytes[ct.EdgeResponseContentType] += float64(ct.Bytes)
+	}
+	ctRequests, ctBytes = topNContentTypes(ctRequests, ctBytes, viper.GetInt("content_type_top_n"))
+	for contentType, requests := range ctRequests {
+		zoneRequestContentType.With(prometheus.Labels{"zone": name, "account": account, "content_type": contentType}).Add(requests)
+		zoneBandwidthContentType.With(prometheus.Labels{"zone": name, "account": account, "content_type": contentType}).Add(ctBytes[contentType])
 	}
 
 	for _, country := range zt.Sum.Country {
@@ -1250,56 +2902,125 @@ func addHTTPGroups(z *models.ZoneRespHTTPGroups, name string, account string) {
 		zoneRequestBrowserMap.With(prometheus.Labels{"zone": name, "account": account, "family": browser.UaBrowserFamily}).Add(float64(browser.PageViews))
 	}
 
-	zoneBandwidthTotal.With(prometheus.Labels{"zone": name, "account": account}).Add(float64(zt.Sum.Bytes))
-	zoneBandwidthCached.With(prometheus.Labels{"zone": name, "account": account}).Add(float64(zt.Sum.CachedBytes))
-	zoneBandwidthSSLEncrypted.With(prometheus.Labels{"zone": name, "account": account}).Add(float64(zt.Sum.EncryptedBytes))
+	zoneBandwidthTotal.With(labels).Add(float64(zt.Sum.Bytes))
+	zoneBandwidthCached.With(labels).Add(float64(zt.Sum.CachedBytes))
+	// Like the availability ratio, a window without traffic keeps the last value
+	if zt.Sum.Bytes > 0 {
+		zoneBandwidthCacheRatio.With(labels).Set(float64(zt.Sum.CachedBytes) / float64(zt.Sum.Bytes))
+	}
+	zoneBandwidthSSLEncrypted.With(labels).Add(float64(zt.Sum.EncryptedBytes))
+
+	zoneThreatsTotal.With(labels).Add(float64(zt.Sum.Threats))
 
-	zoneThreatsTotal.With(prometheus.Labels{"zone": name, "account": account}).Add(float64(zt.Sum.Threats))
+	snapshot := ZoneSnapshot{
+		Zone:           name,
+		Account:        account,
+		Requests:       uint64(zt.Sum.Requests),
+		BandwidthBytes: uint64(zt.Sum.Bytes),
+		Threats:        uint64(zt.Sum.Threats),
+	}
+	if zt.Sum.Requests > 0 {
+		var errors4xx, errors5xx uint64
+		for _, status := range zt.Sum.ResponseStatus {
+			switch {
+			case status.EdgeResponseStatus >= 500:
+				errors5xx += status.Requests
+			case status.EdgeResponseStatus >= 400:
+				errors4xx += status.Requests
+			}
+		}
+		snapshot.ErrorRate4xx = float64(errors4xx) / float64(zt.Sum.Requests)
+		snapshot.ErrorRate5xx = float64(errors5xx) / float64(zt.Sum.Requests)
+	}
+	recordZoneSnapshot(snapshot)
 
 	for _, t := range zt.Sum.ThreatPathing {
 		zoneThreatsType.With(prometheus.Labels{"zone": name, "account": account, "type": t.Name}).Add(float64(t.Requests))
 	}
 
-	zonePageviewsTotal.With(prometheus.Labels{"zone": name, "account": account}).Add(float64(zt.Sum.PageViews))
+	zonePageviewsTotal.With(labels).Add(float64(zt.Sum.PageViews))
+
+	for _, ip := range zt.Sum.IPClass {
+		zoneRequestIPClass.With(prometheus.Labels{"zone": name, "account": account, "ip_class": ip.Type}).Add(float64(ip.Requests))
+	}
+
+	var versionTotal, http2, http3 uint64
+	for _, v := range zt.Sum.ClientHTTPVersion {
+		zoneRequestHTTPVersion.With(prometheus.Labels{"zone": name, "account": account, "http_version": v.Protocol}).Add(float64(v.Requests))
+
+		versionTotal += v.Requests
+		switch {
+		case strings.HasPrefix(v.Protocol, "HTTP/2"):
+			http2 += v.Requests
+		case strings.HasPrefix(v.Protocol, "HTTP/3"):
+			http3 += v.Requests
+		}
+	}
+	// Without requests the shares are undefined; keep the last value
+	if versionTotal > 0 {
+		zoneHTTP2Percentage.With(prometheus.Labels{"zone": name, "account": account}).Set(100 * float64(http2) / float64(versionTotal))
+		zoneHTTP3Percentage.With(prometheus.Labels{"zone": name, "account": account}).Set(100 * float64(http3) / float64(versionTotal))
+	}
+
+	for _, s := range zt.Sum.ClientSSL {
+		zoneRequestSSLProtocol.With(prometheus.Labels{"zone": name, "account": account, "ssl_protocol": s.Protocol}).Add(float64(s.Requests))
+	}
 
 	// Uniques
-	zoneUniquesTotal.With(prometheus.Labels{"zone": name, "account": account}).Add(float64(zt.Unique.Uniques))
+	zoneUniquesTotal.With(labels).Add(float64(zt.Unique.Uniques))
 
 	zoneCacheHit.With(
 		prometheus.Labels{
 			"zone":           name,
 			"account":        account,
-			"requests":       strconv.FormatUint(zt.Sum.Requests, 10),
-			"cachedRequests": strconv.FormatUint(zt.Sum.CachedRequests, 10),
+			"requests":       strconv.FormatUint(uint64(zt.Sum.Requests), 10),
+			"cachedRequests": strconv.FormatUint(uint64(zt.Sum.CachedRequests), 10),
 		}).Set(float64(zt.Sum.CachedRequests) / float64(zt.Sum.Requests))
+}
 
-	// Map to track HTTP method counts
-	methodCounts := make(map[string]float64)
+// threatActions are the firewall event actions counted as threats.
+var threatActions = map[string]bool{
+	"block":                  true,
+	"challenge":              true,
+	"jschallenge":            true,
+	"managed_challenge":      true,
+	"connection_close":       true,
+	"force_connection_close": true,
+}
 
-	// Loop through firewall events
-	for _, g := range z.FirewallEventsAdaptiveGroups {
-		// Extract ClientRequestHTTPHost or other dimensions
-		httpMethod := g.Dimensions.ClientRequestHTTPHost // Adjust based on available data
+func addFirewallGroups(z *models.ZoneRespFirewallGroups, name string, account string) {
 
-		// Increment the count for this HTTP method
-		methodCounts[httpMethod] += float64(g.Count)
+	if z == nil {
+		logging.Error("Received nil zone response in Firewall group", nil)
+		return
 	}
 
-	// Push metrics to Prometheus
-	for method, count := range methodCounts {
-		zoneRequestMethod.With(prometheus.Labels{
+	for _, g := range z.FirewallEventsByKind {
+		zoneFirewallEventsByKindTotal.With(prometheus.Labels{
 			"zone":    name,
 			"account": account,
-			"method":  method, // The HTTP method dimension
-		}).Add(count)
+			"kind":    g.Dimensions.Kind,
+		}).Add(float64(g.Count))
 	}
-}
 
-func addFirewallGroups(z *models.ZoneRespFirewallGroups, name string, account string) {
-
-	if z == nil {
-		logging.Error("Received nil zone response in Firewall group", nil)
-		return
+	// Per-ASN events, capped to the ASNs with the most events to bound cardinality
+	asnEvents := make(map[string]float64, len(z.FirewallEventsByASN))
+	for _, g := range z.FirewallEventsByASN {
+		asnEvents[g.Dimensions.ClientASN] += float64(g.Count)
+	}
+	topASNs := topN(asnEvents, viper.GetInt("cf_asn_top_n"))
+	for _, g := range z.FirewallEventsByASN {
+		asn, description := g.Dimensions.ClientASN, g.Dimensions.ClientASNDescription
+		if _, ok := topASNs[asn]; !ok {
+			asn, description = otherTopNKey, ""
+		}
+		zoneFirewallEventsByASNTotal.With(prometheus.Labels{
+			"zone":            name,
+			"account":         account,
+			"asn":             asn,
+			"asn_description": description,
+			"action":          g.Dimensions.Action,
+		}).Add(float64(g.Count))
 	}
 
 	// Nothing to do if there are no FirewallEventsAdaptiveGroups
@@ -1307,11 +3028,22 @@ func addFirewallGroups(z *models.ZoneRespFirewallGroups, name string, account st
 		return
 	}
 
+	checkTruncated("firewall", name, len(z.FirewallEventsAdaptiveGroups), cloudflareAPI.QueryLimit("firewall"))
+
 	// Fetch firewall rules map
 	// rulesMap := cloudflareAPI.FetchFirewallRules(z.ZoneTag)
 
 	// Process each firewall event group
 	for _, g := range z.FirewallEventsAdaptiveGroups {
+		// An event of a rule in several categories counts in each of them
+		for _, category := range wafRuleCategories(z.ZoneTag, g.Dimensions.RuleID) {
+			zoneWAFCategoryEventsTotal.With(prometheus.Labels{
+				"zone":     name,
+				"account":  account,
+				"category": category,
+			}).Add(float64(g.Count))
+		}
+
 		zoneFirewallEventsCount.With(
 			prometheus.Labels{
 				"zone":    name,
@@ -1325,6 +3057,16 @@ func addFirewallGroups(z *models.ZoneRespFirewallGroups, name string, account st
 				"action":  g.Dimensions.Action,
 			}).Add(float64(g.Count))
 
+		if threatActions[g.Dimensions.Action] {
+			zoneThreatsTypeCountryTotal.With(
+				prometheus.Labels{
+					"zone":    name,
+					"account": account,
+					"type":    g.Dimensions.Source,
+					"country": g.Dimensions.ClientCountryName,
+				}).Add(float64(g.Count))
+		}
+
 		// Generate labels dynamically using getLabels()
 		zoneBotRequestsLabels := getLabels(prometheus.Labels{
 			"zone":    name,
@@ -1332,7 +3074,7 @@ func addFirewallGroups(z *models.ZoneRespFirewallGroups, name string, account st
 			"country": g.Dimensions.ClientCountryName, // Keep dynamic values
 			"action":  g.Dimensions.Action,
 			// "rule":    normalizeRuleName(rulesMap[g.Dimensions.RuleID]),
-		}, g.Dimensions.ClientRequestHTTPHost) // Pass host dynamically
+		}, g.Dimensions.ClientRequestHTTPHost, zoneBotRequestsByCountry) // Pass host dynamically
 
 		if zoneBotRequests != nil {
 			// Use generated labels with Prometheus metric
@@ -1346,7 +3088,7 @@ func addFirewallGroups(z *models.ZoneRespFirewallGroups, name string, account st
 			"source":  g.Dimensions.Source,
 			"action":  g.Dimensions.Action,
 			// "rule":    normalizeRuleName(rulesMap[g.Dimensions.RuleID]),
-		}, g.Dimensions.ClientRequestHTTPHost) // Pass host dynamically
+		}, g.Dimensions.ClientRequestHTTPHost, zoneFirewallBotsDetectedSource) // Pass host dynamically
 
 		// Use the dynamically generated labels with Prometheus metric
 		// zoneFirewallBotsDetected.With(labels).Add(float64(g.Count))
@@ -1354,8 +3096,40 @@ func addFirewallGroups(z *models.ZoneRespFirewallGroups, name string, account st
 			zoneFirewallBotsDetected.With(labels).Add(float64(g.Count))
 		}
 
+		if zoneFirewallEventsDetailedTotal != nil {
+			zoneFirewallEventsDetailedTotal.With(getLabels(prometheus.Labels{
+				"zone":    name,
+				"account": account,
+				"action":  g.Dimensions.Action,
+				"source":  g.Dimensions.Source,
+			}, g.Dimensions.ClientRequestHTTPHost, zoneFirewallEventsDetailedTotalMetricName)).Add(float64(g.Count))
+		}
+
+	}
+
+}
+
+func addRateLimitGroups(z *models.ZoneRespRateLimitGroups, name string, account string) {
+
+	if z == nil {
+		logging.Error("Received nil zone response in Rate limit group", nil)
+		return
+	}
+
+	// Nothing to do if there are no RateLimitEventsAdaptiveGroups
+	if len(z.RateLimitEventsAdaptiveGroups) == 0 {
+		return
 	}
 
+	for _, g := range z.RateLimitEventsAdaptiveGroups {
+		zoneRateLimitEventsTotal.With(
+			prometheus.Labels{
+				"zone":    name,
+				"account": account,
+				"action":  g.Dimensions.Action,
+				"rule_id": g.Dimensions.RuleID,
+			}).Add(float64(g.Count))
+	}
 }
 
 func addHealthCheckGroups(z *models.ZoneRespHealthCheckGroups, name string, account string) {
@@ -1403,6 +3177,32 @@ func addHealthCheckGroups(z *models.ZoneRespHealthCheckGroups, name string, acco
 		}).Set(avgHealthCheckEvents)
 }
 
+// isOriginConnectivityError reports whether status is one of Cloudflare's 52x
+// codes (520-527) for failures reaching the origin, e.g. 522 connection timed
+// out, 523 origin unreachable or 524 timeout waiting for the response.
+func isOriginConnectivityError(status uint16) bool {
+	return status >= 520 && status <= 527
+}
+
+// originDuration accumulates a request-weighted average origin response duration.
+type originDuration struct {
+	labels   prometheus.Labels
+	weighted float64
+	count    uint64
+}
+
+func (d *originDuration) add(avgMs float64, count uint64) {
+	d.weighted += avgMs * float64(count)
+	d.count += count
+}
+
+func (d *originDuration) avg() float64 {
+	if d.count == 0 {
+		return 0
+	}
+	return d.weighted / float64(d.count)
+}
+
 func addHTTPAdaptiveGroups(z *models.ZoneRespAdaptiveGroups, name string, account string) {
 
 	if z == nil {
@@ -1410,6 +3210,8 @@ func addHTTPAdaptiveGroups(z *models.ZoneRespAdaptiveGroups, name string, accoun
 		return
 	}
 
+	checkTruncated("http_adaptive", name, len(z.HTTPRequestsAdaptiveGroups), cloudflareAPI.QueryLimit("adaptive"))
+
 	// Process `HTTPRequestsAdaptiveGroups`
 	for _, g := range z.HTTPRequestsAdaptiveGroups {
 		labels := getLabels(prometheus.Labels{
@@ -1417,7 +3219,7 @@ func addHTTPAdaptiveGroups(z *models.ZoneRespAdaptiveGroups, name string, accoun
 			"account": account,
 			"status":  strconv.Itoa(int(g.Dimensions.OriginResponseStatus)),
 			"country": g.Dimensions.ClientCountryName,
-		}, g.Dimensions.ClientRequestHTTPHost) // Pass host dynamically
+		}, g.Dimensions.ClientRequestHTTPHost, zoneRequestOriginStatusCountryHostMetricName) // Pass host dynamically
 
 		if zoneRequestOriginStatusCountryHost != nil {
 			zoneRequestOriginStatusCountryHost.With(labels).Add(float64(g.Count))
@@ -1425,19 +3227,45 @@ func addHTTPAdaptiveGroups(z *models.ZoneRespAdaptiveGroups, name string, accoun
 
 	}
 
-	// Process `HTTPRequestsAdaptiveGroups`
+	// Per-host bandwidth, capped to the busiest hosts to bound cardinality
+	if zoneBandwidthHostBytesTotal != nil {
+		hostBytes := make(map[string]float64, len(z.HTTPRequestsHostBytes))
+		for _, g := range z.HTTPRequestsHostBytes {
+			hostBytes[g.Dimensions.ClientRequestHTTPHost] += float64(g.Sum.EdgeResponseBytes)
+		}
+		for host, bytes := range topN(hostBytes, viper.GetInt("cf_host_top_n")) {
+			zoneBandwidthHostBytesTotal.With(getLabels(prometheus.Labels{
+				"zone":    name,
+				"account": account,
+			}, host, zoneBandwidthHostBytesTotalMetricName)).Add(bytes)
+		}
+	}
+
+	// Process `HTTPRequestsAdaptiveGroups`. Groups that differ only by host share
+	// a series when exclude_host is set, so average them weighted by request count
+	// instead of letting the last group win.
+	durations := make(map[string]*originDuration)
 	for _, g := range z.HTTPRequestsAdaptiveGroups {
 		labels := getLabels(prometheus.Labels{
 			"zone":    name,
 			"account": account,
 			"status":  strconv.Itoa(int(g.Dimensions.OriginResponseStatus)),
 			"country": g.Dimensions.ClientCountryName,
-		}, g.Dimensions.ClientRequestHTTPHost) // Pass host dynamically
+		}, g.Dimensions.ClientRequestHTTPHost, zoneOriginResponseDurationMsMetricName) // Pass host dynamically
 
-		if zoneOriginResponseDuration != nil {
-			zoneOriginResponseDuration.With(labels).Set(g.Avg.OriginResponseDurationMs)
+		key := labels["status"] + "|" + labels["country"] + "|" + labels["host"]
+		d, ok := durations[key]
+		if !ok {
+			d = &originDuration{labels: labels}
+			durations[key] = d
 		}
+		d.add(g.Avg.OriginResponseDurationMs, g.Count)
+	}
 
+	if zoneOriginResponseDuration != nil {
+		for _, d := range durations {
+			zoneOriginResponseDuration.With(d.labels).Set(d.avg())
+		}
 	}
 
 	// Process `` and EdgeResponseStatus for 4xx
@@ -1473,7 +3301,7 @@ func addHTTPAdaptiveGroups(z *models.ZoneRespAdaptiveGroups, name string, accoun
 				"account": account,
 				"status":  strconv.Itoa(int(g.Dimensions.OriginResponseStatus)),
 				"country": g.Dimensions.ClientCountryName,
-			}, g.Dimensions.ClientRequestHTTPHost) // Pass host dynamically
+			}, g.Dimensions.ClientRequestHTTPHost, zoneCustomerError4xxRate, zoneCustomerError4xxTotal) // Pass host dynamically
 
 			if zoneCustomerError4xx != nil {
 				// Increment the Prometheus metric for 4xx errors
@@ -1499,14 +3327,14 @@ func addHTTPAdaptiveGroups(z *models.ZoneRespAdaptiveGroups, name string, accoun
 		statusCode := g.Dimensions.OriginResponseStatus
 
 		// Check if the status code is a 5xx error
-		if statusCode >= 500 {
+		if statusCode >= 500 && statusCode < 600 {
 			// Generate labels dynamically using getLabels()
 			labels := getLabels(prometheus.Labels{
 				"zone":    name,
 				"account": account,
 				"status":  strconv.Itoa(int(g.Dimensions.OriginResponseStatus)),
 				"country": g.Dimensions.ClientCountryName,
-			}, g.Dimensions.ClientRequestHTTPHost) // Pass host dynamically
+			}, g.Dimensions.ClientRequestHTTPHost, zoneCustomerError5xxRate, zoneCustomerError5xxTotal) // Pass host dynamically
 
 			if zoneCustomerError5xx != nil {
 				// Increment the Prometheus metric for 5xx errors
@@ -1514,71 +3342,388 @@ func addHTTPAdaptiveGroups(z *models.ZoneRespAdaptiveGroups, name string, accoun
 			}
 
 		}
-
+
+	}
+
+	// 52x is generated by the edge when it could not talk to the origin, so
+	// these requests have no origin status and are queried by edge status
+	for _, g := range z.HTTPRequestsOriginConnectivity {
+		if !isOriginConnectivityError(g.Dimensions.EdgeResponseStatus) {
+			continue
+		}
+		zoneOriginConnectivityErrorsTotal.With(prometheus.Labels{
+			"zone":    name,
+			"account": account,
+			"status":  strconv.Itoa(int(g.Dimensions.EdgeResponseStatus)),
+		}).Add(float64(g.Count))
+	}
+
+	// Process `HTTPRequestsOriginStatus` grouped by status class for origin success rate
+	statusClasses := make(map[string]uint64)
+	for _, g := range z.HTTPRequestsOriginStatus {
+		statusCode := g.Dimensions.OriginResponseStatus
+		if statusCode < 100 || statusCode >= 600 {
+			continue
+		}
+		statusClasses[fmt.Sprintf("%dxx", statusCode/100)] += g.Count
+	}
+
+	for class, count := range statusClasses {
+		zoneOriginRequestsTotal.With(prometheus.Labels{
+			"zone":         name,
+			"account":      account,
+			"status_class": class,
+		}).Add(float64(count))
+	}
+
+	// Process `HTTPRequestsMethod` (GET, POST, ...)
+	for _, g := range z.HTTPRequestsMethod {
+		zoneRequestMethod.With(prometheus.Labels{
+			"zone":    name,
+			"account": account,
+			"method":  g.Dimensions.ClientRequestHTTPMethodName,
+		}).Add(float64(g.Count))
+	}
+
+	// Process `HTTPRequestsCacheStatus` (hit, miss, expired, dynamic, revalidated, ...)
+	for _, g := range z.HTTPRequestsCacheStatus {
+		zoneRequestsByCacheStatus.With(prometheus.Labels{
+			"zone":         name,
+			"account":      account,
+			"cache_status": g.Dimensions.CacheStatus,
+		}).Add(float64(g.Count))
+	}
+
+}
+
+func addHTTPRequestsEdgeCountryHost(z *models.ZoneRespHTTPRequestsEdge, name string, account string) {
+
+	if z == nil {
+		logging.Error("Received nil zone response in HTTP Adaptive Group", nil)
+		return
+	}
+
+	// Process `HTTPRequestsEdgeCountryHost` for OriginResponseStatus
+	for _, g := range z.HTTPRequestsEdgeCountryHost {
+		labels := getLabels(prometheus.Labels{
+			"zone":    name,
+			"account": account,
+			"status":  strconv.Itoa(int(g.Dimensions.EdgeResponseStatus)),
+			"country": g.Dimensions.ClientCountryName,
+		}, g.Dimensions.ClientRequestHTTPHost, zoneRequestStatusCountryHostMetricName) // Pass host dynamically
+
+		if zoneRequestStatusCountryHost != nil {
+			zoneRequestStatusCountryHost.With(labels).Add(float64(g.Count))
+		}
+
+	}
+
+	// Process `HTTPRequestsEdgeCountryHost` and EdgeResponseStatus for 5xx
+	for _, g := range z.HTTPRequestsEdgeCountryHost {
+		statusCode := g.Dimensions.EdgeResponseStatus
+
+		// Check if the status code is a 4xx or 5xx error
+		if (statusCode >= 400 && statusCode < 500) || (statusCode >= 500 && statusCode < 600) {
+			// Generate labels dynamically using getLabels()
+			labels := getLabels(prometheus.Labels{
+				"zone":    name,
+				"account": account,
+				"status":  strconv.Itoa(int(g.Dimensions.EdgeResponseStatus)),
+				"country": g.Dimensions.ClientCountryName,
+			}, g.Dimensions.ClientRequestHTTPHost, zoneEdgeErrorRate, zoneEdgeErrorsTotal) // Pass host dynamically
+
+			if zoneEdgeError != nil {
+				// Count the requests in the group, not the group itself
+				zoneEdgeError.With(labels).Add(float64(g.Count))
+			}
+			if zoneEdgeErrorLegacy != nil {
+				zoneEdgeErrorLegacy.With(labels).Inc()
+			}
+
+		}
+
+	}
+
+}
+
+//
+
+func fetchZoneColocationAnalytics(ctx context.Context, zones []cloudflare.Zone) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Error("Panic in fetchZoneColocationAnalytics", map[string]interface{}{
+				"panic": r,
+			})
+		}
+	}()
+
+	// Colocation metrics are not available in non-enterprise zones
+	if viper.GetBool("free_tier") {
+		return
+	}
+
+	zoneIDs := cloudflareAPI.ExtractZoneIDs(filterNonFreePlanZones(zones))
+	if len(zoneIDs) == 0 {
+		return
+	}
+
+	r, err := cloudflareAPI.FetchColoTotals(ctx, zoneIDs)
+	if err != nil {
+		logging.Error("Failed to fetch Colo totals", map[string]interface{}{
+			"zoneIDs": zoneIDs,
+			"error":   err.Error(),
+		})
+		recordFetchError("colocation", err)
+		return
+	}
+	markFamilySuccess("colocation")
+
+	// Check if the response structure is valid
+	if r == nil || r.Viewer.Zones == nil {
+		logging.Error("Nil response received for Colo totals", map[string]interface{}{
+			"zoneIDs": zoneIDs,
+		})
+		return
+	}
+
+	for _, z := range r.Viewer.Zones {
+		z := z
+		name, account, ok := lookupZoneLabels(zones, z.ZoneTag)
+		if !ok {
+			continue
+		}
+		addColoGroups(&z, name, account)
+	}
+}
+
+// addColoGroups emits colocation metrics for a zone. With apply_sampling the
+// count-based metrics are multiplied by each group's sample interval to estimate
+// true totals; these estimates are approximate.
+func addColoGroups(z *models.ZoneRespColo, name string, account string) {
+	applySampling := viper.GetBool("apply_sampling")
+	colos := getColos()
+
+	checkTruncated("colo", name, len(z.ColoGroups), cloudflareAPI.QueryLimit("colo"))
+
+	var weightedInterval float64
+	var sampledCount uint64
+
+	for _, c := range z.ColoGroups {
+		scale := 1.0
+		if c.Avg.SampleInterval > 0 {
+			weightedInterval += c.Avg.SampleInterval * float64(c.Count)
+			sampledCount += c.Count
+			if applySampling {
+				scale = c.Avg.SampleInterval
+			}
+		}
+
+		if len(colos) > 0 && !colos[strings.ToUpper(c.Dimensions.ColoCode)] {
+			continue
+		}
+
+		// Each metric may or may not carry host, see host_label_metrics
+		labels := prometheus.Labels{
+			"zone":       name,
+			"account":    account,
+			"colocation": c.Dimensions.ColoCode,
+		}
+
+		if zoneColocationVisits != nil {
+			zoneColocationVisits.With(getLabels(labels, c.Dimensions.Host, zoneColocationVisitsMetricName)).Add(float64(c.Sum.Visits) * scale)
+		}
+		if zoneColocationEdgeResponseBytes != nil {
+			zoneColocationEdgeResponseBytes.With(getLabels(labels, c.Dimensions.Host, zoneColocationEdgeResponseBytesMetricName)).Add(float64(c.Sum.EdgeResponseBytes) * scale)
+		}
+		if zoneColocationRequestsTotal != nil {
+			zoneColocationRequestsTotal.With(getLabels(labels, c.Dimensions.Host, zoneColocationRequestsTotalMetricName)).Add(float64(c.Count) * scale)
+		}
+
+		// Only process error status codes (4xx/5xx)
+		status := c.Dimensions.OriginResponseStatus
+
+		if status >= 400 {
+			// Create error-specific labels
+			errorLabels := prometheus.Labels{
+				"zone":       name,
+				"account":    account,
+				"colocation": c.Dimensions.ColoCode,
+				"status":     fmt.Sprintf("%dxx", status/100),
+			}
+
+			// Error-specific metrics
+			if zoneColocationVisitsError != nil {
+				zoneColocationVisitsError.With(getLabels(errorLabels, c.Dimensions.Host, zoneColocationVisitsErrorMetricName)).Add(float64(c.Sum.Visits) * scale)
+			}
+			if zoneColocationEdgeResponseBytesError != nil {
+				zoneColocationEdgeResponseBytesError.With(getLabels(errorLabels, c.Dimensions.Host, zoneColocationEdgeResponseBytesErrorMetricName)).Add(float64(c.Sum.EdgeResponseBytes) * scale)
+			}
+			if zoneColocationRequestsTotalError != nil {
+				zoneColocationRequestsTotalError.With(getLabels(errorLabels, c.Dimensions.Host, zoneColocationRequestsTotalErrorMetricName)).Add(float64(c.Count) * scale)
+			}
+		}
+
+	}
+
+	if sampledCount > 0 {
+		interval := weightedInterval / float64(sampledCount)
+		zoneSampleInterval.With(prometheus.Labels{"zone": name, "account": account}).Set(interval)
+		zoneSampleRate.With(prometheus.Labels{"zone": name, "account": account}).Set(1 / interval)
+	}
+}
+
+func fetchBotScore(ctx context.Context, zones []cloudflare.Zone) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Error("Panic in fetchBotScore", map[string]interface{}{
+				"panic": r,
+			})
+		}
+	}()
+
+	// Bot Management is a paid add-on
+	if viper.GetBool("free_tier") {
+		return
+	}
+
+	zoneIDs := cloudflareAPI.ExtractZoneIDs(filterNonFreePlanZones(zones))
+	if len(zoneIDs) == 0 {
+		return
+	}
+
+	r, err := cloudflareAPI.FetchBotScore(ctx, zoneIDs)
+	if err != nil {
+		logging.Error("Failed to fetch bot scores", map[string]interface{}{
+			"zoneIDs": zoneIDs,
+			"error":   err.Error(),
+		})
+		recordFetchError("bot_score", err)
+		return
+	}
+	markFamilySuccess("bot_score")
+
+	for _, z := range r.Viewer.Zones {
+		name, account, ok := lookupZoneLabels(zones, z.ZoneTag)
+		if !ok {
+			continue
+		}
+		z := z
+		addBotScoreGroups(&z, name, account)
 	}
+}
 
+// botScoreBucket maps a bot score to its bucket; 0 means the request was not scored.
+func botScoreBucket(score uint8) string {
+	switch {
+	case score == 0:
+		return "unscored"
+	case score < 30:
+		return "1-29"
+	default:
+		return "30-99"
+	}
 }
 
-func addHTTPRequestsEdgeCountryHost(z *models.ZoneRespHTTPRequestsEdge, name string, account string) {
+func addBotScoreGroups(z *models.ZoneRespBotScore, name string, account string) {
 
 	if z == nil {
-		logging.Error("Received nil zone response in HTTP Adaptive Group", nil)
+		logging.Error("Received nil zone response in addBotScoreGroups", nil)
 		return
 	}
 
-	// Process `HTTPRequestsEdgeCountryHost` for OriginResponseStatus
-	for _, g := range z.HTTPRequestsEdgeCountryHost {
-		labels := getLabels(prometheus.Labels{
-			"zone":    name,
-			"account": account,
-			"status":  strconv.Itoa(int(g.Dimensions.EdgeResponseStatus)),
-			"country": g.Dimensions.ClientCountryName,
-		}, g.Dimensions.ClientRequestHTTPHost) // Pass host dynamically
+	buckets := make(map[string]uint64)
+	for _, g := range z.HTTPRequestsBotScore {
+		buckets[botScoreBucket(g.Dimensions.BotScore)] += g.Count
+	}
 
-		if zoneRequestStatusCountryHost != nil {
-			zoneRequestStatusCountryHost.With(labels).Add(float64(g.Count))
+	for bucket, count := range buckets {
+		zoneBotScoreRequestsTotal.With(prometheus.Labels{
+			"zone":         name,
+			"account":      account,
+			"score_bucket": bucket,
+		}).Add(float64(count))
+	}
+}
+
+func fetchRequestPaths(ctx context.Context, zones []cloudflare.Zone) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Error("Panic in fetchRequestPaths", map[string]interface{}{
+				"panic": r,
+			})
 		}
+	}()
 
+	// Opt-in, paths can have very high cardinality
+	if !viper.GetBool("enable_path_metrics") || viper.GetBool("free_tier") {
+		return
 	}
 
-	// Process `HTTPRequestsEdgeCountryHost` and EdgeResponseStatus for 5xx
-	for _, g := range z.HTTPRequestsEdgeCountryHost {
-		statusCode := g.Dimensions.EdgeResponseStatus
-
-		// Check if the status code is a 4xx or 5xx error
-		if (statusCode >= 400 && statusCode < 500) || (statusCode >= 500 && statusCode < 600) {
-			// Generate labels dynamically using getLabels()
-			labels := getLabels(prometheus.Labels{
-				"zone":    name,
-				"account": account,
-				"status":  strconv.Itoa(int(g.Dimensions.EdgeResponseStatus)),
-				"country": g.Dimensions.ClientCountryName,
-			}, g.Dimensions.ClientRequestHTTPHost) // Pass host dynamically
+	zoneIDs := cloudflareAPI.ExtractZoneIDs(filterNonFreePlanZones(zones))
+	if len(zoneIDs) == 0 {
+		return
+	}
 
-			if zoneEdgeError != nil {
-				// Increment the Prometheus metric for edge errors
-				zoneEdgeError.With(labels).Inc()
-			}
+	r, err := cloudflareAPI.FetchRequestPaths(ctx, zoneIDs)
+	if err != nil {
+		logging.Error("Failed to fetch request paths", map[string]interface{}{
+			"zoneIDs": zoneIDs,
+			"error":   err.Error(),
+		})
+		recordFetchError("request_path", err)
+		return
+	}
+	markFamilySuccess("request_path")
 
+	for _, z := range r.Viewer.Zones {
+		name, account, ok := lookupZoneLabels(zones, z.ZoneTag)
+		if !ok {
+			continue
 		}
+		z := z
+		addRequestPathGroups(&z, name, account)
+	}
+}
+
+// addRequestPathGroups adds requests per path, capped to the busiest
+// cf_path_top_n paths with the rest summed as path="other".
+func addRequestPathGroups(z *models.ZoneRespRequestPath, name string, account string) {
 
+	if z == nil {
+		logging.Error("Received nil zone response in addRequestPathGroups", nil)
+		return
 	}
 
-}
+	checkTruncated("request_path", name, len(z.HTTPRequestsPath), cloudflareAPI.QueryLimit("adaptive"))
 
-//
+	paths := make(map[string]float64, len(z.HTTPRequestsPath))
+	for _, g := range z.HTTPRequestsPath {
+		paths[g.Dimensions.ClientRequestPath] += float64(g.Count)
+	}
+
+	for path, count := range topN(paths, viper.GetInt("cf_path_top_n")) {
+		zoneRequestsByPathTotal.With(prometheus.Labels{
+			"zone":    name,
+			"account": account,
+			"path":    path,
+		}).Add(count)
+	}
+}
 
-func fetchZoneColocationAnalytics(zones []cloudflare.Zone) {
+func fetchArgoAnalytics(ctx context.Context, zones []cloudflare.Zone) {
 
 	defer func() {
 		if r := recover(); r != nil {
-			logging.Error("Panic in fetchZoneColocationAnalytics", map[string]interface{}{
+			logging.Error("Panic in fetchArgoAnalytics", map[string]interface{}{
 				"panic": r,
 			})
 		}
 	}()
 
-	// Colocation metrics are not available in non-enterprise zones
+	// Argo Smart Routing analytics are not available in the free tier
 	if viper.GetBool("free_tier") {
 		return
 	}
@@ -1588,74 +3733,66 @@ func fetchZoneColocationAnalytics(zones []cloudflare.Zone) {
 		return
 	}
 
-	r, err := cloudflareAPI.FetchColoTotals(zoneIDs)
+	r, err := cloudflareAPI.FetchArgoAnalytics(ctx, zoneIDs)
 	if err != nil {
-		logging.Error("Failed to fetch Colo totals", map[string]interface{}{
+		logging.Error("Failed to fetch Argo analytics", map[string]interface{}{
 			"zoneIDs": zoneIDs,
 			"error":   err.Error(),
 		})
+		recordFetchError("argo", err)
 		return
 	}
+	markFamilySuccess("argo")
 
-	// Check if the response structure is valid
-	if r == nil || r.Viewer.Zones == nil {
-		logging.Error("Nil response received for Colo totals", map[string]interface{}{
-			"zoneIDs": zoneIDs,
-		})
-		return
+	for _, z := range r.Viewer.Zones {
+		name, account, ok := lookupZoneLabels(zones, z.ZoneTag)
+		if !ok {
+			continue
+		}
+		z := z
+		addArgoGroups(&z, name, account)
 	}
+}
 
-	for _, z := range r.Viewer.Zones {
-		cg := z.ColoGroups
-		name, account := findZoneAccountName(zones, z.ZoneTag)
+func addArgoGroups(z *models.ZoneRespArgo, name string, account string) {
 
-		for _, c := range cg {
-			labels := getLabels(prometheus.Labels{
-				"zone":       name,
-				"account":    account,
-				"colocation": c.Dimensions.ColoCode,
-			}, c.Dimensions.Host) // Pass actual host dynamically
+	if z == nil {
+		logging.Info("Received nil zone response in addArgoGroups", nil)
+		return
+	}
 
-			if zoneColocationVisits != nil {
-				zoneColocationVisits.With(labels).Add(float64(c.Sum.Visits))
-			}
-			if zoneColocationEdgeResponseBytes != nil {
-				zoneColocationEdgeResponseBytes.With(labels).Add(float64(c.Sum.EdgeResponseBytes))
-			}
-			if zoneColocationRequestsTotal != nil {
-				zoneColocationRequestsTotal.With(labels).Add(float64(c.Count))
-			}
+	if len(z.ArgoAnalyticsAdaptiveGroups) == 0 {
+		return
+	}
 
-			// Only process error status codes (4xx/5xx)
-			status := c.Dimensions.OriginResponseStatus
-
-			if status >= 400 {
-				// Create error-specific labels
-				errorLabels := getLabels(prometheus.Labels{
-					"zone":       name,
-					"account":    account,
-					"colocation": c.Dimensions.ColoCode,
-					"status":     fmt.Sprintf("%dxx", status/100),
-				}, c.Dimensions.Host) // Pass actual host dynamically
-
-				// Error-specific metrics
-				if zoneColocationVisitsError != nil {
-					zoneColocationVisitsError.With(errorLabels).Add(float64(c.Sum.Visits))
-				}
-				if zoneColocationEdgeResponseBytesError != nil {
-					zoneColocationEdgeResponseBytesError.With(errorLabels).Add(float64(c.Sum.EdgeResponseBytes))
-				}
-				if zoneColocationRequestsTotalError != nil {
-					zoneColocationRequestsTotalError.With(errorLabels).Add(float64(c.Count))
-				}
-			}
+	// Requests and summed response times, indexed by smartRouted
+	var requests [2]uint64
+	var weightedDuration [2]float64
+	for _, g := range z.ArgoAnalyticsAdaptiveGroups {
+		i := min(g.Dimensions.SmartRouted, 1)
+		requests[i] += g.Count
+		weightedDuration[i] += g.Avg.OriginResponseDurationMs * float64(g.Count)
+	}
 
-		}
+	labels := prometheus.Labels{"zone": name, "account": account}
+	zoneArgoRequestsTotal.With(labels).Add(float64(requests[1]))
+	if requests[1] == 0 {
+		zoneArgoResponseTimeMs.Delete(labels)
+	} else {
+		zoneArgoResponseTimeMs.With(labels).Set(weightedDuration[1] / float64(requests[1]))
+	}
 
+	// The improvement needs both smart routed and direct requests to compare
+	if requests[0] == 0 || requests[1] == 0 {
+		zoneArgoResponseTimeImprovementMs.Delete(labels)
+		return
 	}
+	direct := weightedDuration[0] / float64(requests[0])
+	smartRouted := weightedDuration[1] / float64(requests[1])
+	zoneArgoResponseTimeImprovementMs.With(labels).Set(direct - smartRouted)
 }
 
-func fetchLoadBalancerAnalytics(zones []cloudflare.Zone) {
+func fetchLoadBalancerAnalytics(ctx context.Context, zones []cloudflare.Zone) {
 
 	// Panic recovery to ensure one failing goroutine does not stop the service
 	defer func() {
@@ -1676,17 +3813,22 @@ func fetchLoadBalancerAnalytics(zones []cloudflare.Zone) {
 		return
 	}
 
-	l, err := cloudflareAPI.FetchLoadBalancerTotals(zoneIDs)
+	l, err := cloudflareAPI.FetchLoadBalancerTotals(ctx, zoneIDs)
 	if err != nil {
 		logging.Error("Failed to fetch Load Balancer totals", map[string]interface{}{
 			"zoneIDs": zoneIDs,
 			"error":   err.Error(),
 		})
+		recordFetchError("load_balancers", err)
 		return
 	}
+	markFamilySuccess("load_balancers")
 
 	for _, lb := range l.Viewer.Zones {
-		name, account := findZoneAccountName(zones, lb.ZoneTag)
+		name, account, ok := lookupZoneLabels(zones, lb.ZoneTag)
+		if !ok {
+			continue
+		}
 		lb := lb
 		addLoadBalancingRequestsAdaptive(&lb, name, account)
 		addLoadBalancingRequestsAdaptiveGroups(&lb, name, account)
@@ -1736,11 +3878,37 @@ func addLoadBalancingRequestsAdaptive(z *models.LbResp, name string, account str
 					"load_balancer_name": g.LbName,
 					"pool_name":          p.PoolName,
 				}).Set(float64(p.Healthy))
+			poolAvgRttMs.With(
+				prometheus.Labels{
+					"zone":               name,
+					"account":            account,
+					"load_balancer_name": g.LbName,
+					"pool_name":          p.PoolName,
+				}).Set(float64(p.AvgRttMs))
+		}
+		for _, o := range g.Origins {
+			originHealth.With(
+				prometheus.Labels{
+					"zone":               name,
+					"account":            account,
+					"load_balancer_name": g.LbName,
+					"origin_name":        o.OriginName,
+				}).Set(float64(o.Health))
+			// The origins array lists the origins of the pool selected for the request
+			originLBHealth.With(
+				prometheus.Labels{
+					"zone":               name,
+					"account":            account,
+					"load_balancer_name": g.LbName,
+					"pool_name":          g.SelectedPoolName,
+					"origin_name":        o.OriginName,
+					"origin_ip":          o.IPv4,
+				}).Set(float64(o.Health))
 		}
 	}
 }
 
-func fetchLogpushAnalyticsForZone(zones []cloudflare.Zone) {
+func fetchLogpushAnalyticsForZone(ctx context.Context, zones []cloudflare.Zone) {
 
 	defer func() {
 		if r := recover(); r != nil {
@@ -1759,7 +3927,7 @@ func fetchLogpushAnalyticsForZone(zones []cloudflare.Zone) {
 		return
 	}
 
-	r, err2 := cloudflareAPI.FetchLogpushZone(zoneIDs)
+	r, err2 := cloudflareAPI.FetchLogpushZone(ctx, zoneIDs)
 	if err2 != nil {
 
 		return
@@ -1773,25 +3941,144 @@ func fetchLogpushAnalyticsForZone(zones []cloudflare.Zone) {
 
 	for _, zone := range r.Viewer.Zones {
 		for _, LogpushHealthAdaptiveGroup := range zone.LogpushHealthAdaptiveGroups {
+			jobName, dataset := logpushJobLabels(ctx, "zones", zone.ZoneTag, LogpushHealthAdaptiveGroup.Dimensions.JobID)
+			labels := prometheus.Labels{
+				"destination": LogpushHealthAdaptiveGroup.Dimensions.DestinationType,
+				"job_id":      strconv.Itoa(LogpushHealthAdaptiveGroup.Dimensions.JobID),
+				"job_name":    jobName,
+				"dataset":     dataset,
+				"final":       logpushFinalLabel(LogpushHealthAdaptiveGroup.Dimensions.Final),
+			}
 			if LogpushHealthAdaptiveGroup.Count == 0 {
 				// Default values in case of no data
-				logpushFailedJobsZone.With(prometheus.Labels{
-					"destination": LogpushHealthAdaptiveGroup.Dimensions.DestinationType,
-					"job_id":      strconv.Itoa(LogpushHealthAdaptiveGroup.Dimensions.JobID),
-					"final":       strconv.Itoa(LogpushHealthAdaptiveGroup.Dimensions.Final),
-				}).Add(0)
+				logpushFailedJobsZone.With(labels).Add(0)
 			} else {
-				logpushFailedJobsZone.With(prometheus.Labels{
-					"destination": LogpushHealthAdaptiveGroup.Dimensions.DestinationType,
-					"job_id":      strconv.Itoa(LogpushHealthAdaptiveGroup.Dimensions.JobID),
-					"final":       strconv.Itoa(LogpushHealthAdaptiveGroup.Dimensions.Final),
-				}).Add(float64(LogpushHealthAdaptiveGroup.Count))
+				logpushFailedJobsZone.With(labels).Add(float64(LogpushHealthAdaptiveGroup.Count))
+			}
+		}
+	}
+}
+
+// fetchPageShield exposes Page Shield script and violation counts per zone.
+func fetchPageShield(ctx context.Context, zones []cloudflare.Zone) {
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Error("Panic in fetchPageShield", map[string]interface{}{
+				"panic": r,
+			})
+		}
+	}()
+
+	if viper.GetBool("free_tier") {
+		return
+	}
+
+	zoneIDs := cloudflareAPI.ExtractZoneIDs(filterNonFreePlanZones(zones))
+	if len(zoneIDs) == 0 {
+		return
+	}
+
+	r, err := cloudflareAPI.FetchPageShieldScripts(ctx, zoneIDs)
+	if err != nil {
+		logging.Error("Error fetching Page Shield scripts", map[string]interface{}{
+			"error": err.Error(),
+		})
+		recordFetchError("page_shield", err)
+		return
+	}
+	// Failed zones are left out of r
+	if len(r) > 0 {
+		markFamilySuccess("page_shield")
+	}
+
+	for zoneID, scripts := range r {
+		name, account, ok := lookupZoneLabels(zones, zoneID)
+		if !ok {
+			continue
+		}
+
+		violations := 0
+		for _, s := range scripts {
+			if s.DomainReportedMalicious || s.URLReportedMalicious {
+				violations++
 			}
 		}
+
+		zonePageShieldScripts.With(prometheus.Labels{"zone": name, "account": account}).Set(float64(len(scripts)))
+		zonePageShieldViolationsTotal.With(prometheus.Labels{"zone": name, "account": account}).Set(float64(violations))
+	}
+}
+
+// wafCategoriesRefresh is how long the managed WAF rule categories of a zone
+// are reused before they are fetched again.
+const wafCategoriesRefresh = time.Hour
+
+// wafCategories are the managed WAF rule categories of a zone.
+type wafCategories struct {
+	fetched time.Time
+	rules   map[string][]string
+}
+
+// zoneWAFCategories holds, per zone ID, the wafCategories last fetched.
+var zoneWAFCategories sync.Map
+
+// wafRuleCategories returns the categories of ruleID on zoneID, if known.
+func wafRuleCategories(zoneID, ruleID string) []string {
+	v, ok := zoneWAFCategories.Load(zoneID)
+	if !ok || ruleID == "" {
+		return nil
+	}
+	return v.(wafCategories).rules[ruleID]
+}
+
+// fetchWAFCategories refreshes the managed WAF rule categories that
+// addFirewallGroups joins with firewall events. It is a no-op unless
+// enable_waf_categories is set; until the first fetch of a zone completes
+// its events are not counted per category.
+func fetchWAFCategories(ctx context.Context, zones []cloudflare.Zone) {
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Error("Panic in fetchWAFCategories", map[string]interface{}{
+				"panic": r,
+			})
+		}
+	}()
+
+	if !viper.GetBool("enable_waf_categories") {
+		return
+	}
+
+	var zoneIDs []string
+	for _, z := range zones {
+		if v, ok := zoneWAFCategories.Load(z.ID); ok && time.Since(v.(wafCategories).fetched) < wafCategoriesRefresh {
+			continue
+		}
+		zoneIDs = append(zoneIDs, z.ID)
+	}
+	if len(zoneIDs) == 0 {
+		return
+	}
+
+	r, err := cloudflareAPI.FetchWAFRuleCategories(ctx, zoneIDs)
+	if err != nil {
+		logging.Error("Error fetching WAF rule categories", map[string]interface{}{
+			"error": err.Error(),
+		})
+		recordFetchError("waf_categories", err)
+		return
+	}
+	// Failed zones are left out of r and retried on the next scrape
+	if len(r) > 0 {
+		markFamilySuccess("waf_categories")
+	}
+
+	now := time.Now()
+	for zoneID, rules := range r {
+		zoneWAFCategories.Store(zoneID, wafCategories{fetched: now, rules: rules})
 	}
 }
 
-func fetchSSLCertificateStatus(zones []cloudflare.Zone) {
+func fetchSSLCertificateStatus(ctx context.Context, zones []cloudflare.Zone) {
 
 	defer func() {
 		if r := recover(); r != nil {
@@ -1810,11 +4097,12 @@ func fetchSSLCertificateStatus(zones []cloudflare.Zone) {
 		return
 	}
 	// Fetch SSL certificate status for the zones
-	r, err := cloudflareAPI.FetchSSLCertificateStatus(zoneIDs)
+	r, err := cloudflareAPI.FetchSSLCertificateStatus(ctx, zoneIDs)
 	if err != nil {
 		logging.Error("Error fetching SSL certificate status", map[string]interface{}{
 			"error": err.Error(),
 		})
+		recordFetchError("ssl_certificates", err)
 		return
 	}
 	if r == nil {
@@ -1823,6 +4111,15 @@ func fetchSSLCertificateStatus(zones []cloudflare.Zone) {
 		})
 		return
 	}
+	if len(r.FetchedZoneIDs) > 0 {
+		markFamilySuccess("ssl_certificates")
+	}
+
+	// Series set per zone and certificate, to drop certificates no longer returned
+	seen := make(map[string]map[string]prometheus.Labels, len(r.FetchedZoneIDs))
+	for _, id := range r.FetchedZoneIDs {
+		seen[id] = map[string]prometheus.Labels{}
+	}
 
 	// Loop through the response and create Prometheus metrics
 	for _, zone := range r.Result {
@@ -1834,7 +4131,10 @@ func fetchSSLCertificateStatus(zones []cloudflare.Zone) {
 			// Convert the string to a time.Time object
 			expiresOnTime, err := time.Parse(time.RFC3339Nano, certificate.ExpiresOn)
 			if err != nil {
-				logging.Warnf("Invalid time format for certificate in zone %s: %v", zone.ZoneID, err)
+				logging.Warn("Invalid time format for certificate", map[string]interface{}{
+					"zone_id": zone.ZoneID,
+					"error":   err.Error(),
+				})
 				continue
 			}
 
@@ -1853,20 +4153,142 @@ func fetchSSLCertificateStatus(zones []cloudflare.Zone) {
 			}
 
 			// Set the value for the metric
-			zoneCertificateValidation.With(prometheus.Labels{
+			certLabels := prometheus.Labels{
 				"zone_id":   zone.ZoneID,
 				"zone_name": zoneName,
 				"status":    certificateStatus,
 				"issuer":    certificate.Issuer,
-			}).Set(expiresOnTimestamp)
+			}
+			zoneCertificateValidation.With(certLabels).Set(expiresOnTimestamp)
+			zoneCertificateDaysUntilExpiry.With(certLabels).Set(certificateDaysUntilExpiry(expiresOnTime, time.Now()))
+			if seen[zone.ZoneID] != nil {
+				seen[zone.ZoneID][certificate.ID] = certLabels
+			}
+		}
+	}
+
+	for zoneID, certs := range seen {
+		resetStaleCertificates(zoneID, certs)
+	}
+
+}
+
+// certificateDaysUntilExpiry returns the days from now until expiresOn,
+// negative for certificates that have already expired.
+func certificateDaysUntilExpiry(expiresOn, now time.Time) float64 {
+	return expiresOn.Sub(now).Seconds() / 86400
+}
+
+// accountFetchers are run for every account, in order, unless their
+// enable_* flag is turned off.
+var accountFetchers = []struct {
+	flag  string
+	fetch func(ctx context.Context, account cloudflare.Account)
+}{
+	{"enable_workers", FetchWorkerAnalytics},
+	{"enable_durable_objects", fetchDurableObjectsAnalytics},
+	{"enable_queues", fetchQueueBacklog},
+	{"enable_logpush", fetchLogpushAnalyticsForAccount},
+	{"enable_magic_transit", fetchMagicTransitHealth},
+	{"enable_turnstile", fetchTurnstileAnalytics},
+	{"enable_stream", fetchStreamAnalytics},
+	{"enable_images", fetchImagesAnalytics},
+}
+
+// runAccountFetchers runs the enabled account fetchers for account. Disabled
+// fetchers are skipped before any API call or rate limiter wait.
+func runAccountFetchers(ctx context.Context, account cloudflare.Account) {
+	for _, f := range accountFetchers {
+		// Unset flags count as enabled, all default to true
+		if viper.IsSet(f.flag) && !viper.GetBool(f.flag) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
 		}
+		f.fetch(ctx, account)
+	}
+}
+
+// graphQLZoneFetchers query the GraphQL API for a batch of zones at once.
+var graphQLZoneFetchers = []func(ctx context.Context, zones []cloudflare.Zone){
+	fetchZoneAnalytics,
+	fetchZoneColocationAnalytics,
+	fetchArgoAnalytics,
+	fetchBotScore,
+	fetchRequestPaths,
+	fetchLoadBalancerAnalytics,
+	fetchLogpushAnalyticsForZone,
+}
+
+// restZoneFetchers call a REST endpoint per zone, up to ssl_fetch_concurrency
+// zones at a time.
+var restZoneFetchers = []func(ctx context.Context, zones []cloudflare.Zone){
+	fetchSSLCertificateStatus,
+	fetchPageShield,
+	fetchWAFCategories,
+}
+
+// submitZoneBatches submits one zone pool job per batch running fetchers in
+// order. The fetchers wait on the rate limiter before each API call.
+func submitZoneBatches(ctx context.Context, pools *Pools, wg *sync.WaitGroup, batches [][]cloudflare.Zone, fetchers []func(ctx context.Context, zones []cloudflare.Zone)) {
+	for _, batch := range batches {
+		wg.Add(1)
+		pools.Zones.Submit(func() {
+			defer wg.Done()
+
+			// Jobs still queued when the scrape is cancelled return
+			// right away
+			for _, fetch := range fetchers {
+				if ctx.Err() != nil {
+					return
+				}
+				fetch(ctx, batch)
+			}
+		})
+	}
+}
+
+// Pools holds separate worker pools for account-level and zone-level jobs so
+// a burst of one kind of work cannot starve the other.
+type Pools struct {
+	Accounts *workerpool.WorkerPool
+	Zones    *workerpool.WorkerPool
+}
+
+// NewPools creates the account and zone worker pools with the given sizes.
+func NewPools(accountConcurrency, zoneConcurrency int) *Pools {
+	return &Pools{
+		Accounts: workerpool.New(accountConcurrency),
+		Zones:    workerpool.New(zoneConcurrency),
 	}
+}
 
+// Stop stops both pools after their queued jobs have run.
+func (p *Pools) Stop() {
+	p.Accounts.Stop()
+	p.Zones.Stop()
 }
 
 // worker pool ::::::
-func FetchMetrics(ctx context.Context, pool *workerpool.WorkerPool) error {
-	fmt.Println("FetchMetrics started")
+func FetchMetrics(ctx context.Context, pools *Pools) (err error) {
+	logging.Info("FetchMetrics started", nil)
+	defer func(start time.Time) { recordScrapeDone(start, err) }(time.Now())
+	resetSnapshot()
+	resetLogpushJobCache()
+	familySucceeded.Store(false)
+
+	// The first scrape after startup reaches back backfill_minutes
+	var backfill time.Duration
+	if !FirstScrapeDone() {
+		backfill = time.Duration(viper.GetInt("backfill_minutes")) * time.Minute
+	}
+	cloudflareAPI.SetBackfill(backfill)
+
+	// Scrapes run every minute, so a staggered zone is queried every cycles
+	// minutes and its queries have to reach back that far
+	cycles := viper.GetInt("cf_stagger_cycles")
+	cloudflareAPI.SetStaggerWindow(time.Duration(max(1, cycles)) * time.Minute)
 
 	// Reuse ALL your existing processing logic
 	zones, accounts, err := fetchInitialData(ctx)
@@ -1877,6 +4299,15 @@ func FetchMetrics(ctx context.Context, pool *workerpool.WorkerPool) error {
 	filteredZones := cloudflareAPI.FilterExcludedZones(
 		filterZones(zones, getTargetZones()), getExcludedZones(),
 	)
+	filteredZones = filterZonesByPlan(filteredZones, getZonePlans())
+	filteredZones = filterPausedZones(filteredZones)
+	// Before capZones so zones skipped by rotation are not treated as removed
+	resetStaleZones(filteredZones)
+	accounts = filterAccounts(accounts, getTargetAccounts(), getExcludedAccounts())
+	exporterZonesTotal.Set(float64(len(filteredZones)))
+	exporterAccountsTotal.Set(float64(len(accounts)))
+	filteredZones = staggerZones(filteredZones, cycles)
+	filteredZones = capZones(filteredZones, viper.GetInt("cf_max_zones"), viper.GetBool("cf_max_zones_rotate"))
 
 	// Minimal changes below...
 	var wg sync.WaitGroup
@@ -1886,77 +4317,26 @@ func FetchMetrics(ctx context.Context, pool *workerpool.WorkerPool) error {
 	for _, account := range accounts {
 		acc := account
 		wg.Add(1)
-		pool.Submit(func() {
+		pools.Accounts.Submit(func() {
 			defer wg.Done()
-
-			// Add rate limiting for each API call
-			if err := limiter.Wait(ctx); err != nil {
-				logging.Error("Rate limit exceeded in worker", err)
-				return
-			}
-			FetchWorkerAnalytics(acc)
-
-			if err := limiter.Wait(ctx); err != nil {
-				logging.Error("Rate limit exceeded in worker", err)
-				return
-			}
-			fetchLogpushAnalyticsForAccount(acc)
-
-			if err := limiter.Wait(ctx); err != nil {
-				logging.Error("Rate limit exceeded in worker", err)
-				return
-			}
-			fmt.Println("::::::::::::::::before calling")
-			fetchMagicTransitHealth(acc)
+			runAccountFetchers(ctx, acc)
 		})
 	}
 
-	// Process zones - NO CHANGES to your functions
-	batchSize := viper.GetInt("cf_batch_size")
-	for len(filteredZones) > 0 {
-		batch := filteredZones[:min(batchSize, len(filteredZones))]
-		filteredZones = filteredZones[len(batch):]
-
-		wg.Add(1)
-		pool.Submit(func() {
-			defer wg.Done()
-
-			if err := limiter.Wait(ctx); err != nil {
-				logging.Error("Rate limit exceeded in worker", err)
-				return
-			}
-			fetchZoneAnalytics(ctx, batch)
-
-			if err := limiter.Wait(ctx); err != nil {
-				logging.Error("Rate limit exceeded in worker", err)
-				return
-			}
-			fetchZoneColocationAnalytics(batch)
-
-			if err := limiter.Wait(ctx); err != nil {
-				logging.Error("Rate limit exceeded in worker", err)
-				return
-			}
-			fetchLoadBalancerAnalytics(batch)
-
-			if err := limiter.Wait(ctx); err != nil {
-				logging.Error("Rate limit exceeded in worker", err)
-				return
-			}
-			fetchLogpushAnalyticsForZone(batch)
-
-			if err := limiter.Wait(ctx); err != nil {
-				logging.Error("Rate limit exceeded in worker", err)
-				return
-			}
-			fetchSSLCertificateStatus(batch)
-		})
-	}
+	// GraphQL queries take up to cf_batch_size zones each, while the per-zone
+	// REST calls are batched by rest_batch_size and fan out within a batch
+	submitZoneBatches(ctx, pools, &wg, batchZones(filteredZones, viper.GetInt("cf_batch_size")), graphQLZoneFetchers)
+	submitZoneBatches(ctx, pools, &wg, batchZones(filteredZones, viper.GetInt("rest_batch_size")), restZoneFetchers)
 
 	// Safe wait with context
 	go func() { wg.Wait(); close(errChan) }()
 	select {
 	case err := <-errChan:
+		if err == nil {
+			now := time.Now()
+			markScrapeSuccess(now)
+			publishSnapshot(now)
+		}
 		return err
 	case <-ctx.Done():
 		return ctx.Err()
@@ -1965,18 +4345,11 @@ func FetchMetrics(ctx context.Context, pool *workerpool.WorkerPool) error {
 
 // Helper functions
 func fetchInitialData(ctx context.Context) ([]cloudflare.Zone, []cloudflare.Account, error) {
-	// / Add rate limiting before each API call
-	if err := limiter.Wait(ctx); err != nil {
-		return nil, nil, fmt.Errorf("rate limit wait failed: %w", err)
-	}
 	zones, err := cloudflareAPI.FetchZones(ctx)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to fetch zones: %w", err)
 	}
 
-	if err := limiter.Wait(ctx); err != nil {
-		return nil, nil, fmt.Errorf("rate limit wait failed: %w", err)
-	}
 	accounts, err := cloudflareAPI.FetchAccounts(ctx)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to fetch accounts: %w", err)
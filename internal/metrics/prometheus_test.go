@@ -1,13 +1,98 @@
 package metrics
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/gin-gonic/gin"
+	"github.com/jarcoal/httpmock"
+	"github.com/klauspost/compress/snappy"
+	cloudflareAPI "github.com/lablabs/cloudflare-exporter/internal/cloudflare"
+	"github.com/lablabs/cloudflare-exporter/internal/handlers"
+	"github.com/lablabs/cloudflare-exporter/internal/limiter"
+	"github.com/lablabs/cloudflare-exporter/internal/middlewares"
+	"github.com/lablabs/cloudflare-exporter/internal/models"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
 )
 
+// setViper sets key to value and restores the previous value when the test ends.
+func setViper(t *testing.T, key string, value interface{}) {
+	t.Helper()
+	prev := viper.Get(key)
+	viper.Set(key, value)
+	t.Cleanup(func() { viper.Set(key, prev) })
+}
+
+// useHostVecs sets exclude_host and free_tier for the test and creates the
+// vecs whose host label depends on exclude_host, as MustRegisterMetrics would.
+// The previous vecs and settings are restored when the test ends.
+func useHostVecs(t *testing.T, excludeHost bool) {
+	t.Helper()
+	setViper(t, "exclude_host", excludeHost)
+	setViper(t, "free_tier", false)
+
+	withHost := func(name MetricName, labels ...string) []string {
+		if hostLabelEnabled(name) {
+			labels = append(labels, "host")
+		}
+		return labels
+	}
+	counters := map[**prometheus.CounterVec][]string{
+		&zoneRequestOriginStatusCountryHost: withHost(zoneRequestOriginStatusCountryHostMetricName, "zone", "account", "status", "country"),
+		&zoneRequestStatusCountryHost:       withHost(zoneRequestStatusCountryHostMetricName, "zone", "account", "status", "country"),
+		&zoneColocationVisits:               withHost(zoneColocationVisitsMetricName, "zone", "account", "colocation"),
+		&zoneColocationEdgeResponseBytes:    withHost(zoneColocationEdgeResponseBytesMetricName, "zone", "account", "colocation"),
+		&zoneColocationRequestsTotal:        withHost(zoneColocationRequestsTotalMetricName, "zone", "account", "colocation"),
+		&zoneCustomerError4xx:               errorMetricLabels(zoneCustomerError4xxRate, zoneCustomerError4xxTotal),
+		&zoneCustomerError5xx:               errorMetricLabels(zoneCustomerError5xxRate, zoneCustomerError5xxTotal),
+		&zoneEdgeError:                      errorMetricLabels(zoneEdgeErrorRate, zoneEdgeErrorsTotal),
+		&zoneOriginError:                    errorMetricLabels(zoneOriginErrorRate, zoneOriginErrorsTotal),
+		&zoneBotRequests:                    withHost(zoneBotRequestsByCountry, "zone", "account", "country", "action"),
+		&zoneFirewallBotsDetected:           withHost(zoneFirewallBotsDetectedSource, "zone", "account", "source", "action"),
+		&zoneFirewallEventsDetailedTotal:    withHost(zoneFirewallEventsDetailedTotalMetricName, "zone", "account", "action", "source"),
+		&zoneBandwidthHostBytesTotal:        withHost(zoneBandwidthHostBytesTotalMetricName, "zone", "account"),
+	}
+	for vec, labels := range counters {
+		prev := *vec
+		t.Cleanup(func() { *vec = prev })
+		*vec = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_host_vec"}, labels)
+	}
+
+	gauges := map[**prometheus.GaugeVec][]string{
+		&zoneEdgeErrorLegacy:        errorMetricLabels(zoneEdgeErrorRate, zoneEdgeErrorsTotal),
+		&zoneOriginResponseDuration: withHost(zoneOriginResponseDurationMsMetricName, "zone", "account", "status", "country"),
+	}
+	for vec, labels := range gauges {
+		prev := *vec
+		t.Cleanup(func() { *vec = prev })
+		*vec = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_host_vec"}, labels)
+	}
+}
+
 // -------- Test: BuildAllMetricsSet --------
 func TestBuildAllMetricsSet(t *testing.T) {
 	metricsSet := BuildAllMetricsSet()
@@ -38,7 +123,7 @@ func TestBuildDeniedMetricsSet_InvalidMetric(t *testing.T) {
 
 // -------- Test: getLabels --------
 func Test_getLabels_WithHost(t *testing.T) {
-	viper.Set("exclude_host", false)
+	setViper(t, "exclude_host", false)
 	base := prometheus.Labels{"zone": "example", "account": "abc"}
 	result := getLabels(base, "test-host")
 
@@ -48,7 +133,7 @@ func Test_getLabels_WithHost(t *testing.T) {
 }
 
 func Test_getLabels_WithoutHost(t *testing.T) {
-	viper.Set("exclude_host", true)
+	setViper(t, "exclude_host", true)
 	base := prometheus.Labels{"zone": "example", "account": "abc"}
 	result := getLabels(base, "test-host")
 
@@ -56,6 +141,18 @@ func Test_getLabels_WithoutHost(t *testing.T) {
 	assert.False(t, exists)
 }
 
+func Test_getLabels_DoesNotMutateBase(t *testing.T) {
+	setViper(t, "exclude_host", false)
+	base := prometheus.Labels{"zone": "example", "account": "abc"}
+
+	first := getLabels(base, "a.example.com")
+	second := getLabels(base, "b.example.com")
+
+	assert.Equal(t, prometheus.Labels{"zone": "example", "account": "abc"}, base)
+	assert.Equal(t, "a.example.com", first["host"])
+	assert.Equal(t, "b.example.com", second["host"])
+}
+
 // -------- Test: MustRegisterMetrics (basic test) --------
 func TestMustRegisterMetrics_NoPanic(t *testing.T) {
 	defer func() {
@@ -66,3 +163,2551 @@ func TestMustRegisterMetrics_NoPanic(t *testing.T) {
 	denied := Set{} // empty set = allow all
 	MustRegisterMetrics(denied)
 }
+
+// -------- Test: rate limit events --------
+func TestAddRateLimitGroups_Decode(t *testing.T) {
+	payload := `{
+		"viewer": {
+			"zones": [{
+				"zoneTag": "zone1",
+				"rateLimitEventsAdaptiveGroups": [
+					{"count": 7, "dimensions": {"action": "block", "ruleId": "rl-1"}},
+					{"count": 3, "dimensions": {"action": "log", "ruleId": "rl-2"}}
+				]
+			}]
+		}
+	}`
+
+	var resp models.CloudflareResponseRateLimitGroups
+	assert.NoError(t, json.Unmarshal([]byte(payload), &resp))
+	assert.Len(t, resp.Viewer.Zones, 1)
+	assert.Len(t, resp.Viewer.Zones[0].RateLimitEventsAdaptiveGroups, 2)
+
+	zoneRateLimitEventsTotal.Reset()
+	addRateLimitGroups(&resp.Viewer.Zones[0], "example.com", "acc")
+
+	assert.Equal(t, float64(7), testutil.ToFloat64(zoneRateLimitEventsTotal.With(prometheus.Labels{
+		"zone": "example.com", "account": "acc", "action": "block", "rule_id": "rl-1",
+	})))
+	assert.Equal(t, float64(3), testutil.ToFloat64(zoneRateLimitEventsTotal.With(prometheus.Labels{
+		"zone": "example.com", "account": "acc", "action": "log", "rule_id": "rl-2",
+	})))
+}
+
+func TestFetchZoneAnalytics_FreeTierSkipsRateLimits(t *testing.T) {
+	setViper(t, "free_tier", true)
+
+	zoneRateLimitEventsTotal.Reset()
+	fetchZoneAnalytics(context.Background(), []cloudflare.Zone{{ID: "zone1", Name: "example.com"}})
+
+	assert.Equal(t, 0, testutil.CollectAndCount(zoneRateLimitEventsTotal))
+}
+
+func TestFetchZoneAnalytics_WaitsOncePerRequest(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"data": {"viewer": {"zones": []}}}`)
+	}))
+	defer srv.Close()
+
+	cloudflareAPI.SetGraphQLEndpoint(srv.URL)
+	defer cloudflareAPI.SetGraphQLEndpoint("")
+
+	setViper(t, "free_tier", false)
+
+	var before dto.Metric
+	assert.NoError(t, limiter.WaitSeconds.Write(&before))
+
+	// Seven zones take two requests, each waiting on the limiter
+	var zones []cloudflare.Zone
+	for i := 0; i < 7; i++ {
+		zones = append(zones, cloudflare.Zone{ID: "zone" + strconv.Itoa(i), Name: "z" + strconv.Itoa(i) + ".example.com"})
+	}
+	fetchZoneAnalytics(context.Background(), zones)
+
+	var after dto.Metric
+	assert.NoError(t, limiter.WaitSeconds.Write(&after))
+	assert.Equal(t, int32(2), requests.Load())
+	assert.Equal(t, uint64(2), after.GetHistogram().GetSampleCount()-before.GetHistogram().GetSampleCount())
+}
+
+func TestFetchZoneAnalytics_FallbackWaitsPerFamily(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"errors": [{"message": "boom"}]}`)
+	}))
+	defer srv.Close()
+
+	cloudflareAPI.SetGraphQLEndpoint(srv.URL)
+	defer cloudflareAPI.SetGraphQLEndpoint("")
+
+	setViper(t, "free_tier", false)
+
+	var before dto.Metric
+	assert.NoError(t, limiter.WaitSeconds.Write(&before))
+
+	// The combined query fails, then every family is queried on its own
+	fetchZoneAnalytics(context.Background(), []cloudflare.Zone{{ID: "zone1", Name: "example.com"}})
+
+	var after dto.Metric
+	assert.NoError(t, limiter.WaitSeconds.Write(&after))
+	assert.Equal(t, int32(1+len(zoneAnalyticsFamilies)), requests.Load())
+	assert.Equal(t, uint64(requests.Load()), after.GetHistogram().GetSampleCount()-before.GetHistogram().GetSampleCount())
+}
+
+// -------- Test: origin status class counters --------
+func TestAddHTTPAdaptiveGroups_OriginStatusClasses(t *testing.T) {
+	payload := `{
+		"zoneTag": "zone1",
+		"httpRequestsAdaptiveGroups": [
+			{"count": 2, "dimensions": {"originResponseStatus": 502, "clientCountryName": "DE"}}
+		],
+		"httpRequestsOriginStatus": [
+			{"count": 90, "dimensions": {"originResponseStatus": 200}},
+			{"count": 5, "dimensions": {"originResponseStatus": 204}},
+			{"count": 3, "dimensions": {"originResponseStatus": 301}},
+			{"count": 2, "dimensions": {"originResponseStatus": 502}}
+		]
+	}`
+
+	var z models.ZoneRespAdaptiveGroups
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	useHostVecs(t, true)
+	zoneOriginRequestsTotal.Reset()
+	addHTTPAdaptiveGroups(&z, "example.com", "acc")
+
+	class := func(c string) float64 {
+		return testutil.ToFloat64(zoneOriginRequestsTotal.With(prometheus.Labels{
+			"zone": "example.com", "account": "acc", "status_class": c,
+		}))
+	}
+	assert.Equal(t, float64(95), class("2xx"))
+	assert.Equal(t, float64(3), class("3xx"))
+	assert.Equal(t, float64(2), class("5xx"))
+
+	// Existing error metrics are still populated from the error groups
+	assert.Equal(t, float64(2), testutil.ToFloat64(zoneCustomerError5xx.With(prometheus.Labels{
+		"zone": "example.com", "account": "acc", "status": "502", "country": "DE",
+	})))
+}
+
+// -------- Test: Argo analytics --------
+func TestAddArgoGroups_Decode(t *testing.T) {
+	payload := `{
+		"viewer": {
+			"zones": [{
+				"zoneTag": "zone1",
+				"argoAnalyticsAdaptiveGroups": [
+					{"count": 30, "avg": {"originResponseDurationMs": 100}, "dimensions": {"smartRouted": 1}},
+					{"count": 10, "avg": {"originResponseDurationMs": 200}, "dimensions": {"smartRouted": 1}},
+					{"count": 20, "avg": {"originResponseDurationMs": 300}, "dimensions": {"smartRouted": 0}}
+				]
+			}]
+		}
+	}`
+
+	var resp models.CloudflareResponseArgo
+	assert.NoError(t, json.Unmarshal([]byte(payload), &resp))
+	assert.Len(t, resp.Viewer.Zones[0].ArgoAnalyticsAdaptiveGroups, 3)
+
+	zoneArgoRequestsTotal.Reset()
+	zoneArgoResponseTimeMs.Reset()
+	zoneArgoResponseTimeImprovementMs.Reset()
+	addArgoGroups(&resp.Viewer.Zones[0], "example.com", "acc")
+
+	// Smart routed requests average 125ms against 300ms for direct ones
+	labels := prometheus.Labels{"zone": "example.com", "account": "acc"}
+	assert.Equal(t, float64(40), testutil.ToFloat64(zoneArgoRequestsTotal.With(labels)))
+	assert.Equal(t, float64(125), testutil.ToFloat64(zoneArgoResponseTimeMs.With(labels)))
+	assert.Equal(t, float64(175), testutil.ToFloat64(zoneArgoResponseTimeImprovementMs.With(labels)))
+
+	// Without direct requests to compare against the improvement is dropped
+	smartOnly := models.ZoneRespArgo{ArgoAnalyticsAdaptiveGroups: resp.Viewer.Zones[0].ArgoAnalyticsAdaptiveGroups[:2]}
+	addArgoGroups(&smartOnly, "example.com", "acc")
+	assert.Equal(t, 0, testutil.CollectAndCount(zoneArgoResponseTimeImprovementMs))
+	assert.Equal(t, float64(125), testutil.ToFloat64(zoneArgoResponseTimeMs.With(labels)))
+}
+
+// -------- Test: Turnstile analytics --------
+func TestAddTurnstileGroups_Decode(t *testing.T) {
+	payload := `{
+		"viewer": {
+			"accounts": [{
+				"turnstileAdaptiveGroups": [
+					{"count": 50, "dimensions": {"siteKey": "0x4AAA", "eventType": "challenge_issued"}},
+					{"count": 42, "dimensions": {"siteKey": "0x4AAA", "eventType": "challenge_solved"}},
+					{"count": 8, "dimensions": {"siteKey": "", "eventType": "challenge_issued"}}
+				]
+			}, {
+				"turnstileAdaptiveGroups": []
+			}]
+		}
+	}`
+
+	var resp models.CloudflareResponseTurnstile
+	assert.NoError(t, json.Unmarshal([]byte(payload), &resp))
+	assert.Len(t, resp.Viewer.Accounts, 2)
+
+	turnstileChallengesTotal.Reset()
+	turnstileSolvesTotal.Reset()
+	for _, acc := range resp.Viewer.Accounts {
+		acc := acc
+		addTurnstileGroups(&acc, "acc")
+	}
+
+	labels := prometheus.Labels{"account": "acc", "sitekey": "0x4AAA"}
+	assert.Equal(t, float64(50), testutil.ToFloat64(turnstileChallengesTotal.With(labels)))
+	assert.Equal(t, float64(42), testutil.ToFloat64(turnstileSolvesTotal.With(labels)))
+	assert.Equal(t, 1, testutil.CollectAndCount(turnstileChallengesTotal))
+}
+
+// -------- Test: Load balancer pool RTT and origin health --------
+func TestAddLoadBalancingRequestsAdaptive_PoolRttAndOriginHealth(t *testing.T) {
+	payload := `{
+		"loadBalancingRequestsAdaptive": [{
+			"lbName": "lb.example.com",
+			"pools": [
+				{"id": "p1", "poolName": "primary", "healthy": 1, "avgRttMs": 42},
+				{"id": "p2", "poolName": "backup", "healthy": 0, "avgRttMs": 120}
+			],
+			"origins": [
+				{"originName": "origin-a", "health": 1, "ipv4": "192.0.2.1", "selected": 1},
+				{"originName": "origin-b", "health": 0, "ipv4": "192.0.2.2", "selected": 0}
+			]
+		}],
+		"zoneTag": "zone1"
+	}`
+
+	var lb models.LbResp
+	assert.NoError(t, json.Unmarshal([]byte(payload), &lb))
+
+	poolHealthStatus.Reset()
+	poolAvgRttMs.Reset()
+	originHealth.Reset()
+	addLoadBalancingRequestsAdaptive(&lb, "example.com", "acc")
+
+	primary := prometheus.Labels{"zone": "example.com", "account": "acc", "load_balancer_name": "lb.example.com", "pool_name": "primary"}
+	backup := prometheus.Labels{"zone": "example.com", "account": "acc", "load_balancer_name": "lb.example.com", "pool_name": "backup"}
+	assert.Equal(t, float64(42), testutil.ToFloat64(poolAvgRttMs.With(primary)))
+	assert.Equal(t, float64(120), testutil.ToFloat64(poolAvgRttMs.With(backup)))
+	assert.Equal(t, float64(0), testutil.ToFloat64(poolHealthStatus.With(backup)))
+
+	originA := prometheus.Labels{"zone": "example.com", "account": "acc", "load_balancer_name": "lb.example.com", "origin_name": "origin-a"}
+	originB := prometheus.Labels{"zone": "example.com", "account": "acc", "load_balancer_name": "lb.example.com", "origin_name": "origin-b"}
+	assert.Equal(t, float64(1), testutil.ToFloat64(originHealth.With(originA)))
+	assert.Equal(t, float64(0), testutil.ToFloat64(originHealth.With(originB)))
+}
+
+// -------- Test: IP class split --------
+func TestAddHTTPGroups_IPClass(t *testing.T) {
+	payload := `{
+		"httpRequests1mGroups": [{
+			"sum": {
+				"requests": 100,
+				"ipClassMap": [
+					{"ipType": "clean", "requests": 80},
+					{"ipType": "badHost", "requests": 15},
+					{"ipType": "tor", "requests": 5}
+				]
+			}
+		}],
+		"zoneTag": "zone1"
+	}`
+
+	var z models.ZoneRespHTTPGroups
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	zoneRequestIPClass.Reset()
+	addHTTPGroups(&z, "example.com", "acc")
+
+	assert.Equal(t, float64(80), testutil.ToFloat64(zoneRequestIPClass.With(prometheus.Labels{"zone": "example.com", "account": "acc", "ip_class": "clean"})))
+	assert.Equal(t, float64(15), testutil.ToFloat64(zoneRequestIPClass.With(prometheus.Labels{"zone": "example.com", "account": "acc", "ip_class": "badHost"})))
+	assert.Equal(t, 3, testutil.CollectAndCount(zoneRequestIPClass))
+}
+
+// -------- Test: client HTTP protocol version --------
+func TestAddHTTPGroups_HTTPVersion(t *testing.T) {
+	payload := `{
+		"httpRequests1mGroups": [{
+			"sum": {
+				"requests": 100,
+				"clientHTTPVersionMap": [
+					{"clientHTTPProtocol": "HTTP/1.1", "requests": 20},
+					{"clientHTTPProtocol": "HTTP/2", "requests": 50},
+					{"clientHTTPProtocol": "HTTP/3", "requests": 30}
+				]
+			}
+		}],
+		"zoneTag": "zone1"
+	}`
+
+	var z models.ZoneRespHTTPGroups
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	zoneRequestHTTPVersion.Reset()
+	addHTTPGroups(&z, "example.com", "acc")
+
+	for version, want := range map[string]float64{"HTTP/1.1": 20, "HTTP/2": 50, "HTTP/3": 30} {
+		got := testutil.ToFloat64(zoneRequestHTTPVersion.With(prometheus.Labels{"zone": "example.com", "account": "acc", "http_version": version}))
+		assert.Equal(t, want, got, version)
+	}
+}
+
+// -------- Test: client SSL/TLS protocol --------
+func TestAddHTTPGroups_SSLProtocol(t *testing.T) {
+	payload := `{
+		"httpRequests1mGroups": [{
+			"sum": {
+				"requests": 100,
+				"clientSSLMap": [
+					{"clientSSLProtocol": "TLSv1.2", "requests": 35},
+					{"clientSSLProtocol": "TLSv1.3", "requests": 60},
+					{"clientSSLProtocol": "none", "requests": 5}
+				]
+			}
+		}],
+		"zoneTag": "zone1"
+	}`
+
+	var z models.ZoneRespHTTPGroups
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+	assert.Equal(t, uint64(60), z.HTTP1mGroups[0].Sum.ClientSSL[1].Requests)
+
+	zoneRequestSSLProtocol.Reset()
+	addHTTPGroups(&z, "example.com", "acc")
+
+	assert.Equal(t, float64(35), testutil.ToFloat64(zoneRequestSSLProtocol.With(prometheus.Labels{"zone": "example.com", "account": "acc", "ssl_protocol": "TLSv1.2"})))
+	assert.Equal(t, float64(60), testutil.ToFloat64(zoneRequestSSLProtocol.With(prometheus.Labels{"zone": "example.com", "account": "acc", "ssl_protocol": "TLSv1.3"})))
+}
+
+// -------- Test: partial batch failure --------
+func TestFetchZoneAnalytics_HTTPFailureKeepsFirewall(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		query := string(body)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(query, "httpRequests1mGroups"):
+			// Both the combined query and the HTTP-only query fail
+			io.WriteString(w, `{"data": null, "errors": [{"message": "httpRequests1mGroups unavailable"}]}`)
+		case strings.Contains(query, "rateLimitEventsAdaptiveGroups"):
+			io.WriteString(w, `{"data": {"viewer": {"zones": []}}}`)
+		case strings.Contains(query, "firewallEventsAdaptiveGroups"):
+			io.WriteString(w, `{"data": {"viewer": {"zones": [{
+				"zoneTag": "zone1",
+				"firewallEventsAdaptiveGroups": [
+					{"count": 7, "dimensions": {"action": "block", "source": "waf", "clientCountryName": "US"}}
+				]
+			}]}}}`)
+		default:
+			io.WriteString(w, `{"data": {"viewer": {"zones": []}}}`)
+		}
+	}))
+	defer srv.Close()
+
+	cloudflareAPI.SetGraphQLEndpoint(srv.URL)
+	defer cloudflareAPI.SetGraphQLEndpoint("")
+
+	zoneFirewallEventsCount.Reset()
+	exporterFetchErrorsTotal.Reset()
+
+	zones := []cloudflare.Zone{{ID: "zone1", Name: "example.com"}}
+	fetchZoneAnalytics(context.Background(), zones)
+
+	assert.Equal(t, float64(7), testutil.ToFloat64(zoneFirewallEventsCount.With(prometheus.Labels{"zone": "example.com", "account": ""})))
+	assert.Equal(t, float64(1), testutil.ToFloat64(exporterFetchErrorsTotal.With(prometheus.Labels{"family": "zone_analytics"})))
+	assert.Equal(t, float64(1), testutil.ToFloat64(exporterFetchErrorsTotal.With(prometheus.Labels{"family": "http"})))
+	assert.Equal(t, float64(0), testutil.ToFloat64(exporterFetchErrorsTotal.With(prometheus.Labels{"family": "firewall"})))
+}
+
+// -------- Test: fetcher errors are counted per family --------
+func TestFetchArgoAnalytics_CountsFetchError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"data": null, "errors": [{"message": "argo unavailable"}]}`)
+	}))
+	defer srv.Close()
+
+	cloudflareAPI.SetGraphQLEndpoint(srv.URL)
+	defer cloudflareAPI.SetGraphQLEndpoint("")
+
+	setViper(t, "free_tier", false)
+
+	exporterFetchErrorsTotal.Reset()
+	before := LastScrapeStatus().FamilyErrors["argo"]
+
+	fetchArgoAnalytics(context.Background(), []cloudflare.Zone{{ID: "zone1", Name: "example.com"}})
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(exporterFetchErrorsTotal.With(prometheus.Labels{"family": "argo"})))
+	assert.Equal(t, before+1, LastScrapeStatus().FamilyErrors["argo"])
+}
+
+// -------- Test: requests by cache status --------
+func TestAddHTTPAdaptiveGroups_CacheStatus(t *testing.T) {
+	payload := `{
+		"zoneTag": "zone1",
+		"httpRequestsCacheStatus": [
+			{"count": 70, "dimensions": {"cacheStatus": "hit"}},
+			{"count": 15, "dimensions": {"cacheStatus": "miss"}},
+			{"count": 5, "dimensions": {"cacheStatus": "expired"}},
+			{"count": 8, "dimensions": {"cacheStatus": "dynamic"}},
+			{"count": 2, "dimensions": {"cacheStatus": "revalidated"}}
+		]
+	}`
+
+	var z models.ZoneRespAnalytics
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	zoneRequestsByCacheStatus.Reset()
+	adaptiveGroups := z.AdaptiveGroups()
+	addHTTPAdaptiveGroups(&adaptiveGroups, "example.com", "acc")
+
+	for status, want := range map[string]float64{"hit": 70, "miss": 15, "expired": 5, "dynamic": 8, "revalidated": 2} {
+		got := testutil.ToFloat64(zoneRequestsByCacheStatus.With(prometheus.Labels{"zone": "example.com", "account": "acc", "cache_status": status}))
+		assert.Equal(t, want, got, status)
+	}
+}
+
+func TestFetchZoneAnalytics_FreeTierSkipsCacheStatus(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(b))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"data": {"viewer": {"zones": []}}}`)
+	}))
+	defer srv.Close()
+
+	cloudflareAPI.SetGraphQLEndpoint(srv.URL)
+	defer cloudflareAPI.SetGraphQLEndpoint("")
+
+	zones := []cloudflare.Zone{{ID: "zone1", Name: "example.com"}}
+
+	// Paid plans select the cache status groups
+	setViper(t, "free_tier", false)
+	fetchZoneAnalytics(context.Background(), zones)
+	mu.Lock()
+	assert.Len(t, bodies, 1)
+	assert.Contains(t, bodies[0], "httpRequestsCacheStatus")
+	assert.Contains(t, bodies[0], "cacheStatus")
+	bodies = nil
+	mu.Unlock()
+
+	// The free tier never sends them
+	setViper(t, "free_tier", true)
+	zoneRequestsByCacheStatus.Reset()
+	fetchZoneAnalytics(context.Background(), zones)
+	mu.Lock()
+	for _, b := range bodies {
+		assert.NotContains(t, b, "httpRequestsCacheStatus")
+		assert.NotContains(t, b, "cacheStatus")
+	}
+	mu.Unlock()
+	assert.Equal(t, 0, testutil.CollectAndCount(zoneRequestsByCacheStatus))
+}
+
+// -------- Test: certificate days until expiry --------
+func TestCertificateDaysUntilExpiry(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, float64(30), certificateDaysUntilExpiry(now.Add(30*24*time.Hour), now))
+	assert.Equal(t, 0.5, certificateDaysUntilExpiry(now.Add(12*time.Hour), now))
+	assert.Equal(t, float64(0), certificateDaysUntilExpiry(now, now))
+	// Already expired certificates report negative days
+	assert.Equal(t, float64(-3), certificateDaysUntilExpiry(now.Add(-72*time.Hour), now))
+}
+
+// -------- Test: Stream analytics --------
+func TestAddStreamGroups_Decode(t *testing.T) {
+	payload := `{
+		"viewer": {
+			"accounts": [{
+				"streamMinutesViewedAdaptiveGroups": [{"sum": {"minutesViewed": 12.5}}],
+				"streamBandwidth": [{"sum": {"egressBytes": 1048576}}]
+			}]
+		}
+	}`
+
+	var resp models.CloudflareResponseStream
+	assert.NoError(t, json.Unmarshal([]byte(payload), &resp))
+
+	streamMinutesViewedTotal.Reset()
+	streamBandwidthBytesTotal.Reset()
+	for _, acc := range resp.Viewer.Accounts {
+		acc := acc
+		addStreamGroups(&acc, "acc")
+	}
+
+	assert.Equal(t, 12.5, testutil.ToFloat64(streamMinutesViewedTotal.With(prometheus.Labels{"account": "acc"})))
+	assert.Equal(t, float64(1048576), testutil.ToFloat64(streamBandwidthBytesTotal.With(prometheus.Labels{"account": "acc"})))
+}
+
+func TestAddStreamGroups_NoUsage(t *testing.T) {
+	var resp models.CloudflareResponseStream
+	assert.NoError(t, json.Unmarshal([]byte(`{"viewer": {"accounts": [{"streamMinutesViewedAdaptiveGroups": [], "streamBandwidth": []}]}}`), &resp))
+
+	streamMinutesViewedTotal.Reset()
+	streamBandwidthBytesTotal.Reset()
+	addStreamGroups(&resp.Viewer.Accounts[0], "acc")
+
+	assert.Equal(t, 0, testutil.CollectAndCount(streamMinutesViewedTotal))
+	assert.Equal(t, 0, testutil.CollectAndCount(streamBandwidthBytesTotal))
+}
+
+// -------- Test: origin response duration aggregation --------
+func TestAddHTTPAdaptiveGroups_OriginDurationWeightedAverage(t *testing.T) {
+	payload := `{
+		"zoneTag": "zone1",
+		"httpRequestsAdaptiveGroups": [
+			{"count": 1, "dimensions": {"originResponseStatus": 502, "clientCountryName": "DE", "clientRequestHTTPHost": "a.example.com"}, "avg": {"originResponseDurationMs": 100}},
+			{"count": 3, "dimensions": {"originResponseStatus": 502, "clientCountryName": "DE", "clientRequestHTTPHost": "b.example.com"}, "avg": {"originResponseDurationMs": 200}}
+		]
+	}`
+
+	var z models.ZoneRespAdaptiveGroups
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	// Without the host label both groups share one series
+	useHostVecs(t, true)
+	addHTTPAdaptiveGroups(&z, "example.com", "acc")
+
+	assert.Equal(t, 1, testutil.CollectAndCount(zoneOriginResponseDuration))
+	assert.Equal(t, float64(175), testutil.ToFloat64(zoneOriginResponseDuration.With(prometheus.Labels{
+		"zone": "example.com", "account": "acc", "status": "502", "country": "DE",
+	})))
+}
+
+// -------- Test: Images analytics --------
+func TestAddImagesGroups_Decode(t *testing.T) {
+	payload := `{
+		"viewer": {
+			"accounts": [{
+				"imagesRequestsAdaptiveGroups": [{"count": 300}, {"count": 20}],
+				"imagesUniqueTransformations": [{"count": 12}]
+			}]
+		}
+	}`
+
+	var resp models.CloudflareResponseImages
+	assert.NoError(t, json.Unmarshal([]byte(payload), &resp))
+
+	imagesRequestsTotal.Reset()
+	imagesTransformationsTotal.Reset()
+	for _, acc := range resp.Viewer.Accounts {
+		acc := acc
+		addImagesGroups(&acc, "acc")
+	}
+
+	assert.Equal(t, float64(320), testutil.ToFloat64(imagesRequestsTotal.With(prometheus.Labels{"account": "acc"})))
+	assert.Equal(t, float64(12), testutil.ToFloat64(imagesTransformationsTotal.With(prometheus.Labels{"account": "acc"})))
+}
+
+func TestAddImagesGroups_Empty(t *testing.T) {
+	var resp models.CloudflareResponseImages
+	assert.NoError(t, json.Unmarshal([]byte(`{"viewer": {"accounts": [{"imagesRequestsAdaptiveGroups": [], "imagesUniqueTransformations": []}]}}`), &resp))
+
+	imagesRequestsTotal.Reset()
+	imagesTransformationsTotal.Reset()
+	addImagesGroups(&resp.Viewer.Accounts[0], "acc")
+
+	assert.Equal(t, 0, testutil.CollectAndCount(imagesRequestsTotal))
+	assert.Equal(t, 0, testutil.CollectAndCount(imagesTransformationsTotal))
+}
+
+// -------- Test: colocation sampling --------
+func TestAddColoGroups_ApplySampling(t *testing.T) {
+	payload := `{
+		"zoneTag": "zone1",
+		"httpRequestsAdaptiveGroups": [
+			{"count": 10, "dimensions": {"coloCode": "FRA"}, "sum": {"visits": 4, "edgeResponseBytes": 1000}, "avg": {"sampleInterval": 10}}
+		]
+	}`
+
+	var z models.ZoneRespColo
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	useHostVecs(t, true)
+	labels := prometheus.Labels{"zone": "example.com", "account": "acc", "colocation": "FRA"}
+
+	// Raw counts when sampling is off
+	setViper(t, "apply_sampling", false)
+	zoneColocationRequestsTotal.Reset()
+	zoneSampleInterval.Reset()
+	addColoGroups(&z, "example.com", "acc")
+	assert.Equal(t, float64(10), testutil.ToFloat64(zoneColocationRequestsTotal.With(labels)))
+	assert.Equal(t, float64(10), testutil.ToFloat64(zoneSampleInterval.With(prometheus.Labels{"zone": "example.com", "account": "acc"})))
+
+	// Estimated totals when sampling is on
+	setViper(t, "apply_sampling", true)
+	zoneColocationRequestsTotal.Reset()
+	addColoGroups(&z, "example.com", "acc")
+	assert.Equal(t, float64(100), testutil.ToFloat64(zoneColocationRequestsTotal.With(labels)))
+}
+
+// -------- Test: Durable Objects and Queues --------
+func TestAddDurableObjectsGroups_Decode(t *testing.T) {
+	payload := `{
+		"viewer": {
+			"accounts": [{
+				"durableObjectsInvocationsAdaptiveGroups": [
+					{"sum": {"requests": 40}, "dimensions": {"namespaceId": "ns-counter"}},
+					{"sum": {"requests": 2}, "dimensions": {"namespaceId": "ns-chat"}}
+				]
+			}]
+		}
+	}`
+
+	var resp models.CloudflareResponseDurableObjects
+	assert.NoError(t, json.Unmarshal([]byte(payload), &resp))
+
+	durableObjectsRequestsTotal.Reset()
+	addDurableObjectsGroups(&resp.Viewer.Accounts[0], "acc", map[string]string{"ns-counter": "COUNTER"})
+
+	assert.Equal(t, float64(40), testutil.ToFloat64(durableObjectsRequestsTotal.With(prometheus.Labels{"account": "acc", "namespace": "COUNTER"})))
+	// Namespaces without a known name keep their ID
+	assert.Equal(t, float64(2), testutil.ToFloat64(durableObjectsRequestsTotal.With(prometheus.Labels{"account": "acc", "namespace": "ns-chat"})))
+}
+
+func TestAddQueueBacklogGroups_Decode(t *testing.T) {
+	payload := `{
+		"viewer": {
+			"accounts": [{
+				"queueBacklogAdaptiveGroups": [
+					{"avg": {"messages": 12.5}, "dimensions": {"queueId": "q-orders"}}
+				]
+			}, {
+				"queueBacklogAdaptiveGroups": []
+			}]
+		}
+	}`
+
+	var resp models.CloudflareResponseQueues
+	assert.NoError(t, json.Unmarshal([]byte(payload), &resp))
+
+	queueBacklogMessages.Reset()
+	for _, acc := range resp.Viewer.Accounts {
+		acc := acc
+		addQueueBacklogGroups(&acc, "acc", nil)
+	}
+
+	assert.Equal(t, 1, testutil.CollectAndCount(queueBacklogMessages))
+	assert.Equal(t, 12.5, testutil.ToFloat64(queueBacklogMessages.With(prometheus.Labels{"account": "acc", "queue": "q-orders"})))
+}
+
+func TestFetchQueueBacklog_ResolvesQueueNames(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	setViper(t, "cf_api_token", "dummy-token")
+
+	httpmock.RegisterRegexpResponder("POST", regexp.MustCompile(`/graphql`),
+		httpmock.NewStringResponder(200, `{"data": {"viewer": {"accounts": [{
+			"queueBacklogAdaptiveGroups": [
+				{"avg": {"messages": 3}, "dimensions": {"queueId": "q-1"}}
+			]
+		}]}}}`))
+	httpmock.RegisterRegexpResponder("GET", regexp.MustCompile(`/accounts/acc1/queues`),
+		httpmock.NewStringResponder(200, `{"success": true, "result": [
+			{"queue_id": "q-1", "queue_name": "orders"}
+		]}`))
+
+	queueBacklogMessages.Reset()
+	fetchQueueBacklog(context.Background(), cloudflare.Account{ID: "acc1", Name: "Acc"})
+
+	assert.Equal(t, float64(3), testutil.ToFloat64(queueBacklogMessages.With(prometheus.Labels{"account": "acc", "queue": "orders"})))
+}
+
+func TestFetchDurableObjectsAnalytics_NotEnabledIsEmpty(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	setViper(t, "cf_api_token", "dummy-token")
+
+	httpmock.RegisterRegexpResponder("POST", regexp.MustCompile(`/graphql`),
+		httpmock.NewStringResponder(200, `{"data": null, "errors": [{"message": "account 'acc1' does not have access to the path"}]}`))
+
+	exporterFetchErrorsTotal.Reset()
+	durableObjectsRequestsTotal.Reset()
+	before := LastScrapeStatus().FamilyErrors["durable_objects"]
+
+	fetchDurableObjectsAnalytics(context.Background(), cloudflare.Account{ID: "acc1", Name: "Acc"})
+
+	assert.Equal(t, 0, testutil.CollectAndCount(exporterFetchErrorsTotal))
+	assert.Equal(t, 0, testutil.CollectAndCount(durableObjectsRequestsTotal))
+	assert.Equal(t, before, LastScrapeStatus().FamilyErrors["durable_objects"])
+}
+
+// -------- Test: overlapping scrapes --------
+func TestStartScrape_SkipsWhileRunning(t *testing.T) {
+	before := testutil.ToFloat64(exporterScrapesSkippedTotal)
+
+	release := make(chan struct{})
+	assert.True(t, StartScrape(func() { <-release }))
+
+	// The slow scrape is still running, so the next tick is skipped
+	assert.False(t, StartScrape(func() { t.Error("overlapping scrape must not run") }))
+	assert.Equal(t, before+1, testutil.ToFloat64(exporterScrapesSkippedTotal))
+
+	close(release)
+	WaitForScrapes()
+
+	ran := make(chan struct{})
+	assert.True(t, StartScrape(func() { close(ran) }))
+	WaitForScrapes()
+	<-ran
+}
+
+// -------- Test: scrape success needs a successful family --------
+func TestMarkScrapeSuccess_RequiresFamilySuccess(t *testing.T) {
+	prev := lastScrapeSuccess.Load()
+	t.Cleanup(func() {
+		lastScrapeSuccess.Store(prev)
+		familySucceeded.Store(false)
+	})
+
+	first := time.Unix(1700000000, 0)
+	familySucceeded.Store(false)
+	lastScrapeSuccess.Store(first.UnixNano())
+
+	// Every family failed, the previous success is kept
+	markScrapeSuccess(first.Add(time.Minute))
+	assert.Equal(t, first, LastScrapeSuccess())
+
+	markFamilySuccess("http")
+	markScrapeSuccess(first.Add(2 * time.Minute))
+	assert.Equal(t, first.Add(2*time.Minute), LastScrapeSuccess())
+}
+
+// -------- Test: bot score buckets --------
+func TestAddBotScoreGroups_Decode(t *testing.T) {
+	payload := `{
+		"viewer": {
+			"zones": [{
+				"zoneTag": "zone1",
+				"httpRequestsBotScore": [
+					{"count": 10, "dimensions": {"botScore": 1}},
+					{"count": 5, "dimensions": {"botScore": 29}},
+					{"count": 80, "dimensions": {"botScore": 30}},
+					{"count": 3, "dimensions": {"botScore": 99}},
+					{"count": 2, "dimensions": {"botScore": 0}}
+				]
+			}]
+		}
+	}`
+
+	var resp models.CloudflareResponseBotScore
+	assert.NoError(t, json.Unmarshal([]byte(payload), &resp))
+
+	zoneBotScoreRequestsTotal.Reset()
+	addBotScoreGroups(&resp.Viewer.Zones[0], "example.com", "acc")
+
+	bucket := func(b string) float64 {
+		return testutil.ToFloat64(zoneBotScoreRequestsTotal.With(prometheus.Labels{"zone": "example.com", "account": "acc", "score_bucket": b}))
+	}
+	assert.Equal(t, float64(15), bucket("1-29"))
+	assert.Equal(t, float64(83), bucket("30-99"))
+	assert.Equal(t, float64(2), bucket("unscored"))
+}
+
+func TestFetchBotScore_FreeTierSkips(t *testing.T) {
+	setViper(t, "free_tier", true)
+
+	zoneBotScoreRequestsTotal.Reset()
+	fetchBotScore(context.Background(), []cloudflare.Zone{{ID: "zone1", Name: "example.com"}})
+
+	assert.Equal(t, 0, testutil.CollectAndCount(zoneBotScoreRequestsTotal))
+}
+
+// -------- Test: cf_max_zones cap --------
+func TestCapZones(t *testing.T) {
+	zones := []cloudflare.Zone{{ID: "z1"}, {ID: "z2"}, {ID: "z3"}, {ID: "z4"}, {ID: "z5"}}
+	ids := func(zs []cloudflare.Zone) []string {
+		return cloudflareAPI.ExtractZoneIDs(zs)
+	}
+
+	// No cap when unset or above the zone count
+	assert.Len(t, capZones(zones, 0, false), 5)
+	assert.Len(t, capZones(zones, 10, false), 5)
+
+	// Truncates at the configured limit
+	assert.Equal(t, []string{"z1", "z2"}, ids(capZones(zones, 2, false)))
+
+	// Rotation covers every zone across scrapes
+	zoneRotationOffset = 0
+	assert.Equal(t, []string{"z1", "z2"}, ids(capZones(zones, 2, true)))
+	assert.Equal(t, []string{"z3", "z4"}, ids(capZones(zones, 2, true)))
+	assert.Equal(t, []string{"z5", "z1"}, ids(capZones(zones, 2, true)))
+}
+
+// -------- Test: request method --------
+func TestAddHTTPAdaptiveGroups_RequestMethod(t *testing.T) {
+	payload := `{
+		"zoneTag": "zone1",
+		"firewallEventsAdaptiveGroups": [
+			{"count": 9, "dimensions": {"action": "block", "clientRequestHTTPHost": "www.example.com"}}
+		],
+		"httpRequestsMethod": [
+			{"count": 120, "dimensions": {"clientRequestHTTPMethodName": "GET"}},
+			{"count": 30, "dimensions": {"clientRequestHTTPMethodName": "POST"}}
+		]
+	}`
+
+	var z models.ZoneRespAnalytics
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	zoneRequestMethod.Reset()
+	httpGroups := z.HTTPGroups()
+	addHTTPGroups(&httpGroups, "example.com", "acc")
+	adaptiveGroups := z.AdaptiveGroups()
+	addHTTPAdaptiveGroups(&adaptiveGroups, "example.com", "acc")
+
+	method := func(m string) float64 {
+		return testutil.ToFloat64(zoneRequestMethod.With(prometheus.Labels{"zone": "example.com", "account": "acc", "method": m}))
+	}
+	assert.Equal(t, float64(120), method("GET"))
+	assert.Equal(t, float64(30), method("POST"))
+	// The host is no longer reported as a method
+	assert.Equal(t, 2, testutil.CollectAndCount(zoneRequestMethod))
+}
+
+// -------- Test: threats by type and country --------
+func TestAddFirewallGroups_ThreatsTypeCountry(t *testing.T) {
+	payload := `{
+		"zoneTag": "zone1",
+		"firewallEventsAdaptiveGroups": [
+			{"count": 5, "dimensions": {"action": "block", "source": "waf", "clientCountryName": "US"}},
+			{"count": 3, "dimensions": {"action": "managed_challenge", "source": "waf", "clientCountryName": "US"}},
+			{"count": 2, "dimensions": {"action": "block", "source": "firewallrules", "clientCountryName": "DE"}},
+			{"count": 7, "dimensions": {"action": "log", "source": "waf", "clientCountryName": "FR"}}
+		]
+	}`
+
+	var z models.ZoneRespFirewallGroups
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	zoneThreatsTypeCountryTotal.Reset()
+	addFirewallGroups(&z, "example.com", "acc")
+
+	threats := func(typ, country string) float64 {
+		return testutil.ToFloat64(zoneThreatsTypeCountryTotal.With(prometheus.Labels{"zone": "example.com", "account": "acc", "type": typ, "country": country}))
+	}
+	assert.Equal(t, float64(8), threats("waf", "US"))
+	assert.Equal(t, float64(2), threats("firewallrules", "DE"))
+	// Logged events are not threats
+	assert.Equal(t, 2, testutil.CollectAndCount(zoneThreatsTypeCountryTotal))
+}
+
+// -------- Test: zone plan filter --------
+func TestFilterZonesByPlan(t *testing.T) {
+	zone := func(id, legacyID, name string) cloudflare.Zone {
+		z := cloudflare.Zone{ID: id}
+		z.Plan.LegacyID = legacyID
+		z.Plan.ZonePlanCommon.Name = name
+		return z
+	}
+	zones := []cloudflare.Zone{
+		zone("z1", "free", "Free Website"),
+		zone("z2", "pro", "Pro Website"),
+		zone("z3", "business", "Business Website"),
+		zone("z4", "enterprise", "Enterprise Website"),
+		zone("z5", "", "Enterprise Website"),
+	}
+	ids := func(zs []cloudflare.Zone) []string {
+		return cloudflareAPI.ExtractZoneIDs(zs)
+	}
+
+	// Unset keeps every zone
+	assert.Len(t, filterZonesByPlan(zones, nil), 5)
+
+	// Enterprise only, matching legacy ID or plan name
+	assert.Equal(t, []string{"z4", "z5"}, ids(filterZonesByPlan(zones, []string{"enterprise"})))
+
+	// Business and enterprise
+	setViper(t, "cf_zone_plans", " Enterprise, business ")
+	assert.Equal(t, []string{"z3", "z4", "z5"}, ids(filterZonesByPlan(zones, getZonePlans())))
+}
+
+// -------- Test: build info --------
+func TestSetBuildInfo(t *testing.T) {
+	setViper(t, "free_tier", true)
+	setViper(t, "exclude_host", false)
+	setViper(t, "cf_batch_size", 7)
+
+	setBuildInfo()
+
+	assert.Equal(t, 1, testutil.CollectAndCount(exporterBuildInfo))
+	assert.Equal(t, float64(1), testutil.ToFloat64(exporterBuildInfo.With(prometheus.Labels{
+		"version":      Version,
+		"go_version":   runtime.Version(),
+		"free_tier":    "true",
+		"exclude_host": "false",
+		"batch_size":   "7",
+	})))
+}
+
+// -------- Test: batches split by scrape delay --------
+func TestBatchZones_GroupsByScrapeDelay(t *testing.T) {
+	assert.NoError(t, cloudflareAPI.SetZoneScrapeDelays("z2=900,z4=900"))
+	defer cloudflareAPI.SetZoneScrapeDelays("")
+
+	zones := []cloudflare.Zone{{ID: "z1"}, {ID: "z2"}, {ID: "z3"}, {ID: "z4"}, {ID: "z5"}}
+
+	var got [][]string
+	for _, b := range batchZones(zones, 2) {
+		got = append(got, cloudflareAPI.ExtractZoneIDs(b))
+	}
+	assert.Equal(t, [][]string{{"z1", "z3"}, {"z5"}, {"z2", "z4"}}, got)
+}
+
+// -------- Test: detailed firewall events --------
+func TestAddFirewallGroups_EventsDetailed(t *testing.T) {
+	useHostVecs(t, true)
+
+	payload := `{
+		"zoneTag": "zone1",
+		"firewallEventsAdaptiveGroups": [
+			{"count": 5, "dimensions": {"action": "block", "source": "waf", "clientRequestHTTPHost": "www.example.com"}},
+			{"count": 2, "dimensions": {"action": "block", "source": "waf", "clientRequestHTTPHost": "www.example.com", "clientCountryName": "DE"}},
+			{"count": 3, "dimensions": {"action": "managed_challenge", "source": "firewallrules", "clientRequestHTTPHost": "api.example.com"}},
+			{"count": 4, "dimensions": {"action": "log", "source": "waf", "clientRequestHTTPHost": "api.example.com"}}
+		]
+	}`
+
+	var z models.ZoneRespFirewallGroups
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+	addFirewallGroups(&z, "example.com", "acc")
+
+	events := func(action, source string) float64 {
+		return testutil.ToFloat64(zoneFirewallEventsDetailedTotal.With(prometheus.Labels{
+			"zone": "example.com", "account": "acc", "action": action, "source": source,
+		}))
+	}
+	assert.Equal(t, float64(7), events("block", "waf"))
+	assert.Equal(t, float64(3), events("managed_challenge", "firewallrules"))
+	assert.Equal(t, float64(4), events("log", "waf"))
+	assert.Equal(t, 3, testutil.CollectAndCount(zoneFirewallEventsDetailedTotal))
+}
+
+// -------- Test: /snapshot after a scrape --------
+func TestSnapshot_AfterScrape(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"data": {"viewer": {"zones": [{
+			"zoneTag": "zone1",
+			"httpRequests1mGroups": [{"sum": {
+				"requests": 200, "bytes": 4096, "threats": 3,
+				"responseStatusMap": [
+					{"edgeResponseStatus": 200, "requests": 180},
+					{"edgeResponseStatus": 404, "requests": 10},
+					{"edgeResponseStatus": 502, "requests": 10}
+				]
+			}}]
+		}]}}}`)
+	}))
+	defer srv.Close()
+
+	cloudflareAPI.SetGraphQLEndpoint(srv.URL)
+	defer cloudflareAPI.SetGraphQLEndpoint("")
+
+	resetSnapshot()
+	fetchZoneAnalytics(context.Background(), []cloudflare.Zone{{ID: "zone1", Name: "example.com"}})
+	publishSnapshot(time.Now())
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/snapshot", middlewares.BearerAuth("secret"), handlers.Snapshot(func() interface{} {
+		return LastSnapshot()
+	}))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/snapshot", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	req.Header.Set("Authorization", "Bearer secret")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var got Snapshot
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, []ZoneSnapshot{{
+		Zone:           "example.com",
+		Requests:       200,
+		BandwidthBytes: 4096,
+		Threats:        3,
+		ErrorRate4xx:   0.05,
+		ErrorRate5xx:   0.05,
+	}}, got.Zones)
+}
+
+// -------- Test: account filter --------
+func TestFilterAccounts(t *testing.T) {
+	accounts := []cloudflare.Account{{ID: "a1"}, {ID: "a2"}, {ID: "a3"}}
+	ids := func(as []cloudflare.Account) []string {
+		var out []string
+		for _, a := range as {
+			out = append(out, a.ID)
+		}
+		return out
+	}
+
+	// No filters keeps every account
+	assert.Equal(t, []string{"a1", "a2", "a3"}, ids(filterAccounts(accounts, nil, nil)))
+
+	// Include only the listed accounts
+	setViper(t, "cf_accounts", "a1,a3")
+	assert.Equal(t, []string{"a1", "a3"}, ids(filterAccounts(accounts, getTargetAccounts(), getExcludedAccounts())))
+
+	// Exclude wins over include
+	setViper(t, "cf_exclude_accounts", "a3")
+	assert.Equal(t, []string{"a1"}, ids(filterAccounts(accounts, getTargetAccounts(), getExcludedAccounts())))
+
+	// Exclude alone
+	setViper(t, "cf_accounts", "")
+	assert.Equal(t, []string{"a1", "a2"}, ids(filterAccounts(accounts, getTargetAccounts(), getExcludedAccounts())))
+}
+
+// -------- Test: Page Shield scripts --------
+func TestFetchPageShield_PaginatesAndCountsViolations(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "free_tier", false)
+
+	httpmock.RegisterRegexpResponder("GET", regexp.MustCompile(`/zones/zone1/page_shield/scripts`),
+		func(req *http.Request) (*http.Response, error) {
+			if req.URL.Query().Get("page") == "2" {
+				return httpmock.NewStringResponse(200, `{"success": true, "result": [
+					{"id": "s3", "url": "https://evil.example/x.js", "url_reported_malicious": true}
+				], "result_info": {"page": 2, "total_pages": 2}}`), nil
+			}
+			return httpmock.NewStringResponse(200, `{"success": true, "result": [
+				{"id": "s1", "url": "https://cdn.example.com/app.js"},
+				{"id": "s2", "url": "https://bad.example/y.js", "domain_reported_malicious": true}
+			], "result_info": {"page": 1, "total_pages": 2}}`), nil
+		})
+
+	zonePageShieldScripts.Reset()
+	zonePageShieldViolationsTotal.Reset()
+
+	fetchPageShield(context.Background(), []cloudflare.Zone{{ID: "zone1", Name: "example.com"}})
+
+	labels := prometheus.Labels{"zone": "example.com", "account": ""}
+	assert.Equal(t, float64(3), testutil.ToFloat64(zonePageShieldScripts.With(labels)))
+	assert.Equal(t, float64(2), testutil.ToFloat64(zonePageShieldViolationsTotal.With(labels)))
+}
+
+// -------- Test: renamed error counters keep the deprecated name --------
+func TestRenamedCounter_EmitsOldAndNewNames(t *testing.T) {
+	labels := []string{"zone", "account", "status", "country"}
+	vec := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: zoneOriginErrorsTotal.String(),
+		Help: "Number of origin 4xx and 5xx responses",
+	}, labels)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(newRenamedCounter(vec, zoneOriginErrorRate, zoneOriginErrorsTotal, prometheus.CounterValue, labels))
+
+	vec.With(prometheus.Labels{"zone": "example.com", "account": "acc", "status": "502", "country": "DE"}).Add(4)
+
+	expected := `
+# HELP cloudflare_zone_origin_error_rate Deprecated: use cloudflare_zone_origin_errors_total
+# TYPE cloudflare_zone_origin_error_rate counter
+cloudflare_zone_origin_error_rate{account="acc",country="DE",status="502",zone="example.com"} 4
+# HELP cloudflare_zone_origin_errors_total Number of origin 4xx and 5xx responses
+# TYPE cloudflare_zone_origin_errors_total counter
+cloudflare_zone_origin_errors_total{account="acc",country="DE",status="502",zone="example.com"} 4
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected)))
+}
+
+// -------- Test: deprecated edge error gauge counts groups --------
+func TestAddHTTPRequestsEdgeCountryHost_LegacyCountsGroups(t *testing.T) {
+	useHostVecs(t, true)
+
+	payload := `{
+		"httpRequestsEdgeCountryHost": [
+			{"count": 7, "dimensions": {"edgeResponseStatus": 502, "clientCountryName": "DE", "clientRequestHTTPHost": "a.example.com"}},
+			{"count": 3, "dimensions": {"edgeResponseStatus": 502, "clientCountryName": "DE", "clientRequestHTTPHost": "b.example.com"}},
+			{"count": 50, "dimensions": {"edgeResponseStatus": 200, "clientCountryName": "DE", "clientRequestHTTPHost": "a.example.com"}}
+		]
+	}`
+	var z models.ZoneRespHTTPRequestsEdge
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	addHTTPRequestsEdgeCountryHost(&z, "example.com", "acc")
+
+	series := prometheus.Labels{"zone": "example.com", "account": "acc", "status": "502", "country": "DE"}
+	assert.Equal(t, 10.0, testutil.ToFloat64(zoneEdgeError.With(series)))
+	assert.Equal(t, 2.0, testutil.ToFloat64(zoneEdgeErrorLegacy.With(series)))
+}
+
+// -------- Test: colo allowlist --------
+func TestAddColoGroups_ColoAllowlist(t *testing.T) {
+	payload := `{
+		"zoneTag": "zone1",
+		"httpRequestsAdaptiveGroups": [
+			{"count": 10, "dimensions": {"coloCode": "FRA"}},
+			{"count": 20, "dimensions": {"coloCode": "LAX"}},
+			{"count": 30, "dimensions": {"coloCode": "SIN"}}
+		]
+	}`
+
+	var z models.ZoneRespColo
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	useHostVecs(t, true)
+	setViper(t, "cf_colos", "lax, FRA")
+	addColoGroups(&z, "example.com", "acc")
+
+	colo := func(c string) float64 {
+		return testutil.ToFloat64(zoneColocationRequestsTotal.With(prometheus.Labels{"zone": "example.com", "account": "acc", "colocation": c}))
+	}
+	assert.Equal(t, float64(10), colo("FRA"))
+	assert.Equal(t, float64(20), colo("LAX"))
+	assert.Equal(t, 2, testutil.CollectAndCount(zoneColocationRequestsTotal))
+}
+
+// -------- Test: Magic Transit per-tunnel health --------
+func TestAddMagicTransitTunnelHealth(t *testing.T) {
+	payload := `{
+		"magicTransitTunnelHealthChecksAdaptiveGroups": [
+			{"count": 1, "dimensions": {"tunnelName": "tun-a", "siteName": "dc1", "edgePopName": "fra01", "resultStatus": "healthy", "datetime": "2026-01-01T00:00:00Z"}},
+			{"count": 1, "dimensions": {"tunnelName": "tun-b", "siteName": "dc2", "edgePopName": "lax01", "resultStatus": "healthy", "datetime": "2026-01-01T00:00:00Z"}},
+			{"count": 1, "dimensions": {"tunnelName": "tun-b", "siteName": "dc2", "edgePopName": "lax01", "resultStatus": "unhealthy", "datetime": "2026-01-01T00:00:30Z"}}
+		]
+	}`
+
+	var acc models.MagicTransitAccount
+	assert.NoError(t, json.Unmarshal([]byte(payload), &acc))
+
+	magicTransitTunnelHealth.Reset()
+	addMagicTransitTunnelHealth(&acc, "acc")
+
+	health := func(tunnel, site, colo string) float64 {
+		return testutil.ToFloat64(magicTransitTunnelHealth.With(prometheus.Labels{
+			"account": "acc", "tunnel_name": tunnel, "site_name": site, "edge_colo": colo,
+		}))
+	}
+	assert.Equal(t, float64(1), health("tun-a", "dc1", "fra01"))
+	// The latest check wins
+	assert.Equal(t, float64(0), health("tun-b", "dc2", "lax01"))
+	assert.Equal(t, 2, testutil.CollectAndCount(magicTransitTunnelHealth))
+}
+
+// -------- Test: truncated query results --------
+func TestFetchZoneColocationAnalytics_WarnsWhenTruncated(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"data": {"viewer": {"zones": [{
+			"zoneTag": "zone1",
+			"httpRequestsAdaptiveGroups": [
+				{"count": 10, "dimensions": {"coloCode": "FRA"}},
+				{"count": 20, "dimensions": {"coloCode": "LAX"}}
+			]
+		}]}}}`)
+	}))
+	defer srv.Close()
+
+	cloudflareAPI.SetGraphQLEndpoint(srv.URL)
+	defer cloudflareAPI.SetGraphQLEndpoint("")
+
+	useHostVecs(t, true)
+	setViper(t, "cf_query_limit_colo", 2)
+	exporterQueryTruncatedTotal.Reset()
+
+	zones := []cloudflare.Zone{{ID: "zone1", Name: "example.com"}}
+	fetchZoneColocationAnalytics(context.Background(), zones)
+	assert.Equal(t, float64(1), testutil.ToFloat64(exporterQueryTruncatedTotal.With(prometheus.Labels{"query": "colo"})))
+
+	// Below the limit nothing is counted
+	setViper(t, "cf_query_limit_colo", 3)
+	fetchZoneColocationAnalytics(context.Background(), zones)
+	assert.Equal(t, float64(1), testutil.ToFloat64(exporterQueryTruncatedTotal.With(prometheus.Labels{"query": "colo"})))
+}
+
+// -------- Test: OTLP push --------
+func TestOTLPPusher_PushesRegisteredMetrics(t *testing.T) {
+	received := make(chan *colmetricpb.ExportMetricsServiceRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req colmetricpb.ExportMetricsServiceRequest
+		if err := proto.Unmarshal(body, &req); err == nil && r.URL.Path == "/v1/metrics" {
+			received <- &req
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	reg := prometheus.NewRegistry()
+	requests := prometheus.NewCounter(prometheus.CounterOpts{Name: "cloudflare_test_requests_total", Help: "test"})
+	reg.MustRegister(requests)
+	requests.Add(3)
+
+	pusher, err := NewOTLPPusher(context.Background(), srv.URL+"/v1/metrics", reg)
+	assert.NoError(t, err)
+	defer pusher.Shutdown(context.Background())
+
+	assert.NoError(t, pusher.Push(context.Background()))
+
+	select {
+	case req := <-received:
+		var names []string
+		for _, rm := range req.GetResourceMetrics() {
+			for _, sm := range rm.GetScopeMetrics() {
+				for _, m := range sm.GetMetrics() {
+					names = append(names, m.GetName())
+				}
+			}
+		}
+		assert.Contains(t, names, "cloudflare_test_requests_total")
+	default:
+		t.Fatal("no OTLP export received")
+	}
+}
+
+// -------- Test: Pushgateway push --------
+func TestPushGateway_SendsMetricsAndHeaders(t *testing.T) {
+	type pushed struct {
+		method, path, auth string
+		families           map[string]*dto.MetricFamily
+	}
+	received := make(chan pushed, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		families := map[string]*dto.MetricFamily{}
+		dec := expfmt.NewDecoder(r.Body, expfmt.ResponseFormat(r.Header))
+		for {
+			mf := &dto.MetricFamily{}
+			if err := dec.Decode(mf); err != nil {
+				break
+			}
+			families[mf.GetName()] = mf
+		}
+		received <- pushed{r.Method, r.URL.Path, r.Header.Get("Authorization"), families}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	reg := prometheus.NewRegistry()
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "cloudflare_test_requests_total", Help: "test"}, []string{"zone"})
+	reg.MustRegister(requests)
+	requests.WithLabelValues("example.com").Add(7)
+
+	header, err := ParsePushHeaders("Authorization=Bearer secret")
+	assert.NoError(t, err)
+	assert.NoError(t, PushGateway(context.Background(), srv.URL, "cloudflare_exporter", header, reg))
+
+	got := <-received
+	assert.Equal(t, http.MethodPut, got.method)
+	assert.Equal(t, "/metrics/job/cloudflare_exporter", got.path)
+	assert.Equal(t, "Bearer secret", got.auth)
+	mf, ok := got.families["cloudflare_test_requests_total"]
+	if assert.True(t, ok) && assert.Len(t, mf.GetMetric(), 1) {
+		m := mf.GetMetric()[0]
+		assert.Equal(t, 7.0, m.GetCounter().GetValue())
+		assert.Equal(t, "zone", m.GetLabel()[0].GetName())
+		assert.Equal(t, "example.com", m.GetLabel()[0].GetValue())
+	}
+}
+
+// -------- Test: remote write push --------
+func TestRemoteWrite_SendsSnappyWriteRequest(t *testing.T) {
+	var body []byte
+	var encoding string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		compressed, _ := io.ReadAll(r.Body)
+		body, _ = snappy.Decode(nil, compressed)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	reg := prometheus.NewRegistry()
+	up := prometheus.NewGauge(prometheus.GaugeOpts{Name: "cloudflare_test_up", Help: "test"})
+	reg.MustRegister(up)
+	up.Set(1)
+
+	assert.NoError(t, RemoteWrite(context.Background(), srv.URL, http.Header{}, reg))
+	assert.Equal(t, "snappy", encoding)
+
+	// WriteRequest.timeseries(1) -> TimeSeries{labels(1), samples(2)}
+	_, _, series := protowire.ConsumeField(body)
+	assert.Equal(t, len(body), series, "expected a single time series")
+	_, _, n := protowire.ConsumeTag(body)
+	ts, _ := protowire.ConsumeBytes(body[n:])
+	labels := map[string]string{}
+	var value float64
+	for len(ts) > 0 {
+		num, _, n := protowire.ConsumeTag(ts)
+		msg, m := protowire.ConsumeBytes(ts[n:])
+		ts = ts[n+m:]
+		switch num {
+		case 1:
+			_, _, n = protowire.ConsumeTag(msg)
+			name, m := protowire.ConsumeString(msg[n:])
+			_, _, k := protowire.ConsumeTag(msg[n+m:])
+			val, _ := protowire.ConsumeString(msg[n+m+k:])
+			labels[name] = val
+		case 2:
+			_, _, n = protowire.ConsumeTag(msg)
+			bits, _ := protowire.ConsumeFixed64(msg[n:])
+			value = math.Float64frombits(bits)
+		}
+	}
+	assert.Equal(t, map[string]string{"__name__": "cloudflare_test_up"}, labels)
+	assert.Equal(t, 1.0, value)
+}
+
+func TestParsePushHeaders_Invalid(t *testing.T) {
+	_, err := ParsePushHeaders("Authorization")
+	assert.Error(t, err)
+}
+
+// -------- Test: stale zone series reset --------
+func TestResetStaleZones_DeletesRemovedZoneSeries(t *testing.T) {
+	vec := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "cloudflare_test_stale_requests_total", Help: "test"}, []string{"zone", "account"})
+	mustRegister(vec)
+	defer prometheus.Unregister(vec)
+
+	staleMu.Lock()
+	knownZones = nil
+	staleMu.Unlock()
+	setViper(t, "reset_stale_metrics", true)
+
+	vec.WithLabelValues("a.com", "acct").Add(1)
+	vec.WithLabelValues("b.com", "acct").Add(2)
+	resetStaleZones([]cloudflare.Zone{{Name: "a.com"}, {Name: "b.com"}})
+	assert.Equal(t, 2, testutil.CollectAndCount(vec))
+
+	resetStaleZones([]cloudflare.Zone{{Name: "a.com"}})
+	assert.Equal(t, 1, testutil.CollectAndCount(vec))
+	assert.Equal(t, 1.0, testutil.ToFloat64(vec.WithLabelValues("a.com", "acct")))
+
+	// Disabled: series of removed zones are kept
+	setViper(t, "reset_stale_metrics", false)
+	resetStaleZones(nil)
+	assert.Equal(t, 1, testutil.CollectAndCount(vec))
+}
+
+// -------- Test: per-host bandwidth --------
+func TestAddHTTPAdaptiveGroups_HostBandwidthTopN(t *testing.T) {
+	payload := `{
+		"zoneTag": "zone1",
+		"httpRequestsHostBytes": [
+			{"sum": {"edgeResponseBytes": 5000}, "dimensions": {"clientRequestHTTPHost": "www.example.com"}},
+			{"sum": {"edgeResponseBytes": 3000}, "dimensions": {"clientRequestHTTPHost": "api.example.com"}},
+			{"sum": {"edgeResponseBytes": 200}, "dimensions": {"clientRequestHTTPHost": "a.example.com"}},
+			{"sum": {"edgeResponseBytes": 100}, "dimensions": {"clientRequestHTTPHost": "b.example.com"}}
+		]
+	}`
+
+	var z models.ZoneRespAnalytics
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+	adaptiveGroups := z.AdaptiveGroups()
+	assert.Len(t, adaptiveGroups.HTTPRequestsHostBytes, 4)
+
+	useHostVecs(t, false)
+	setViper(t, "cf_host_top_n", 2)
+
+	addHTTPAdaptiveGroups(&adaptiveGroups, "example.com", "acc")
+
+	host := func(h string) float64 {
+		return testutil.ToFloat64(zoneBandwidthHostBytesTotal.With(prometheus.Labels{
+			"zone": "example.com", "account": "acc", "host": h,
+		}))
+	}
+	assert.Equal(t, 3, testutil.CollectAndCount(zoneBandwidthHostBytesTotal))
+	assert.Equal(t, float64(5000), host("www.example.com"))
+	assert.Equal(t, float64(3000), host("api.example.com"))
+	assert.Equal(t, float64(300), host("other"))
+}
+
+// -------- Test: unknown zone tags are skipped --------
+func TestFetchZoneAnalytics_SkipsUnknownZoneTag(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"data": {"viewer": {"zones": [
+			{"zoneTag": "zone1", "firewallEventsAdaptiveGroups": [
+				{"count": 4, "dimensions": {"action": "block", "source": "waf", "clientCountryName": "US"}}
+			]},
+			{"zoneTag": "ghost", "firewallEventsAdaptiveGroups": [
+				{"count": 9, "dimensions": {"action": "block", "source": "waf", "clientCountryName": "US"}}
+			]}
+		]}}}`)
+	}))
+	defer srv.Close()
+
+	cloudflareAPI.SetGraphQLEndpoint(srv.URL)
+	defer cloudflareAPI.SetGraphQLEndpoint("")
+
+	zoneFirewallEventsCount.Reset()
+
+	zones := []cloudflare.Zone{{ID: "zone1", Name: "example.com"}}
+	fetchZoneAnalytics(context.Background(), zones)
+
+	assert.Equal(t, 1, testutil.CollectAndCount(zoneFirewallEventsCount))
+	assert.Equal(t, float64(4), testutil.ToFloat64(zoneFirewallEventsCount.With(prometheus.Labels{"zone": "example.com", "account": ""})))
+	assert.Equal(t, 0, zoneFirewallEventsCount.DeletePartialMatch(prometheus.Labels{"zone": ""}))
+}
+
+// -------- Test: available metrics endpoint --------
+func TestAvailableMetricsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/metrics/available", handlers.AvailableMetrics(func() []string {
+		return BuildAllMetricsSet().Names()
+	}))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/metrics/available", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var got struct {
+		Metrics []string `json:"metrics"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Contains(t, got.Metrics, "cloudflare_zone_requests_total")
+	assert.Contains(t, got.Metrics, "cloudflare_zone_bandwidth_total")
+	assert.True(t, sort.StringsAreSorted(got.Metrics))
+	assert.Len(t, got.Metrics, len(BuildAllMetricsSet()))
+}
+
+// -------- Test: firewall events by kind --------
+func TestAddFirewallGroups_EventsByKind(t *testing.T) {
+	payload := `{
+		"zoneTag": "zone1",
+		"firewallEventsAdaptiveGroups": [
+			{"count": 6, "dimensions": {"action": "block", "source": "waf", "clientCountryName": "US"}}
+		],
+		"firewallEventsByKind": [
+			{"count": 4, "dimensions": {"kind": "firewall"}},
+			{"count": 2, "dimensions": {"kind": "l7ddos"}},
+			{"count": 1, "dimensions": {"kind": "sanitized"}}
+		]
+	}`
+
+	var z models.ZoneRespAnalytics
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+	firewallGroups := z.FirewallGroups()
+	assert.Len(t, firewallGroups.FirewallEventsByKind, 3)
+
+	zoneFirewallEventsByKindTotal.Reset()
+	addFirewallGroups(&firewallGroups, "example.com", "acc")
+
+	kind := func(k string) float64 {
+		return testutil.ToFloat64(zoneFirewallEventsByKindTotal.With(prometheus.Labels{
+			"zone": "example.com", "account": "acc", "kind": k,
+		}))
+	}
+	assert.Equal(t, 3, testutil.CollectAndCount(zoneFirewallEventsByKindTotal))
+	assert.Equal(t, float64(4), kind("firewall"))
+	assert.Equal(t, float64(2), kind("l7ddos"))
+	assert.Equal(t, float64(1), kind("sanitized"))
+}
+
+// -------- Test: account_type label fallback --------
+func TestAccountLabels_EmptyTypeFallback(t *testing.T) {
+	setViper(t, "account_type_label", true)
+	setViper(t, "account_type_fallback", "standard")
+
+	vec := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "cloudflare_test_account_gauge"}, accountMetricLabels())
+	vec.With(accountLabels(cloudflare.Account{Name: "acme"}, nil)).Set(1)
+	vec.With(accountLabels(cloudflare.Account{Name: "corp", Type: "enterprise"}, nil)).Set(2)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(vec.With(prometheus.Labels{"account": "acme", "account_type": "standard"})))
+	assert.Equal(t, float64(2), testutil.ToFloat64(vec.With(prometheus.Labels{"account": "corp", "account_type": "enterprise"})))
+
+	// Disabled: the label is dropped entirely
+	setViper(t, "account_type_label", false)
+	assert.Equal(t, []string{"account", "job_id"}, accountMetricLabels("job_id"))
+	assert.Equal(t, prometheus.Labels{"account": "acme", "job_id": "1"}, accountLabels(cloudflare.Account{Name: "acme"}, prometheus.Labels{"job_id": "1"}))
+}
+
+// -------- Test: logpush job name resolution --------
+func TestFetchLogpushAnalyticsForZone_ResolvesJobNames(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "free_tier", false)
+
+	httpmock.RegisterRegexpResponder("POST", regexp.MustCompile(`/graphql`),
+		httpmock.NewStringResponder(200, `{"data": {"viewer": {"zones": [{
+			"zoneTag": "zone1",
+			"logpushHealthAdaptiveGroups": [
+				{"count": 3, "dimensions": {"jobId": 42, "destinationType": "s3", "final": 1}},
+				{"count": 1, "dimensions": {"jobId": 43, "destinationType": "http", "final": 0}}
+			]
+		}]}}}`))
+	httpmock.RegisterRegexpResponder("GET", regexp.MustCompile(`/zones/zone1/logpush/jobs`),
+		httpmock.NewStringResponder(200, `{"success": true, "result": [
+			{"id": 42, "name": "http-to-s3", "dataset": "http_requests"}
+		]}`))
+
+	resetLogpushJobCache()
+	logpushFailedJobsZone.Reset()
+
+	zones := []cloudflare.Zone{{ID: "zone1", Name: "example.com"}}
+	fetchLogpushAnalyticsForZone(context.Background(), zones)
+	fetchLogpushAnalyticsForZone(context.Background(), zones)
+
+	assert.Equal(t, float64(6), testutil.ToFloat64(logpushFailedJobsZone.With(prometheus.Labels{
+		"destination": "s3", "job_id": "42", "job_name": "http-to-s3", "dataset": "http_requests", "final": "1",
+	})))
+	// Unknown jobs keep their ID as the name
+	assert.Equal(t, float64(2), testutil.ToFloat64(logpushFailedJobsZone.With(prometheus.Labels{
+		"destination": "http", "job_id": "43", "job_name": "43", "dataset": "", "final": "0",
+	})))
+	// Jobs are looked up once per scrape
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()[`GET =~/zones/zone1/logpush/jobs`])
+}
+
+// -------- Test: hourly HTTP groups are added once --------
+func TestAddHTTPGroups_HourlyGroupAddedOnce(t *testing.T) {
+	payload := `{"zoneTag": "zone1", "httpRequests1mGroups": [{"dimensions": {"datetime": "2024-01-01T10:00:00Z"}, "sum": {"requests": 500}}]}`
+	var z models.ZoneRespHTTPGroups
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	setViper(t, "cf_group_granularity", "1h")
+	zoneRequestTotal.Reset()
+	lastHourlyHTTPGroup.Delete("hourly.example.com")
+
+	addHTTPGroups(&z, "hourly.example.com", "acc")
+	addHTTPGroups(&z, "hourly.example.com", "acc")
+
+	assert.Equal(t, float64(500), testutil.ToFloat64(zoneRequestTotal.With(prometheus.Labels{"zone": "hourly.example.com", "account": "acc"})))
+}
+
+// -------- Test: discovered zones and accounts gauges --------
+func TestFetchMetrics_DiscoveredTotals(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "free_tier", true)
+	setViper(t, "cf_exclude_zones", "zone3")
+	setViper(t, "cf_batch_size", 10)
+	setViper(t, "rest_batch_size", 10)
+
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/zones",
+		httpmock.NewStringResponder(200, `{"success": true, "result": [
+			{"id": "zone1", "name": "one.example.com"},
+			{"id": "zone2", "name": "two.example.com"},
+			{"id": "zone3", "name": "three.example.com"}
+		]}`))
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/accounts",
+		httpmock.NewStringResponder(200, `{"success": true, "result": []}`))
+	httpmock.RegisterRegexpResponder("POST", regexp.MustCompile(`/graphql`),
+		httpmock.NewStringResponder(200, `{"data": {"viewer": {"zones": [], "accounts": []}}}`))
+	httpmock.RegisterNoResponder(httpmock.NewStringResponder(200, `{"success": true, "result": []}`))
+
+	pools := NewPools(1, 2)
+	defer pools.Stop()
+
+	assert.NoError(t, FetchMetrics(context.Background(), pools))
+	assert.Equal(t, float64(2), testutil.ToFloat64(exporterZonesTotal))
+	assert.Equal(t, float64(0), testutil.ToFloat64(exporterAccountsTotal))
+}
+
+// -------- Test: 52x origin connectivity errors --------
+func TestAddHTTPAdaptiveGroups_OriginConnectivityErrors(t *testing.T) {
+	payload := `{
+		"zoneTag": "zone1",
+		"httpRequestsAdaptiveGroups": [
+			{"count": 4, "dimensions": {"originResponseStatus": 500, "clientCountryName": "DE"}},
+			{"count": 3, "dimensions": {"originResponseStatus": 0, "clientCountryName": "DE"}}
+		],
+		"httpRequestsOriginConnectivity": [
+			{"count": 3, "dimensions": {"edgeResponseStatus": 522}},
+			{"count": 1, "dimensions": {"edgeResponseStatus": 524}}
+		]
+	}`
+
+	var z models.ZoneRespAdaptiveGroups
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	useHostVecs(t, true)
+	zoneOriginConnectivityErrorsTotal.Reset()
+	addHTTPAdaptiveGroups(&z, "example.com", "acc")
+
+	appErr := func(status string) float64 {
+		return testutil.ToFloat64(zoneCustomerError5xx.With(prometheus.Labels{
+			"zone": "example.com", "account": "acc", "status": status, "country": "DE",
+		}))
+	}
+	connErr := func(status string) float64 {
+		return testutil.ToFloat64(zoneOriginConnectivityErrorsTotal.With(prometheus.Labels{
+			"zone": "example.com", "account": "acc", "status": status,
+		}))
+	}
+	assert.Equal(t, float64(4), appErr("500"))
+	assert.Equal(t, float64(3), connErr("522"))
+	assert.Equal(t, float64(1), connErr("524"))
+
+	// Unreachable origins have no origin status and are not counted as origin 5xx
+	assert.Equal(t, 1, testutil.CollectAndCount(zoneCustomerError5xx))
+	assert.Equal(t, 2, testutil.CollectAndCount(zoneOriginConnectivityErrorsTotal))
+}
+
+// -------- Test: account logpush totals --------
+func TestAddLogpushAccountGroups_SuccessAndFailure(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterRegexpResponder("GET", regexp.MustCompile(`/accounts/acc1/logpush/jobs`),
+		httpmock.NewStringResponder(200, `{"success": true, "result": [{"id": 7, "name": "http-to-s3", "dataset": "http_requests"}]}`))
+
+	payload := `{"viewer": {"accounts": [{
+		"logpushHealthAdaptiveGroups": [
+			{"count": 2, "dimensions": {"jobId": 7, "destinationType": "s3", "status": 503, "final": 0}},
+			{"count": 1, "dimensions": {"jobId": 7, "destinationType": "s3", "status": 0, "final": 1}}
+		],
+		"logpushHealthSuccess": [
+			{"count": 97, "dimensions": {"jobId": 7, "destinationType": "s3"}}
+		]
+	}]}}`
+	var r models.CloudflareResponseLogpushAccount
+	assert.NoError(t, json.Unmarshal([]byte(payload), &r))
+
+	setViper(t, "account_type_label", false)
+	if logpushJobsTotal == nil {
+		logpushJobsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: logpushJobsTotalMetricName.String(),
+		}, accountMetricLabels("destination", "job_id", "status_class"))
+	}
+	if logpushFailedJobsAccount == nil {
+		logpushFailedJobsAccount = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: logpushFailedJobsAccountMetricName.String(),
+		}, accountMetricLabels("destination", "job_id", "job_name", "dataset", "final"))
+	}
+	logpushJobsTotal.Reset()
+	logpushFailedJobsAccount.Reset()
+	resetLogpushJobCache()
+
+	addLogpushAccountGroups(context.Background(), cloudflare.Account{ID: "acc1", Name: "acme"}, r.Viewer.Accounts)
+
+	total := func(class string) float64 {
+		return testutil.ToFloat64(logpushJobsTotal.With(prometheus.Labels{
+			"account": "acme", "destination": "s3", "job_id": "7", "status_class": class,
+		}))
+	}
+	assert.Equal(t, float64(97), total("2xx"))
+	assert.Equal(t, float64(1), total("error"))
+	// Retried attempts are only counted as failed jobs, not as deliveries
+	assert.Equal(t, 2, testutil.CollectAndCount(logpushJobsTotal))
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(logpushFailedJobsAccount.With(prometheus.Labels{
+		"account": "acme", "destination": "s3", "job_id": "7", "job_name": "http-to-s3", "dataset": "http_requests", "final": "0",
+	})))
+}
+
+// -------- Test: staggered zones --------
+func TestStaggerZones_CoversAllZonesOverCycles(t *testing.T) {
+	var zones []cloudflare.Zone
+	for i := 0; i < 20; i++ {
+		zones = append(zones, cloudflare.Zone{ID: "zone" + strconv.Itoa(i)})
+	}
+
+	zoneStaggerTick.Store(0)
+	defer zoneStaggerTick.Store(0)
+
+	seen := map[string]int{}
+	var ticks [][]cloudflare.Zone
+	for tick := 0; tick < 3; tick++ {
+		due := staggerZones(zones, 3)
+		ticks = append(ticks, due)
+		for _, z := range due {
+			seen[z.ID]++
+		}
+	}
+	// Every zone exactly once per full cycle
+	assert.Len(t, seen, len(zones))
+	for id, n := range seen {
+		assert.Equal(t, 1, n, id)
+	}
+
+	// The next cycle repeats the same partition
+	assert.Equal(t, ticks[0], staggerZones(zones, 3))
+
+	// Removing a zone doesn't move the others between slots
+	removed := ticks[0][0].ID
+	remaining := slices.DeleteFunc(slices.Clone(zones), func(z cloudflare.Zone) bool { return z.ID == removed })
+	zoneStaggerTick.Store(0)
+	assert.Equal(t, ticks[0][1:], staggerZones(remaining, 3))
+
+	assert.Equal(t, zones, staggerZones(zones, 1))
+}
+
+// -------- Test: firewall events by ASN --------
+func TestAddFirewallGroups_EventsByASN(t *testing.T) {
+	payload := `{
+		"zoneTag": "zone1",
+		"firewallEventsByAsn": [
+			{"count": 50, "dimensions": {"clientAsn": "14061", "clientASNDescription": "DIGITALOCEAN-ASN", "action": "block"}},
+			{"count": 10, "dimensions": {"clientAsn": "14061", "clientASNDescription": "DIGITALOCEAN-ASN", "action": "managed_challenge"}},
+			{"count": 20, "dimensions": {"clientAsn": "16509", "clientASNDescription": "AMAZON-02", "action": "block"}},
+			{"count": 3, "dimensions": {"clientAsn": "3320", "clientASNDescription": "DTAG", "action": "block"}},
+			{"count": 2, "dimensions": {"clientAsn": "7922", "clientASNDescription": "COMCAST-7922", "action": "block"}}
+		]
+	}`
+
+	var z models.ZoneRespAnalytics
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+	firewallGroups := z.FirewallGroups()
+	assert.Len(t, firewallGroups.FirewallEventsByASN, 5)
+
+	setViper(t, "cf_asn_top_n", 2)
+	zoneFirewallEventsByASNTotal.Reset()
+	addFirewallGroups(&firewallGroups, "example.com", "acc")
+
+	asn := func(asn, description, action string) float64 {
+		return testutil.ToFloat64(zoneFirewallEventsByASNTotal.With(prometheus.Labels{
+			"zone": "example.com", "account": "acc", "asn": asn, "asn_description": description, "action": action,
+		}))
+	}
+	assert.Equal(t, 4, testutil.CollectAndCount(zoneFirewallEventsByASNTotal))
+	assert.Equal(t, float64(50), asn("14061", "DIGITALOCEAN-ASN", "block"))
+	assert.Equal(t, float64(10), asn("14061", "DIGITALOCEAN-ASN", "managed_challenge"))
+	assert.Equal(t, float64(20), asn("16509", "AMAZON-02", "block"))
+	// ASNs outside the top 2 are summed per action
+	assert.Equal(t, float64(5), asn("other", "", "block"))
+}
+
+// -------- Test: stale certificate series --------
+func TestFetchSSLCertificateStatus_RemovesReplacedCertificates(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "free_tier", false)
+	setViper(t, "reset_stale_metrics", true)
+
+	packs := `{"success": true, "result": [{"certificates": [
+		{"id": "cert-old", "status": "active", "issuer": "LetsEncrypt", "expires_on": "2026-01-01T00:00:00Z", "hosts": ["cert.example.com"]},
+		{"id": "cert-other", "status": "active", "issuer": "GoogleTrust", "expires_on": "2026-06-01T00:00:00Z", "hosts": ["cert.example.com"]}
+	]}]}`
+	httpmock.RegisterRegexpResponder("GET", regexp.MustCompile(`/zones/certzone/ssl/certificate_packs`),
+		func(*http.Request) (*http.Response, error) { return httpmock.NewStringResponse(200, packs), nil })
+
+	zoneCertificateValidation.Reset()
+	zoneCertificateDaysUntilExpiry.Reset()
+	zones := []cloudflare.Zone{{ID: "certzone", Name: "cert.example.com"}}
+	fetchSSLCertificateStatus(context.Background(), zones)
+	assert.Equal(t, 2, testutil.CollectAndCount(zoneCertificateValidation))
+
+	// cert-old is replaced by a renewed certificate from another issuer
+	packs = `{"success": true, "result": [{"certificates": [
+		{"id": "cert-new", "status": "active", "issuer": "GoogleTrust", "expires_on": "2026-09-01T00:00:00Z", "hosts": ["cert.example.com"]},
+		{"id": "cert-other", "status": "active", "issuer": "GoogleTrust", "expires_on": "2026-06-01T00:00:00Z", "hosts": ["cert.example.com"]}
+	]}]}`
+	fetchSSLCertificateStatus(context.Background(), zones)
+
+	assert.Equal(t, 1, testutil.CollectAndCount(zoneCertificateValidation))
+	assert.Equal(t, 1, testutil.CollectAndCount(zoneCertificateDaysUntilExpiry))
+	assert.Equal(t, 0, zoneCertificateValidation.DeletePartialMatch(prometheus.Labels{"issuer": "LetsEncrypt"}))
+
+	// A zone without certificates loses all its series
+	packs = `{"success": true, "result": []}`
+	fetchSSLCertificateStatus(context.Background(), zones)
+	assert.Equal(t, 0, testutil.CollectAndCount(zoneCertificateValidation))
+}
+
+// -------- Test: zone availability ratio --------
+func TestAddHTTPGroups_AvailabilityRatio(t *testing.T) {
+	payload := `{
+		"httpRequests1mGroups": [{
+			"sum": {
+				"requests": 1000,
+				"responseStatusMap": [
+					{"edgeResponseStatus": 200, "requests": 960},
+					{"edgeResponseStatus": 404, "requests": 15},
+					{"edgeResponseStatus": 502, "requests": 20},
+					{"edgeResponseStatus": 522, "requests": 5}
+				]
+			}
+		}],
+		"zoneTag": "zone1"
+	}`
+
+	var z models.ZoneRespHTTPGroups
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	zoneAvailabilityRatio.Reset()
+	addHTTPGroups(&z, "example.com", "acc")
+
+	ratio := zoneAvailabilityRatio.With(prometheus.Labels{"zone": "example.com", "account": "acc"})
+	assert.InDelta(t, 0.975, testutil.ToFloat64(ratio), 1e-9)
+
+	// A window without requests keeps the last ratio instead of NaN
+	var empty models.ZoneRespHTTPGroups
+	assert.NoError(t, json.Unmarshal([]byte(`{"httpRequests1mGroups": [{"sum": {"requests": 0}}], "zoneTag": "zone1"}`), &empty))
+	addHTTPGroups(&empty, "example.com", "acc")
+	assert.InDelta(t, 0.975, testutil.ToFloat64(ratio), 1e-9)
+}
+
+// -------- Test: per-metric host label --------
+func TestHostLabelMetrics_OverridesExcludeHost(t *testing.T) {
+	setViper(t, "exclude_host", true)
+	set, err := BuildHostLabelMetricsSet([]string{zoneCustomerError4xxTotal.String()})
+	assert.NoError(t, err)
+	SetHostLabelMetrics(set)
+	defer SetHostLabelMetrics(Set{})
+
+	withHost := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_with_host"},
+		errorMetricLabels(zoneCustomerError4xxRate, zoneCustomerError4xxTotal))
+	withoutHost := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_without_host"},
+		errorMetricLabels(zoneCustomerError5xxRate, zoneCustomerError5xxTotal))
+
+	base := prometheus.Labels{"zone": "example.com", "account": "acc", "status": "404", "country": "DE"}
+	assert.NotPanics(t, func() {
+		withHost.With(getLabels(base, "www.example.com", zoneCustomerError4xxRate, zoneCustomerError4xxTotal)).Inc()
+		withoutHost.With(getLabels(base, "www.example.com", zoneCustomerError5xxRate, zoneCustomerError5xxTotal)).Inc()
+	})
+	assert.Equal(t, float64(1), testutil.ToFloat64(withHost.With(prometheus.Labels{
+		"zone": "example.com", "account": "acc", "status": "404", "country": "DE", "host": "www.example.com",
+	})))
+	assert.Equal(t, float64(1), testutil.ToFloat64(withoutHost.With(base)))
+	assert.NotContains(t, getLabels(base, "www.example.com"), "host")
+
+	// Only metrics with a host label can be listed
+	_, err = BuildHostLabelMetricsSet([]string{zoneRequestTotalMetricName.String()})
+	assert.Error(t, err)
+}
+
+// -------- Test: worker invocations by status --------
+func TestFetchWorkerAnalytics_InvocationsByStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"data": {"viewer": {"accounts": [{"workersInvocationsAdaptive": [
+			{"dimensions": {"scriptName": "api", "status": "success"}, "sum": {"requests": 90, "errors": 0}},
+			{"dimensions": {"scriptName": "api", "status": "scriptThrewException"}, "sum": {"requests": 7, "errors": 7}},
+			{"dimensions": {"scriptName": "api", "status": "exceededCpu"}, "sum": {"requests": 3, "errors": 3}}
+		]}]}}}`)
+	}))
+	defer srv.Close()
+
+	cloudflareAPI.SetGraphQLEndpoint(srv.URL)
+	defer cloudflareAPI.SetGraphQLEndpoint("")
+
+	workerInvocationsByStatusTotal.Reset()
+	FetchWorkerAnalytics(context.Background(), cloudflare.Account{ID: "acc1", Name: "My Account"})
+
+	assert.Equal(t, 3, testutil.CollectAndCount(workerInvocationsByStatusTotal))
+	for status, want := range map[string]float64{"success": 90, "scriptThrewException": 7, "exceededCpu": 3} {
+		got := testutil.ToFloat64(workerInvocationsByStatusTotal.With(prometheus.Labels{"script_name": "api", "account": "my-account", "environment": "", "status": status}))
+		assert.Equal(t, want, got, status)
+	}
+}
+
+// -------- Test: separate account and zone pools --------
+func TestNewPools_Sizes(t *testing.T) {
+	pools := NewPools(3, 7)
+	defer pools.Stop()
+
+	assert.Equal(t, 3, pools.Accounts.Size())
+	assert.Equal(t, 7, pools.Zones.Size())
+	assert.NotSame(t, pools.Accounts, pools.Zones)
+}
+
+// -------- Test: last scrape error in scrape status --------
+func TestFetchMetrics_RecordsLastError(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "cf_api_max_retries", 1)
+
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/zones",
+		httpmock.NewStringResponder(500, `{"success": false, "errors": [{"code": 1000, "message": "internal error"}]}`))
+
+	pools := NewPools(1, 1)
+	defer pools.Stop()
+
+	assert.Error(t, FetchMetrics(context.Background(), pools))
+
+	status := LastScrapeStatus()
+	assert.Contains(t, status.LastError, "failed to fetch zones")
+	assert.False(t, status.LastScrape.IsZero())
+
+	payload, err := json.Marshal(status)
+	assert.NoError(t, err)
+	assert.Contains(t, string(payload), `"last_error":"failed to fetch zones`)
+}
+
+// -------- Test: logpush final label --------
+func TestLogpushFinalLabel(t *testing.T) {
+	assert.Equal(t, "1", logpushFinalLabel(1))
+	assert.Equal(t, "0", logpushFinalLabel(0))
+
+	setViper(t, "logpush_final_bool", true)
+	assert.Equal(t, "true", logpushFinalLabel(1))
+	assert.Equal(t, "false", logpushFinalLabel(0))
+}
+
+// -------- Test: per-origin load balancer health --------
+func TestAddLoadBalancingRequestsAdaptive_OriginHealth(t *testing.T) {
+	payload := `{
+		"zoneTag": "zone1",
+		"loadBalancingRequestsAdaptive": [{
+			"lbName": "lb.example.com",
+			"selectedPoolName": "primary",
+			"pools": [{"poolName": "primary", "healthy": 1, "avgRttMs": 20}],
+			"origins": [
+				{"originName": "origin-a", "health": 1, "ipv4": "192.0.2.10", "selected": 1},
+				{"originName": "origin-b", "health": 0, "ipv4": "192.0.2.11", "selected": 0}
+			]
+		}]
+	}`
+
+	var z models.LbResp
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	originLBHealth.Reset()
+	addLoadBalancingRequestsAdaptive(&z, "example.com", "acc")
+
+	labels := func(origin, ip string) prometheus.Labels {
+		return prometheus.Labels{
+			"zone": "example.com", "account": "acc", "load_balancer_name": "lb.example.com",
+			"pool_name": "primary", "origin_name": origin, "origin_ip": ip,
+		}
+	}
+	assert.Equal(t, 2, testutil.CollectAndCount(originLBHealth))
+	assert.Equal(t, float64(1), testutil.ToFloat64(originLBHealth.With(labels("origin-a", "192.0.2.10"))))
+	assert.Equal(t, float64(0), testutil.ToFloat64(originLBHealth.With(labels("origin-b", "192.0.2.11"))))
+}
+
+// -------- Test: requests by path top-N --------
+func TestAddRequestPathGroups_TopN(t *testing.T) {
+	setViper(t, "cf_path_top_n", 2)
+
+	payload := `{
+		"zoneTag": "zone1",
+		"httpRequestsPath": [
+			{"count": 50, "dimensions": {"clientRequestPath": "/api/items"}},
+			{"count": 30, "dimensions": {"clientRequestPath": "/"}},
+			{"count": 5, "dimensions": {"clientRequestPath": "/login"}},
+			{"count": 2, "dimensions": {"clientRequestPath": "/favicon.ico"}}
+		]
+	}`
+
+	var z models.ZoneRespRequestPath
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	zoneRequestsByPathTotal.Reset()
+	addRequestPathGroups(&z, "example.com", "acc")
+
+	path := func(p string) float64 {
+		return testutil.ToFloat64(zoneRequestsByPathTotal.With(prometheus.Labels{"zone": "example.com", "account": "acc", "path": p}))
+	}
+	assert.Equal(t, 3, testutil.CollectAndCount(zoneRequestsByPathTotal))
+	assert.Equal(t, float64(50), path("/api/items"))
+	assert.Equal(t, float64(30), path("/"))
+	assert.Equal(t, float64(7), path(otherTopNKey))
+}
+
+// -------- Test: bandwidth cache ratio --------
+func TestAddHTTPGroups_BandwidthCacheRatio(t *testing.T) {
+	var z models.ZoneRespHTTPGroups
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"httpRequests1mGroups": [{"sum": {"requests": 10, "bytes": 4000, "cachedBytes": 3000}}],
+		"zoneTag": "zone1"
+	}`), &z))
+
+	zoneBandwidthCacheRatio.Reset()
+	addHTTPGroups(&z, "example.com", "acc")
+
+	ratio := zoneBandwidthCacheRatio.With(prometheus.Labels{"zone": "example.com", "account": "acc"})
+	assert.InDelta(t, 0.75, testutil.ToFloat64(ratio), 1e-9)
+
+	// A window without bytes keeps the last ratio instead of NaN
+	var empty models.ZoneRespHTTPGroups
+	assert.NoError(t, json.Unmarshal([]byte(`{"httpRequests1mGroups": [{"sum": {"bytes": 0, "cachedBytes": 0}}], "zoneTag": "zone1"}`), &empty))
+	addHTTPGroups(&empty, "example.com", "acc")
+	assert.InDelta(t, 0.75, testutil.ToFloat64(ratio), 1e-9)
+}
+
+// -------- Test: permission errors per family --------
+func TestRecordPermissionError_CountsOnlyPermissionErrors(t *testing.T) {
+	exporterPermissionErrorsTotal.Reset()
+
+	recordPermissionError("workers", &cloudflareAPI.PermissionError{StatusCode: 403, Err: errors.New("forbidden")})
+	recordPermissionError("workers", errors.New("timeout"))
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(exporterPermissionErrorsTotal.With(prometheus.Labels{"family": "workers"})))
+}
+
+// -------- Test: worker script environment split --------
+func TestSplitWorkerScript(t *testing.T) {
+	defer SetWorkerScriptPattern("")
+
+	name, env := splitWorkerScript("api-staging")
+	assert.Equal(t, "api-staging", name)
+	assert.Equal(t, "", env)
+
+	assert.NoError(t, SetWorkerScriptPattern(`^(?P<name>.+)-(?P<environment>staging|production)$`))
+	name, env = splitWorkerScript("api-gateway-staging")
+	assert.Equal(t, "api-gateway", name)
+	assert.Equal(t, "staging", env)
+
+	// Scripts without an environment suffix are kept as they are
+	name, env = splitWorkerScript("cron-jobs")
+	assert.Equal(t, "cron-jobs", name)
+	assert.Equal(t, "", env)
+
+	assert.Error(t, SetWorkerScriptPattern(`^(?P<name>.+)$`))
+	assert.Error(t, SetWorkerScriptPattern(`(`))
+}
+
+// -------- Test: disabled account fetchers --------
+func TestRunAccountFetchers_SkipsDisabled(t *testing.T) {
+	orig := accountFetchers
+	defer func() { accountFetchers = orig }()
+
+	var called []string
+	record := func(name string) func(context.Context, cloudflare.Account) {
+		return func(context.Context, cloudflare.Account) { called = append(called, name) }
+	}
+	accountFetchers = []struct {
+		flag  string
+		fetch func(ctx context.Context, account cloudflare.Account)
+	}{
+		{"enable_workers", record("workers")},
+		{"enable_magic_transit", record("magic_transit")},
+		{"enable_logpush", record("logpush")},
+	}
+
+	setViper(t, "enable_magic_transit", false)
+
+	runAccountFetchers(context.Background(), cloudflare.Account{ID: "acc1"})
+	assert.Equal(t, []string{"workers", "logpush"}, called)
+}
+
+// -------- Test: GraphQL and REST zone batch sizes --------
+func TestFetchMetrics_SeparateGraphQLAndRESTBatches(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "cf_batch_size", 2)
+	setViper(t, "rest_batch_size", 4)
+
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/zones",
+		httpmock.NewStringResponder(200, `{"success": true, "result": [
+			{"id": "zone1", "name": "one.example.com"},
+			{"id": "zone2", "name": "two.example.com"},
+			{"id": "zone3", "name": "three.example.com"},
+			{"id": "zone4", "name": "four.example.com"},
+			{"id": "zone5", "name": "five.example.com"}
+		]}`))
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/accounts",
+		httpmock.NewStringResponder(200, `{"success": true, "result": []}`))
+
+	origGraphQL, origREST := graphQLZoneFetchers, restZoneFetchers
+	defer func() { graphQLZoneFetchers, restZoneFetchers = origGraphQL, origREST }()
+
+	var mu sync.Mutex
+	var graphQLSizes, restSizes []int
+	record := func(sizes *[]int) func(context.Context, []cloudflare.Zone) {
+		return func(_ context.Context, zones []cloudflare.Zone) {
+			mu.Lock()
+			defer mu.Unlock()
+			*sizes = append(*sizes, len(zones))
+		}
+	}
+	graphQLZoneFetchers = []func(context.Context, []cloudflare.Zone){record(&graphQLSizes)}
+	restZoneFetchers = []func(context.Context, []cloudflare.Zone){record(&restSizes)}
+
+	pools := NewPools(1, 2)
+	defer pools.Stop()
+
+	assert.NoError(t, FetchMetrics(context.Background(), pools))
+	assert.ElementsMatch(t, []int{2, 2, 1}, graphQLSizes)
+	assert.ElementsMatch(t, []int{4, 1}, restSizes)
+}
+
+// -------- Test: per-family last success timestamp --------
+func TestFamilyLastSuccess_OnlySuccessfulFamilyUpdates(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(string(body), "workersInvocationsAdaptive") {
+			io.WriteString(w, `{"data": {"viewer": {"accounts": [{"workersInvocationsAdaptive": []}]}}}`)
+			return
+		}
+		io.WriteString(w, `{"errors": [{"message": "internal error"}]}`)
+	}))
+	defer srv.Close()
+
+	cloudflareAPI.SetGraphQLEndpoint(srv.URL)
+	defer cloudflareAPI.SetGraphQLEndpoint("")
+
+	exporterFamilyLastSuccess.Reset()
+	account := cloudflare.Account{ID: "acc1", Name: "My Account"}
+	FetchWorkerAnalytics(context.Background(), account)
+	fetchMagicTransitHealth(context.Background(), account)
+
+	assert.Equal(t, 1, testutil.CollectAndCount(exporterFamilyLastSuccess))
+	assert.Greater(t, testutil.ToFloat64(exporterFamilyLastSuccess.With(prometheus.Labels{"family": "workers"})), float64(0))
+}
+
+// -------- Test: startup backfill window --------
+func TestFetchMetrics_BackfillOnlyOnFirstScrape(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "cf_batch_size", 10)
+	setViper(t, "rest_batch_size", 10)
+	setViper(t, "backfill_minutes", 30)
+
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/zones",
+		httpmock.NewStringResponder(200, `{"success": true, "result": [{"id": "zone1", "name": "example.com"}]}`))
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/accounts",
+		httpmock.NewStringResponder(200, `{"success": true, "result": []}`))
+
+	origGraphQL, origREST := graphQLZoneFetchers, restZoneFetchers
+	defer func() { graphQLZoneFetchers, restZoneFetchers = origGraphQL, origREST }()
+
+	var windows []time.Duration
+	graphQLZoneFetchers = []func(context.Context, []cloudflare.Zone){
+		func(context.Context, []cloudflare.Zone) { windows = append(windows, cloudflareAPI.QueryWindow()) },
+	}
+	restZoneFetchers = nil
+
+	pools := NewPools(1, 1)
+	defer pools.Stop()
+
+	firstScrapeDone.Store(false)
+	assert.NoError(t, FetchMetrics(context.Background(), pools))
+	assert.NoError(t, FetchMetrics(context.Background(), pools))
+	assert.Equal(t, []time.Duration{30 * time.Minute, time.Minute}, windows)
+}
+
+func TestFetchMetrics_StaggeredZoneQueriesSinceLastScrape(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "cf_batch_size", 10)
+	setViper(t, "rest_batch_size", 10)
+	setViper(t, "cf_stagger_cycles", 3)
+
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/zones",
+		httpmock.NewStringResponder(200, `{"success": true, "result": [{"id": "zone1", "name": "example.com"}]}`))
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/accounts",
+		httpmock.NewStringResponder(200, `{"success": true, "result": []}`))
+
+	var variables []map[string]interface{}
+	httpmock.RegisterRegexpResponder("POST", regexp.MustCompile(`/graphql`),
+		func(r *http.Request) (*http.Response, error) {
+			var body struct {
+				Variables map[string]interface{} `json:"variables"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				return nil, err
+			}
+			variables = append(variables, body.Variables)
+			return httpmock.NewStringResponse(200, `{"data": {"viewer": {"zones": []}}}`), nil
+		})
+
+	origGraphQL, origREST := graphQLZoneFetchers, restZoneFetchers
+	defer func() { graphQLZoneFetchers, restZoneFetchers = origGraphQL, origREST }()
+	graphQLZoneFetchers = []func(context.Context, []cloudflare.Zone){fetchZoneAnalytics}
+	restZoneFetchers = nil
+
+	zoneStaggerTick.Store(0)
+	defer zoneStaggerTick.Store(0)
+	defer cloudflareAPI.SetStaggerWindow(0)
+
+	pools := NewPools(1, 1)
+	defer pools.Stop()
+
+	firstScrapeDone.Store(true)
+	for range 3 {
+		assert.NoError(t, FetchMetrics(context.Background(), pools))
+	}
+
+	// zone1 is due on one scrape out of three and covers all three minutes
+	assert.Len(t, variables, 1)
+	for _, keys := range [][2]string{{"mintime", "maxtime"}, {"httpMintime", "httpMaxtime"}} {
+		mintime, err := time.Parse(time.RFC3339, variables[0][keys[0]].(string))
+		assert.NoError(t, err)
+		maxtime, err := time.Parse(time.RFC3339, variables[0][keys[1]].(string))
+		assert.NoError(t, err)
+		assert.Equal(t, 3*time.Minute, maxtime.Sub(mintime), keys[0])
+	}
+}
+
+func TestAddHTTPGroups_SumsBackfillGroups(t *testing.T) {
+	payload := `{
+		"httpRequests1mGroups": [
+			{
+				"dimensions": {"datetime": "2024-05-01T10:01:00Z"},
+				"uniq": {"uniques": 2},
+				"sum": {"requests": 10, "bytes": 1000, "threats": 1, "pageViews": 4,
+					"countryMap": [{"clientCountryName": "US", "requests": 10, "bytes": 1000, "threats": 1}],
+					"responseStatusMap": [{"edgeResponseStatus": 200, "requests": 10}]}
+			},
+			{
+				"dimensions": {"datetime": "2024-05-01T10:02:00Z"},
+				"uniq": {"uniques": 3},
+				"sum": {"requests": 5, "bytes": 500, "threats": 2, "pageViews": 1,
+					"countryMap": [{"clientCountryName": "US", "requests": 4, "bytes": 400, "threats": 2}, {"clientCountryName": "DE", "requests": 1, "bytes": 100}],
+					"responseStatusMap": [{"edgeResponseStatus": 200, "requests": 4}, {"edgeResponseStatus": 503, "requests": 1}]}
+			}
+		],
+		"zoneTag": "zone1"
+	}`
+
+	var z models.ZoneRespHTTPGroups
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	zoneRequestTotal.Reset()
+	zoneBandwidthTotal.Reset()
+	zoneThreatsTotal.Reset()
+	zonePageviewsTotal.Reset()
+	zoneUniquesTotal.Reset()
+	zoneRequestCountry.Reset()
+	zoneRequestHTTPStatus.Reset()
+	addHTTPGroups(&z, "backfill.example.com", "acc")
+
+	labels := prometheus.Labels{"zone": "backfill.example.com", "account": "acc"}
+	assert.Equal(t, float64(15), testutil.ToFloat64(zoneRequestTotal.With(labels)))
+	assert.Equal(t, float64(1500), testutil.ToFloat64(zoneBandwidthTotal.With(labels)))
+	assert.Equal(t, float64(3), testutil.ToFloat64(zoneThreatsTotal.With(labels)))
+	assert.Equal(t, float64(5), testutil.ToFloat64(zonePageviewsTotal.With(labels)))
+	assert.Equal(t, float64(5), testutil.ToFloat64(zoneUniquesTotal.With(labels)))
+	assert.Equal(t, float64(14), testutil.ToFloat64(zoneRequestCountry.With(prometheus.Labels{"zone": "backfill.example.com", "account": "acc", "country": "US"})))
+	assert.Equal(t, float64(1), testutil.ToFloat64(zoneRequestCountry.With(prometheus.Labels{"zone": "backfill.example.com", "account": "acc", "country": "DE"})))
+	assert.Equal(t, float64(14), testutil.ToFloat64(zoneRequestHTTPStatus.With(prometheus.Labels{"zone": "backfill.example.com", "account": "acc", "status": "200"})))
+	// The first group is left as decoded
+	assert.Len(t, z.HTTP1mGroups[0].Sum.Country, 1)
+}
+
+// -------- Test: optional zone_id label --------
+func TestAddHTTPGroups_ZoneIDLabel(t *testing.T) {
+	SetZoneIDLabel(true)
+	defer SetZoneIDLabel(false)
+
+	payload := `{
+		"httpRequests1mGroups": [{"sum": {"requests": 100, "bytes": 2048}}],
+		"zoneTag": "zone1"
+	}`
+
+	var z models.ZoneRespHTTPGroups
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+	addHTTPGroups(&z, "example.com", "acc")
+
+	labels := prometheus.Labels{"zone": "example.com", "account": "acc", "zone_id": "zone1"}
+	assert.Equal(t, float64(100), testutil.ToFloat64(zoneRequestTotal.With(labels)))
+	assert.Equal(t, float64(2048), testutil.ToFloat64(zoneBandwidthTotal.With(labels)))
+	assert.NoError(t, testutil.CollectAndCompare(zoneRequestTotal, strings.NewReader(`
+# HELP cloudflare_zone_requests_total Number of requests for zone
+# TYPE cloudflare_zone_requests_total counter
+cloudflare_zone_requests_total{account="acc",zone="example.com",zone_id="zone1"} 100
+`)))
+}
+
+// -------- Test: tolerant decoding of counter fields --------
+func TestZoneRespHTTPGroups_TolerantCounts(t *testing.T) {
+	payload := `{
+		"httpRequests1mGroups": [{
+			"uniq": {"uniques": null},
+			"sum": {
+				"requests": "100",
+				"bytes": {"value": 2048},
+				"cachedBytes": 1024,
+				"threats": 1e3
+			}
+		}],
+		"zoneTag": "zone1"
+	}`
+
+	before := testutil.ToFloat64(models.DecodeErrorsTotal)
+
+	var z models.ZoneRespHTTPGroups
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	sum := z.HTTP1mGroups[0].Sum
+	assert.Equal(t, models.Count(100), sum.Requests)
+	assert.Equal(t, models.Count(0), sum.Bytes)
+	assert.Equal(t, models.Count(1024), sum.CachedBytes)
+	assert.Equal(t, models.Count(1000), sum.Threats)
+	assert.Equal(t, models.Count(0), z.HTTP1mGroups[0].Unique.Uniques)
+	assert.Equal(t, "zone1", z.ZoneTag)
+	assert.Equal(t, float64(1), testutil.ToFloat64(models.DecodeErrorsTotal)-before)
+}
+
+// -------- Test: zone sample rate --------
+func TestAddColoGroups_SampleRate(t *testing.T) {
+	payload := `{
+		"zoneTag": "zone1",
+		"httpRequestsAdaptiveGroups": [
+			{"count": 30, "dimensions": {"coloCode": "FRA"}, "avg": {"sampleInterval": 1}},
+			{"count": 10, "dimensions": {"coloCode": "AMS"}, "avg": {"sampleInterval": 4}}
+		]
+	}`
+
+	var z models.ZoneRespColo
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	zoneSampleRate.Reset()
+	addColoGroups(&z, "example.com", "acc")
+
+	// Count-weighted interval (30*1 + 10*4) / 40 = 1.75
+	got := testutil.ToFloat64(zoneSampleRate.With(prometheus.Labels{"zone": "example.com", "account": "acc"}))
+	assert.InDelta(t, 1/1.75, got, 1e-9)
+}
+
+// -------- Test: paused zones are skipped --------
+func TestFetchMetrics_ExcludesPausedZones(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "cf_batch_size", 10)
+	setViper(t, "rest_batch_size", 10)
+
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/zones",
+		httpmock.NewStringResponder(200, `{"success": true, "result": [
+			{"id": "zone1", "name": "active.example.com", "status": "active"},
+			{"id": "zone2", "name": "paused.example.com", "status": "active", "paused": true},
+			{"id": "zone3", "name": "inactive.example.com", "status": "paused"}
+		]}`))
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/accounts",
+		httpmock.NewStringResponder(200, `{"success": true, "result": []}`))
+
+	origGraphQL, origREST := graphQLZoneFetchers, restZoneFetchers
+	defer func() { graphQLZoneFetchers, restZoneFetchers = origGraphQL, origREST }()
+
+	var processed []string
+	graphQLZoneFetchers = []func(context.Context, []cloudflare.Zone){
+		func(_ context.Context, zones []cloudflare.Zone) {
+			for _, z := range zones {
+				processed = append(processed, z.ID)
+			}
+		},
+	}
+	restZoneFetchers = nil
+
+	pools := NewPools(1, 1)
+	defer pools.Stop()
+
+	assert.NoError(t, FetchMetrics(context.Background(), pools))
+	assert.Equal(t, []string{"zone1"}, processed)
+
+	// Disabled, paused zones are scraped as well
+	setViper(t, "exclude_paused_zones", false)
+	processed = nil
+	assert.NoError(t, FetchMetrics(context.Background(), pools))
+	assert.ElementsMatch(t, []string{"zone1", "zone2", "zone3"}, processed)
+}
+
+// -------- Test: content type top-N --------
+func TestTopNContentTypes_BucketsBytesWithRequests(t *testing.T) {
+	requests := map[string]float64{"html": 50, "js": 30, "css": 15, "png": 5}
+	bytes := map[string]float64{"html": 1000, "js": 400, "css": 100, "png": 9000}
+
+	cappedRequests, cappedBytes := topNContentTypes(requests, bytes, 2)
+	assert.Equal(t, map[string]float64{"html": 50, "js": 30, "other": 20}, cappedRequests)
+	// png has the most bytes but few requests, so it goes to other in both
+	assert.Equal(t, map[string]float64{"html": 1000, "js": 400, "other": 9100}, cappedBytes)
+
+	// One type over the limit still gets bucketed
+	cappedRequests, cappedBytes = topNContentTypes(requests, bytes, 3)
+	assert.Equal(t, map[string]float64{"html": 50, "js": 30, "css": 15, "other": 5}, cappedRequests)
+	assert.Equal(t, float64(9000), cappedBytes["other"])
+
+	cappedRequests, cappedBytes = topNContentTypes(requests, bytes, 0)
+	assert.Equal(t, requests, cappedRequests)
+	assert.Equal(t, bytes, cappedBytes)
+}
+
+// -------- Test: scrape cancelled as a whole --------
+func TestFetchMetrics_CancelledScrapeSkipsQueuedBatches(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "cf_batch_size", 1)
+	setViper(t, "rest_batch_size", 10)
+
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/zones",
+		httpmock.NewStringResponder(200, `{"success": true, "result": [
+			{"id": "zone1", "name": "one.example.com"},
+			{"id": "zone2", "name": "two.example.com"}
+		]}`))
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/accounts",
+		httpmock.NewStringResponder(200, `{"success": true, "result": []}`))
+
+	origGraphQL, origREST := graphQLZoneFetchers, restZoneFetchers
+	defer func() { graphQLZoneFetchers, restZoneFetchers = origGraphQL, origREST }()
+
+	var calls atomic.Int32
+	started := make(chan struct{}, 2)
+	graphQLZoneFetchers = []func(context.Context, []cloudflare.Zone){
+		// A hung call that only returns once the scrape is cancelled
+		func(ctx context.Context, _ []cloudflare.Zone) {
+			calls.Add(1)
+			started <- struct{}{}
+			<-ctx.Done()
+		},
+	}
+	restZoneFetchers = nil
+
+	// One zone worker: the second batch is still queued behind the hung one
+	pools := NewPools(1, 1)
+	defer pools.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	assert.ErrorIs(t, FetchMetrics(ctx, pools), context.Canceled)
+
+	// Let the queued batch run; it must not call the fetcher
+	pools.Zones.StopWait()
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+// -------- Test: HTTP/2 and HTTP/3 percentages --------
+func TestAddHTTPGroups_HTTPVersionPercentages(t *testing.T) {
+	payload := `{
+		"httpRequests1mGroups": [{
+			"sum": {
+				"requests": 200,
+				"clientHTTPVersionMap": [
+					{"clientHTTPProtocol": "HTTP/1.1", "requests": 40},
+					{"clientHTTPProtocol": "HTTP/2", "requests": 110},
+					{"clientHTTPProtocol": "HTTP/3", "requests": 50}
+				]
+			}
+		}],
+		"zoneTag": "zone1"
+	}`
+
+	var z models.ZoneRespHTTPGroups
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	zoneHTTP2Percentage.Reset()
+	zoneHTTP3Percentage.Reset()
+	addHTTPGroups(&z, "example.com", "acc")
+
+	labels := prometheus.Labels{"zone": "example.com", "account": "acc"}
+	assert.Equal(t, float64(55), testutil.ToFloat64(zoneHTTP2Percentage.With(labels)))
+	assert.Equal(t, float64(25), testutil.ToFloat64(zoneHTTP3Percentage.With(labels)))
+
+	// A window without requests keeps the last percentages
+	empty := `{"httpRequests1mGroups": [{"sum": {"requests": 0, "clientHTTPVersionMap": []}}], "zoneTag": "zone1"}`
+	var zEmpty models.ZoneRespHTTPGroups
+	assert.NoError(t, json.Unmarshal([]byte(empty), &zEmpty))
+	addHTTPGroups(&zEmpty, "example.com", "acc")
+	assert.Equal(t, float64(55), testutil.ToFloat64(zoneHTTP2Percentage.With(labels)))
+	assert.Equal(t, float64(25), testutil.ToFloat64(zoneHTTP3Percentage.With(labels)))
+
+	// A zone that never had requests gets no series
+	addHTTPGroups(&zEmpty, "quiet.example.com", "acc")
+	assert.Equal(t, 1, testutil.CollectAndCount(zoneHTTP2Percentage))
+}
+
+// -------- Test: sample timestamps --------
+func TestAddHTTPGroups_SampleTimestamps(t *testing.T) {
+	payload := `{
+		"httpRequests1mGroups": [{
+			"dimensions": {"datetime": "2024-05-01T10:15:00Z"},
+			"sum": {"requests": 7}
+		}],
+		"zoneTag": "zone1"
+	}`
+
+	var z models.ZoneRespHTTPGroups
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	SetSampleTimestamps(true)
+	defer SetSampleTimestamps(false)
+	defer zoneGroupTimes.Delete("stamped.example.com")
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(withSampleTimestamps(zoneRequestTotal))
+	addHTTPGroups(&z, "stamped.example.com", "acc")
+
+	families, err := reg.Gather()
+	assert.NoError(t, err)
+	var found bool
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "zone" && l.GetValue() == "stamped.example.com" {
+					found = true
+					assert.Equal(t, time.Date(2024, 5, 1, 10, 15, 0, 0, time.UTC).UnixMilli(), m.GetTimestampMs())
+				}
+			}
+		}
+	}
+	assert.True(t, found)
+
+	// Off by default: the collector is not wrapped and carries no timestamp
+	SetSampleTimestamps(false)
+	assert.Same(t, zoneRequestTotal, withSampleTimestamps(zoneRequestTotal).(*prometheus.CounterVec))
+}
+
+// -------- Test: firewall events by WAF category --------
+func TestAddFirewallGroups_WAFCategories(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	setViper(t, "cf_api_token", "dummy-token")
+	setViper(t, "enable_waf_categories", true)
+	defer zoneWAFCategories.Delete("zone1")
+
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/zones/zone1/rulesets",
+		httpmock.NewStringResponder(200, `{"success": true, "result": [{"id": "managed", "phase": "http_request_firewall_managed"}]}`))
+	httpmock.RegisterResponder("GET", "https://api.cloudflare.com/client/v4/zones/zone1/rulesets/managed",
+		httpmock.NewStringResponder(200, `{"success": true, "result": {"id": "managed", "rules": [
+			{"id": "r-sqli", "categories": ["sqli"]},
+			{"id": "r-both", "categories": ["xss", "sqli"]}
+		]}}`))
+
+	fetchWAFCategories(context.Background(), []cloudflare.Zone{{ID: "zone1", Name: "example.com"}})
+
+	payload := `{
+		"zoneTag": "zone1",
+		"firewallEventsAdaptiveGroups": [
+			{"count": 5, "dimensions": {"action": "block", "source": "waf", "ruleId": "r-sqli"}},
+			{"count": 3, "dimensions": {"action": "block", "source": "waf", "ruleId": "r-both"}},
+			{"count": 2, "dimensions": {"action": "block", "source": "firewallrules", "ruleId": "custom"}}
+		]
+	}`
+
+	var z models.ZoneRespFirewallGroups
+	assert.NoError(t, json.Unmarshal([]byte(payload), &z))
+
+	zoneWAFCategoryEventsTotal.Reset()
+	addFirewallGroups(&z, "example.com", "acc")
+
+	category := func(c string) float64 {
+		return testutil.ToFloat64(zoneWAFCategoryEventsTotal.With(prometheus.Labels{"zone": "example.com", "account": "acc", "category": c}))
+	}
+	assert.Equal(t, float64(8), category("sqli"))
+	assert.Equal(t, float64(3), category("xss"))
+	// Rules without categories are not counted
+	assert.Equal(t, 2, testutil.CollectAndCount(zoneWAFCategoryEventsTotal))
+
+	// The categories are reused until they are due for a refresh
+	fetchWAFCategories(context.Background(), []cloudflare.Zone{{ID: "zone1", Name: "example.com"}})
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["GET https://api.cloudflare.com/client/v4/zones/zone1/rulesets"])
+}
@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/lablabs/cloudflare-exporter/internal/client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ParsePushHeaders parses a comma delimited Name=Value list into headers
+// sent with every push request.
+func ParsePushHeaders(s string) (http.Header, error) {
+	header := http.Header{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid push header %q, expected Name=Value", entry)
+		}
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return header, nil
+}
+
+// PushGateway replaces the job's metrics on a Pushgateway with those from gatherer.
+func PushGateway(ctx context.Context, url, job string, header http.Header, gatherer prometheus.Gatherer) error {
+	pusher := push.New(url, job).
+		Gatherer(gatherer).
+		Header(header).
+		Client(&http.Client{Transport: client.SharedTransport()})
+	if err := pusher.PushContext(ctx); err != nil {
+		return fmt.Errorf("push to pushgateway: %w", err)
+	}
+	return nil
+}
+
+// RemoteWrite sends the metrics from gatherer to a Prometheus remote-write
+// endpoint as a single snappy-compressed WriteRequest.
+func RemoteWrite(ctx context.Context, url string, header http.Header, gatherer prometheus.Gatherer) error {
+	families, err := gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("gather metrics: %w", err)
+	}
+
+	body := snappy.Encode(nil, encodeWriteRequest(families, time.Now().UnixMilli()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create remote write request: %w", err)
+	}
+	for name, values := range header {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := (&http.Client{Transport: client.SharedTransport()}).Do(req)
+	if err != nil {
+		return fmt.Errorf("remote write: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("remote write: unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// encodeWriteRequest flattens the metric families into remote-write time
+// series. Histograms and summaries are split into their classic
+// _bucket/_sum/_count series, matching what a scrape would ingest.
+func encodeWriteRequest(families []*dto.MetricFamily, ts int64) []byte {
+	var buf []byte
+	series := func(name string, labels []*dto.LabelPair, extra map[string]string, value float64) {
+		pairs := map[string]string{"__name__": name}
+		for _, l := range labels {
+			pairs[l.GetName()] = l.GetValue()
+		}
+		for k, v := range extra {
+			pairs[k] = v
+		}
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, encodeTimeSeries(pairs, value, ts))
+	}
+
+	for _, mf := range families {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			labels := m.GetLabel()
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				series(name, labels, nil, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				series(name, labels, nil, m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				series(name, labels, nil, m.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				sawInf := false
+				for _, b := range h.GetBucket() {
+					sawInf = sawInf || math.IsInf(b.GetUpperBound(), +1)
+					series(name+"_bucket", labels, map[string]string{"le": formatFloat(b.GetUpperBound())}, float64(b.GetCumulativeCount()))
+				}
+				if !sawInf {
+					series(name+"_bucket", labels, map[string]string{"le": "+Inf"}, float64(h.GetSampleCount()))
+				}
+				series(name+"_sum", labels, nil, h.GetSampleSum())
+				series(name+"_count", labels, nil, float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					series(name, labels, map[string]string{"quantile": formatFloat(q.GetQuantile())}, q.GetValue())
+				}
+				series(name+"_sum", labels, nil, s.GetSampleSum())
+				series(name+"_count", labels, nil, float64(s.GetSampleCount()))
+			}
+		}
+	}
+	return buf
+}
+
+// encodeTimeSeries encodes one TimeSeries message with a single sample.
+// Remote write requires labels sorted by name.
+func encodeTimeSeries(pairs map[string]string, value float64, ts int64) []byte {
+	names := make([]string, 0, len(pairs))
+	for k := range pairs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var buf []byte
+	for _, k := range names {
+		var label []byte
+		label = protowire.AppendTag(label, 1, protowire.BytesType)
+		label = protowire.AppendString(label, k)
+		label = protowire.AppendTag(label, 2, protowire.BytesType)
+		label = protowire.AppendString(label, pairs[k])
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, label)
+	}
+
+	var sample []byte
+	sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(value))
+	sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, uint64(ts))
+	buf = protowire.AppendTag(buf, 2, protowire.BytesType)
+	buf = protowire.AppendBytes(buf, sample)
+	return buf
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, +1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// renamedCounter collects a counter vec under its new name and, for one
+// release, also under its deprecated old name so dashboards keep working.
+type renamedCounter struct {
+	vec       *prometheus.CounterVec
+	oldDesc   *prometheus.Desc
+	oldType   prometheus.ValueType
+	labelKeys []string
+}
+
+// newRenamedCounter wraps vec, re-exporting every series as oldName with oldType.
+func newRenamedCounter(vec *prometheus.CounterVec, oldName, newName MetricName, oldType prometheus.ValueType, labelKeys []string) *renamedCounter {
+	return &renamedCounter{
+		vec:       vec,
+		oldDesc:   prometheus.NewDesc(oldName.String(), fmt.Sprintf("Deprecated: use %s", newName), labelKeys, nil),
+		oldType:   oldType,
+		labelKeys: labelKeys,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (r *renamedCounter) Describe(ch chan<- *prometheus.Desc) {
+	r.vec.Describe(ch)
+	ch <- r.oldDesc
+}
+
+// Collect implements prometheus.Collector.
+func (r *renamedCounter) Collect(ch chan<- prometheus.Metric) {
+	series := make(chan prometheus.Metric)
+	go func() {
+		r.vec.Collect(series)
+		close(series)
+	}()
+
+	for m := range series {
+		ch <- m
+
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			continue
+		}
+		values := make(map[string]string, len(pb.GetLabel()))
+		for _, l := range pb.GetLabel() {
+			values[l.GetName()] = l.GetValue()
+		}
+		labelValues := make([]string, len(r.labelKeys))
+		for i, k := range r.labelKeys {
+			labelValues[i] = values[k]
+		}
+		ch <- prometheus.MustNewConstMetric(r.oldDesc, r.oldType, pb.GetCounter().GetValue(), labelValues...)
+	}
+}
+
+// DeletePartialMatch deletes matching series from the wrapped vec, which also
+// removes them from the deprecated name.
+func (r *renamedCounter) DeletePartialMatch(labels prometheus.Labels) int {
+	return r.vec.DeletePartialMatch(labels)
+}
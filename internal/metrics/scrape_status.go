@@ -0,0 +1,190 @@
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cloudflareAPI "github.com/lablabs/cloudflare-exporter/internal/cloudflare"
+	"github.com/lablabs/cloudflare-exporter/internal/logging"
+)
+
+// lastScrapeSuccess holds the unix nano timestamp of the last successful FetchMetrics cycle.
+var lastScrapeSuccess atomic.Int64
+
+// familySucceeded is set once any metric family has been fetched successfully
+// in the current FetchMetrics cycle.
+var familySucceeded atomic.Bool
+
+// firstScrapeDone is set once the first FetchMetrics cycle has finished.
+var firstScrapeDone atomic.Bool
+
+// scrapeRunning is set while a scrape started by StartScrape is in flight.
+var scrapeRunning atomic.Bool
+
+// scrapeWG tracks the in-flight scrape so shutdown can wait for it.
+var scrapeWG sync.WaitGroup
+
+// ScrapeStatus describes the most recent FetchMetrics cycle for debugging.
+type ScrapeStatus struct {
+	LastScrape      time.Time      `json:"last_scrape"`
+	LastSuccess     time.Time      `json:"last_success"`
+	DurationSeconds float64        `json:"duration_seconds"`
+	FamilyErrors    map[string]int `json:"family_errors"`
+	LastError       string         `json:"last_error,omitempty"`
+	LastErrorAt     time.Time      `json:"last_error_at"`
+}
+
+var (
+	scrapeStatusMu sync.Mutex
+	scrapeStatus   = ScrapeStatus{FamilyErrors: map[string]int{}}
+)
+
+// familyPermissions names the API token permission each metric family needs,
+// for permission error messages.
+var familyPermissions = map[string]string{
+	"zone_analytics":   "Zone > Analytics",
+	"http":             "Zone > Analytics",
+	"firewall":         "Zone > Analytics",
+	"health_check":     "Zone > Analytics",
+	"http_adaptive":    "Zone > Analytics",
+	"edge_country":     "Zone > Analytics",
+	"rate_limit":       "Zone > Analytics",
+	"colocation":       "Zone > Analytics",
+	"bot_score":        "Zone > Analytics",
+	"request_path":     "Zone > Analytics",
+	"argo":             "Zone > Analytics",
+	"load_balancers":   "Zone > Load Balancers",
+	"workers":          "Account > Workers Scripts",
+	"logpush":          "Account > Logpush",
+	"magic_transit":    "Account > Magic Transit",
+	"turnstile":        "Account > Account Analytics",
+	"stream":           "Account > Account Analytics",
+	"ssl_certificates": "Zone > SSL and Certificates",
+	"page_shield":      "Zone > Page Shield",
+	"waf_categories":   "Zone > Firewall Services",
+	"images":           "Account > Account Analytics",
+	"durable_objects":  "Account > Account Analytics",
+	"queues":           "Account > Account Analytics",
+}
+
+// recordPermissionError logs which permission a metric family is missing and
+// counts it when err is a permission error. Other errors are ignored.
+func recordPermissionError(family string, err error) {
+	if !cloudflareAPI.IsPermissionError(err) {
+		return
+	}
+	permission, ok := familyPermissions[family]
+	if !ok {
+		permission = "unknown"
+	}
+	logging.Error("Missing API token permission for metric family", map[string]interface{}{
+		"family":     family,
+		"permission": permission,
+		"error":      err.Error(),
+	})
+	exporterPermissionErrorsTotal.With(prometheus.Labels{"family": family}).Inc()
+}
+
+// RecordScrapeTimeout counts a scrape cancelled by scrape_timeout.
+func RecordScrapeTimeout() {
+	exporterScrapeTimeoutsTotal.Inc()
+}
+
+// markFamilySuccess records now as the last successful fetch of family.
+func markFamilySuccess(family string) {
+	exporterFamilyLastSuccess.With(prometheus.Labels{"family": family}).SetToCurrentTime()
+	familySucceeded.Store(true)
+}
+
+// recordFetchError counts a failed fetch for a metric family and keeps the
+// error message for the verbose health endpoint.
+func recordFetchError(family string, err error) {
+	exporterFetchErrorsTotal.With(prometheus.Labels{"family": family}).Inc()
+	recordPermissionError(family, err)
+
+	scrapeStatusMu.Lock()
+	defer scrapeStatusMu.Unlock()
+	scrapeStatus.FamilyErrors[family]++
+	scrapeStatus.LastError = family + ": " + err.Error()
+	scrapeStatus.LastErrorAt = time.Now().UTC()
+}
+
+// recordScrapeDone records the end of a FetchMetrics cycle started at start.
+func recordScrapeDone(start time.Time, err error) {
+	now := time.Now()
+	firstScrapeDone.Store(true)
+
+	scrapeStatusMu.Lock()
+	defer scrapeStatusMu.Unlock()
+	scrapeStatus.LastScrape = now.UTC()
+	scrapeStatus.DurationSeconds = now.Sub(start).Seconds()
+	if err != nil {
+		scrapeStatus.LastError = err.Error()
+		scrapeStatus.LastErrorAt = now.UTC()
+	}
+}
+
+// LastScrapeStatus returns a copy of the state of the last FetchMetrics cycle.
+func LastScrapeStatus() ScrapeStatus {
+	scrapeStatusMu.Lock()
+	defer scrapeStatusMu.Unlock()
+
+	status := scrapeStatus
+	status.LastSuccess = LastScrapeSuccess().UTC()
+	status.FamilyErrors = make(map[string]int, len(scrapeStatus.FamilyErrors))
+	for family, n := range scrapeStatus.FamilyErrors {
+		status.FamilyErrors[family] = n
+	}
+	return status
+}
+
+// FirstScrapeDone reports whether a FetchMetrics cycle has finished, whether
+// or not it succeeded.
+func FirstScrapeDone() bool {
+	return firstScrapeDone.Load()
+}
+
+// markScrapeSuccess records the completion time of a FetchMetrics cycle that
+// fetched at least one metric family. A cycle in which every family failed is
+// not a success and leaves the previous timestamp in place.
+func markScrapeSuccess(t time.Time) {
+	if !familySucceeded.Load() {
+		return
+	}
+	lastScrapeSuccess.Store(t.UnixNano())
+}
+
+// LastScrapeSuccess returns the time of the last successful FetchMetrics cycle,
+// or the zero time if no cycle has completed yet.
+func LastScrapeSuccess() time.Time {
+	ns := lastScrapeSuccess.Load()
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// StartScrape runs scrape in a tracked goroutine unless a previous scrape is
+// still running, in which case the tick is skipped and false is returned.
+func StartScrape(scrape func()) bool {
+	if !scrapeRunning.CompareAndSwap(false, true) {
+		exporterScrapesSkippedTotal.Inc()
+		return false
+	}
+
+	scrapeWG.Add(1)
+	go func() {
+		defer scrapeWG.Done()
+		defer scrapeRunning.Store(false)
+		scrape()
+	}()
+	return true
+}
+
+// WaitForScrapes blocks until the in-flight scrape, if any, has finished.
+func WaitForScrapes() {
+	scrapeWG.Wait()
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ZoneSnapshot holds the key totals for one zone from a scrape.
+type ZoneSnapshot struct {
+	Zone           string  `json:"zone"`
+	Account        string  `json:"account"`
+	Requests       uint64  `json:"requests"`
+	BandwidthBytes uint64  `json:"bandwidth_bytes"`
+	Threats        uint64  `json:"threats"`
+	ErrorRate4xx   float64 `json:"error_rate_4xx"`
+	ErrorRate5xx   float64 `json:"error_rate_5xx"`
+}
+
+// Snapshot is the per-zone summary of the last successful scrape.
+type Snapshot struct {
+	ScrapedAt time.Time      `json:"scraped_at"`
+	Zones     []ZoneSnapshot `json:"zones"`
+}
+
+var (
+	snapshotMu sync.Mutex
+	// pendingZones collects zone totals while a scrape is running.
+	pendingZones = map[string]ZoneSnapshot{}
+	lastSnapshot = Snapshot{Zones: []ZoneSnapshot{}}
+)
+
+// recordZoneSnapshot stores the totals for a zone in the running scrape.
+func recordZoneSnapshot(s ZoneSnapshot) {
+	snapshotMu.Lock()
+	defer snapshotMu.Unlock()
+	pendingZones[s.Zone] = s
+}
+
+// resetSnapshot drops totals collected by an earlier, unpublished scrape.
+func resetSnapshot() {
+	snapshotMu.Lock()
+	defer snapshotMu.Unlock()
+	pendingZones = map[string]ZoneSnapshot{}
+}
+
+// publishSnapshot makes the collected totals visible through LastSnapshot.
+func publishSnapshot(t time.Time) {
+	snapshotMu.Lock()
+	defer snapshotMu.Unlock()
+
+	zones := make([]ZoneSnapshot, 0, len(pendingZones))
+	for _, z := range pendingZones {
+		zones = append(zones, z)
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].Zone < zones[j].Zone })
+
+	lastSnapshot = Snapshot{ScrapedAt: t.UTC(), Zones: zones}
+	pendingZones = map[string]ZoneSnapshot{}
+}
+
+// LastSnapshot returns the per-zone summary of the last successful scrape.
+func LastSnapshot() Snapshot {
+	snapshotMu.Lock()
+	defer snapshotMu.Unlock()
+	return lastSnapshot
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/lablabs/cloudflare-exporter/internal/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+)
+
+// partialDeleter is implemented by metric vecs whose series can be removed by label.
+type partialDeleter interface {
+	DeletePartialMatch(labels prometheus.Labels) int
+}
+
+var (
+	staleMu sync.Mutex
+	// registeredVecs holds every registered collector that can drop series by label.
+	registeredVecs []partialDeleter
+	// knownZones holds the zone names present in the previous scrape.
+	knownZones map[string]struct{}
+	// knownCertificates holds, per zone ID, the certificate series set in the
+	// previous scrape keyed by certificate ID.
+	knownCertificates = map[string]map[string]prometheus.Labels{}
+)
+
+// mustRegister registers c and remembers it for stale series cleanup.
+func mustRegister(c prometheus.Collector) {
+	prometheus.MustRegister(c)
+	if d, ok := c.(partialDeleter); ok {
+		staleMu.Lock()
+		registeredVecs = append(registeredVecs, d)
+		staleMu.Unlock()
+	}
+}
+
+// resetStaleZones deletes the series of zones that were present in the
+// previous scrape but not in zones. It is a no-op unless reset_stale_metrics is set.
+func resetStaleZones(zones []cloudflare.Zone) {
+	if !viper.GetBool("reset_stale_metrics") {
+		return
+	}
+
+	current := make(map[string]struct{}, len(zones))
+	for _, z := range zones {
+		current[z.Name] = struct{}{}
+	}
+
+	staleMu.Lock()
+	defer staleMu.Unlock()
+
+	for name := range knownZones {
+		if _, ok := current[name]; ok {
+			continue
+		}
+		deleted := 0
+		for _, vec := range registeredVecs {
+			deleted += vec.DeletePartialMatch(prometheus.Labels{"zone": name})
+		}
+		logging.Info("Removed stale zone series", map[string]interface{}{"zone": name, "series": deleted})
+	}
+	knownZones = current
+}
+
+// resetStaleCertificates deletes the certificate series of zoneID that were
+// set in the previous scrape but not in current, e.g. after a certificate was
+// renewed or its status changed. It is a no-op unless reset_stale_metrics is set.
+func resetStaleCertificates(zoneID string, current map[string]prometheus.Labels) {
+	if !viper.GetBool("reset_stale_metrics") {
+		return
+	}
+
+	// Certificates can share a label set, so only drop sets no longer in use
+	inUse := make(map[string]struct{}, len(current))
+	for _, labels := range current {
+		inUse[labelsKey(labels)] = struct{}{}
+	}
+
+	staleMu.Lock()
+	defer staleMu.Unlock()
+
+	for certID, labels := range knownCertificates[zoneID] {
+		if _, ok := inUse[labelsKey(labels)]; ok {
+			continue
+		}
+		zoneCertificateValidation.Delete(labels)
+		zoneCertificateDaysUntilExpiry.Delete(labels)
+		logging.Info("Removed stale certificate series", map[string]interface{}{"zone_id": zoneID, "certificate_id": certID})
+	}
+	knownCertificates[zoneID] = current
+}
+
+// labelsKey returns a stable string for a label set.
+func labelsKey(labels prometheus.Labels) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\xff")
+}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	// sampleTimestamps exports the zone totals with the Cloudflare group
+	// datetime as sample timestamp. See SetSampleTimestamps.
+	sampleTimestamps bool
+	// zoneGroupTimes holds, per zone name, the datetime of the last HTTP group.
+	zoneGroupTimes sync.Map
+)
+
+// SetSampleTimestamps makes the zone totals registered by MustRegisterMetrics
+// carry the datetime of the Cloudflare group they were last updated from
+// instead of the scrape time. It must be called before MustRegisterMetrics.
+func SetSampleTimestamps(enabled bool) {
+	sampleTimestamps = enabled
+}
+
+// recordZoneGroupTime remembers datetime, an RFC 3339 group datetime, as the
+// sample timestamp of the totals of zone. Unparsable datetimes are ignored.
+func recordZoneGroupTime(zone, datetime string) {
+	if !sampleTimestamps {
+		return
+	}
+	t, err := time.Parse(time.RFC3339, datetime)
+	if err != nil {
+		return
+	}
+	zoneGroupTimes.Store(zone, t)
+}
+
+// timestampedCollector sets the group datetime recorded for each series'
+// zone as its sample timestamp. Series of zones without a datetime keep the
+// scrape time.
+type timestampedCollector struct {
+	prometheus.Collector
+}
+
+// withSampleTimestamps wraps c in a timestampedCollector when sample
+// timestamps are enabled, otherwise it returns c.
+func withSampleTimestamps(c prometheus.Collector) prometheus.Collector {
+	if !sampleTimestamps {
+		return c
+	}
+	return &timestampedCollector{Collector: c}
+}
+
+// Collect implements prometheus.Collector.
+func (t *timestampedCollector) Collect(ch chan<- prometheus.Metric) {
+	series := make(chan prometheus.Metric)
+	go func() {
+		t.Collector.Collect(series)
+		close(series)
+	}()
+
+	for m := range series {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			ch <- m
+			continue
+		}
+		ts, ok := time.Time{}, false
+		for _, l := range pb.GetLabel() {
+			if l.GetName() == "zone" {
+				var v any
+				if v, ok = zoneGroupTimes.Load(l.GetValue()); ok {
+					ts = v.(time.Time)
+				}
+				break
+			}
+		}
+		if !ok {
+			ch <- m
+			continue
+		}
+		ch <- prometheus.NewMetricWithTimestamp(ts, m)
+	}
+}
+
+// DeletePartialMatch deletes matching series from the wrapped collector so
+// stale zone cleanup keeps working.
+func (t *timestampedCollector) DeletePartialMatch(labels prometheus.Labels) int {
+	if d, ok := t.Collector.(partialDeleter); ok {
+		return d.DeletePartialMatch(labels)
+	}
+	return 0
+}
@@ -0,0 +1,20 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WarmupGate responds 503 until ready reports true, so scrapers don't read
+// the near-empty registry before the first scrape has finished.
+func WarmupGate(ready func() bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !ready() {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"status": "warming up"})
+			return
+		}
+
+		c.Next()
+	}
+}
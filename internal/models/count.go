@@ -0,0 +1,43 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DecodeErrorsTotal counts Count values that could not be decoded.
+// It is registered by metrics.MustRegisterMetrics.
+var DecodeErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "cloudflare_exporter_decode_errors_total",
+	Help: "Number of Cloudflare API response fields with an unexpected type, decoded as 0",
+})
+
+// Count is a counter field of a GraphQL response. Besides a JSON number it
+// accepts a numeric string and null, so a changed field type in the API does
+// not fail the whole response: values that are not numbers decode to 0 and are
+// counted in DecodeErrorsTotal.
+type Count uint64
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *Count) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" {
+		*c = 0
+		return nil
+	}
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		*c = Count(n)
+		return nil
+	}
+	// Large counts may come in exponent notation
+	if f, err := strconv.ParseFloat(s, 64); err == nil && f >= 0 {
+		*c = Count(f)
+		return nil
+	}
+
+	*c = 0
+	DecodeErrorsTotal.Inc()
+	return nil
+}
@@ -58,6 +58,32 @@ type LogpushResponse struct {
 			Final           int    `json:"final"`
 		}
 	} `json:"logpushHealthAdaptiveGroups"`
+
+	// LogpushHealthSuccessGroups holds the successful (status 200) deliveries
+	// and is only set for account-level results.
+	LogpushHealthSuccessGroups []struct {
+		Count uint64 `json:"count"`
+
+		Dimensions struct {
+			DestinationType string `json:"destinationType"`
+			JobID           int    `json:"jobId"`
+		}
+	} `json:"logpushHealthSuccess"`
+
+	// ZoneTag is only set for zone-level results.
+	ZoneTag string `json:"zoneTag"`
+}
+
+// LogpushJobsResponse is the list of logpush jobs of an account or zone.
+type LogpushJobsResponse struct {
+	Result []LogpushJob `json:"result"`
+}
+
+// LogpushJob is a configured logpush job.
+type LogpushJob struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Dataset string `json:"dataset"`
 }
 
 // AccountResp represents an account's invocations and statistics.
@@ -69,8 +95,8 @@ type AccountResp struct {
 		}
 
 		Sum struct {
-			Requests uint64  `json:"requests"`
-			Errors   uint64  `json:"errors"`
+			Requests Count   `json:"requests"`
+			Errors   Count   `json:"errors"`
 			Duration float64 `json:"duration"`
 		} `json:"sum"`
 
@@ -116,13 +142,13 @@ type ZoneResp struct {
 			Datetime string `json:"datetime"`
 		} `json:"dimensions"`
 		Unique struct {
-			Uniques uint64 `json:"uniques"`
+			Uniques Count `json:"uniques"`
 		} `json:"uniq"`
 		Sum struct {
-			Bytes          uint64 `json:"bytes"`
-			CachedBytes    uint64 `json:"cachedBytes"`
-			CachedRequests uint64 `json:"cachedRequests"`
-			Requests       uint64 `json:"requests"`
+			Bytes          Count `json:"bytes"`
+			CachedBytes    Count `json:"cachedBytes"`
+			CachedRequests Count `json:"cachedRequests"`
+			Requests       Count `json:"requests"`
 			BrowserMap     []struct {
 				PageViews       uint64 `json:"pageViews"`
 				UaBrowserFamily string `json:"uaBrowserFamily"`
@@ -133,6 +159,7 @@ type ZoneResp struct {
 			} `json:"clientHTTPVersionMap"`
 			ClientSSL []struct {
 				Protocol string `json:"clientSSLProtocol"`
+				Requests uint64 `json:"requests"`
 			} `json:"clientSSLMap"`
 			ContentType []struct {
 				Bytes                   uint64 `json:"bytes"`
@@ -145,13 +172,13 @@ type ZoneResp struct {
 				Requests          uint64 `json:"requests"`
 				Threats           uint64 `json:"threats"`
 			} `json:"countryMap"`
-			EncryptedBytes    uint64 `json:"encryptedBytes"`
-			EncryptedRequests uint64 `json:"encryptedRequests"`
+			EncryptedBytes    Count `json:"encryptedBytes"`
+			EncryptedRequests Count `json:"encryptedRequests"`
 			IPClass           []struct {
 				Type     string `json:"ipType"`
 				Requests uint64 `json:"requests"`
 			} `json:"ipClassMap"`
-			PageViews      uint64 `json:"pageViews"`
+			PageViews      Count `json:"pageViews"`
 			ResponseStatus []struct {
 				EdgeResponseStatus int    `json:"edgeResponseStatus"`
 				Requests           uint64 `json:"requests"`
@@ -160,7 +187,7 @@ type ZoneResp struct {
 				Name     string `json:"threatPathingName"`
 				Requests uint64 `json:"requests"`
 			} `json:"threatPathingMap"`
-			Threats uint64 `json:"threats"`
+			Threats Count `json:"threats"`
 		} `json:"sum"`
 	} `json:"httpRequests1mGroups"`
 
@@ -297,9 +324,53 @@ type Zone struct {
 	Certificates []Certificate `json:"certificates"`
 }
 
+// PageShieldResponse is a page of Page Shield scripts for a zone.
+type PageShieldResponse struct {
+	Result     []PageShieldScript `json:"result"`
+	ResultInfo struct {
+		Page       int `json:"page"`
+		TotalPages int `json:"total_pages"`
+	} `json:"result_info"`
+}
+
+// PageShieldScript is a script seen by Page Shield on a zone.
+type PageShieldScript struct {
+	ID                      string `json:"id"`
+	URL                     string `json:"url"`
+	Host                    string `json:"host"`
+	DomainReportedMalicious bool   `json:"domain_reported_malicious"`
+	URLReportedMalicious    bool   `json:"url_reported_malicious"`
+}
+
+// RulesetsResponse lists the rulesets of a zone.
+type RulesetsResponse struct {
+	Result []struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Kind  string `json:"kind"`
+		Phase string `json:"phase"`
+	} `json:"result"`
+}
+
+// RulesetResponse is a ruleset with its rules. Unlike cloudflare-go it keeps
+// the categories of managed rules.
+type RulesetResponse struct {
+	Result struct {
+		ID    string `json:"id"`
+		Rules []struct {
+			ID         string   `json:"id"`
+			Categories []string `json:"categories"`
+		} `json:"rules"`
+	} `json:"result"`
+}
+
 // SSLResponse represents array of Zones.
 type SSLResponse struct {
 	Result []Zone `json:"result"`
+
+	// FetchedZoneIDs lists the zones whose certificate packs were fetched,
+	// including zones without any certificate.
+	FetchedZoneIDs []string `json:"-"`
 }
 
 // CloudflareResponse represents the Cloudflare API response for zones.
@@ -318,13 +389,13 @@ type ZoneRespHTTPGroups struct {
 			Datetime string `json:"datetime"`
 		} `json:"dimensions"`
 		Unique struct {
-			Uniques uint64 `json:"uniques"`
+			Uniques Count `json:"uniques"`
 		} `json:"uniq"`
 		Sum struct {
-			Bytes          uint64 `json:"bytes"`
-			CachedBytes    uint64 `json:"cachedBytes"`
-			CachedRequests uint64 `json:"cachedRequests"`
-			Requests       uint64 `json:"requests"`
+			Bytes          Count `json:"bytes"`
+			CachedBytes    Count `json:"cachedBytes"`
+			CachedRequests Count `json:"cachedRequests"`
+			Requests       Count `json:"requests"`
 			BrowserMap     []struct {
 				PageViews       uint64 `json:"pageViews"`
 				UaBrowserFamily string `json:"uaBrowserFamily"`
@@ -335,6 +406,7 @@ type ZoneRespHTTPGroups struct {
 			} `json:"clientHTTPVersionMap"`
 			ClientSSL []struct {
 				Protocol string `json:"clientSSLProtocol"`
+				Requests uint64 `json:"requests"`
 			} `json:"clientSSLMap"`
 			ContentType []struct {
 				Bytes                   uint64 `json:"bytes"`
@@ -347,13 +419,13 @@ type ZoneRespHTTPGroups struct {
 				Requests          uint64 `json:"requests"`
 				Threats           uint64 `json:"threats"`
 			} `json:"countryMap"`
-			EncryptedBytes    uint64 `json:"encryptedBytes"`
-			EncryptedRequests uint64 `json:"encryptedRequests"`
+			EncryptedBytes    Count `json:"encryptedBytes"`
+			EncryptedRequests Count `json:"encryptedRequests"`
 			IPClass           []struct {
 				Type     string `json:"ipType"`
 				Requests uint64 `json:"requests"`
 			} `json:"ipClassMap"`
-			PageViews      uint64 `json:"pageViews"`
+			PageViews      Count `json:"pageViews"`
 			ResponseStatus []struct {
 				EdgeResponseStatus int    `json:"edgeResponseStatus"`
 				Requests           uint64 `json:"requests"`
@@ -362,7 +434,7 @@ type ZoneRespHTTPGroups struct {
 				Name     string `json:"threatPathingName"`
 				Requests uint64 `json:"requests"`
 			} `json:"threatPathingMap"`
-			Threats uint64 `json:"threats"`
+			Threats Count `json:"threats"`
 		} `json:"sum"`
 	} `json:"httpRequests1mGroups"`
 	FirewallEventsAdaptiveGroups []struct {
@@ -401,6 +473,22 @@ type ZoneRespFirewallGroups struct {
 		} `json:"dimensions"`
 	} `json:"firewallEventsAdaptiveGroups"`
 
+	FirewallEventsByKind []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			Kind string `json:"kind"`
+		} `json:"dimensions"`
+	} `json:"firewallEventsByKind"`
+
+	FirewallEventsByASN []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			ClientASN            string `json:"clientAsn"`
+			ClientASNDescription string `json:"clientASNDescription"`
+			Action               string `json:"action"`
+		} `json:"dimensions"`
+	} `json:"firewallEventsByAsn"`
+
 	ZoneTag string `json:"zoneTag"`
 }
 
@@ -475,5 +563,480 @@ type ZoneRespAdaptiveGroups struct {
 		}
 	} `json:"httpRequestsAdaptiveGroups"`
 
+	HTTPRequestsOriginStatus []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			OriginResponseStatus uint16 `json:"originResponseStatus"`
+		} `json:"dimensions"`
+	} `json:"httpRequestsOriginStatus"`
+
+	HTTPRequestsOriginConnectivity []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			EdgeResponseStatus uint16 `json:"edgeResponseStatus"`
+		} `json:"dimensions"`
+	} `json:"httpRequestsOriginConnectivity"`
+
+	HTTPRequestsCacheStatus []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			CacheStatus string `json:"cacheStatus"`
+		} `json:"dimensions"`
+	} `json:"httpRequestsCacheStatus"`
+
+	HTTPRequestsMethod []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			ClientRequestHTTPMethodName string `json:"clientRequestHTTPMethodName"`
+		} `json:"dimensions"`
+	} `json:"httpRequestsMethod"`
+
+	HTTPRequestsHostBytes []struct {
+		Dimensions struct {
+			ClientRequestHTTPHost string `json:"clientRequestHTTPHost"`
+		} `json:"dimensions"`
+		Sum struct {
+			EdgeResponseBytes uint64 `json:"edgeResponseBytes"`
+		} `json:"sum"`
+	} `json:"httpRequestsHostBytes"`
+
+	ZoneTag string `json:"zoneTag"`
+}
+
+// CloudflareResponseRateLimitGroups represents the Cloudflare API response for rate limit events.
+type CloudflareResponseRateLimitGroups struct {
+	Viewer struct {
+		Zones []ZoneRespRateLimitGroups `json:"zones"`
+	} `json:"viewer"`
+}
+
+// ZoneRespRateLimitGroups represents a zone's rate limit events grouped by action and rule.
+type ZoneRespRateLimitGroups struct {
+	RateLimitEventsAdaptiveGroups []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			Action string `json:"action"`
+			RuleID string `json:"ruleId"`
+		} `json:"dimensions"`
+	} `json:"rateLimitEventsAdaptiveGroups"`
+
+	ZoneTag string `json:"zoneTag"`
+}
+
+// CloudflareResponseZoneAnalytics represents the combined zone analytics response
+// returned by a single GraphQL request for a zone batch.
+type CloudflareResponseZoneAnalytics struct {
+	Viewer struct {
+		Zones []ZoneRespAnalytics `json:"zones"`
+	} `json:"viewer"`
+}
+
+// ZoneRespAnalytics represents a zone's HTTP, firewall, health check and rate limit groups.
+type ZoneRespAnalytics struct {
+	HTTP1mGroups []struct {
+		Dimensions struct {
+			Datetime string `json:"datetime"`
+		} `json:"dimensions"`
+		Unique struct {
+			Uniques Count `json:"uniques"`
+		} `json:"uniq"`
+		Sum struct {
+			Bytes          Count `json:"bytes"`
+			CachedBytes    Count `json:"cachedBytes"`
+			CachedRequests Count `json:"cachedRequests"`
+			Requests       Count `json:"requests"`
+			BrowserMap     []struct {
+				PageViews       uint64 `json:"pageViews"`
+				UaBrowserFamily string `json:"uaBrowserFamily"`
+			} `json:"browserMap"`
+			ClientHTTPVersion []struct {
+				Protocol string `json:"clientHTTPProtocol"`
+				Requests uint64 `json:"requests"`
+			} `json:"clientHTTPVersionMap"`
+			ClientSSL []struct {
+				Protocol string `json:"clientSSLProtocol"`
+				Requests uint64 `json:"requests"`
+			} `json:"clientSSLMap"`
+			ContentType []struct {
+				Bytes                   uint64 `json:"bytes"`
+				Requests                uint64 `json:"requests"`
+				EdgeResponseContentType string `json:"edgeResponseContentTypeName"`
+			} `json:"contentTypeMap"`
+			Country []struct {
+				Bytes             uint64 `json:"bytes"`
+				ClientCountryName string `json:"clientCountryName"`
+				Requests          uint64 `json:"requests"`
+				Threats           uint64 `json:"threats"`
+			} `json:"countryMap"`
+			EncryptedBytes    Count `json:"encryptedBytes"`
+			EncryptedRequests Count `json:"encryptedRequests"`
+			IPClass           []struct {
+				Type     string `json:"ipType"`
+				Requests uint64 `json:"requests"`
+			} `json:"ipClassMap"`
+			PageViews      Count `json:"pageViews"`
+			ResponseStatus []struct {
+				EdgeResponseStatus int    `json:"edgeResponseStatus"`
+				Requests           uint64 `json:"requests"`
+			} `json:"responseStatusMap"`
+			ThreatPathing []struct {
+				Name     string `json:"threatPathingName"`
+				Requests uint64 `json:"requests"`
+			} `json:"threatPathingMap"`
+			Threats Count `json:"threats"`
+		} `json:"sum"`
+	} `json:"httpRequests1mGroups"`
+
+	FirewallEventsAdaptiveGroups []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			Action                string `json:"action"`
+			Source                string `json:"source"`
+			RuleID                string `json:"ruleId"`
+			ClientCountryName     string `json:"clientCountryName"`
+			ClientRequestHTTPHost string `json:"clientRequestHTTPHost"`
+		} `json:"dimensions"`
+	} `json:"firewallEventsAdaptiveGroups"`
+
+	FirewallEventsByKind []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			Kind string `json:"kind"`
+		} `json:"dimensions"`
+	} `json:"firewallEventsByKind"`
+
+	FirewallEventsByASN []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			ClientASN            string `json:"clientAsn"`
+			ClientASNDescription string `json:"clientASNDescription"`
+			Action               string `json:"action"`
+		} `json:"dimensions"`
+	} `json:"firewallEventsByAsn"`
+
+	HealthCheckEventsAdaptiveGroups []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			HealthStatus  string `json:"healthStatus"`
+			OriginIP      string `json:"originIP"`
+			FailureReason string `json:"failureReason"`
+			Region        string `json:"region"`
+			Fqdn          string `json:"fqdn"`
+		} `json:"dimensions"`
+	} `json:"healthCheckEventsAdaptiveGroups"`
+
+	HTTPRequestsAdaptiveGroups []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			OriginResponseStatus  uint16 `json:"originResponseStatus"`
+			ClientCountryName     string `json:"clientCountryName"`
+			ClientRequestHTTPHost string `json:"clientRequestHTTPHost"`
+		} `json:"dimensions"`
+		Avg struct {
+			OriginResponseDurationMs float64 `json:"originResponseDurationMs"`
+		}
+	} `json:"httpRequestsAdaptiveGroups"`
+
+	HTTPRequestsOriginStatus []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			OriginResponseStatus uint16 `json:"originResponseStatus"`
+		} `json:"dimensions"`
+	} `json:"httpRequestsOriginStatus"`
+
+	HTTPRequestsOriginConnectivity []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			EdgeResponseStatus uint16 `json:"edgeResponseStatus"`
+		} `json:"dimensions"`
+	} `json:"httpRequestsOriginConnectivity"`
+
+	HTTPRequestsCacheStatus []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			CacheStatus string `json:"cacheStatus"`
+		} `json:"dimensions"`
+	} `json:"httpRequestsCacheStatus"`
+
+	HTTPRequestsMethod []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			ClientRequestHTTPMethodName string `json:"clientRequestHTTPMethodName"`
+		} `json:"dimensions"`
+	} `json:"httpRequestsMethod"`
+
+	HTTPRequestsHostBytes []struct {
+		Dimensions struct {
+			ClientRequestHTTPHost string `json:"clientRequestHTTPHost"`
+		} `json:"dimensions"`
+		Sum struct {
+			EdgeResponseBytes uint64 `json:"edgeResponseBytes"`
+		} `json:"sum"`
+	} `json:"httpRequestsHostBytes"`
+
+	HTTPRequestsEdgeCountryHost []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			EdgeResponseStatus    uint16 `json:"edgeResponseStatus"`
+			ClientCountryName     string `json:"clientCountryName"`
+			ClientRequestHTTPHost string `json:"clientRequestHTTPHost"`
+		} `json:"dimensions"`
+	} `json:"httpRequestsEdgeCountryHost"`
+
+	RateLimitEventsAdaptiveGroups []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			Action string `json:"action"`
+			RuleID string `json:"ruleId"`
+		} `json:"dimensions"`
+	} `json:"rateLimitEventsAdaptiveGroups"`
+
+	ZoneTag string `json:"zoneTag"`
+}
+
+// HTTPGroups returns the zone's HTTP 1m groups and firewall events as ZoneRespHTTPGroups.
+func (z ZoneRespAnalytics) HTTPGroups() ZoneRespHTTPGroups {
+	return ZoneRespHTTPGroups{
+		HTTP1mGroups:                 z.HTTP1mGroups,
+		FirewallEventsAdaptiveGroups: z.FirewallEventsAdaptiveGroups,
+		ZoneTag:                      z.ZoneTag,
+	}
+}
+
+// FirewallGroups returns the zone's firewall events as ZoneRespFirewallGroups.
+func (z ZoneRespAnalytics) FirewallGroups() ZoneRespFirewallGroups {
+	return ZoneRespFirewallGroups{
+		FirewallEventsAdaptiveGroups: z.FirewallEventsAdaptiveGroups,
+		FirewallEventsByKind:         z.FirewallEventsByKind,
+		FirewallEventsByASN:          z.FirewallEventsByASN,
+		ZoneTag:                      z.ZoneTag,
+	}
+}
+
+// HealthCheckGroups returns the zone's health check events as ZoneRespHealthCheckGroups.
+func (z ZoneRespAnalytics) HealthCheckGroups() ZoneRespHealthCheckGroups {
+	return ZoneRespHealthCheckGroups{
+		HealthCheckEventsAdaptiveGroups: z.HealthCheckEventsAdaptiveGroups,
+		ZoneTag:                         z.ZoneTag,
+	}
+}
+
+// AdaptiveGroups returns the zone's origin error groups as ZoneRespAdaptiveGroups.
+func (z ZoneRespAnalytics) AdaptiveGroups() ZoneRespAdaptiveGroups {
+	return ZoneRespAdaptiveGroups{
+		HTTPRequestsAdaptiveGroups:     z.HTTPRequestsAdaptiveGroups,
+		HTTPRequestsOriginStatus:       z.HTTPRequestsOriginStatus,
+		HTTPRequestsOriginConnectivity: z.HTTPRequestsOriginConnectivity,
+		HTTPRequestsCacheStatus:        z.HTTPRequestsCacheStatus,
+		HTTPRequestsMethod:             z.HTTPRequestsMethod,
+		HTTPRequestsHostBytes:          z.HTTPRequestsHostBytes,
+		ZoneTag:                        z.ZoneTag,
+	}
+}
+
+// HTTPRequestsEdge returns the zone's edge status groups as ZoneRespHTTPRequestsEdge.
+func (z ZoneRespAnalytics) HTTPRequestsEdge() ZoneRespHTTPRequestsEdge {
+	return ZoneRespHTTPRequestsEdge{
+		HTTPRequestsEdgeCountryHost: z.HTTPRequestsEdgeCountryHost,
+		ZoneTag:                     z.ZoneTag,
+	}
+}
+
+// RateLimitGroups returns the zone's rate limit events as ZoneRespRateLimitGroups.
+func (z ZoneRespAnalytics) RateLimitGroups() ZoneRespRateLimitGroups {
+	return ZoneRespRateLimitGroups{
+		RateLimitEventsAdaptiveGroups: z.RateLimitEventsAdaptiveGroups,
+		ZoneTag:                       z.ZoneTag,
+	}
+}
+
+// CloudflareResponseArgo represents the Cloudflare API response for Argo Smart Routing analytics.
+type CloudflareResponseArgo struct {
+	Viewer struct {
+		Zones []ZoneRespArgo `json:"zones"`
+	} `json:"viewer"`
+}
+
+// ZoneRespArgo represents a zone's request counts and response times with and
+// without Argo Smart Routing.
+type ZoneRespArgo struct {
+	ArgoAnalyticsAdaptiveGroups []struct {
+		Count uint64 `json:"count"`
+		Avg   struct {
+			OriginResponseDurationMs float64 `json:"originResponseDurationMs"`
+		} `json:"avg"`
+		Dimensions struct {
+			SmartRouted uint8 `json:"smartRouted"`
+		} `json:"dimensions"`
+	} `json:"argoAnalyticsAdaptiveGroups"`
+
+	ZoneTag string `json:"zoneTag"`
+}
+
+// CloudflareResponseBotScore represents the Cloudflare API response for bot management scores.
+type CloudflareResponseBotScore struct {
+	Viewer struct {
+		Zones []ZoneRespBotScore `json:"zones"`
+	} `json:"viewer"`
+}
+
+// ZoneRespBotScore represents a zone's requests grouped by bot score.
+type ZoneRespBotScore struct {
+	HTTPRequestsBotScore []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			BotScore uint8 `json:"botScore"`
+		} `json:"dimensions"`
+	} `json:"httpRequestsBotScore"`
+
 	ZoneTag string `json:"zoneTag"`
 }
+
+// CloudflareResponseRequestPath represents the Cloudflare API response for requests by URL path.
+type CloudflareResponseRequestPath struct {
+	Viewer struct {
+		Zones []ZoneRespRequestPath `json:"zones"`
+	} `json:"viewer"`
+}
+
+// ZoneRespRequestPath represents a zone's requests grouped by client request path.
+type ZoneRespRequestPath struct {
+	HTTPRequestsPath []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			ClientRequestPath string `json:"clientRequestPath"`
+		} `json:"dimensions"`
+	} `json:"httpRequestsPath"`
+
+	ZoneTag string `json:"zoneTag"`
+}
+
+// CloudflareResponseTurnstile represents the Cloudflare API response for Turnstile analytics.
+type CloudflareResponseTurnstile struct {
+	Viewer struct {
+		Accounts []TurnstileAccount `json:"accounts"`
+	} `json:"viewer"`
+}
+
+// TurnstileAccount represents TurnstileAdaptiveGroups grouped by sitekey and event type.
+type TurnstileAccount struct {
+	TurnstileAdaptiveGroups []struct {
+		Count      uint64 `json:"count"`
+		Dimensions struct {
+			SiteKey   string `json:"siteKey"`
+			EventType string `json:"eventType"` // challenge_issued, challenge_solved, ...
+		} `json:"dimensions"`
+	} `json:"turnstileAdaptiveGroups"`
+}
+
+// CloudflareResponseStream represents the Cloudflare API response for Stream analytics.
+type CloudflareResponseStream struct {
+	Viewer struct {
+		Accounts []StreamAccount `json:"accounts"`
+	} `json:"viewer"`
+}
+
+// StreamAccount represents Stream minutes viewed and delivered bandwidth for an account.
+type StreamAccount struct {
+	StreamMinutesViewedAdaptiveGroups []struct {
+		Sum struct {
+			MinutesViewed float64 `json:"minutesViewed"`
+		} `json:"sum"`
+	} `json:"streamMinutesViewedAdaptiveGroups"`
+
+	StreamBandwidth []struct {
+		Sum struct {
+			EgressBytes uint64 `json:"egressBytes"`
+		} `json:"sum"`
+	} `json:"streamBandwidth"`
+}
+
+// CloudflareResponseImages represents the Cloudflare API response for Images analytics.
+type CloudflareResponseImages struct {
+	Viewer struct {
+		Accounts []ImagesAccount `json:"accounts"`
+	} `json:"viewer"`
+}
+
+// ImagesAccount represents Images requests and transformations for an account.
+type ImagesAccount struct {
+	ImagesRequestsAdaptiveGroups []struct {
+		Count uint64 `json:"count"`
+	} `json:"imagesRequestsAdaptiveGroups"`
+
+	ImagesUniqueTransformations []struct {
+		Count uint64 `json:"count"`
+	} `json:"imagesUniqueTransformations"`
+}
+
+// CloudflareResponseDurableObjects represents the Cloudflare API response for Durable Objects invocations.
+type CloudflareResponseDurableObjects struct {
+	Viewer struct {
+		Accounts []DurableObjectsAccount `json:"accounts"`
+	} `json:"viewer"`
+}
+
+// DurableObjectsAccount represents DurableObjectsInvocationsAdaptiveGroups grouped by namespace.
+type DurableObjectsAccount struct {
+	DurableObjectsInvocationsAdaptiveGroups []struct {
+		Sum struct {
+			Requests uint64 `json:"requests"`
+		} `json:"sum"`
+		Dimensions struct {
+			NamespaceID string `json:"namespaceId"`
+		} `json:"dimensions"`
+	} `json:"durableObjectsInvocationsAdaptiveGroups"`
+}
+
+// CloudflareResponseQueues represents the Cloudflare API response for Queue backlog.
+type CloudflareResponseQueues struct {
+	Viewer struct {
+		Accounts []QueuesAccount `json:"accounts"`
+	} `json:"viewer"`
+}
+
+// QueuesAccount represents QueueBacklogAdaptiveGroups grouped by queue.
+type QueuesAccount struct {
+	QueueBacklogAdaptiveGroups []struct {
+		Avg struct {
+			Messages float64 `json:"messages"`
+		} `json:"avg"`
+		Dimensions struct {
+			QueueID string `json:"queueId"`
+		} `json:"dimensions"`
+	} `json:"queueBacklogAdaptiveGroups"`
+}
+
+// DurableObjectNamespacesResponse is the list of Durable Object namespaces of an account.
+type DurableObjectNamespacesResponse struct {
+	Result []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"result"`
+}
+
+// QueuesResponse is the list of Queues of an account.
+type QueuesResponse struct {
+	Result []struct {
+		QueueID   string `json:"queue_id"`
+		QueueName string `json:"queue_name"`
+	} `json:"result"`
+}
+
+// GraphQLExtensions is the optional extensions object of a GraphQL response.
+// Cloudflare may report the analytics query cost there.
+type GraphQLExtensions struct {
+	Extensions struct {
+		Cost *struct {
+			RowsRead *float64 `json:"rowsRead"`
+		} `json:"cost"`
+	} `json:"extensions"`
+}
+
+// RowsRead returns the rows read by the query, if the response reported it.
+func (e GraphQLExtensions) RowsRead() (float64, bool) {
+	if e.Extensions.Cost == nil || e.Extensions.Cost.RowsRead == nil {
+		return 0, false
+	}
+	return *e.Extensions.Cost.RowsRead, true
+}
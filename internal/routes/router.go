@@ -2,36 +2,279 @@ package routes
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
-	"github.com/gammazero/workerpool"
 	"github.com/gin-gonic/gin"
+	"github.com/lablabs/cloudflare-exporter/internal/client"
+	cloudflareAPI "github.com/lablabs/cloudflare-exporter/internal/cloudflare"
 	"github.com/lablabs/cloudflare-exporter/internal/handlers"
+	"github.com/lablabs/cloudflare-exporter/internal/logging"
 	"github.com/lablabs/cloudflare-exporter/internal/metrics"
 	"github.com/lablabs/cloudflare-exporter/internal/middlewares"
-	logging "github.com/sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/viper"
 )
 
 // RunExporter starts the metric exporter and serves metrics on the /metrics endpoint
 func RunExporter() {
 
-	// Log the beginning of the exporter setup
-	logging.Info("Starting metric exporter setup version : 1.11")
+	configureExporter()
+
+	adminListen := viper.GetString("admin_listen")
+	r, admin := newEngines(adminListen != "")
+
+	// Stop scraping and serving on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Pick up rotated tokens without a restart
+	if path := viper.GetString("cf_api_token_file"); path != "" {
+		if err := cloudflareAPI.WatchTokenFile(ctx, path); err != nil {
+			logging.Fatal("Error watching CF_API_TOKEN_FILE", map[string]interface{}{"error": err.Error()})
+		}
+	}
+
+	// Optionally mirror every scrape to an OTLP collector
+	var otlpPusher *metrics.OTLPPusher
+	if endpoint := viper.GetString("otlp_endpoint"); endpoint != "" {
+		var err error
+		otlpPusher, err = metrics.NewOTLPPusher(ctx, endpoint, prometheus.DefaultGatherer)
+		if err != nil {
+			logging.Fatal("Error creating OTLP exporter", map[string]interface{}{"error": err.Error()})
+		}
+		logging.Info("Pushing metrics to OTLP endpoint", map[string]interface{}{"endpoint": endpoint})
+	}
+
+	// Start the improved periodic metric fetcher
+	exporterDone := make(chan struct{})
+	go func() {
+		startMetricsExporter(ctx, otlpPusher)
+		close(exporterDone)
+	}()
+
+	// Start the Gin servers
+	servers := []*http.Server{{Addr: viper.GetString("listen"), Handler: r}}
+	if adminListen != "" {
+		servers = append(servers, &http.Server{Addr: adminListen, Handler: admin})
+	}
+	for _, srv := range servers {
+		go func() {
+			logging.Info("Beginning to serve", map[string]interface{}{"listen": srv.Addr})
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logging.Fatal("Error starting server", map[string]interface{}{"error": err.Error()})
+			}
+		}()
+	}
+	// The loopback pprof listener is best effort and never stops the exporter
+	if adminListen == "" {
+		pprofServer := &http.Server{Addr: pprofListen, Handler: admin}
+		go func() {
+			if err := pprofServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logging.ErrorErr("pprof server stopped", err)
+			}
+		}()
+		servers = append(servers, pprofServer)
+	}
+
+	<-ctx.Done()
+	logging.Info("Shutting down", nil)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logging.ErrorErr("Server shutdown failed", err)
+		}
+	}
+	<-exporterDone
+
+	if otlpPusher != nil {
+		if err := otlpPusher.Shutdown(shutdownCtx); err != nil {
+			logging.ErrorErr("OTLP exporter shutdown failed", err)
+		}
+	}
+}
+
+// pprofListen serves pprof when no admin_listen is set. It is bound to
+// loopback so profiles are never exposed next to the metrics.
+const pprofListen = "localhost:6060"
 
+// newEngines builds the engine served on listen and a second one for
+// admin_listen with the health, readiness and pprof endpoints. Without
+// separateAdmin the probes stay on the first engine and the second one only
+// serves pprof, on pprofListen.
+func newEngines(separateAdmin bool) (*gin.Engine, *gin.Engine) {
+	r := newEngine()
+	registerMetricsRoutes(r)
+
+	admin := newEngine()
+	if separateAdmin {
+		registerAdminRoutes(admin)
+	} else {
+		registerAdminRoutes(r)
+	}
+	admin.GET("/debug/pprof/*profile", handlers.Pprof)
+	logging.Info("pprof endpoint registered", map[string]interface{}{"path": "/debug/pprof/"})
+	return r, admin
+}
+
+// configureGinMode sets the gin mode from gin_mode. It must be called before
+// the engines are created.
+func configureGinMode() {
+	mode := viper.GetString("gin_mode")
+	switch mode {
+	case gin.ReleaseMode, gin.DebugMode, gin.TestMode:
+		gin.SetMode(mode)
+	default:
+		logging.Fatal("GIN_MODE must be one of release, debug or test", map[string]interface{}{"gin_mode": mode})
+	}
+}
+
+func newEngine() *gin.Engine {
+	r := gin.New()
+
+	// gin's request log bypasses logrus, keep it to debug mode
+	if gin.IsDebugging() {
+		r.Use(gin.Logger())
+	}
+	r.Use(gin.Recovery())
+	r.Use(middlewares.CORS())      // For handling CORS requests
+	r.Use(handlers.ErrorHandler()) // for hanfling error
+	return r
+}
+
+// registerMetricsRoutes registers the metrics and snapshot endpoints.
+func registerMetricsRoutes(r *gin.Engine) {
 	cfgMetricsPath := viper.GetString("metrics_path")
 
-	if !(len(viper.GetString("cf_api_token")) > 0 || (len(viper.GetString("cf_api_email")) > 0 && len(viper.GetString("cf_api_key")) > 0)) {
-		logging.Fatal("Please provide CF_API_KEY+CF_API_EMAIL or CF_API_TOKEN")
+	// Define /metrics route, optionally answering 503 until the first scrape finished
+	if viper.GetBool("metrics_warmup_gate") {
+		r.GET(cfgMetricsPath, middlewares.WarmupGate(metrics.FirstScrapeDone), metrics.Handler)
+	} else {
+		r.GET(cfgMetricsPath, metrics.Handler)
+	}
+
+	logging.Info("Metrics endpoint registered", map[string]interface{}{"path": cfgMetricsPath})
+
+	// Every metric name the exporter can expose, for composing metrics_denylist
+	r.GET("/metrics/available", handlers.AvailableMetrics(func() []string {
+		return metrics.BuildAllMetricsSet().Names()
+	}))
+	logging.Info("Available metrics endpoint registered", map[string]interface{}{"path": "/metrics/available"})
+
+	// JSON summary of the last scrape, behind the optional bearer token
+	r.GET("/snapshot", middlewares.BearerAuth(viper.GetString("web_auth_token")), handlers.Snapshot(func() interface{} {
+		return metrics.LastSnapshot()
+	}))
+	logging.Info("Snapshot endpoint registered", map[string]interface{}{"path": "/snapshot"})
+}
+
+// registerAdminRoutes registers the liveness and readiness probes.
+func registerAdminRoutes(r *gin.Engine) {
+	// Liveness probe; ?verbose=1 adds last scrape timing and errors
+	r.GET("/health", handlers.VerboseHealthCheck(func() interface{} {
+		return metrics.LastScrapeStatus()
+	}))
+	logging.Info("Health check endpoint registered", map[string]interface{}{"path": "/health"})
+
+	// Readiness reports 503 until a recent FetchMetrics cycle has succeeded
+	r.GET("/ready", handlers.ReadinessCheck(metrics.LastScrapeSuccess))
+	logging.Info("Readiness endpoint registered", map[string]interface{}{"path": "/ready"})
+}
+
+// configureExporter validates the configuration, sets up the HTTP transport
+// and API endpoints, and registers the metrics.
+func configureExporter() {
+	logging.InitializeLogger()
+
+	// Log the beginning of the exporter setup
+	logging.Info("Starting metric exporter setup", map[string]interface{}{"version": metrics.Version})
+
+	if path := viper.GetString("cf_api_token_file"); path != "" {
+		if err := cloudflareAPI.LoadTokenFile(path); err != nil {
+			logging.Fatal("Error loading CF_API_TOKEN_FILE", map[string]interface{}{"error": err.Error()})
+		}
+	}
+	configureGinMode()
+
+	if !(len(viper.GetString("cf_api_token")) > 0 || len(viper.GetString("cf_api_token_file")) > 0 || (len(viper.GetString("cf_api_email")) > 0 && len(viper.GetString("cf_api_key")) > 0)) {
+		logging.Fatal("Please provide CF_API_KEY+CF_API_EMAIL or CF_API_TOKEN", nil)
 	}
 	if viper.GetInt("cf_batch_size") < 1 || viper.GetInt("cf_batch_size") > 10 {
-		logging.Fatal("CF_BATCH_SIZE must be between 1 and 10")
+		logging.Fatal("CF_BATCH_SIZE must be between 1 and 10", nil)
+	}
+	if g := viper.GetString("cf_group_granularity"); g != "1m" && g != "1h" {
+		logging.Fatal("CF_GROUP_GRANULARITY must be 1m or 1h", nil)
+	}
+	if viper.GetInt("cf_request_timeout") < 1 || viper.GetInt("cf_request_timeout") > 300 {
+		logging.Fatal("CF_REQUEST_TIMEOUT must be between 1 and 300", nil)
+	}
+	if viper.GetInt("cf_stagger_cycles") < 0 || viper.GetInt("cf_stagger_cycles") > 60 {
+		logging.Fatal("CF_STAGGER_CYCLES must be between 0 and 60", nil)
+	}
+	if viper.GetInt("cf_stagger_cycles") > 1 {
+		logging.Warn("Zones are staggered across scrapes; each zone is queried every cycles minutes over the time since its last scrape", map[string]interface{}{
+			"cycles": viper.GetInt("cf_stagger_cycles"),
+		})
+	}
+	if viper.GetInt("cf_api_max_retries") < 1 || viper.GetInt("cf_api_max_retries") > 10 {
+		logging.Fatal("CF_API_MAX_RETRIES must be between 1 and 10", nil)
+	}
+	if viper.GetInt("scrape_timeout") < 0 || viper.GetInt("scrape_timeout") > 3600 {
+		logging.Fatal("SCRAPE_TIMEOUT must be between 0 and 3600", nil)
+	}
+	if viper.GetInt("cf_retry_budget") < 0 || viper.GetInt("cf_retry_budget") > 10000 {
+		logging.Fatal("CF_RETRY_BUDGET must be between 0 and 10000", nil)
+	}
+	if viper.GetInt("cf_api_retry_backoff") < 0 || viper.GetInt("cf_api_retry_backoff") > 60 {
+		logging.Fatal("CF_API_RETRY_BACKOFF must be between 0 and 60", nil)
+	}
+	if viper.GetInt("account_concurrency") < 1 || viper.GetInt("account_concurrency") > 100 {
+		logging.Fatal("ACCOUNT_CONCURRENCY must be between 1 and 100", nil)
+	}
+	if viper.GetInt("zone_concurrency") < 1 || viper.GetInt("zone_concurrency") > 100 {
+		logging.Fatal("ZONE_CONCURRENCY must be between 1 and 100", nil)
+	}
+	if viper.GetInt("backfill_minutes") < 0 || viper.GetInt("backfill_minutes") > 1440 {
+		logging.Fatal("BACKFILL_MINUTES must be between 0 and 1440", nil)
+	}
+	if viper.GetInt("rest_batch_size") < 1 || viper.GetInt("rest_batch_size") > 100 {
+		logging.Fatal("REST_BATCH_SIZE must be between 1 and 100", nil)
+	}
+	if viper.GetInt("ssl_fetch_concurrency") < 1 || viper.GetInt("ssl_fetch_concurrency") > 50 {
+		logging.Fatal("SSL_FETCH_CONCURRENCY must be between 1 and 50", nil)
+	}
+	if viper.GetInt("ssl_fetch_timeout") < 1 || viper.GetInt("ssl_fetch_timeout") > 120 {
+		logging.Fatal("SSL_FETCH_TIMEOUT must be between 1 and 120", nil)
+	}
+	if viper.GetInt("ssl_fetch_retries") < 1 || viper.GetInt("ssl_fetch_retries") > 10 {
+		logging.Fatal("SSL_FETCH_RETRIES must be between 1 and 10", nil)
+	}
+	if viper.GetInt("http_max_idle_conns") < 1 {
+		logging.Fatal("HTTP_MAX_IDLE_CONNS must be at least 1", nil)
+	}
+	if viper.GetInt("http_max_idle_conns_per_host") < 1 || viper.GetInt("http_max_idle_conns_per_host") > viper.GetInt("http_max_idle_conns") {
+		logging.Fatal("HTTP_MAX_IDLE_CONNS_PER_HOST must be between 1 and HTTP_MAX_IDLE_CONNS", nil)
+	}
+	if viper.GetInt("http_idle_conn_timeout") < 1 {
+		logging.Fatal("HTTP_IDLE_CONN_TIMEOUT must be at least 1", nil)
+	}
+
+	if err := ConfigureAPIClient(); err != nil {
+		logging.Fatal("Error configuring the Cloudflare API client", map[string]interface{}{"error": err.Error()})
+	}
+	logging.Info("Using Cloudflare GraphQL endpoint", map[string]interface{}{"endpoint": viper.GetString("cf_graphql_endpoint")})
+	logging.Info("Using Cloudflare REST API base URL", map[string]interface{}{"base_url": viper.GetString("cf_api_base_url")})
+	if err := cloudflareAPI.SetZoneScrapeDelays(viper.GetString("cf_zone_scrape_delays")); err != nil {
+		logging.Fatal("Error parsing CF_ZONE_SCRAPE_DELAYS", map[string]interface{}{"error": err.Error()})
 	}
-	customFormatter := new(logging.TextFormatter)
-	customFormatter.TimestampFormat = "2006-01-02 15:04:05"
-	logging.SetFormatter(customFormatter)
-	customFormatter.FullTimestamp = true
 
 	metricsDenylist := []string{}
 	if len(viper.GetString("metrics_denylist")) > 0 {
@@ -41,57 +284,169 @@ func RunExporter() {
 	if err != nil {
 		logging.Fatal("Error building denied metrics set", map[string]interface{}{"error": err.Error()})
 	}
+	hostLabelMetrics := []string{}
+	if len(viper.GetString("host_label_metrics")) > 0 {
+		hostLabelMetrics = strings.Split(viper.GetString("host_label_metrics"), ",")
+	}
+	hostLabelMetricsSet, err := metrics.BuildHostLabelMetricsSet(hostLabelMetrics)
+	if err != nil {
+		logging.Fatal("Error building host label metrics set", map[string]interface{}{"error": err.Error()})
+	}
+	metrics.SetHostLabelMetrics(hostLabelMetricsSet)
+	if err := metrics.SetWorkerScriptPattern(viper.GetString("worker_script_pattern")); err != nil {
+		logging.Fatal("Error parsing WORKER_SCRIPT_PATTERN", map[string]interface{}{"error": err.Error()})
+	}
+	metrics.SetZoneIDLabel(viper.GetBool("zone_id_label"))
+	metrics.SetSampleTimestamps(viper.GetBool("sample_timestamps"))
 	metrics.MustRegisterMetrics(deniedMetricsSet)
 	logging.Info("Metrics registered successfully", map[string]interface{}{"metricsDenylist": metricsDenylist})
+}
 
-	// Initialize Gin
-	r := gin.Default()
+// ConfigureAPIClient sets up the shared HTTP transport (connection pool, egress
+// TLS and proxy), the User-Agent and the API endpoints from the configuration.
+// The exporter and the credential check both call it.
+func ConfigureAPIClient() error {
+	egressTLS, err := client.NewEgressTLSConfig(
+		viper.GetString("egress_client_cert"),
+		viper.GetString("egress_client_key"),
+		viper.GetString("egress_ca_bundle"),
+	)
+	if err != nil {
+		return fmt.Errorf("building egress TLS config: %w", err)
+	}
+	proxy, err := client.NewProxyFunc(viper.GetString("proxy_url"))
+	if err != nil {
+		return fmt.Errorf("configuring PROXY_URL: %w", err)
+	}
+	client.ConfigureTransport(client.TransportOptions{
+		MaxIdleConns:        viper.GetInt("http_max_idle_conns"),
+		MaxIdleConnsPerHost: viper.GetInt("http_max_idle_conns_per_host"),
+		IdleConnTimeout:     time.Duration(viper.GetInt("http_idle_conn_timeout")) * time.Second,
+		TLSConfig:           egressTLS,
+		Proxy:               proxy,
+	})
 
-	r.Use(middlewares.CORS())      // For handling CORS requests
-	r.Use(handlers.ErrorHandler()) // for hanfling error
+	userAgent := viper.GetString("user_agent")
+	if userAgent == "" {
+		userAgent = "cloudflare-exporter/" + metrics.Version
+	}
+	client.SetUserAgent(userAgent)
 
-	// Define /metrics route
-	r.GET(cfgMetricsPath, metrics.Handler)
+	cloudflareAPI.SetGraphQLEndpoint(viper.GetString("cf_graphql_endpoint"))
+	cloudflareAPI.SetAPIBaseURL(viper.GetString("cf_api_base_url"))
+	return nil
+}
+
+// RunPush runs a single scrape, pushes the result to the configured
+// remote-write endpoint and/or Pushgateway, then returns.
+func RunPush(ctx context.Context) error {
+	remoteWriteURL := viper.GetString("remote_write_url")
+	pushgatewayURL := viper.GetString("pushgateway_url")
+	if remoteWriteURL == "" && pushgatewayURL == "" {
+		return errors.New("push mode requires REMOTE_WRITE_URL or PUSHGATEWAY_URL")
+	}
+	header, err := metrics.ParsePushHeaders(viper.GetString("push_headers"))
+	if err != nil {
+		return err
+	}
 
-	logging.Info("Metrics endpoint registered at ", cfgMetricsPath)
+	configureExporter()
 
-	// Use the HealthCheck function for the health endpoint
-	r.GET("/health", handlers.HealthCheck)
-	logging.Info("Health check endpoint registered at /health")
+	pools := metrics.NewPools(viper.GetInt("account_concurrency"), viper.GetInt("zone_concurrency"))
+	defer pools.Stop()
 
-	// Start the improved periodic metric fetcher
-	go startMetricsExporter()
+	// Push whatever was collected even when some fetches failed
+	fetchErr := metrics.FetchMetrics(ctx, pools)
+	if fetchErr != nil {
+		logging.ErrorErr("Fetch failed", fetchErr)
+	}
 
-	// Start the Gin server
-	logging.Info("Beginning to serve metrics on ", viper.GetString("listen"))
-	if err := r.Run(viper.GetString("listen")); err != nil {
-		logging.Fatal("Error starting server: ", map[string]interface{}{"error": err.Error()})
+	var pushErrs []error
+	if remoteWriteURL != "" {
+		if err := metrics.RemoteWrite(ctx, remoteWriteURL, header, prometheus.DefaultGatherer); err != nil {
+			pushErrs = append(pushErrs, err)
+		} else {
+			logging.Info("Pushed metrics via remote write", map[string]interface{}{"url": remoteWriteURL})
+		}
 	}
+	if pushgatewayURL != "" {
+		if err := metrics.PushGateway(ctx, pushgatewayURL, viper.GetString("push_job"), header, prometheus.DefaultGatherer); err != nil {
+			pushErrs = append(pushErrs, err)
+		} else {
+			logging.Info("Pushed metrics to pushgateway", map[string]interface{}{"url": pushgatewayURL})
+		}
+	}
+
+	return errors.Join(append(pushErrs, fetchErr)...)
 }
 
-func startMetricsExporter() {
-	ctx, cancel := context.WithCancel(context.Background())
+// startMetricsExporter scrapes on startup and then on every tick until ctx is
+// cancelled. Only one scrape runs at a time; ticks that overlap a running
+// scrape are skipped. When otlpPusher is set, metrics are pushed after every scrape.
+func startMetricsExporter(ctx context.Context, otlpPusher *metrics.OTLPPusher) {
+	// Worker pools reused across scrapes
+	pools := metrics.NewPools(viper.GetInt("account_concurrency"), viper.GetInt("zone_concurrency"))
+	defer pools.Stop()
+
+	runScrapeLoop(ctx, 60*time.Second, func() {
+		// Cancel the whole cycle after scrape_timeout so a hung call can't stall it
+		err := fetchWithTimeout(ctx, time.Duration(viper.GetInt("scrape_timeout"))*time.Second, func(ctx context.Context) error {
+			return metrics.FetchMetrics(ctx, pools)
+		})
+		if err != nil {
+			logging.ErrorErr("Fetch failed", err)
+		}
+
+		if otlpPusher != nil {
+			if err := otlpPusher.Push(ctx); err != nil {
+				logging.ErrorErr("OTLP push failed", err)
+			}
+		}
+	})
+}
+
+// fetchWithTimeout runs fetch with a context cancelled after timeout, counting
+// the scrape as timed out if it was. A timeout of 0 disables it.
+func fetchWithTimeout(ctx context.Context, timeout time.Duration, fetch func(context.Context) error) error {
+	if timeout <= 0 {
+		return fetch(ctx)
+	}
+
+	scrapeCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	ticker := time.NewTicker(60 * time.Second)
+	err := fetch(scrapeCtx)
+	if errors.Is(scrapeCtx.Err(), context.DeadlineExceeded) {
+		logging.Warn("Scrape cancelled after SCRAPE_TIMEOUT", map[string]interface{}{
+			"timeout": timeout.String(),
+		})
+		metrics.RecordScrapeTimeout()
+	}
+	return err
+}
+
+// runScrapeLoop runs scrape right away and then on every interval tick until
+// ctx is cancelled, waiting for the in-flight scrape before returning.
+func runScrapeLoop(ctx context.Context, interval time.Duration, scrape func()) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	// Worker pool reused across scrapes
-	pool := workerpool.New(20)
-	defer pool.Stop()
+	start := func() {
+		if !metrics.StartScrape(scrape) {
+			logging.Warn("Previous scrape still running, skipping tick", nil)
+		}
+	}
 
+	// Don't leave /metrics empty until the first tick
+	start()
 	for {
 		select {
 		case <-ctx.Done():
+			// Let the in-flight scrape finish before the pool is stopped
+			metrics.WaitForScrapes()
 			return
 		case <-ticker.C:
-			go func() {
-				// Wrap existing FetchMetrics with context
-				err := metrics.FetchMetrics(ctx, pool)
-				if err != nil {
-					logging.Error("Fetch failed", err)
-				}
-			}()
+			start()
 		}
 	}
 }
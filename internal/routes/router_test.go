@@ -0,0 +1,141 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lablabs/cloudflare-exporter/internal/client"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// setViper sets key to value and restores the previous value when the test ends.
+func setViper(t *testing.T, key string, value interface{}) {
+	t.Helper()
+	prev := viper.Get(key)
+	viper.Set(key, value)
+	t.Cleanup(func() { viper.Set(key, prev) })
+}
+
+func TestRunScrapeLoop_ScrapesBeforeFirstTick(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	scraped := make(chan struct{}, 1)
+	done := make(chan struct{})
+
+	go func() {
+		runScrapeLoop(ctx, time.Hour, func() {
+			select {
+			case scraped <- struct{}{}:
+			default:
+			}
+		})
+		close(done)
+	}()
+
+	select {
+	case <-scraped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no scrape before the first tick")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		assert.Fail(t, "scrape loop did not stop after cancel")
+	}
+}
+
+func TestNewEngines_SeparateAdminRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setViper(t, "metrics_path", "/metrics")
+
+	r, admin := newEngines(true)
+
+	get := func(engine *gin.Engine, path string) int {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, get(r, "/metrics"))
+	assert.Equal(t, http.StatusNotFound, get(r, "/health"))
+	assert.Equal(t, http.StatusNotFound, get(r, "/debug/pprof/"))
+
+	assert.Equal(t, http.StatusOK, get(admin, "/health"))
+	assert.Equal(t, http.StatusOK, get(admin, "/debug/pprof/"))
+	assert.Equal(t, http.StatusNotFound, get(admin, "/metrics"))
+
+	// Without an admin port the probes stay next to the metrics and pprof
+	// keeps its own engine
+	r, admin = newEngines(false)
+	assert.Equal(t, http.StatusOK, get(r, "/health"))
+	assert.Equal(t, http.StatusNotFound, get(r, "/debug/pprof/"))
+	assert.Equal(t, http.StatusOK, get(admin, "/debug/pprof/"))
+	assert.Equal(t, http.StatusNotFound, get(admin, "/health"))
+}
+
+func TestFetchWithTimeout_CancelsSlowFetch(t *testing.T) {
+	start := time.Now()
+	err := fetchWithTimeout(context.Background(), 50*time.Millisecond, func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	// No timeout, the fetch gets the caller's context
+	assert.NoError(t, fetchWithTimeout(context.Background(), 0, func(ctx context.Context) error {
+		_, hasDeadline := ctx.Deadline()
+		assert.False(t, hasDeadline)
+		return nil
+	}))
+}
+
+func TestConfigureGinMode_FromConfig(t *testing.T) {
+	defer gin.SetMode(gin.TestMode)
+
+	setViper(t, "gin_mode", "debug")
+	configureGinMode()
+	assert.Equal(t, gin.DebugMode, gin.Mode())
+	debugHandlers := len(newEngine().Handlers)
+
+	setViper(t, "gin_mode", "release")
+	configureGinMode()
+	assert.Equal(t, gin.ReleaseMode, gin.Mode())
+	// Only debug mode adds gin's request logger
+	assert.Equal(t, debugHandlers-1, len(newEngine().Handlers))
+}
+
+func TestConfigureAPIClient_UserAgent(t *testing.T) {
+	var gotUA string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUA = r.UserAgent()
+	}))
+	defer srv.Close()
+
+	setViper(t, "user_agent", "check-agent/1.0")
+	setViper(t, "http_max_idle_conns", 10)
+	setViper(t, "http_max_idle_conns_per_host", 2)
+	setViper(t, "http_idle_conn_timeout", 30)
+	t.Cleanup(func() { client.SetUserAgent("") })
+
+	assert.NoError(t, ConfigureAPIClient())
+
+	resp, err := (&http.Client{Transport: client.SharedTransport()}).Get(srv.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "check-agent/1.0", gotUA)
+
+	// An invalid proxy is reported instead of ignored
+	setViper(t, "proxy_url", "ftp://proxy.example")
+	assert.Error(t, ConfigureAPIClient())
+}